@@ -0,0 +1,108 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ArgoCDAccessReportSpec defines when an access report of the Argo CD RBAC policy is generated and where it is written to.
+type ArgoCDAccessReportSpec struct {
+	// Schedule is the cron expression the report is generated at, e.g. "0 6 1 */3 *" for quarterly.
+	// The report is generated once, and again on every change of the spec, if not set.
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+	// TimeZone the schedule is evaluated in, e.g. "Europe/Berlin". Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+	// Format of the report (csv, markdown or json).
+	// +kubebuilder:default=csv
+	// +kubebuilder:validation:Enum=csv;markdown;json
+	// +optional
+	Format string `json:"format,omitempty"`
+	// Output is the ConfigMap or Secret the report is written to, in the namespace of the ArgoCDAccessReport.
+	Output AccessReportOutput `json:"output"`
+}
+
+// AccessReportOutput references the ConfigMap or Secret an access report is written to. The report is written to the
+// key report.<format>, the changes since the previous report to changes.<format> and the rows as JSON to rows.json.
+type AccessReportOutput struct {
+	// Kind of the output (ConfigMap or Secret).
+	// +kubebuilder:default=ConfigMap
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// Name of the ConfigMap or Secret. It is created if it does not exist, an existing ConfigMap or Secret
+	// not created by the ArgoCDAccessReport is not written to.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// ArgoCDAccessReportStatus defines the observed state of ArgoCDAccessReport
+type ArgoCDAccessReportStatus struct {
+	// ObservedGeneration is the generation of the spec the last report was generated for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastReportTime is the time the last report was generated.
+	// +optional
+	LastReportTime *metav1.Time `json:"lastReportTime,omitempty"`
+	// NextReportTime is the time the next report is generated, if the report is scheduled.
+	// +optional
+	NextReportTime *metav1.Time `json:"nextReportTime,omitempty"`
+	// Rows is the number of rows of the last report.
+	// +optional
+	Rows int `json:"rows,omitempty"`
+	// Added is the number of rows added since the previous report.
+	// +optional
+	Added int `json:"added,omitempty"`
+	// Removed is the number of rows removed since the previous report.
+	// +optional
+	Removed int `json:"removed,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	// Conditions defines the list of conditions.
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+// +kubebuilder:printcolumn:name="Rows",type=integer,JSONPath=`.status.rows`
+// +kubebuilder:printcolumn:name="Last Report",type=date,JSONPath=`.status.lastReportTime`
+// +genclient
+
+// ArgoCDAccessReport is the Schema for the argocdaccessreports API
+type ArgoCDAccessReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ArgoCDAccessReportSpec   `json:"spec,omitempty"`
+	Status ArgoCDAccessReportStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ArgoCDAccessReportList contains a list of ArgoCDAccessReport
+type ArgoCDAccessReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ArgoCDAccessReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ArgoCDAccessReport{}, &ArgoCDAccessReportList{})
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ArgoCDAccessRequestSpec defines the access requested for a limited time.
+// The spec can't be changed after the request is created.
+type ArgoCDAccessRequestSpec struct {
+	// Reference to the ArgoCDRole requested, in the namespace of the ArgoCDAccessRequest.
+	ArgoCDRoleRef ArgoCDRoleRef `json:"argocdRoleRef"`
+	// Subject the role is requested for.
+	Subject AccessRequestSubject `json:"subject"`
+	// Duration of the access, starting with the approval.
+	Duration metav1.Duration `json:"duration"`
+	// Justification of the request, shown to the approvers.
+	// +kubebuilder:validation:MinLength=1
+	Justification string `json:"justification"`
+}
+
+// AccessRequestSubject defines the subject the role is requested for.
+type AccessRequestSubject struct {
+	// +kubebuilder:validation:Enum=sso;local
+	// Kind of the subject (sso or local).
+	Kind string `json:"kind"`
+	// Name of the subject.
+	Name string `json:"name"`
+}
+
+// AccessRequestPhase is the phase of an ArgoCDAccessRequest.
+// +kubebuilder:validation:Enum=Requested;Approved;Expired
+type AccessRequestPhase string
+
+const (
+	// AccessRequestPhaseRequested requests are waiting for an approval.
+	AccessRequestPhaseRequested AccessRequestPhase = "Requested"
+	// AccessRequestPhaseApproved requests grant the role until status.expiresAt.
+	AccessRequestPhaseApproved AccessRequestPhase = "Approved"
+	// AccessRequestPhaseExpired requests do not grant the role anymore.
+	AccessRequestPhaseExpired AccessRequestPhase = "Expired"
+)
+
+// ArgoCDAccessRequestStatus defines the observed state of ArgoCDAccessRequest
+type ArgoCDAccessRequestStatus struct {
+	// Phase of the request (Requested, Approved or Expired).
+	Phase AccessRequestPhase `json:"phase,omitempty"`
+	// ApprovedBy is the identity that approved the request.
+	ApprovedBy string `json:"approvedBy,omitempty"`
+	// ApprovedAt is the time the request was approved.
+	ApprovedAt *metav1.Time `json:"approvedAt,omitempty"`
+	// ExpiresAt is the time the access is revoked.
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// ArgoCDRoleBinding is the name of the ArgoCDRoleBinding granting the access.
+	ArgoCDRoleBinding string `json:"argocdRoleBinding,omitempty"`
+	// History of the request.
+	History []AccessRequestEvent `json:"history,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	// Conditions defines the list of conditions.
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// AccessRequestEvent is an entry of the history of an ArgoCDAccessRequest.
+type AccessRequestEvent struct {
+	// Phase the request entered.
+	Phase AccessRequestPhase `json:"phase"`
+	// Time the request entered the phase.
+	Time metav1.Time `json:"time"`
+	// By is the identity that moved the request into the phase, empty if done by the operator.
+	// +optional
+	By string `json:"by,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Role",type=string,JSONPath=`.spec.argocdRoleRef.name`
+// +kubebuilder:printcolumn:name="Subject",type=string,JSONPath=`.spec.subject.name`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Expires",type=date,JSONPath=`.status.expiresAt`
+// +genclient
+
+// ArgoCDAccessRequest is the Schema for the argocdaccessrequests API
+type ArgoCDAccessRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+	Spec   ArgoCDAccessRequestSpec   `json:"spec,omitempty"`
+	Status ArgoCDAccessRequestStatus `json:"status,omitempty"`
+}
+
+// CheckApproval returns an error if the approver can't approve the request under any of the policies.
+// The requester can't approve its own request.
+func (r *ArgoCDAccessRequest) CheckApproval(policies []ArgoCDApprovalPolicy, requester, approver string, groups []string) error {
+	if approver == "" {
+		return fmt.Errorf("approver is empty")
+	}
+	if approver == requester {
+		return fmt.Errorf("%s can't approve its own request", approver)
+	}
+	applies := false
+	for _, policy := range policies {
+		if !policy.AppliesTo(r.Spec.ArgoCDRoleRef.Name) {
+			continue
+		}
+		applies = true
+		if policy.IsApprover(approver, groups) && policy.AllowsDuration(r.Spec.Duration) {
+			return nil
+		}
+	}
+	if !applies {
+		return fmt.Errorf("no ArgoCDApprovalPolicy applies to ArgoCDRole %s", r.Spec.ArgoCDRoleRef.Name)
+	}
+	return fmt.Errorf("%s is not allowed to approve %s of ArgoCDRole %s", approver, r.Spec.Duration.Duration, r.Spec.ArgoCDRoleRef.Name)
+}
+
+// AddHistory appends the phase to the history and sets it as current phase
+func (r *ArgoCDAccessRequest) AddHistory(phase AccessRequestPhase, at metav1.Time, by string) {
+	r.Status.Phase = phase
+	r.Status.History = append(r.Status.History, AccessRequestEvent{Phase: phase, Time: at, By: by})
+}
+
+// +kubebuilder:object:root=true
+
+// ArgoCDAccessRequestList contains a list of ArgoCDAccessRequest
+type ArgoCDAccessRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ArgoCDAccessRequest `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ArgoCDAccessRequest{}, &ArgoCDAccessRequestList{})
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ArgoCDApprovalPolicySpec defines who may approve ArgoCDAccessRequests in the namespace of the policy.
+type ArgoCDApprovalPolicySpec struct {
+	// Names of the ArgoCDRoles the policy applies to. Applies to all ArgoCDRoles in the namespace if empty.
+	// +optional
+	ArgoCDRoles []string `json:"argocdRoles,omitempty"`
+	// Approvers allowed to approve ArgoCDAccessRequests for the roles.
+	// +kubebuilder:validation:MinItems=1
+	Approvers []Approver `json:"approvers"`
+	// MaxDuration is the longest duration that can be approved. Not limited if not set.
+	// +optional
+	MaxDuration *metav1.Duration `json:"maxDuration,omitempty"`
+}
+
+// Approver defines a Kubernetes identity allowed to approve ArgoCDAccessRequests.
+type Approver struct {
+	// +kubebuilder:validation:Enum=user;group
+	// Kind of the approver (user or group).
+	Kind string `json:"kind"`
+	// Name of the Kubernetes user or group.
+	Name string `json:"name"`
+}
+
+const (
+	// ApproverKindUser is an approver matched by the Kubernetes user name.
+	ApproverKindUser = "user"
+	// ApproverKindGroup is an approver matched by a Kubernetes group.
+	ApproverKindGroup = "group"
+)
+
+// +kubebuilder:object:root=true
+// +genclient
+
+// ArgoCDApprovalPolicy is the Schema for the argocdapprovalpolicies API
+type ArgoCDApprovalPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ArgoCDApprovalPolicySpec `json:"spec,omitempty"`
+}
+
+// AppliesTo returns true if the policy applies to access requests for the ArgoCDRole
+func (p *ArgoCDApprovalPolicy) AppliesTo(roleName string) bool {
+	return len(p.Spec.ArgoCDRoles) == 0 || slices.Contains(p.Spec.ArgoCDRoles, roleName)
+}
+
+// IsApprover returns true if the user or one of the groups is an approver of the policy
+func (p *ArgoCDApprovalPolicy) IsApprover(user string, groups []string) bool {
+	return slices.ContainsFunc(p.Spec.Approvers, func(a Approver) bool {
+		switch a.Kind {
+		case ApproverKindUser:
+			return a.Name == user
+		case ApproverKindGroup:
+			return slices.Contains(groups, a.Name)
+		}
+		return false
+	})
+}
+
+// AllowsDuration returns true if the duration does not exceed the max duration of the policy
+func (p *ArgoCDApprovalPolicy) AllowsDuration(d metav1.Duration) bool {
+	return p.Spec.MaxDuration == nil || d.Duration <= p.Spec.MaxDuration.Duration
+}
+
+// +kubebuilder:object:root=true
+
+// ArgoCDApprovalPolicyList contains a list of ArgoCDApprovalPolicy
+type ArgoCDApprovalPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ArgoCDApprovalPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ArgoCDApprovalPolicy{}, &ArgoCDApprovalPolicyList{})
+}
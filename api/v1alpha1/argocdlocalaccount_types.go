@@ -0,0 +1,186 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ArgoCDLocalAccountSpec defines the desired state of an Argo CD local account.
+// The name of the ArgoCDLocalAccount is used as the name of the account.
+type ArgoCDLocalAccountSpec struct {
+	// Capabilities of the account (apiKey, login).
+	// +kubebuilder:default={login}
+	// +kubebuilder:validation:MinItems=1
+	Capabilities []AccountCapability `json:"capabilities,omitempty"`
+	// Enabled defines if the account is enabled. Defaults to true.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+	// Reference to the Secret holding the password of the account, in the namespace of the ArgoCDLocalAccount.
+	// The account has no password if not set. Password changes are applied immediately if the Secret has the
+	// rbac-operator.argoproj-labs.io/secret label, otherwise with the next periodic reconcile.
+	// +optional
+	PasswordSecretRef *SecretKeyRef `json:"passwordSecretRef,omitempty"`
+	// API tokens generated for the account. Requires the apiKey capability.
+	// +optional
+	// +listType=map
+	// +listMapKey=id
+	Tokens []LocalAccountToken `json:"tokens,omitempty"`
+}
+
+// AccountCapability is a capability of an Argo CD local account.
+// +kubebuilder:validation:Enum=apiKey;login
+type AccountCapability string
+
+const (
+	// AccountCapabilityAPIKey allows the account to generate API tokens.
+	AccountCapabilityAPIKey AccountCapability = "apiKey"
+	// AccountCapabilityLogin allows the account to log in to the UI.
+	AccountCapabilityLogin AccountCapability = "login"
+)
+
+// SecretKeyRef defines the reference to a key of a Secret.
+type SecretKeyRef struct {
+	// Name of the Secret.
+	Name string `json:"name"`
+	// Key in the Secret. Defaults to "password".
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// LocalAccountToken defines an API token generated for the local account.
+type LocalAccountToken struct {
+	// ID of the token. Every issued token gets a unique ID "<id>-<issued-at>".
+	ID string `json:"id"`
+	// ExpiresIn defines how long the token is valid. The token does not expire if not set.
+	// An expired token is reissued.
+	// +optional
+	ExpiresIn *metav1.Duration `json:"expiresIn,omitempty"`
+	// Name of the Secret the token is written to (key "token"). The Secret is created in the namespace of the ArgoCDLocalAccount.
+	SecretName string `json:"secretName"`
+}
+
+// ArgoCDLocalAccountStatus defines the observed state of ArgoCDLocalAccount.
+type ArgoCDLocalAccountStatus struct {
+	// PasswordMtime is the time the password of the account was last changed.
+	PasswordMtime *metav1.Time `json:"passwordMtime,omitempty"`
+	// Tokens issued for the account.
+	// +listType=map
+	// +listMapKey=id
+	Tokens []LocalAccountTokenStatus `json:"tokens,omitempty"`
+	// Bindings referencing the account as local subject.
+	// +listType=map
+	// +listMapKey=kind
+	// +listMapKey=name
+	Bindings []LocalAccountBindingStatus `json:"bindings,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	// Conditions defines the list of conditions.
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// LocalAccountTokenStatus defines the observed state of an API token of the local account.
+type LocalAccountTokenStatus struct {
+	// ID of the token in spec.tokens.
+	ID string `json:"id"`
+	// TokenID is the ID (jti) of the currently issued token.
+	TokenID string `json:"tokenID"`
+	// SecretName is the name of the Secret the token is written to.
+	SecretName string `json:"secretName"`
+	// IssuedAt is the time the current token was issued.
+	IssuedAt metav1.Time `json:"issuedAt"`
+	// ExpiresAt is the time the current token expires.
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+}
+
+// LocalAccountBindingStatus defines whether a binding referencing the local account is valid.
+type LocalAccountBindingStatus struct {
+	// Kind of the binding (ArgoCDRoleBinding or ArgoCDProjectRoleBinding).
+	Kind string `json:"kind"`
+	// Name of the binding, "<namespace>/<name>".
+	Name string `json:"name"`
+	// Valid is true if the account is enabled and the role referenced by the binding exists.
+	Valid bool `json:"valid"`
+	// Message describing why the binding is not valid.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Capabilities",type=string,JSONPath=`.spec.capabilities`
+// +kubebuilder:printcolumn:name="Enabled",type=boolean,JSONPath=`.spec.enabled`
+// +genclient
+
+// ArgoCDLocalAccount is the Schema for the argocdlocalaccounts API.
+type ArgoCDLocalAccount struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ArgoCDLocalAccountSpec   `json:"spec,omitempty"`
+	Status ArgoCDLocalAccountStatus `json:"status,omitempty"`
+}
+
+// IsEnabled returns true if the account is enabled
+func (a *ArgoCDLocalAccount) IsEnabled() bool {
+	return a.Spec.Enabled == nil || *a.Spec.Enabled
+}
+
+// HasCapability returns true if the account has the capability
+func (a *ArgoCDLocalAccount) HasCapability(capability AccountCapability) bool {
+	return slices.Contains(a.Spec.Capabilities, capability)
+}
+
+// IsBeingDeleted returns true if a deletion timestamp is set
+func (a *ArgoCDLocalAccount) IsBeingDeleted() bool {
+	return !a.DeletionTimestamp.IsZero()
+}
+
+// ArgoCDLocalAccountFinalizerName is the name of the finalizer used to remove the account from Argo CD
+const ArgoCDLocalAccountFinalizerName = "rbac-operator.argoproj-labs.io/finalizer"
+
+// HasFinalizer returns true if the account has the finalizer
+func (a *ArgoCDLocalAccount) HasFinalizer(finalizerName string) bool {
+	return slices.Contains(a.Finalizers, finalizerName)
+}
+
+// AddFinalizer adds the finalizer to the account
+func (a *ArgoCDLocalAccount) AddFinalizer(finalizerName string) {
+	a.Finalizers = append(a.Finalizers, finalizerName)
+}
+
+// RemoveFinalizer removes the finalizer from the account
+func (a *ArgoCDLocalAccount) RemoveFinalizer(finalizerName string) {
+	a.Finalizers = slices.DeleteFunc(a.Finalizers, func(s string) bool {
+		return s == finalizerName
+	})
+}
+
+// +kubebuilder:object:root=true
+
+// ArgoCDLocalAccountList contains a list of ArgoCDLocalAccount.
+type ArgoCDLocalAccountList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ArgoCDLocalAccount `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ArgoCDLocalAccount{}, &ArgoCDLocalAccountList{})
+}
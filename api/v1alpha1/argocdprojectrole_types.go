@@ -36,8 +36,10 @@ type ProjectRule struct {
 	// Target resource type.
 	Resource string `json:"resource"`
 	// Verbs define the operations that are being performed on the resource.
+	// +kubebuilder:validation:items:Pattern=`^[^,\r\n]+$`
 	Verbs []string `json:"verbs"`
 	// List of resource's objects the permissions are granted for.
+	// +kubebuilder:validation:items:Pattern=`^[^,\r\n]+$`
 	Objects []string `json:"objects"`
 }
 
@@ -49,6 +51,34 @@ type ArgoCDProjectRoleStatus struct {
 	// +listMapKey=type
 	// Conditions defines the list of conditions.
 	Conditions []Condition `json:"conditions,omitempty"`
+	// +listType=map
+	// +listMapKey=appProject
+	// AppProjects defines the sync state of the role in each bound AppProject.
+	AppProjects []AppProjectSyncStatus `json:"appProjects,omitempty"`
+	// PlannedChanges are the changes to the Argo CD RBAC ConfigMap and AppProjects not written in dry-run mode,
+	// as "<kind> <namespace>/<name> <key>: +<line>" or "-<line>".
+	PlannedChanges []string `json:"plannedChanges,omitempty"`
+	// AppliedGeneration is the generation of the spec rendered into the policy.
+	AppliedGeneration int64 `json:"appliedGeneration,omitempty"`
+	// AppliedSpec is the spec rendered into the policy while spec changes wait for approval,
+	// if the role has the require-approval label.
+	AppliedSpec *ArgoCDProjectRoleSpec `json:"appliedSpec,omitempty"`
+	// PendingChanges are the policy lines added ("+") and removed ("-") by the spec changes waiting for approval.
+	PendingChanges []string `json:"pendingChanges,omitempty"`
+}
+
+// AppProjectSyncStatus defines the sync state of the role in a bound AppProject.
+type AppProjectSyncStatus struct {
+	// AppProject the role is bound to. AppProjects outside of the role's namespace are listed as "<namespace>/<name>".
+	AppProject string `json:"appProject"`
+	// Synced is true if the role in the AppProject matches the spec of the ArgoCDProjectRole.
+	Synced bool `json:"synced"`
+	// ObservedGeneration is the .metadata.generation of the ArgoCDProjectRole the AppProject was synced with.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Message containing details about the last sync, if any.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
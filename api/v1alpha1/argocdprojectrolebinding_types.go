@@ -28,14 +28,54 @@ type ArgoCDProjectRoleBindingSpec struct {
 	// +kubebuilder:validation:MinItems=1
 	Subjects             []AppProjectSubject  `json:"subjects"`
 	ArgoCDProjectRoleRef ArgoCDProjectRoleRef `json:"argocdProjectRoleRef"`
+	// NotBefore is the time the role is bound to the AppProjects from. Bound immediately if not set.
+	// +optional
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+	// ExpiresAt is the time the role is removed from the AppProjects. Never removed if not set.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// Schedules restrict the binding to recurring windows. The role is granted only while one of the windows is open.
+	// Not restricted if empty.
+	// +optional
+	Schedules []Schedule `json:"schedules,omitempty"`
 }
 
 // AppProjectSubject defines the subject being bound to ArgoCDProjectRole.
 type AppProjectSubject struct {
 	// Reference to the AppProject the ArgoCDRole is bound to.
+	// It is rendered into the global policy for users, so it must not contain commas or line breaks.
+	// +kubebuilder:validation:Pattern=`^[^,\r\n]+$`
 	AppProjectRef string `json:"appProjectRef"`
+	// Namespace of the referenced AppProject. Defaults to the Argo CD namespace the operator
+	// is configured with, or to the namespace of the ArgoCDProjectRoleBinding if none is configured.
+	// AppProjects outside of the binding's namespace must allow the binding's namespace
+	// via the "rbac-operator.argoproj-labs.io/allowed-namespaces" annotation.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
 	// List of groups the role will be granted to.
-	Groups []string `json:"groups"`
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+	// List of SSO users and local accounts the role will be granted to.
+	// They are bound via global policy in the Argo CD RBAC ConfigMap (g, <name>, proj:<appProject>:<role>).
+	// +optional
+	Users []AppProjectUser `json:"users,omitempty"`
+	// NotBefore is the time the role is bound to the AppProject from. Restricts spec.notBefore of the binding.
+	// +optional
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+	// ExpiresAt is the time the role is removed from the AppProject. Restricts spec.expiresAt of the binding.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+}
+
+// AppProjectUser defines a user being bound to ArgoCDProjectRole via global policy.
+type AppProjectUser struct {
+	// +kubebuilder:validation:Enum=sso;local
+	// Kind of the user (sso or local).
+	Kind string `json:"kind"`
+	// Name of the SSO user or the local account. It is rendered into the global policy, so it must not contain
+	// commas or line breaks.
+	// +kubebuilder:validation:Pattern=`^[^,\r\n]+$`
+	Name string `json:"name"`
 }
 
 // ArgocdProjectRoleRef defines the reference to the role being granted.
@@ -51,7 +91,20 @@ type ArgoCDProjectRoleBindingStatus struct {
 	// Conditions defines the list of conditions.
 	Conditions []Condition `json:"conditions,omitempty"`
 	// AppProjectsBound is a list of AppProjects that the role is bound to.
+	// AppProjects outside of the binding's namespace are listed as "<namespace>/<name>".
 	AppProjectsBound []string `json:"appProjectsBound,omitempty"`
+	// ActiveSubjects is the list of AppProjects the role is currently granted in by the subjects.
+	// AppProjects outside of the binding's namespace are listed as "<namespace>/<name>".
+	ActiveSubjects []string `json:"activeSubjects,omitempty"`
+	// NextTransition is the time the role is bound or removed next, by a window opening or closing.
+	NextTransition *metav1.Time `json:"nextTransition,omitempty"`
+	// UpcomingWindows are the next windows of spec.schedules.
+	UpcomingWindows []TimeWindow `json:"upcomingWindows,omitempty"`
+	// Recertification is the recertification state of the binding, if an ArgoCDRecertificationPolicy applies to it.
+	Recertification *RecertificationStatus `json:"recertification,omitempty"`
+	// PlannedChanges are the changes to the Argo CD RBAC ConfigMap and AppProjects not written in dry-run mode,
+	// as "<kind> <namespace>/<name> <key>: +<line>" or "-<line>".
+	PlannedChanges []string `json:"plannedChanges,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,116 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ArgoCDProjectRoleTokenSpec defines the desired state of a JWT token issued for a project role.
+type ArgoCDProjectRoleTokenSpec struct {
+	// Name of the ArgoCDProjectRoleBinding granting the project role the token is issued for.
+	ArgoCDProjectRoleBindingRef string `json:"argocdProjectRoleBindingRef"`
+	// Reference to the AppProject the token is issued for. The AppProject has to be bound by the ArgoCDProjectRoleBinding.
+	AppProjectRef string `json:"appProjectRef"`
+	// Namespace of the referenced AppProject. Resolved the same way as the namespace of the ArgoCDProjectRoleBinding subject.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// ID of the token. Every issued token gets a unique ID "<id>-<issued-at>". Defaults to the name of the ArgoCDProjectRoleToken.
+	// +optional
+	ID string `json:"id,omitempty"`
+	// ExpiresIn defines how long the issued token is valid. The token does not expire if not set.
+	// +optional
+	ExpiresIn *metav1.Duration `json:"expiresIn,omitempty"`
+	// RenewBefore defines how long before the expiry the token is rotated. Defaults to rotating at the expiry.
+	// +optional
+	RenewBefore *metav1.Duration `json:"renewBefore,omitempty"`
+	// Name of the Secret the token is written to (key "token"). The Secret is created in the namespace of the ArgoCDProjectRoleToken.
+	SecretName string `json:"secretName"`
+}
+
+// ArgoCDProjectRoleTokenStatus defines the observed state of ArgoCDProjectRoleToken.
+type ArgoCDProjectRoleTokenStatus struct {
+	// TokenID is the ID (jti) of the currently issued token.
+	TokenID string `json:"tokenID,omitempty"`
+	// AppProject the token is issued for, "<namespace>/<name>".
+	AppProject string `json:"appProject,omitempty"`
+	// IssuedAt is the time the current token was issued.
+	IssuedAt *metav1.Time `json:"issuedAt,omitempty"`
+	// ExpiresAt is the time the current token expires.
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// ObservedGeneration is the .metadata.generation the current token was issued for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	// Conditions defines the list of conditions.
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Token ID",type=string,JSONPath=`.status.tokenID`
+// +kubebuilder:printcolumn:name="Expires At",type=string,JSONPath=`.status.expiresAt`
+// +genclient
+
+// ArgoCDProjectRoleToken is the Schema for the argocdprojectroletokens API.
+type ArgoCDProjectRoleToken struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ArgoCDProjectRoleTokenSpec   `json:"spec,omitempty"`
+	Status ArgoCDProjectRoleTokenStatus `json:"status,omitempty"`
+}
+
+// IsBeingDeleted returns true if a deletion timestamp is set
+func (t *ArgoCDProjectRoleToken) IsBeingDeleted() bool {
+	return !t.DeletionTimestamp.IsZero()
+}
+
+// ArgoCDProjectRoleTokenFinalizerName is the name of the finalizer used to revoke the token
+const ArgoCDProjectRoleTokenFinalizerName = "rbac-operator.argoproj-labs.io/finalizer"
+
+// HasFinalizer returns true if the token has the finalizer
+func (t *ArgoCDProjectRoleToken) HasFinalizer(finalizerName string) bool {
+	return slices.Contains(t.Finalizers, finalizerName)
+}
+
+// AddFinalizer adds the finalizer to the token
+func (t *ArgoCDProjectRoleToken) AddFinalizer(finalizerName string) {
+	t.Finalizers = append(t.Finalizers, finalizerName)
+}
+
+// RemoveFinalizer removes the finalizer from the token
+func (t *ArgoCDProjectRoleToken) RemoveFinalizer(finalizerName string) {
+	t.Finalizers = slices.DeleteFunc(t.Finalizers, func(s string) bool {
+		return s == finalizerName
+	})
+}
+
+// +kubebuilder:object:root=true
+
+// ArgoCDProjectRoleTokenList contains a list of ArgoCDProjectRoleToken.
+type ArgoCDProjectRoleTokenList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ArgoCDProjectRoleToken `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ArgoCDProjectRoleToken{}, &ArgoCDProjectRoleTokenList{})
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ArgoCDRBACRevisionSpec records the policy written to the Argo CD RBAC ConfigMap or an AppProject by a reconciliation.
+// Exactly one of ConfigMap and AppProject is set.
+type ArgoCDRBACRevisionSpec struct {
+	// Trigger is the resource whose reconciliation wrote the policy.
+	Trigger RevisionTrigger `json:"trigger"`
+	// RecordedAt is the time the policy was written.
+	RecordedAt metav1.MicroTime `json:"recordedAt"`
+	// ConfigMap is the namespace/name of the Argo CD RBAC ConfigMap written.
+	// +optional
+	ConfigMap string `json:"configMap,omitempty"`
+	// Policies are the policy.*.csv keys of the ConfigMap after the write.
+	// +optional
+	Policies map[string]string `json:"policies,omitempty"`
+	// AppProject is the namespace/name of the AppProject written.
+	// +optional
+	AppProject string `json:"appProject,omitempty"`
+	// ProjectRoles are the roles of the AppProject after the write.
+	// +optional
+	ProjectRoles []RevisionProjectRole `json:"projectRoles,omitempty"`
+}
+
+// RevisionTrigger references the resource whose reconciliation wrote the policy.
+type RevisionTrigger struct {
+	// Kind of the resource, e.g. ArgoCDRole.
+	Kind string `json:"kind"`
+	// Namespace of the resource.
+	Namespace string `json:"namespace"`
+	// Name of the resource.
+	Name string `json:"name"`
+	// Generation of the resource that was rendered.
+	Generation int64 `json:"generation,omitempty"`
+}
+
+// String returns the trigger as "<kind> <namespace>/<name>".
+func (t RevisionTrigger) String() string {
+	return t.Kind + " " + t.Namespace + "/" + t.Name
+}
+
+// RevisionProjectRole is a role of an AppProject as rendered by the operator.
+// The JWT tokens of the role are not recorded, they are kept on rollback.
+type RevisionProjectRole struct {
+	// Name of the role.
+	Name string `json:"name"`
+	// Description of the role.
+	// +optional
+	Description string `json:"description,omitempty"`
+	// Groups bound to the role.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+	// Policies of the role.
+	// +optional
+	Policies []string `json:"policies,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Trigger Kind",type=string,JSONPath=`.spec.trigger.kind`
+// +kubebuilder:printcolumn:name="Trigger",type=string,JSONPath=`.spec.trigger.name`
+// +kubebuilder:printcolumn:name="Generation",type=integer,JSONPath=`.spec.trigger.generation`
+// +kubebuilder:printcolumn:name="ConfigMap",type=string,JSONPath=`.spec.configMap`
+// +kubebuilder:printcolumn:name="AppProject",type=string,JSONPath=`.spec.appProject`
+// +kubebuilder:printcolumn:name="Recorded",type=date,JSONPath=`.spec.recordedAt`
+// +genclient
+
+// ArgoCDRBACRevision is the Schema for the argocdrbacrevisions API
+type ArgoCDRBACRevision struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+	Spec ArgoCDRBACRevisionSpec `json:"spec,omitempty"`
+}
+
+// Target returns the ConfigMap or AppProject the revision was recorded for, as "<kind> <namespace>/<name>".
+func (r *ArgoCDRBACRevision) Target() string {
+	if r.Spec.AppProject != "" {
+		return "AppProject " + r.Spec.AppProject
+	}
+	return "ConfigMap " + r.Spec.ConfigMap
+}
+
+// RecordedBefore returns true if the revision was recorded before the other one.
+// Revisions recorded at the same time are ordered by name.
+func (r *ArgoCDRBACRevision) RecordedBefore(other *ArgoCDRBACRevision) bool {
+	if !r.Spec.RecordedAt.Equal(&other.Spec.RecordedAt) {
+		return r.Spec.RecordedAt.Before(&other.Spec.RecordedAt)
+	}
+	return r.Name < other.Name
+}
+
+// +kubebuilder:object:root=true
+
+// ArgoCDRBACRevisionList contains a list of ArgoCDRBACRevision
+type ArgoCDRBACRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ArgoCDRBACRevision `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ArgoCDRBACRevision{}, &ArgoCDRBACRevisionList{})
+}
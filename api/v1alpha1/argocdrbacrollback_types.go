@@ -0,0 +1,90 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ArgoCDRBACRollbackSpec defines the revision to restore.
+type ArgoCDRBACRollbackSpec struct {
+	// Revision is the name of the ArgoCDRBACRevision to restore, in the namespace of the rollback.
+	// The Argo CD RBAC ConfigMap and AppProjects written since are restored to their state at that revision.
+	// +kubebuilder:validation:MinLength=1
+	Revision string `json:"revision"`
+}
+
+// RollbackPhase is the phase of an ArgoCDRBACRollback.
+// +kubebuilder:validation:Enum=Completed;Failed
+type RollbackPhase string
+
+const (
+	// RollbackPhaseCompleted rollbacks restored the revision.
+	RollbackPhaseCompleted RollbackPhase = "Completed"
+	// RollbackPhaseFailed rollbacks could not restore the revision and are not retried.
+	RollbackPhaseFailed RollbackPhase = "Failed"
+)
+
+// ArgoCDRBACRollbackStatus defines the observed state of ArgoCDRBACRollback
+type ArgoCDRBACRollbackStatus struct {
+	// Phase of the rollback (Completed or Failed).
+	Phase RollbackPhase `json:"phase,omitempty"`
+	// CompletedAt is the time the revision was restored.
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+	// Restored are the ConfigMap and AppProjects restored, as "<kind> <namespace>/<name>".
+	Restored []string `json:"restored,omitempty"`
+	// Skipped are the ConfigMap and AppProjects written since the revision without a revision to restore,
+	// because they were first written after it or their revisions were pruned.
+	Skipped []string `json:"skipped,omitempty"`
+	// Paused are the resources that wrote the policy since the revision and are paused until fixed,
+	// as "<kind> <namespace>/<name>".
+	Paused []string `json:"paused,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	// Conditions defines the list of conditions.
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Revision",type=string,JSONPath=`.spec.revision`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Completed",type=date,JSONPath=`.status.completedAt`
+// +genclient
+
+// ArgoCDRBACRollback is the Schema for the argocdrbacrollbacks API
+type ArgoCDRBACRollback struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+	Spec   ArgoCDRBACRollbackSpec   `json:"spec,omitempty"`
+	Status ArgoCDRBACRollbackStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ArgoCDRBACRollbackList contains a list of ArgoCDRBACRollback
+type ArgoCDRBACRollbackList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ArgoCDRBACRollback `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ArgoCDRBACRollback{}, &ArgoCDRBACRollbackList{})
+}
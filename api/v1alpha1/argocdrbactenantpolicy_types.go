@@ -0,0 +1,74 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ArgoCDRBACTenantPolicySpec defines what the roles and bindings in a set of namespaces may grant.
+// A namespace matched by several policies has to satisfy all of them.
+type ArgoCDRBACTenantPolicySpec struct {
+	// Namespaces the policy applies to, as glob patterns (e.g. "team-a-*").
+	// +kubebuilder:validation:MinItems=1
+	Namespaces []string `json:"namespaces"`
+	// Resources the rules of ArgoCDRoles and ArgoCDProjectRoles may grant. Not restricted if empty.
+	// +optional
+	Resources []string `json:"resources,omitempty"`
+	// Verbs the rules of ArgoCDRoles and ArgoCDProjectRoles may grant. Not restricted if empty.
+	// A "*" verb in a rule is only allowed if "*" is listed.
+	// +optional
+	Verbs []string `json:"verbs,omitempty"`
+	// Objects are glob patterns (e.g. "team-a-*/*") the objects of ArgoCDRole rules must match. Not restricted if empty.
+	// ArgoCDProjectRoles are not restricted, Argo CD confines their policies to the AppProject.
+	// +optional
+	Objects []string `json:"objects,omitempty"`
+	// Subjects are glob patterns the names of the SSO users, local accounts, roles and groups bound in the namespaces must match.
+	// Not restricted if empty.
+	// +optional
+	Subjects []string `json:"subjects,omitempty"`
+	// AllowBuiltInRoles allows ArgoCDRoleBindings in the namespaces to bind the built-in admin and readonly roles,
+	// and ArgoCDRoles named admin or readonly to add their rules to them.
+	// +optional
+	AllowBuiltInRoles bool `json:"allowBuiltInRoles,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +genclient
+// +genclient:nonNamespaced
+
+// ArgoCDRBACTenantPolicy is the Schema for the argocdrbactenantpolicies API
+type ArgoCDRBACTenantPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ArgoCDRBACTenantPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ArgoCDRBACTenantPolicyList contains a list of ArgoCDRBACTenantPolicy
+type ArgoCDRBACTenantPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ArgoCDRBACTenantPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ArgoCDRBACTenantPolicy{}, &ArgoCDRBACTenantPolicyList{})
+}
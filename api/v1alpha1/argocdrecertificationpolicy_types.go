@@ -0,0 +1,102 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ArgoCDRecertificationPolicySpec defines how often the bindings in the namespace of the policy have to be re-attested.
+// A policy in the Argo CD namespace applies to the bindings of all namespaces, the strictest policy applies if there are several.
+type ArgoCDRecertificationPolicySpec struct {
+	// Interval after the last attestation at which a binding is due for recertification, e.g. 2160h for 90 days.
+	// +kubebuilder:validation:XValidation:rule="duration(self) > duration('0s')",message="interval must be positive"
+	Interval metav1.Duration `json:"interval"`
+	// GracePeriod after the interval before the subjects of a binding that is not re-attested are suspended.
+	// Suspended as soon as the binding is due if not set.
+	// +kubebuilder:validation:XValidation:rule="duration(self) >= duration('0s')",message="gracePeriod must not be negative"
+	// +optional
+	GracePeriod *metav1.Duration `json:"gracePeriod,omitempty"`
+}
+
+// RecertificationStatus defines the observed recertification state of a binding.
+type RecertificationStatus struct {
+	// Policy is the namespace/name of the ArgoCDRecertificationPolicy applied to the binding.
+	Policy string `json:"policy"`
+	// LastAttestedAt is the last time the binding was attested, or its creation time if it never was.
+	LastAttestedAt metav1.Time `json:"lastAttestedAt"`
+	// LastAttestedBy is the user that last attested the binding, empty if it never was.
+	// +optional
+	LastAttestedBy string `json:"lastAttestedBy,omitempty"`
+	// DueAt is the time the binding is due for recertification.
+	DueAt metav1.Time `json:"dueAt"`
+	// SuspendAt is the time the subjects of the binding are suspended if it is not re-attested.
+	SuspendAt metav1.Time `json:"suspendAt"`
+}
+
+// IsDue returns true if the binding has to be re-attested at the given time.
+func (s *RecertificationStatus) IsDue(now time.Time) bool {
+	return s != nil && !now.Before(s.DueAt.Time)
+}
+
+// IsSuspended returns true if the subjects of the binding are suspended at the given time.
+func (s *RecertificationStatus) IsSuspended(now time.Time) bool {
+	return s != nil && !now.Before(s.SuspendAt.Time)
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Interval",type=string,JSONPath=`.spec.interval`
+// +kubebuilder:printcolumn:name="Grace Period",type=string,JSONPath=`.spec.gracePeriod`
+// +genclient
+
+// ArgoCDRecertificationPolicy is the Schema for the argocdrecertificationpolicies API
+type ArgoCDRecertificationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ArgoCDRecertificationPolicySpec `json:"spec,omitempty"`
+}
+
+// Recertification returns the recertification status of a binding last attested at the given time.
+func (p *ArgoCDRecertificationPolicy) Recertification(lastAttestedAt time.Time) *RecertificationStatus {
+	due := lastAttestedAt.Add(p.Spec.Interval.Duration)
+	suspend := due
+	if p.Spec.GracePeriod != nil && p.Spec.GracePeriod.Duration > 0 {
+		suspend = due.Add(p.Spec.GracePeriod.Duration)
+	}
+	return &RecertificationStatus{
+		Policy:         p.Namespace + "/" + p.Name,
+		LastAttestedAt: metav1.NewTime(lastAttestedAt),
+		DueAt:          metav1.NewTime(due),
+		SuspendAt:      metav1.NewTime(suspend),
+	}
+}
+
+// +kubebuilder:object:root=true
+
+// ArgoCDRecertificationPolicyList contains a list of ArgoCDRecertificationPolicy
+type ArgoCDRecertificationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ArgoCDRecertificationPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ArgoCDRecertificationPolicy{}, &ArgoCDRecertificationPolicyList{})
+}
@@ -25,6 +25,10 @@ import (
 // ArgoCDRoleSpec defines the desired state of global scoped Role (written to argocd-rbac-cm ConfigMap)
 type ArgoCDRoleSpec struct {
 	Rules []GlobalRule `json:"rules"`
+	// BreakGlass marks the role for emergency access only. Bindings to the role need a reason annotation
+	// and are deleted after the maximum break-glass duration. The built-in admin role is break-glass unless --break-glass-admin=false.
+	// +optional
+	BreakGlass bool `json:"breakGlass,omitempty"`
 }
 
 // Rules define the desired set of permissions.
@@ -34,9 +38,32 @@ type GlobalRule struct {
 	// Target resource type.
 	Resource string `json:"resource"`
 	// Verbs define the operations that are being performed on the resource.
+	// +kubebuilder:validation:items:Pattern=`^[^,\r\n]+$`
 	Verbs []string `json:"verbs"`
 	// List of resource's objects the permissions are granted for.
-	Objects []string `json:"objects"`
+	// +kubebuilder:validation:items:Pattern=`^[^,\r\n]+$`
+	// +optional
+	Objects []string `json:"objects,omitempty"`
+	// ApplicationObjects are objects of applications, applicationsets, logs and exec given by project, namespace and
+	// name. They are rendered in the object format of the Argo CD instance, with or without applications in any namespace.
+	// +optional
+	ApplicationObjects []ApplicationObject `json:"applicationObjects,omitempty"`
+}
+
+// ApplicationObject is an application, or an applicationset, of the Argo CD policy. All fields are globs.
+// With applications in any namespace enabled, the project and name of applications in the namespace of Argo CD
+// must not contain *, it would also match the applications of the other namespaces.
+type ApplicationObject struct {
+	// Project of the application, e.g. "team-a" or "*".
+	// +kubebuilder:validation:MinLength=1
+	Project string `json:"project"`
+	// Namespace of the application, e.g. "team-a-apps" or "*". Applications in the namespace of Argo CD if not set.
+	// Other namespaces require applications in any namespace to be enabled for them.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Name of the application, e.g. "guestbook" or "*".
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
 }
 
 // ArgoCDRoleStatus defines the observed state of Role
@@ -47,6 +74,16 @@ type ArgoCDRoleStatus struct {
 	// +listMapKey=type
 	// Conditions defines the list of conditions.
 	Conditions []Condition `json:"conditions,omitempty"`
+	// PlannedChanges are the changes to the Argo CD RBAC ConfigMap and AppProjects not written in dry-run mode,
+	// as "<kind> <namespace>/<name> <key>: +<line>" or "-<line>".
+	PlannedChanges []string `json:"plannedChanges,omitempty"`
+	// AppliedGeneration is the generation of the spec rendered into the policy.
+	AppliedGeneration int64 `json:"appliedGeneration,omitempty"`
+	// AppliedSpec is the spec rendered into the policy while spec changes wait for approval,
+	// if the role has the require-approval label.
+	AppliedSpec *ArgoCDRoleSpec `json:"appliedSpec,omitempty"`
+	// PendingChanges are the policy lines added ("+") and removed ("-") by the spec changes waiting for approval.
+	PendingChanges []string `json:"pendingChanges,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -27,6 +27,16 @@ type ArgoCDRoleBindingSpec struct {
 	// List of subjects being bound to ArgoCDRole (argocdRoleRef).
 	Subjects      []GlobalSubject `json:"subjects"`
 	ArgoCDRoleRef ArgoCDRoleRef   `json:"argocdRoleRef"`
+	// NotBefore is the time the subjects are granted the role from. Granted immediately if not set.
+	// +optional
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+	// ExpiresAt is the time the role is revoked from the subjects. Never revoked if not set.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// Schedules restrict the binding to recurring windows. The role is granted only while one of the windows is open.
+	// Not restricted if empty.
+	// +optional
+	Schedules []Schedule `json:"schedules,omitempty"`
 }
 
 // GlobalSubject defines the subject being bound to ArgoCDRole.
@@ -35,7 +45,14 @@ type GlobalSubject struct {
 	// Kind of the subject (sso, local or role).
 	Kind string `json:"kind"`
 	// Name of the subject. If Kind is "role", it shouldn't start with "role:"
+	// +kubebuilder:validation:Pattern=`^[^,\r\n]+$`
 	Name string `json:"name"`
+	// NotBefore is the time the subject is granted the role from. Restricts spec.notBefore of the binding.
+	// +optional
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+	// ExpiresAt is the time the role is revoked from the subject. Restricts spec.expiresAt of the binding.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
 }
 
 // ArgocdRoleRef defines the reference to the role being granted.
@@ -46,10 +63,23 @@ type ArgoCDRoleRef struct {
 
 // ArgoCDRoleBindingStatus defines the observed state of ArgoCDRoleBinding
 type ArgoCDRoleBindingStatus struct {
+	// ActiveSubjects is the list of subjects currently granted the role, "<kind>:<name>".
+	ActiveSubjects []string `json:"activeSubjects,omitempty"`
+	// NextTransition is the time the subjects are granted or revoked the role next, by a window opening or closing.
+	NextTransition *metav1.Time `json:"nextTransition,omitempty"`
+	// UpcomingWindows are the next windows of spec.schedules.
+	UpcomingWindows []TimeWindow `json:"upcomingWindows,omitempty"`
+	// BreakGlassDeadline is the time the binding to a break-glass role is deleted at.
+	BreakGlassDeadline *metav1.Time `json:"breakGlassDeadline,omitempty"`
+	// Recertification is the recertification state of the binding, if an ArgoCDRecertificationPolicy applies to it.
+	Recertification *RecertificationStatus `json:"recertification,omitempty"`
 	// +listType=map
 	// +listMapKey=type
 	// Conditions defines the list of conditions.
 	Conditions []Condition `json:"conditions,omitempty"`
+	// PlannedChanges are the changes to the Argo CD RBAC ConfigMap and AppProjects not written in dry-run mode,
+	// as "<kind> <namespace>/<name> <key>: +<line>" or "-<line>".
+	PlannedChanges []string `json:"plannedChanges,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,127 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ArgoCDSubjectPermissionsSpec is the effective Argo CD permissions of a subject, computed by the operator
+// from the Argo CD RBAC ConfigMap, the built-in policy of Argo CD and the roles of the AppProjects.
+type ArgoCDSubjectPermissionsSpec struct {
+	// Subject is the SSO user or group, or the local account.
+	Subject string `json:"subject"`
+	// Roles are the global roles held by the subject, directly or through other roles.
+	// +optional
+	Roles []RoleGrant `json:"roles,omitempty"`
+	// ProjectRoles are the AppProject roles held by the subject, through the groups of the AppProject roles
+	// or through global policy.
+	// +optional
+	ProjectRoles []RoleGrant `json:"projectRoles,omitempty"`
+	// Rules are the flattened allow and deny rules of the subject and the roles it holds.
+	// +optional
+	Rules []PermissionRule `json:"rules,omitempty"`
+}
+
+// RoleGrant is a role held by a subject.
+type RoleGrant struct {
+	// Role held, as "role:<name>" or "proj:<appProject>:<name>".
+	Role string `json:"role"`
+	// Via is the role the role is inherited through. Empty if the role is granted to the subject directly.
+	// +optional
+	Via string `json:"via,omitempty"`
+	// Source is the resource the grant was rendered from.
+	Source PermissionSource `json:"source"`
+}
+
+// PermissionRule is an allow or deny rule of a subject.
+type PermissionRule struct {
+	// Resource the rule applies to, e.g. applications.
+	Resource string `json:"resource"`
+	// Action allowed or denied, e.g. get.
+	Action string `json:"action"`
+	// Object the rule applies to, e.g. */*.
+	Object string `json:"object"`
+	// Effect of the rule, allow or deny.
+	Effect string `json:"effect"`
+	// Role the rule is held through. Empty if the rule is granted to the subject directly.
+	// +optional
+	Role string `json:"role,omitempty"`
+	// Source is the resource the rule was rendered from.
+	Source PermissionSource `json:"source"`
+}
+
+// PermissionSource references the resource a grant or rule was rendered from.
+type PermissionSource struct {
+	// Kind of the resource, e.g. ArgoCDRoleBinding. Lines not written by the operator are of kind ConfigMap
+	// or AppProject, lines of the built-in policy of Argo CD are of kind BuiltinPolicy.
+	Kind string `json:"kind"`
+	// Namespace of the resource.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Name of the resource.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Key of the ConfigMap the line was read from.
+	// +optional
+	Key string `json:"key,omitempty"`
+	// Field of the resource the line was rendered from, e.g. spec.rules[1], as recorded when the line was written.
+	// It refers to the spec the line was rendered from, which may differ from the live spec, e.g. while it is paused.
+	// +optional
+	Field string `json:"field,omitempty"`
+}
+
+// String returns the source as "<kind> <namespace>/<name>", followed by the key of a ConfigMap and the field.
+func (s PermissionSource) String() string {
+	source := s.Kind
+	if s.Name != "" {
+		source += " " + s.Namespace + "/" + s.Name
+	}
+	if s.Key != "" {
+		source += " " + s.Key
+	}
+	if s.Field != "" {
+		source += " " + s.Field
+	}
+	return source
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Subject",type=string,JSONPath=`.spec.subject`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +genclient
+
+// ArgoCDSubjectPermissions is the Schema for the argocdsubjectpermissions API
+type ArgoCDSubjectPermissions struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ArgoCDSubjectPermissionsSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ArgoCDSubjectPermissionsList contains a list of ArgoCDSubjectPermissions
+type ArgoCDSubjectPermissionsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ArgoCDSubjectPermissions `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ArgoCDSubjectPermissions{}, &ArgoCDSubjectPermissionsList{})
+}
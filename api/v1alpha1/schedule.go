@@ -0,0 +1,41 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Schedule defines a recurring window a binding grants access in, similar to Argo CD sync windows.
+type Schedule struct {
+	// Cron expression the window opens at, e.g. "0 8 * * 1-5".
+	// +kubebuilder:validation:MinLength=1
+	Cron string `json:"cron"`
+	// Duration the window stays open for.
+	Duration metav1.Duration `json:"duration"`
+	// TimeZone the cron expression is evaluated in, e.g. "Europe/Berlin". Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// TimeWindow is a time range a binding grants access in.
+type TimeWindow struct {
+	// Start of the window.
+	Start metav1.Time `json:"start"`
+	// End of the window.
+	End metav1.Time `json:"end"`
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"slices"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -35,6 +37,23 @@ const (
 
 	// TypePending resources are believed to be pending.
 	TypePending ConditionType = "Pending"
+
+	// TypeActive bindings are within their notBefore/expiresAt window.
+	TypeActive ConditionType = "Active"
+
+	// TypeRecertified bindings were attested within the interval of their ArgoCDRecertificationPolicy.
+	TypeRecertified ConditionType = "Recertified"
+
+	// TypeCompliant resources grant only what the ArgoCDRBACTenantPolicies of their namespace allow.
+	TypeCompliant ConditionType = "Compliant"
+	// TypePolicyRisk resources grant something matched by a lint rule.
+	TypePolicyRisk ConditionType = "PolicyRisk"
+	// TypeApproved roles requiring approval render their latest generation.
+	TypeApproved ConditionType = "Approved"
+	// TypeResolved bindings reference existing roles with their role subjects, without cycles, and their role is held by a user.
+	TypeResolved ConditionType = "Resolved"
+	// TypeBound roles are referenced by a binding, and bindings have active subjects and are bound to AppProjects.
+	TypeBound ConditionType = "Bound"
 )
 
 // A ConditionReason represents the reason a resource is in a condition.
@@ -45,6 +64,56 @@ const (
 	ReasonReconcileSuccess ConditionReason = "ReconcileSuccess"
 	ReasonReconcileError   ConditionReason = "ReconcileError"
 	ReasonDeleting         ConditionReason = "Deleting"
+	ReasonPaused           ConditionReason = "Paused"
+)
+
+// Reasons a binding is or is not active.
+const (
+	ReasonActive    ConditionReason = "Active"
+	ReasonExpired   ConditionReason = "Expired"
+	ReasonScheduled ConditionReason = "Scheduled"
+	ReasonRejected  ConditionReason = "Rejected"
+	ReasonSuspended ConditionReason = "Suspended"
+)
+
+// Reasons a binding is or is not recertified.
+const (
+	ReasonRecertified        ConditionReason = "Recertified"
+	ReasonRecertificationDue ConditionReason = "RecertificationDue"
+)
+
+// Reasons a resource is or is not compliant.
+const (
+	ReasonCompliant       ConditionReason = "Compliant"
+	ReasonPolicyViolation ConditionReason = "PolicyViolation"
+)
+
+// Reasons a resource is or is not risky.
+const (
+	ReasonNoRisk    ConditionReason = "NoRisk"
+	ReasonRiskFound ConditionReason = "RiskFound"
+)
+
+// Reasons the spec changes of a role are or are not approved.
+const (
+	ReasonApproved        ConditionReason = "Approved"
+	ReasonApprovalPending ConditionReason = "ApprovalPending"
+)
+
+// Reasons the role subjects of a binding are or are not resolved.
+const (
+	ReasonResolved        ConditionReason = "Resolved"
+	ReasonRoleCycle       ConditionReason = "RoleCycle"
+	ReasonDanglingRole    ConditionReason = "DanglingRole"
+	ReasonUnreachableRole ConditionReason = "UnreachableRole"
+)
+
+// Reasons a role or binding is or is not bound.
+const (
+	ReasonBound            ConditionReason = "Bound"
+	ReasonUnbound          ConditionReason = "Unbound"
+	ReasonNoActiveSubjects ConditionReason = "NoActiveSubjects"
+	ReasonNoAppProjects    ConditionReason = "NoAppProjects"
 )
 
 // A Condition that may apply to a resource.
@@ -172,6 +241,25 @@ func (r *ArgoCDProjectRole) HasArgoCDProjectRoleBindingRef() bool {
 	return r.Status.ArgoCDProjectRoleBindingRef != ""
 }
 
+// SetAppProjectSyncStatus sets the supplied sync state, replacing any existing
+// state of the same AppProject.
+func (r *ArgoCDProjectRole) SetAppProjectSyncStatus(s AppProjectSyncStatus) {
+	for i, existing := range r.Status.AppProjects {
+		if existing.AppProject == s.AppProject {
+			r.Status.AppProjects[i] = s
+			return
+		}
+	}
+	r.Status.AppProjects = append(r.Status.AppProjects, s)
+}
+
+// RemoveStaleAppProjectSyncStatus removes the sync state of all AppProjects not in the supplied list.
+func (r *ArgoCDProjectRole) RemoveStaleAppProjectSyncStatus(appProjects []string) {
+	r.Status.AppProjects = slices.DeleteFunc(r.Status.AppProjects, func(s AppProjectSyncStatus) bool {
+		return !slices.Contains(appProjects, s.AppProject)
+	})
+}
+
 // SetConditions sets the supplied conditions, replacing any existing conditions
 // of the same type. This is a no-op if all supplied conditions are identical,
 // ignoring the last transition time, to those already set.
@@ -230,6 +318,141 @@ func (rb *ArgoCDProjectRoleBinding) SetConditions(c ...Condition) {
 	}
 }
 
+// SetConditions sets the supplied conditions, replacing any existing conditions
+// of the same type. This is a no-op if all supplied conditions are identical,
+// ignoring the last transition time, to those already set.
+// Observed generation is updated if higher than the existing one.
+func (t *ArgoCDProjectRoleToken) SetConditions(c ...Condition) {
+	for _, new := range c {
+		exists := false
+		for i, existing := range t.Status.Conditions {
+			if existing.Type != new.Type {
+				continue
+			}
+			if existing.Equal(new) {
+				exists = true
+				if t.Status.Conditions[i].ObservedGeneration < new.ObservedGeneration {
+					t.Status.Conditions[i].ObservedGeneration = new.ObservedGeneration
+				}
+				continue
+			}
+			t.Status.Conditions[i] = new
+			exists = true
+		}
+		if !exists {
+			t.Status.Conditions = append(t.Status.Conditions, new)
+		}
+	}
+}
+
+// SetConditions sets the supplied conditions, replacing any existing conditions
+// of the same type. This is a no-op if all supplied conditions are identical,
+// ignoring the last transition time, to those already set.
+// Observed generation is updated if higher than the existing one.
+func (a *ArgoCDLocalAccount) SetConditions(c ...Condition) {
+	for _, new := range c {
+		exists := false
+		for i, existing := range a.Status.Conditions {
+			if existing.Type != new.Type {
+				continue
+			}
+			if existing.Equal(new) {
+				exists = true
+				if a.Status.Conditions[i].ObservedGeneration < new.ObservedGeneration {
+					a.Status.Conditions[i].ObservedGeneration = new.ObservedGeneration
+				}
+				continue
+			}
+			a.Status.Conditions[i] = new
+			exists = true
+		}
+		if !exists {
+			a.Status.Conditions = append(a.Status.Conditions, new)
+		}
+	}
+}
+
+// SetConditions sets the supplied conditions, replacing any existing conditions
+// of the same type. This is a no-op if all supplied conditions are identical,
+// ignoring the last transition time, to those already set.
+// Observed generation is updated if higher than the existing one.
+func (r *ArgoCDAccessRequest) SetConditions(c ...Condition) {
+	for _, new := range c {
+		exists := false
+		for i, existing := range r.Status.Conditions {
+			if existing.Type != new.Type {
+				continue
+			}
+			if existing.Equal(new) {
+				exists = true
+				if r.Status.Conditions[i].ObservedGeneration < new.ObservedGeneration {
+					r.Status.Conditions[i].ObservedGeneration = new.ObservedGeneration
+				}
+				continue
+			}
+			r.Status.Conditions[i] = new
+			exists = true
+		}
+		if !exists {
+			r.Status.Conditions = append(r.Status.Conditions, new)
+		}
+	}
+}
+
+// SetConditions sets the supplied conditions, replacing any existing conditions
+// of the same type. This is a no-op if all supplied conditions are identical,
+// ignoring the last transition time, to those already set.
+// Observed generation is updated if higher than the existing one.
+func (r *ArgoCDRBACRollback) SetConditions(c ...Condition) {
+	for _, new := range c {
+		exists := false
+		for i, existing := range r.Status.Conditions {
+			if existing.Type != new.Type {
+				continue
+			}
+			if existing.Equal(new) {
+				exists = true
+				if r.Status.Conditions[i].ObservedGeneration < new.ObservedGeneration {
+					r.Status.Conditions[i].ObservedGeneration = new.ObservedGeneration
+				}
+				continue
+			}
+			r.Status.Conditions[i] = new
+			exists = true
+		}
+		if !exists {
+			r.Status.Conditions = append(r.Status.Conditions, new)
+		}
+	}
+}
+
+// SetConditions sets the supplied conditions, replacing any existing conditions
+// of the same type. This is a no-op if all supplied conditions are identical,
+// ignoring the last transition time, to those already set.
+// Observed generation is updated if higher than the existing one.
+func (r *ArgoCDAccessReport) SetConditions(c ...Condition) {
+	for _, new := range c {
+		exists := false
+		for i, existing := range r.Status.Conditions {
+			if existing.Type != new.Type {
+				continue
+			}
+			if existing.Equal(new) {
+				exists = true
+				if r.Status.Conditions[i].ObservedGeneration < new.ObservedGeneration {
+					r.Status.Conditions[i].ObservedGeneration = new.ObservedGeneration
+				}
+				continue
+			}
+			r.Status.Conditions[i] = new
+			exists = true
+		}
+		if !exists {
+			r.Status.Conditions = append(r.Status.Conditions, new)
+		}
+	}
+}
+
 // Deleting returns a condition that indicates the resource is currently
 // being deleted.
 func Deleting() Condition {
@@ -263,6 +486,17 @@ func ReconcileSuccess() Condition {
 	}
 }
 
+// Paused returns a condition indicating that the Controller does not write the policy of the resource,
+// because it was paused by an ArgoCDRBACRollback.
+func Paused() Condition {
+	return Condition{
+		Type:               TypeSynced,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonPaused,
+	}
+}
+
 // ReconcileError returns a condition indicating that the Controller encountered an
 // error while reconciling the resource.
 func ReconcileError(err error) Condition {
@@ -274,3 +508,215 @@ func ReconcileError(err error) Condition {
 		Message:            err.Error(),
 	}
 }
+
+// Active returns a condition indicating that the binding grants access.
+func Active() Condition {
+	return Condition{
+		Type:               TypeActive,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonActive,
+	}
+}
+
+// Expired returns a condition indicating that the binding does not grant access anymore.
+func Expired() Condition {
+	return Condition{
+		Type:               TypeActive,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonExpired,
+	}
+}
+
+// Scheduled returns a condition indicating that the binding does not grant access yet.
+func Scheduled() Condition {
+	return Condition{
+		Type:               TypeActive,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonScheduled,
+	}
+}
+
+// Rejected returns a condition indicating that the binding does not grant access, because it is not allowed.
+func Rejected() Condition {
+	return Condition{
+		Type:               TypeActive,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonRejected,
+	}
+}
+
+// Suspended returns a condition indicating that the binding does not grant access, because it was not re-attested in time.
+func Suspended() Condition {
+	return Condition{
+		Type:               TypeActive,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonSuspended,
+	}
+}
+
+// Recertified returns a condition indicating that the binding was attested within its recertification interval.
+func Recertified() Condition {
+	return Condition{
+		Type:               TypeRecertified,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonRecertified,
+	}
+}
+
+// RecertificationDue returns a condition indicating that the binding has to be re-attested.
+func RecertificationDue() Condition {
+	return Condition{
+		Type:               TypeRecertified,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonRecertificationDue,
+	}
+}
+
+// Compliant returns a condition indicating that the resource grants only what the tenant policies allow.
+func Compliant() Condition {
+	return Condition{
+		Type:               TypeCompliant,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonCompliant,
+	}
+}
+
+// PolicyViolation returns a condition indicating that parts of the resource are not granted, because the tenant policies do not allow them.
+func PolicyViolation() Condition {
+	return Condition{
+		Type:               TypeCompliant,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonPolicyViolation,
+	}
+}
+
+// NoPolicyRisk returns a condition indicating that no lint rule matches what the resource grants.
+func NoPolicyRisk() Condition {
+	return Condition{
+		Type:               TypePolicyRisk,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNoRisk,
+	}
+}
+
+// PolicyRisk returns a condition indicating that lint rules match what the resource grants.
+func PolicyRisk() Condition {
+	return Condition{
+		Type:               TypePolicyRisk,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonRiskFound,
+	}
+}
+
+// Approved returns a condition indicating that the latest generation of the role is rendered.
+func Approved() Condition {
+	return Condition{
+		Type:               TypeApproved,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonApproved,
+	}
+}
+
+// ApprovalPending returns a condition indicating that the spec changes of the role wait for approval.
+func ApprovalPending() Condition {
+	return Condition{
+		Type:               TypeApproved,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonApprovalPending,
+	}
+}
+
+// Resolved returns a condition indicating that the role subjects of the binding reference existing roles without cycles,
+// and that its role is held by a user.
+func Resolved() Condition {
+	return Condition{
+		Type:               TypeResolved,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonResolved,
+	}
+}
+
+// RoleCycle returns a condition indicating that role subjects of the binding close a cycle, and are not rendered.
+func RoleCycle() Condition {
+	return Condition{
+		Type:               TypeResolved,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonRoleCycle,
+	}
+}
+
+// DanglingRole returns a condition indicating that role subjects of the binding reference roles that don't exist.
+func DanglingRole() Condition {
+	return Condition{
+		Type:               TypeResolved,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDanglingRole,
+	}
+}
+
+// UnreachableRole returns a condition indicating that no user holds the role of the binding.
+func UnreachableRole() Condition {
+	return Condition{
+		Type:               TypeResolved,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonUnreachableRole,
+	}
+}
+
+// Bound returns a condition indicating that the role is referenced by a binding, or that the binding grants its role.
+func Bound() Condition {
+	return Condition{
+		Type:               TypeBound,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonBound,
+	}
+}
+
+// Unbound returns a condition indicating that no binding references the role.
+func Unbound() Condition {
+	return Condition{
+		Type:               TypeBound,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonUnbound,
+	}
+}
+
+// NoActiveSubjects returns a condition indicating that all subjects of the binding are expired or reference roles that
+// don't exist.
+func NoActiveSubjects() Condition {
+	return Condition{
+		Type:               TypeBound,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNoActiveSubjects,
+	}
+}
+
+// NoAppProjects returns a condition indicating that the project role is not bound to any AppProject.
+func NoAppProjects() Condition {
+	return Condition{
+		Type:               TypeBound,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNoAppProjects,
+	}
+}
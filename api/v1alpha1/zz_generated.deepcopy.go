@@ -21,9 +21,56 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessReportOutput) DeepCopyInto(out *AccessReportOutput) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessReportOutput.
+func (in *AccessReportOutput) DeepCopy() *AccessReportOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessReportOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessRequestEvent) DeepCopyInto(out *AccessRequestEvent) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessRequestEvent.
+func (in *AccessRequestEvent) DeepCopy() *AccessRequestEvent {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessRequestEvent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessRequestSubject) DeepCopyInto(out *AccessRequestSubject) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessRequestSubject.
+func (in *AccessRequestSubject) DeepCopy() *AccessRequestSubject {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessRequestSubject)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AppProjectSubject) DeepCopyInto(out *AppProjectSubject) {
 	*out = *in
@@ -32,6 +79,19 @@ func (in *AppProjectSubject) DeepCopyInto(out *AppProjectSubject) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]AppProjectUser, len(*in))
+		copy(*out, *in)
+	}
+	if in.NotBefore != nil {
+		in, out := &in.NotBefore, &out.NotBefore
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppProjectSubject.
@@ -45,26 +105,86 @@ func (in *AppProjectSubject) DeepCopy() *AppProjectSubject {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ArgoCDProjectRole) DeepCopyInto(out *ArgoCDProjectRole) {
+func (in *AppProjectSyncStatus) DeepCopyInto(out *AppProjectSyncStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppProjectSyncStatus.
+func (in *AppProjectSyncStatus) DeepCopy() *AppProjectSyncStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AppProjectSyncStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppProjectUser) DeepCopyInto(out *AppProjectUser) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppProjectUser.
+func (in *AppProjectUser) DeepCopy() *AppProjectUser {
+	if in == nil {
+		return nil
+	}
+	out := new(AppProjectUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationObject) DeepCopyInto(out *ApplicationObject) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationObject.
+func (in *ApplicationObject) DeepCopy() *ApplicationObject {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationObject)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Approver) DeepCopyInto(out *Approver) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Approver.
+func (in *Approver) DeepCopy() *Approver {
+	if in == nil {
+		return nil
+	}
+	out := new(Approver)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDAccessReport) DeepCopyInto(out *ArgoCDAccessReport) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
+	out.Spec = in.Spec
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRole.
-func (in *ArgoCDProjectRole) DeepCopy() *ArgoCDProjectRole {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDAccessReport.
+func (in *ArgoCDAccessReport) DeepCopy() *ArgoCDAccessReport {
 	if in == nil {
 		return nil
 	}
-	out := new(ArgoCDProjectRole)
+	out := new(ArgoCDAccessReport)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ArgoCDProjectRole) DeepCopyObject() runtime.Object {
+func (in *ArgoCDAccessReport) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -72,26 +192,104 @@ func (in *ArgoCDProjectRole) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ArgoCDProjectRoleBinding) DeepCopyInto(out *ArgoCDProjectRoleBinding) {
+func (in *ArgoCDAccessReportList) DeepCopyInto(out *ArgoCDAccessReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ArgoCDAccessReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDAccessReportList.
+func (in *ArgoCDAccessReportList) DeepCopy() *ArgoCDAccessReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDAccessReportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDAccessReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDAccessReportSpec) DeepCopyInto(out *ArgoCDAccessReportSpec) {
+	*out = *in
+	out.Output = in.Output
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDAccessReportSpec.
+func (in *ArgoCDAccessReportSpec) DeepCopy() *ArgoCDAccessReportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDAccessReportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDAccessReportStatus) DeepCopyInto(out *ArgoCDAccessReportStatus) {
+	*out = *in
+	if in.LastReportTime != nil {
+		in, out := &in.LastReportTime, &out.LastReportTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextReportTime != nil {
+		in, out := &in.NextReportTime, &out.NextReportTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDAccessReportStatus.
+func (in *ArgoCDAccessReportStatus) DeepCopy() *ArgoCDAccessReportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDAccessReportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDAccessRequest) DeepCopyInto(out *ArgoCDAccessRequest) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
+	out.Spec = in.Spec
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleBinding.
-func (in *ArgoCDProjectRoleBinding) DeepCopy() *ArgoCDProjectRoleBinding {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDAccessRequest.
+func (in *ArgoCDAccessRequest) DeepCopy() *ArgoCDAccessRequest {
 	if in == nil {
 		return nil
 	}
-	out := new(ArgoCDProjectRoleBinding)
+	out := new(ArgoCDAccessRequest)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ArgoCDProjectRoleBinding) DeepCopyObject() runtime.Object {
+func (in *ArgoCDAccessRequest) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -99,31 +297,31 @@ func (in *ArgoCDProjectRoleBinding) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ArgoCDProjectRoleBindingList) DeepCopyInto(out *ArgoCDProjectRoleBindingList) {
+func (in *ArgoCDAccessRequestList) DeepCopyInto(out *ArgoCDAccessRequestList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]ArgoCDProjectRoleBinding, len(*in))
+		*out = make([]ArgoCDAccessRequest, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleBindingList.
-func (in *ArgoCDProjectRoleBindingList) DeepCopy() *ArgoCDProjectRoleBindingList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDAccessRequestList.
+func (in *ArgoCDAccessRequestList) DeepCopy() *ArgoCDAccessRequestList {
 	if in == nil {
 		return nil
 	}
-	out := new(ArgoCDProjectRoleBindingList)
+	out := new(ArgoCDAccessRequestList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ArgoCDProjectRoleBindingList) DeepCopyObject() runtime.Object {
+func (in *ArgoCDAccessRequestList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -131,31 +329,41 @@ func (in *ArgoCDProjectRoleBindingList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ArgoCDProjectRoleBindingSpec) DeepCopyInto(out *ArgoCDProjectRoleBindingSpec) {
+func (in *ArgoCDAccessRequestSpec) DeepCopyInto(out *ArgoCDAccessRequestSpec) {
 	*out = *in
-	if in.Subjects != nil {
-		in, out := &in.Subjects, &out.Subjects
-		*out = make([]AppProjectSubject, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	out.ArgoCDProjectRoleRef = in.ArgoCDProjectRoleRef
+	out.ArgoCDRoleRef = in.ArgoCDRoleRef
+	out.Subject = in.Subject
+	out.Duration = in.Duration
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleBindingSpec.
-func (in *ArgoCDProjectRoleBindingSpec) DeepCopy() *ArgoCDProjectRoleBindingSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDAccessRequestSpec.
+func (in *ArgoCDAccessRequestSpec) DeepCopy() *ArgoCDAccessRequestSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ArgoCDProjectRoleBindingSpec)
+	out := new(ArgoCDAccessRequestSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ArgoCDProjectRoleBindingStatus) DeepCopyInto(out *ArgoCDProjectRoleBindingStatus) {
+func (in *ArgoCDAccessRequestStatus) DeepCopyInto(out *ArgoCDAccessRequestStatus) {
 	*out = *in
+	if in.ApprovedAt != nil {
+		in, out := &in.ApprovedAt, &out.ApprovedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]AccessRequestEvent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]Condition, len(*in))
@@ -163,49 +371,70 @@ func (in *ArgoCDProjectRoleBindingStatus) DeepCopyInto(out *ArgoCDProjectRoleBin
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.AppProjectsBound != nil {
-		in, out := &in.AppProjectsBound, &out.AppProjectsBound
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDAccessRequestStatus.
+func (in *ArgoCDAccessRequestStatus) DeepCopy() *ArgoCDAccessRequestStatus {
+	if in == nil {
+		return nil
 	}
+	out := new(ArgoCDAccessRequestStatus)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleBindingStatus.
-func (in *ArgoCDProjectRoleBindingStatus) DeepCopy() *ArgoCDProjectRoleBindingStatus {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDApprovalPolicy) DeepCopyInto(out *ArgoCDApprovalPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDApprovalPolicy.
+func (in *ArgoCDApprovalPolicy) DeepCopy() *ArgoCDApprovalPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(ArgoCDProjectRoleBindingStatus)
+	out := new(ArgoCDApprovalPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDApprovalPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ArgoCDProjectRoleList) DeepCopyInto(out *ArgoCDProjectRoleList) {
+func (in *ArgoCDApprovalPolicyList) DeepCopyInto(out *ArgoCDApprovalPolicyList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]ArgoCDProjectRole, len(*in))
+		*out = make([]ArgoCDApprovalPolicy, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleList.
-func (in *ArgoCDProjectRoleList) DeepCopy() *ArgoCDProjectRoleList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDApprovalPolicyList.
+func (in *ArgoCDApprovalPolicyList) DeepCopy() *ArgoCDApprovalPolicyList {
 	if in == nil {
 		return nil
 	}
-	out := new(ArgoCDProjectRoleList)
+	out := new(ArgoCDApprovalPolicyList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ArgoCDProjectRoleList) DeepCopyObject() runtime.Object {
+func (in *ArgoCDApprovalPolicyList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -213,60 +442,946 @@ func (in *ArgoCDProjectRoleList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ArgoCDProjectRoleRef) DeepCopyInto(out *ArgoCDProjectRoleRef) {
+func (in *ArgoCDApprovalPolicySpec) DeepCopyInto(out *ArgoCDApprovalPolicySpec) {
 	*out = *in
+	if in.ArgoCDRoles != nil {
+		in, out := &in.ArgoCDRoles, &out.ArgoCDRoles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Approvers != nil {
+		in, out := &in.Approvers, &out.Approvers
+		*out = make([]Approver, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxDuration != nil {
+		in, out := &in.MaxDuration, &out.MaxDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleRef.
-func (in *ArgoCDProjectRoleRef) DeepCopy() *ArgoCDProjectRoleRef {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDApprovalPolicySpec.
+func (in *ArgoCDApprovalPolicySpec) DeepCopy() *ArgoCDApprovalPolicySpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ArgoCDProjectRoleRef)
+	out := new(ArgoCDApprovalPolicySpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ArgoCDProjectRoleSpec) DeepCopyInto(out *ArgoCDProjectRoleSpec) {
+func (in *ArgoCDLocalAccount) DeepCopyInto(out *ArgoCDLocalAccount) {
 	*out = *in
-	if in.Rules != nil {
-		in, out := &in.Rules, &out.Rules
-		*out = make([]ProjectRule, len(*in))
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDLocalAccount.
+func (in *ArgoCDLocalAccount) DeepCopy() *ArgoCDLocalAccount {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDLocalAccount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDLocalAccount) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDLocalAccountList) DeepCopyInto(out *ArgoCDLocalAccountList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ArgoCDLocalAccount, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleSpec.
-func (in *ArgoCDProjectRoleSpec) DeepCopy() *ArgoCDProjectRoleSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDLocalAccountList.
+func (in *ArgoCDLocalAccountList) DeepCopy() *ArgoCDLocalAccountList {
 	if in == nil {
 		return nil
 	}
-	out := new(ArgoCDProjectRoleSpec)
-	in.DeepCopyInto(out)
-	return out
+	out := new(ArgoCDLocalAccountList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDLocalAccountList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDLocalAccountSpec) DeepCopyInto(out *ArgoCDLocalAccountSpec) {
+	*out = *in
+	if in.Capabilities != nil {
+		in, out := &in.Capabilities, &out.Capabilities
+		*out = make([]AccountCapability, len(*in))
+		copy(*out, *in)
+	}
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.PasswordSecretRef != nil {
+		in, out := &in.PasswordSecretRef, &out.PasswordSecretRef
+		*out = new(SecretKeyRef)
+		**out = **in
+	}
+	if in.Tokens != nil {
+		in, out := &in.Tokens, &out.Tokens
+		*out = make([]LocalAccountToken, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDLocalAccountSpec.
+func (in *ArgoCDLocalAccountSpec) DeepCopy() *ArgoCDLocalAccountSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDLocalAccountSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDLocalAccountStatus) DeepCopyInto(out *ArgoCDLocalAccountStatus) {
+	*out = *in
+	if in.PasswordMtime != nil {
+		in, out := &in.PasswordMtime, &out.PasswordMtime
+		*out = (*in).DeepCopy()
+	}
+	if in.Tokens != nil {
+		in, out := &in.Tokens, &out.Tokens
+		*out = make([]LocalAccountTokenStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Bindings != nil {
+		in, out := &in.Bindings, &out.Bindings
+		*out = make([]LocalAccountBindingStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDLocalAccountStatus.
+func (in *ArgoCDLocalAccountStatus) DeepCopy() *ArgoCDLocalAccountStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDLocalAccountStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDProjectRole) DeepCopyInto(out *ArgoCDProjectRole) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRole.
+func (in *ArgoCDProjectRole) DeepCopy() *ArgoCDProjectRole {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDProjectRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDProjectRole) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDProjectRoleBinding) DeepCopyInto(out *ArgoCDProjectRoleBinding) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleBinding.
+func (in *ArgoCDProjectRoleBinding) DeepCopy() *ArgoCDProjectRoleBinding {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDProjectRoleBinding)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDProjectRoleBinding) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDProjectRoleBindingList) DeepCopyInto(out *ArgoCDProjectRoleBindingList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ArgoCDProjectRoleBinding, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleBindingList.
+func (in *ArgoCDProjectRoleBindingList) DeepCopy() *ArgoCDProjectRoleBindingList {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDProjectRoleBindingList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDProjectRoleBindingList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDProjectRoleBindingSpec) DeepCopyInto(out *ArgoCDProjectRoleBindingSpec) {
+	*out = *in
+	if in.Subjects != nil {
+		in, out := &in.Subjects, &out.Subjects
+		*out = make([]AppProjectSubject, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.ArgoCDProjectRoleRef = in.ArgoCDProjectRoleRef
+	if in.NotBefore != nil {
+		in, out := &in.NotBefore, &out.NotBefore
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Schedules != nil {
+		in, out := &in.Schedules, &out.Schedules
+		*out = make([]Schedule, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleBindingSpec.
+func (in *ArgoCDProjectRoleBindingSpec) DeepCopy() *ArgoCDProjectRoleBindingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDProjectRoleBindingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDProjectRoleBindingStatus) DeepCopyInto(out *ArgoCDProjectRoleBindingStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AppProjectsBound != nil {
+		in, out := &in.AppProjectsBound, &out.AppProjectsBound
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ActiveSubjects != nil {
+		in, out := &in.ActiveSubjects, &out.ActiveSubjects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NextTransition != nil {
+		in, out := &in.NextTransition, &out.NextTransition
+		*out = (*in).DeepCopy()
+	}
+	if in.UpcomingWindows != nil {
+		in, out := &in.UpcomingWindows, &out.UpcomingWindows
+		*out = make([]TimeWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Recertification != nil {
+		in, out := &in.Recertification, &out.Recertification
+		*out = new(RecertificationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PlannedChanges != nil {
+		in, out := &in.PlannedChanges, &out.PlannedChanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleBindingStatus.
+func (in *ArgoCDProjectRoleBindingStatus) DeepCopy() *ArgoCDProjectRoleBindingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDProjectRoleBindingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDProjectRoleList) DeepCopyInto(out *ArgoCDProjectRoleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ArgoCDProjectRole, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleList.
+func (in *ArgoCDProjectRoleList) DeepCopy() *ArgoCDProjectRoleList {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDProjectRoleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDProjectRoleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDProjectRoleRef) DeepCopyInto(out *ArgoCDProjectRoleRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleRef.
+func (in *ArgoCDProjectRoleRef) DeepCopy() *ArgoCDProjectRoleRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDProjectRoleRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDProjectRoleSpec) DeepCopyInto(out *ArgoCDProjectRoleSpec) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]ProjectRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleSpec.
+func (in *ArgoCDProjectRoleSpec) DeepCopy() *ArgoCDProjectRoleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDProjectRoleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDProjectRoleStatus) DeepCopyInto(out *ArgoCDProjectRoleStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AppProjects != nil {
+		in, out := &in.AppProjects, &out.AppProjects
+		*out = make([]AppProjectSyncStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.PlannedChanges != nil {
+		in, out := &in.PlannedChanges, &out.PlannedChanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AppliedSpec != nil {
+		in, out := &in.AppliedSpec, &out.AppliedSpec
+		*out = new(ArgoCDProjectRoleSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PendingChanges != nil {
+		in, out := &in.PendingChanges, &out.PendingChanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleStatus.
+func (in *ArgoCDProjectRoleStatus) DeepCopy() *ArgoCDProjectRoleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDProjectRoleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDProjectRoleToken) DeepCopyInto(out *ArgoCDProjectRoleToken) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleToken.
+func (in *ArgoCDProjectRoleToken) DeepCopy() *ArgoCDProjectRoleToken {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDProjectRoleToken)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDProjectRoleToken) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDProjectRoleTokenList) DeepCopyInto(out *ArgoCDProjectRoleTokenList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ArgoCDProjectRoleToken, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleTokenList.
+func (in *ArgoCDProjectRoleTokenList) DeepCopy() *ArgoCDProjectRoleTokenList {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDProjectRoleTokenList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDProjectRoleTokenList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDProjectRoleTokenSpec) DeepCopyInto(out *ArgoCDProjectRoleTokenSpec) {
+	*out = *in
+	if in.ExpiresIn != nil {
+		in, out := &in.ExpiresIn, &out.ExpiresIn
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.RenewBefore != nil {
+		in, out := &in.RenewBefore, &out.RenewBefore
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleTokenSpec.
+func (in *ArgoCDProjectRoleTokenSpec) DeepCopy() *ArgoCDProjectRoleTokenSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDProjectRoleTokenSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDProjectRoleTokenStatus) DeepCopyInto(out *ArgoCDProjectRoleTokenStatus) {
+	*out = *in
+	if in.IssuedAt != nil {
+		in, out := &in.IssuedAt, &out.IssuedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleTokenStatus.
+func (in *ArgoCDProjectRoleTokenStatus) DeepCopy() *ArgoCDProjectRoleTokenStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDProjectRoleTokenStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDRBACRevision) DeepCopyInto(out *ArgoCDRBACRevision) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDRBACRevision.
+func (in *ArgoCDRBACRevision) DeepCopy() *ArgoCDRBACRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDRBACRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDRBACRevision) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDRBACRevisionList) DeepCopyInto(out *ArgoCDRBACRevisionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ArgoCDRBACRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDRBACRevisionList.
+func (in *ArgoCDRBACRevisionList) DeepCopy() *ArgoCDRBACRevisionList {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDRBACRevisionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDRBACRevisionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDRBACRevisionSpec) DeepCopyInto(out *ArgoCDRBACRevisionSpec) {
+	*out = *in
+	out.Trigger = in.Trigger
+	in.RecordedAt.DeepCopyInto(&out.RecordedAt)
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ProjectRoles != nil {
+		in, out := &in.ProjectRoles, &out.ProjectRoles
+		*out = make([]RevisionProjectRole, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDRBACRevisionSpec.
+func (in *ArgoCDRBACRevisionSpec) DeepCopy() *ArgoCDRBACRevisionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDRBACRevisionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDRBACRollback) DeepCopyInto(out *ArgoCDRBACRollback) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDRBACRollback.
+func (in *ArgoCDRBACRollback) DeepCopy() *ArgoCDRBACRollback {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDRBACRollback)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDRBACRollback) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDRBACRollbackList) DeepCopyInto(out *ArgoCDRBACRollbackList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ArgoCDRBACRollback, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDRBACRollbackList.
+func (in *ArgoCDRBACRollbackList) DeepCopy() *ArgoCDRBACRollbackList {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDRBACRollbackList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDRBACRollbackList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDRBACRollbackSpec) DeepCopyInto(out *ArgoCDRBACRollbackSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDRBACRollbackSpec.
+func (in *ArgoCDRBACRollbackSpec) DeepCopy() *ArgoCDRBACRollbackSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDRBACRollbackSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDRBACRollbackStatus) DeepCopyInto(out *ArgoCDRBACRollbackStatus) {
+	*out = *in
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Restored != nil {
+		in, out := &in.Restored, &out.Restored
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Skipped != nil {
+		in, out := &in.Skipped, &out.Skipped
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Paused != nil {
+		in, out := &in.Paused, &out.Paused
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDRBACRollbackStatus.
+func (in *ArgoCDRBACRollbackStatus) DeepCopy() *ArgoCDRBACRollbackStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDRBACRollbackStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDRBACTenantPolicy) DeepCopyInto(out *ArgoCDRBACTenantPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDRBACTenantPolicy.
+func (in *ArgoCDRBACTenantPolicy) DeepCopy() *ArgoCDRBACTenantPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDRBACTenantPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDRBACTenantPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDRBACTenantPolicyList) DeepCopyInto(out *ArgoCDRBACTenantPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ArgoCDRBACTenantPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDRBACTenantPolicyList.
+func (in *ArgoCDRBACTenantPolicyList) DeepCopy() *ArgoCDRBACTenantPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDRBACTenantPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDRBACTenantPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDRBACTenantPolicySpec) DeepCopyInto(out *ArgoCDRBACTenantPolicySpec) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Verbs != nil {
+		in, out := &in.Verbs, &out.Verbs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Objects != nil {
+		in, out := &in.Objects, &out.Objects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Subjects != nil {
+		in, out := &in.Subjects, &out.Subjects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDRBACTenantPolicySpec.
+func (in *ArgoCDRBACTenantPolicySpec) DeepCopy() *ArgoCDRBACTenantPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDRBACTenantPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDRecertificationPolicy) DeepCopyInto(out *ArgoCDRecertificationPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDRecertificationPolicy.
+func (in *ArgoCDRecertificationPolicy) DeepCopy() *ArgoCDRecertificationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDRecertificationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDRecertificationPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDRecertificationPolicyList) DeepCopyInto(out *ArgoCDRecertificationPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ArgoCDRecertificationPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDRecertificationPolicyList.
+func (in *ArgoCDRecertificationPolicyList) DeepCopy() *ArgoCDRecertificationPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDRecertificationPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDRecertificationPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ArgoCDProjectRoleStatus) DeepCopyInto(out *ArgoCDProjectRoleStatus) {
+func (in *ArgoCDRecertificationPolicySpec) DeepCopyInto(out *ArgoCDRecertificationPolicySpec) {
 	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]Condition, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	out.Interval = in.Interval
+	if in.GracePeriod != nil {
+		in, out := &in.GracePeriod, &out.GracePeriod
+		*out = new(v1.Duration)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDProjectRoleStatus.
-func (in *ArgoCDProjectRoleStatus) DeepCopy() *ArgoCDProjectRoleStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDRecertificationPolicySpec.
+func (in *ArgoCDRecertificationPolicySpec) DeepCopy() *ArgoCDRecertificationPolicySpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ArgoCDProjectRoleStatus)
+	out := new(ArgoCDRecertificationPolicySpec)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -363,9 +1478,24 @@ func (in *ArgoCDRoleBindingSpec) DeepCopyInto(out *ArgoCDRoleBindingSpec) {
 	if in.Subjects != nil {
 		in, out := &in.Subjects, &out.Subjects
 		*out = make([]GlobalSubject, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	out.ArgoCDRoleRef = in.ArgoCDRoleRef
+	if in.NotBefore != nil {
+		in, out := &in.NotBefore, &out.NotBefore
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Schedules != nil {
+		in, out := &in.Schedules, &out.Schedules
+		*out = make([]Schedule, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDRoleBindingSpec.
@@ -381,6 +1511,31 @@ func (in *ArgoCDRoleBindingSpec) DeepCopy() *ArgoCDRoleBindingSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ArgoCDRoleBindingStatus) DeepCopyInto(out *ArgoCDRoleBindingStatus) {
 	*out = *in
+	if in.ActiveSubjects != nil {
+		in, out := &in.ActiveSubjects, &out.ActiveSubjects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NextTransition != nil {
+		in, out := &in.NextTransition, &out.NextTransition
+		*out = (*in).DeepCopy()
+	}
+	if in.UpcomingWindows != nil {
+		in, out := &in.UpcomingWindows, &out.UpcomingWindows
+		*out = make([]TimeWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BreakGlassDeadline != nil {
+		in, out := &in.BreakGlassDeadline, &out.BreakGlassDeadline
+		*out = (*in).DeepCopy()
+	}
+	if in.Recertification != nil {
+		in, out := &in.Recertification, &out.Recertification
+		*out = new(RecertificationStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]Condition, len(*in))
@@ -388,6 +1543,11 @@ func (in *ArgoCDRoleBindingStatus) DeepCopyInto(out *ArgoCDRoleBindingStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PlannedChanges != nil {
+		in, out := &in.PlannedChanges, &out.PlannedChanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDRoleBindingStatus.
@@ -479,6 +1639,21 @@ func (in *ArgoCDRoleStatus) DeepCopyInto(out *ArgoCDRoleStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PlannedChanges != nil {
+		in, out := &in.PlannedChanges, &out.PlannedChanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AppliedSpec != nil {
+		in, out := &in.AppliedSpec, &out.AppliedSpec
+		*out = new(ArgoCDRoleSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PendingChanges != nil {
+		in, out := &in.PendingChanges, &out.PendingChanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDRoleStatus.
@@ -491,6 +1666,94 @@ func (in *ArgoCDRoleStatus) DeepCopy() *ArgoCDRoleStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDSubjectPermissions) DeepCopyInto(out *ArgoCDSubjectPermissions) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDSubjectPermissions.
+func (in *ArgoCDSubjectPermissions) DeepCopy() *ArgoCDSubjectPermissions {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDSubjectPermissions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDSubjectPermissions) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDSubjectPermissionsList) DeepCopyInto(out *ArgoCDSubjectPermissionsList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ArgoCDSubjectPermissions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDSubjectPermissionsList.
+func (in *ArgoCDSubjectPermissionsList) DeepCopy() *ArgoCDSubjectPermissionsList {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDSubjectPermissionsList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ArgoCDSubjectPermissionsList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDSubjectPermissionsSpec) DeepCopyInto(out *ArgoCDSubjectPermissionsSpec) {
+	*out = *in
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]RoleGrant, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProjectRoles != nil {
+		in, out := &in.ProjectRoles, &out.ProjectRoles
+		*out = make([]RoleGrant, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]PermissionRule, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDSubjectPermissionsSpec.
+func (in *ArgoCDSubjectPermissionsSpec) DeepCopy() *ArgoCDSubjectPermissionsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDSubjectPermissionsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Condition) DeepCopyInto(out *Condition) {
 	*out = *in
@@ -520,6 +1783,11 @@ func (in *GlobalRule) DeepCopyInto(out *GlobalRule) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ApplicationObjects != nil {
+		in, out := &in.ApplicationObjects, &out.ApplicationObjects
+		*out = make([]ApplicationObject, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalRule.
@@ -535,6 +1803,14 @@ func (in *GlobalRule) DeepCopy() *GlobalRule {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GlobalSubject) DeepCopyInto(out *GlobalSubject) {
 	*out = *in
+	if in.NotBefore != nil {
+		in, out := &in.NotBefore, &out.NotBefore
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalSubject.
@@ -547,6 +1823,92 @@ func (in *GlobalSubject) DeepCopy() *GlobalSubject {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalAccountBindingStatus) DeepCopyInto(out *LocalAccountBindingStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalAccountBindingStatus.
+func (in *LocalAccountBindingStatus) DeepCopy() *LocalAccountBindingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalAccountBindingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalAccountToken) DeepCopyInto(out *LocalAccountToken) {
+	*out = *in
+	if in.ExpiresIn != nil {
+		in, out := &in.ExpiresIn, &out.ExpiresIn
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalAccountToken.
+func (in *LocalAccountToken) DeepCopy() *LocalAccountToken {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalAccountToken)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalAccountTokenStatus) DeepCopyInto(out *LocalAccountTokenStatus) {
+	*out = *in
+	in.IssuedAt.DeepCopyInto(&out.IssuedAt)
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalAccountTokenStatus.
+func (in *LocalAccountTokenStatus) DeepCopy() *LocalAccountTokenStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalAccountTokenStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PermissionRule) DeepCopyInto(out *PermissionRule) {
+	*out = *in
+	out.Source = in.Source
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PermissionRule.
+func (in *PermissionRule) DeepCopy() *PermissionRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PermissionRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PermissionSource) DeepCopyInto(out *PermissionSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PermissionSource.
+func (in *PermissionSource) DeepCopy() *PermissionSource {
+	if in == nil {
+		return nil
+	}
+	out := new(PermissionSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProjectRule) DeepCopyInto(out *ProjectRule) {
 	*out = *in
@@ -571,3 +1933,125 @@ func (in *ProjectRule) DeepCopy() *ProjectRule {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecertificationStatus) DeepCopyInto(out *RecertificationStatus) {
+	*out = *in
+	in.LastAttestedAt.DeepCopyInto(&out.LastAttestedAt)
+	in.DueAt.DeepCopyInto(&out.DueAt)
+	in.SuspendAt.DeepCopyInto(&out.SuspendAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecertificationStatus.
+func (in *RecertificationStatus) DeepCopy() *RecertificationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RecertificationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RevisionProjectRole) DeepCopyInto(out *RevisionProjectRole) {
+	*out = *in
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RevisionProjectRole.
+func (in *RevisionProjectRole) DeepCopy() *RevisionProjectRole {
+	if in == nil {
+		return nil
+	}
+	out := new(RevisionProjectRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RevisionTrigger) DeepCopyInto(out *RevisionTrigger) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RevisionTrigger.
+func (in *RevisionTrigger) DeepCopy() *RevisionTrigger {
+	if in == nil {
+		return nil
+	}
+	out := new(RevisionTrigger)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoleGrant) DeepCopyInto(out *RoleGrant) {
+	*out = *in
+	out.Source = in.Source
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoleGrant.
+func (in *RoleGrant) DeepCopy() *RoleGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(RoleGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Schedule) DeepCopyInto(out *Schedule) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Schedule.
+func (in *Schedule) DeepCopy() *Schedule {
+	if in == nil {
+		return nil
+	}
+	out := new(Schedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeyRef) DeepCopyInto(out *SecretKeyRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyRef.
+func (in *SecretKeyRef) DeepCopy() *SecretKeyRef {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeyRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TimeWindow) DeepCopyInto(out *TimeWindow) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TimeWindow.
+func (in *TimeWindow) DeepCopy() *TimeWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(TimeWindow)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -20,23 +20,33 @@ import (
 	"crypto/tls"
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	argoprojiov1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/audit"
 	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/lint"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/notify"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+	webhookv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/internal/webhook/v1alpha1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -61,12 +71,78 @@ func main() {
 	var enableHTTP2 bool
 	var argoCDRBACConfigMapName string
 	var argoCDRBACConfigMapNamespace string
+	var argoCDNamespace string
+	var argoCDSecretName string
+	var argoCDConfigMapName string
+	var enableWebhooks bool
+	var breakGlassMaxDuration time.Duration
+	var breakGlassAdmin bool
+	var enableEscalationCheck bool
+	var escalationUserPrefix string
+	var escalationGroupPrefix string
+	var lintConfig string
+	var dryRun bool
+	var revisionHistoryLimit int
+	var enableSubjectPermissions bool
+	var auditSink string
+	var notificationConfig string
+	var enableGraphEndpoint bool
+	var skipUnboundRoles bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metric endpoint binds to. "+
 		"Use the port :8080. If not set, it will be 0 in order to disable the metrics server")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.StringVar(&argoCDRBACConfigMapName, "argocd-rbac-cm-name", "argocd-rbac-cm", "The name of ArgoCD RBAC configmap.")
 	flag.StringVar(&argoCDRBACConfigMapNamespace, "argocd-rbac-cm-namespace", "argocd",
 		"The namespace of ArgoCD RBAC configmap.")
+	flag.StringVar(&argoCDNamespace, "argocd-namespace", "",
+		"The namespace AppProjects are looked up in, if not specified by the ArgoCDProjectRoleBinding subject. "+
+			"If not set, the namespace of the ArgoCDProjectRoleBinding is used.")
+	flag.StringVar(&argoCDConfigMapName, "argocd-cm-name", "argocd-cm",
+		"The name of ArgoCD configmap local accounts are managed in. "+
+			"The configmap is looked up in the namespace of ArgoCD RBAC configmap.")
+	flag.StringVar(&argoCDSecretName, "argocd-secret-name", "argocd-secret",
+		"The name of ArgoCD secret holding the key to sign project role tokens with. "+
+			"The secret is looked up in the namespace of ArgoCD RBAC configmap.")
+	flag.DurationVar(&breakGlassMaxDuration, "break-glass-max-duration", 4*time.Hour,
+		"The longest time a break-glass role can be bound for. The binding is deleted afterwards.")
+	flag.BoolVar(&breakGlassAdmin, "break-glass-admin", true,
+		"If set, the built-in admin role is a break-glass role: bindings to it need a reason and are deleted after the "+
+			"maximum break-glass duration. Set --break-glass-admin=false to keep standing admin bindings.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"If set, the admission webhooks are served. Requires a serving certificate in /tmp/k8s-webhook-server/serving-certs.")
+	flag.BoolVar(&enableEscalationCheck, "enable-escalation-check", false,
+		"If set, the webhooks reject roles and bindings granting Argo CD permissions the requester does not hold, "+
+			"unless it is allowed the escalate verb on them. Requires --enable-webhooks.")
+	flag.StringVar(&escalationUserPrefix, "escalation-user-prefix", "",
+		"The prefix removed from Kubernetes user names to get the Argo CD subject of the requester. "+
+			"Users without the prefix only hold the default role.")
+	flag.StringVar(&escalationGroupPrefix, "escalation-group-prefix", "",
+		"The prefix removed from Kubernetes group names to get the Argo CD groups of the requester. "+
+			"Groups without the prefix are ignored.")
+	flag.StringVar(&lintConfig, "lint-config", "",
+		"The YAML file adding, replacing and disabling lint rules. If not set, the built-in lint rules are applied.")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"If set, the changes to the Argo CD RBAC ConfigMap and AppProjects are reported in the status and events "+
+			"of the roles and bindings instead of being written. Tokens, local accounts and rollbacks are not reconciled.")
+	flag.IntVar(&revisionHistoryLimit, "revision-history-limit", 100,
+		"The number of ArgoCDRBACRevisions kept in the namespace of ArgoCD RBAC configmap, older ones are pruned. "+
+			"If 0, no revisions are recorded.")
+	flag.BoolVar(&enableSubjectPermissions, "enable-subject-permissions", false,
+		"If set, the effective permissions of every subject of the Argo CD RBAC policy are reported as "+
+			"ArgoCDSubjectPermissions in the namespace of ArgoCD RBAC configmap.")
+	flag.StringVar(&auditSink, "audit-sink", "",
+		"Where an audit entry is written for every change to the Argo CD RBAC ConfigMap and AppProjects: "+
+			"stdout, a file path or an http(s) URL entries are posted to. If not set, no audit log is written.")
+	flag.StringVar(&notificationConfig, "notification-config", "",
+		"The YAML file of the HTTP sinks CloudEvents of the changes to the Argo CD RBAC policy are posted to. "+
+			"If not set, no events are posted.")
+	flag.BoolVar(&enableGraphEndpoint, "enable-graph-endpoint", false,
+		"If set, the graph of the Argo CD RBAC policy is served on "+controller.GraphPath+" of the metrics endpoint "+
+			"in Graphviz DOT, Mermaid or JSON, to clients authorized to get "+controller.GraphPath+". "+
+			"Requires --metrics-bind-address and --metrics-secure.")
+	flag.BoolVar(&skipUnboundRoles, "skip-unbound-roles", false,
+		"If set, the rules of ArgoCDRoles no ArgoCDRoleBinding references are removed from the Argo CD RBAC ConfigMap "+
+			"instead of being rendered.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
@@ -102,13 +178,24 @@ func main() {
 		TLSOpts: tlsOpts,
 	})
 
+	metricsServerOptions := metricsserver.Options{
+		BindAddress:   metricsAddr,
+		SecureServing: secureMetrics,
+		TLSOpts:       tlsOpts,
+	}
+	// The graph exposes every subject and its permissions, so it is only served over TLS to clients authenticated
+	// and authorized with TokenReviews and SubjectAccessReviews.
+	if enableGraphEndpoint {
+		if !secureMetrics {
+			setupLog.Error(nil, "--enable-graph-endpoint requires --metrics-secure")
+			os.Exit(1)
+		}
+		metricsServerOptions.FilterProvider = filters.WithAuthenticationAndAuthorization
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme: scheme,
-		Metrics: metricsserver.Options{
-			BindAddress:   metricsAddr,
-			SecureServing: secureMetrics,
-			TLSOpts:       tlsOpts,
-		},
+		Scheme:                 scheme,
+		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
@@ -124,18 +211,75 @@ func main() {
 		// if you are doing or is intended to do any operation such as perform cleanups
 		// after the manager stops then its usage might be unsafe.
 		// LeaderElectionReleaseOnCancel: true,
+		Cache: cache.Options{
+			// Only the Secrets of the operator are cached, see controller.SecretCacheSelector
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.Secret{}: {Label: controller.SecretCacheSelector()},
+			},
+		},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	linterConfig := lint.Config{}
+	if lintConfig != "" {
+		if linterConfig, err = lint.LoadConfig(lintConfig); err != nil {
+			setupLog.Error(err, "unable to load lint configuration")
+			os.Exit(1)
+		}
+	}
+	linter, err := lint.New(linterConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to create linter")
+		os.Exit(1)
+	}
+
+	var history *controller.RevisionHistory
+	if revisionHistoryLimit > 0 {
+		history = &controller.RevisionHistory{Namespace: argoCDRBACConfigMapNamespace, Limit: revisionHistoryLimit}
+	}
+
+	var auditLog *controller.AuditLog
+	if auditSink != "" {
+		sink, err := audit.NewSink(auditSink)
+		if err != nil {
+			setupLog.Error(err, "unable to create audit sink")
+			os.Exit(1)
+		}
+		auditLog = &controller.AuditLog{Sink: sink, TrustChangedBy: enableWebhooks}
+	}
+
+	var notifications *controller.Notifications
+	if notificationConfig != "" {
+		config, err := notify.LoadConfig(notificationConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to load notification config")
+			os.Exit(1)
+		}
+		notifier, err := notify.New(config)
+		if err != nil {
+			setupLog.Error(err, "unable to create notifier")
+			os.Exit(1)
+		}
+		notifications = &controller.Notifications{Notifier: notifier}
+	}
+
 	if err = (&controller.ArgoCDRoleReconciler{
 		Client:                       mgr.GetClient(),
 		Scheme:                       mgr.GetScheme(),
 		ArgoCDRBACConfigMapName:      argoCDRBACConfigMapName,
 		ArgoCDRBACConfigMapNamespace: argoCDRBACConfigMapNamespace,
 		Log:                          ctrl.Log.WithName("controllers").WithName("ArgoCDRole"),
+		Linter:                       linter,
+		Recorder:                     mgr.GetEventRecorderFor("argocdrole-controller"),
+		TrustApprovals:               enableWebhooks,
+		DryRun:                       dryRun,
+		History:                      history,
+		Audit:                        auditLog,
+		Notifications:                notifications,
+		SkipUnboundRoles:             skipUnboundRoles,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Role")
 		os.Exit(1)
@@ -146,28 +290,173 @@ func main() {
 		ArgoCDRBACConfigMapName:      argoCDRBACConfigMapName,
 		ArgoCDRBACConfigMapNamespace: argoCDRBACConfigMapNamespace,
 		Log:                          ctrl.Log.WithName("controllers").WithName("ArgoCDRoleBinding"),
+		Recorder:                     mgr.GetEventRecorderFor("argocdrolebinding-controller"),
+		BreakGlassMaxDuration:        breakGlassMaxDuration,
+		BreakGlassAdmin:              breakGlassAdmin,
+		TrustAttestations:            enableWebhooks,
+		Linter:                       linter,
+		DryRun:                       dryRun,
+		History:                      history,
+		Audit:                        auditLog,
+		Notifications:                notifications,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ArgoCDRoleBinding")
 		os.Exit(1)
 	}
 	if err := (&controller.ArgoCDProjectRoleReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("ArgoCDProjectRole"),
-		Scheme: mgr.GetScheme(),
+		Client:          mgr.GetClient(),
+		Log:             ctrl.Log.WithName("controllers").WithName("ArgoCDProjectRole"),
+		Scheme:          mgr.GetScheme(),
+		ArgoCDNamespace: argoCDNamespace,
+		Linter:          linter,
+		Recorder:        mgr.GetEventRecorderFor("argocdprojectrole-controller"),
+		TrustApprovals:  enableWebhooks,
+		DryRun:          dryRun,
+		History:         history,
+		Audit:           auditLog,
+		Notifications:   notifications,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ArgoCDProjectRole")
 		os.Exit(1)
 	}
 	if err := (&controller.ArgoCDProjectRoleBindingReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Log:    ctrl.Log.WithName("controllers").WithName("ArgoCDProjectRoleBinding"),
+		Client:                       mgr.GetClient(),
+		Scheme:                       mgr.GetScheme(),
+		Log:                          ctrl.Log.WithName("controllers").WithName("ArgoCDProjectRoleBinding"),
+		ArgoCDNamespace:              argoCDNamespace,
+		ArgoCDRBACConfigMapName:      argoCDRBACConfigMapName,
+		ArgoCDRBACConfigMapNamespace: argoCDRBACConfigMapNamespace,
+		Recorder:                     mgr.GetEventRecorderFor("argocdprojectrolebinding-controller"),
+		TrustAttestations:            enableWebhooks,
+		Linter:                       linter,
+		DryRun:                       dryRun,
+		History:                      history,
+		Audit:                        auditLog,
+		Notifications:                notifications,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ArgoCDProjectRoleBinding")
 		os.Exit(1)
 	}
+	if err := (&controller.ArgoCDProjectRoleTokenReconciler{
+		Client:                mgr.GetClient(),
+		APIReader:             mgr.GetAPIReader(),
+		Scheme:                mgr.GetScheme(),
+		Log:                   ctrl.Log.WithName("controllers").WithName("ArgoCDProjectRoleToken"),
+		ArgoCDNamespace:       argoCDNamespace,
+		ArgoCDSecretName:      argoCDSecretName,
+		ArgoCDSecretNamespace: argoCDRBACConfigMapNamespace,
+		DryRun:                dryRun,
+		Audit:                 auditLog,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ArgoCDProjectRoleToken")
+		os.Exit(1)
+	}
+	if err := (&controller.ArgoCDLocalAccountReconciler{
+		Client:                   mgr.GetClient(),
+		APIReader:                mgr.GetAPIReader(),
+		Scheme:                   mgr.GetScheme(),
+		Log:                      ctrl.Log.WithName("controllers").WithName("ArgoCDLocalAccount"),
+		ArgoCDConfigMapName:      argoCDConfigMapName,
+		ArgoCDConfigMapNamespace: argoCDRBACConfigMapNamespace,
+		ArgoCDSecretName:         argoCDSecretName,
+		ArgoCDSecretNamespace:    argoCDRBACConfigMapNamespace,
+		DryRun:                   dryRun,
+		Audit:                    auditLog,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ArgoCDLocalAccount")
+		os.Exit(1)
+	}
+	if err := (&controller.ArgoCDAccessRequestReconciler{
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Log:            ctrl.Log.WithName("controllers").WithName("ArgoCDAccessRequest"),
+		Recorder:       mgr.GetEventRecorderFor("argocdaccessrequest-controller"),
+		TrustApprovals: enableWebhooks,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ArgoCDAccessRequest")
+		os.Exit(1)
+	}
+	if err := (&controller.ArgoCDRBACRollbackReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Log:           ctrl.Log.WithName("controllers").WithName("ArgoCDRBACRollback"),
+		Recorder:      mgr.GetEventRecorderFor("argocdrbacrollback-controller"),
+		History:       history,
+		Audit:         auditLog,
+		Notifications: notifications,
+		DryRun:        dryRun,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ArgoCDRBACRollback")
+		os.Exit(1)
+	}
+	if err := (&controller.ArgoCDAccessReportReconciler{
+		Client:                       mgr.GetClient(),
+		APIReader:                    mgr.GetAPIReader(),
+		Scheme:                       mgr.GetScheme(),
+		Log:                          ctrl.Log.WithName("controllers").WithName("ArgoCDAccessReport"),
+		Recorder:                     mgr.GetEventRecorderFor("argocdaccessreport-controller"),
+		ArgoCDRBACConfigMapName:      argoCDRBACConfigMapName,
+		ArgoCDRBACConfigMapNamespace: argoCDRBACConfigMapNamespace,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ArgoCDAccessReport")
+		os.Exit(1)
+	}
+	if enableSubjectPermissions {
+		if err := (&controller.ArgoCDSubjectPermissionsReconciler{
+			Client:                       mgr.GetClient(),
+			Scheme:                       mgr.GetScheme(),
+			Log:                          ctrl.Log.WithName("controllers").WithName("ArgoCDSubjectPermissions"),
+			ArgoCDRBACConfigMapName:      argoCDRBACConfigMapName,
+			ArgoCDRBACConfigMapNamespace: argoCDRBACConfigMapNamespace,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ArgoCDSubjectPermissions")
+			os.Exit(1)
+		}
+	}
+	if enableWebhooks {
+		var escalation *webhookv1alpha1.EscalationCheck
+		if enableEscalationCheck {
+			escalation = &webhookv1alpha1.EscalationCheck{
+				Client:                       mgr.GetClient(),
+				ArgoCDRBACConfigMapName:      argoCDRBACConfigMapName,
+				ArgoCDRBACConfigMapNamespace: argoCDRBACConfigMapNamespace,
+				Mapping:                      policy.SubjectMapping{UserPrefix: escalationUserPrefix, GroupPrefix: escalationGroupPrefix},
+			}
+		}
+		if err := webhookv1alpha1.SetupArgoCDAccessRequestWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ArgoCDAccessRequest")
+			os.Exit(1)
+		}
+		if err := webhookv1alpha1.SetupArgoCDRoleWebhookWithManager(mgr, argoCDRBACConfigMapNamespace, escalation); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ArgoCDRole")
+			os.Exit(1)
+		}
+		if err := webhookv1alpha1.SetupArgoCDRoleBindingWebhookWithManager(mgr, argoCDRBACConfigMapNamespace, escalation); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ArgoCDRoleBinding")
+			os.Exit(1)
+		}
+		if err := webhookv1alpha1.SetupArgoCDProjectRoleWebhookWithManager(mgr, escalation); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ArgoCDProjectRole")
+			os.Exit(1)
+		}
+		if err := webhookv1alpha1.SetupArgoCDProjectRoleBindingWebhookWithManager(mgr, escalation); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ArgoCDProjectRoleBinding")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
+	if enableGraphEndpoint {
+		if err := mgr.AddMetricsServerExtraHandler(controller.GraphPath, &controller.GraphHandler{
+			Client:                       mgr.GetClient(),
+			ArgoCDRBACConfigMapName:      argoCDRBACConfigMapName,
+			ArgoCDRBACConfigMapNamespace: argoCDRBACConfigMapNamespace,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up graph endpoint")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -178,7 +467,13 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctrl.SetupSignalHandler())
+	if notifications != nil {
+		// Post the events still being retried before exiting, also if the manager failed
+		setupLog.Info("waiting for the notifications to be posted")
+		notifications.Notifier.Wait()
+	}
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
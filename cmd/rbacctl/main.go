@@ -0,0 +1,412 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// rbacctl checks ArgoCDRoles, ArgoCDProjectRoles and their bindings in manifest files, e.g. in CI,
+// rolls the Argo CD RBAC policy back to an ArgoCDRBACRevision, explains its decisions, exports it as a graph
+// and reports who has what access.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/yaml"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/accessreport"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/graph"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/lint"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+)
+
+const usage = `Usage: rbacctl <command> [flags] <args>...
+
+Commands:
+  lint <file>...          report risky grants of the ArgoCDRoles, ArgoCDProjectRoles and their bindings in the manifest files
+  rollback <revision>     restore the Argo CD RBAC policy of the ArgoCDRBACRevision and pause the resources written since
+  explain <subject> <resource> <action> <object>
+                          decide the request and trace the deciding rule back to the roles and bindings it was rendered from
+  graph                   export the subjects, bindings, roles and rules of the Argo CD RBAC policy as DOT, Mermaid or JSON
+  report                  report every rule of every subject of the Argo CD RBAC policy as CSV, Markdown or JSON
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "lint":
+		os.Exit(runLint(os.Args[2:], os.Stdout, os.Stderr))
+	case "rollback":
+		os.Exit(runRollback(os.Args[2:], os.Stdout, os.Stderr))
+	case "explain":
+		os.Exit(runExplain(os.Args[2:], os.Stdout, os.Stderr))
+	case "graph":
+		os.Exit(runGraph(os.Args[2:], os.Stdout, os.Stderr))
+	case "report":
+		os.Exit(runReport(os.Args[2:], os.Stdout, os.Stderr))
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// runLint lints the manifest files and returns the exit code: 1 if a finding is at least as severe as --fail-on.
+func runLint(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	config := flags.String("config", "", "The YAML file adding, replacing and disabling lint rules.")
+	failOn := flags.String("fail-on", string(lint.SeverityHigh), "The least severity failing the check (info, low, medium, high or critical).")
+	argoCDNamespace := flags.String("argocd-namespace", "argocd", "The namespace of Argo CD, application objects in it are rendered without namespace.")
+	applicationNamespaces := flags.String("application-namespaces", "",
+		"The application.namespaces of Argo CD (comma separated), application objects in them are rendered with namespace.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if lint.Severity(*failOn).Rank() == 0 {
+		fmt.Fprintf(stderr, "unknown severity %q\n", *failOn)
+		return 2
+	}
+
+	linterConfig := lint.Config{}
+	if *config != "" {
+		var err error
+		if linterConfig, err = lint.LoadConfig(*config); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+	linter, err := lint.New(linterConfig)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	format := policy.ObjectFormat{Namespace: *argoCDNamespace}
+	if *applicationNamespaces != "" {
+		format.ApplicationNamespaces = strings.Split(*applicationNamespaces, ",")
+	}
+
+	code := 0
+	for _, name := range flags.Args() {
+		findings, err := lintFile(linter, format, name)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		for _, finding := range findings {
+			fmt.Fprintln(stdout, finding)
+			if finding.Severity.Rank() >= lint.Severity(*failOn).Rank() {
+				code = 1
+			}
+		}
+	}
+	return code
+}
+
+// runRollback creates an ArgoCDRBACRollback of the revision in the current Kubernetes context and returns the exit code.
+func runRollback(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	namespace := flags.String("namespace", "argocd", "The namespace of the ArgoCDRBACRevisions, the namespace of the Argo CD RBAC ConfigMap.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	c, err := newClient()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	ctx := context.Background()
+	revision := rbacoperatorv1alpha1.ArgoCDRBACRevision{}
+	if err := c.Get(ctx, client.ObjectKey{Name: flags.Arg(0), Namespace: *namespace}, &revision); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	rollback := rbacoperatorv1alpha1.ArgoCDRBACRollback{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "rollback-", Namespace: *namespace},
+		Spec:       rbacoperatorv1alpha1.ArgoCDRBACRollbackSpec{Revision: revision.Name},
+	}
+	if err := c.Create(ctx, &rollback); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "argocdrbacrollback %s/%s created, restoring revision %s recorded at %s for %s\n",
+		rollback.Namespace, rollback.Name, revision.Name, revision.Spec.RecordedAt.Format(time.RFC3339), revision.Spec.Trigger)
+	return 0
+}
+
+// runExplain decides the request of the subject with the Argo CD RBAC policy of the current Kubernetes context, and
+// prints the chain of lines from the subject to the deciding rule, every line with the resource it was rendered from.
+// Returns 0 if the request is allowed, 1 if it is denied.
+func runExplain(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("explain", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	namespace := flags.String("namespace", "argocd", "The namespace of the Argo CD RBAC ConfigMap.")
+	cmName := flags.String("configmap", "argocd-rbac-cm", "The name of the Argo CD RBAC ConfigMap.")
+	groups := flags.String("groups", "", "The groups (comma separated) of the subject, their permissions are explained as well.")
+	output := flags.String("output", "text", "The output format, text or json.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 4 || (*output != "text" && *output != "json") {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	c, err := newClient()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	p, err := controller.LoadPolicy(context.Background(), c, *cmName, *namespace)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	subjects := []string{flags.Arg(0)}
+	if *groups != "" {
+		subjects = append(subjects, strings.Split(*groups, ",")...)
+	}
+	decision := p.Explain(subjects, flags.Arg(1), flags.Arg(2), flags.Arg(3))
+
+	if *output == "json" {
+		out, err := json.MarshalIndent(decision, "", "  ")
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		fmt.Fprintln(stdout, string(out))
+	} else {
+		fmt.Fprint(stdout, decision)
+	}
+	if !decision.Allowed {
+		return 1
+	}
+	return 0
+}
+
+// runGraph writes the graph of the Argo CD RBAC policy of the current Kubernetes context and returns the exit code.
+func runGraph(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("graph", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	namespace := flags.String("namespace", "argocd", "The namespace of the Argo CD RBAC ConfigMap.")
+	cmName := flags.String("configmap", "argocd-rbac-cm", "The name of the Argo CD RBAC ConfigMap.")
+	output := flags.String("output", graph.FormatDOT, "The output format, dot, mermaid or json.")
+	filter := graph.Filter{}
+	flags.StringVar(&filter.Namespace, "source-namespace", "", "Only the lines rendered from roles, bindings and AppProjects in the namespace.")
+	flags.StringVar(&filter.Subject, "subject", "", "Only the bindings, roles and rules the user, group or account holds.")
+	flags.StringVar(&filter.Resource, "resource", "", "Only the rules applying to the resource, e.g. applications, and what leads to them.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 || (*output != graph.FormatDOT && *output != graph.FormatMermaid && *output != graph.FormatJSON) {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	c, err := newClient()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	p, err := controller.LoadPolicy(context.Background(), c, *cmName, *namespace)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if err := graph.Build(p, filter).Write(stdout, *output); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// runReport writes the access report of the Argo CD RBAC policy of the current Kubernetes context, or the changes
+// since a previous report written as JSON, and returns the exit code.
+func runReport(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	namespace := flags.String("namespace", "argocd", "The namespace of the Argo CD RBAC ConfigMap.")
+	cmName := flags.String("configmap", "argocd-rbac-cm", "The name of the Argo CD RBAC ConfigMap.")
+	output := flags.String("output", accessreport.FormatCSV, "The output format, csv, markdown or json.")
+	previous := flags.String("previous", "", "A previous report written as JSON, the changes since are written instead of the report.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 || accessreport.ValidateFormat(*output) != nil {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	var previousRows []accessreport.Row
+	if *previous != "" {
+		data, err := os.ReadFile(*previous)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		if previousRows, err = accessreport.Parse(data); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", *previous, err)
+			return 2
+		}
+	}
+
+	c, err := newClient()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	rows, err := controller.LoadAccessReport(context.Background(), c, *cmName, *namespace)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if previousRows != nil {
+		err = accessreport.WriteChanges(stdout, *output, accessreport.Diff(previousRows, rows))
+	} else {
+		err = accessreport.Write(stdout, *output, rows)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// newClient returns a client of the current Kubernetes context.
+func newClient() (client.Client, error) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := argocdv1alpha.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := rbacoperatorv1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	restConfig, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	return client.New(restConfig, client.Options{Scheme: scheme})
+}
+
+// objectFinding is a finding of an object of a manifest file.
+type objectFinding struct {
+	lint.Finding
+	file string
+	kind string
+	name string
+}
+
+func (f objectFinding) String() string {
+	return fmt.Sprintf("%s: %s %s: %s", f.file, f.kind, f.name, f.Finding)
+}
+
+// lintFile lints every document of the manifest file. Other kinds are skipped.
+func lintFile(linter *lint.Linter, format policy.ObjectFormat, name string) ([]objectFinding, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close() //nolint:errcheck
+
+	findings := []objectFinding{}
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(file))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return findings, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		typeMeta := runtime.TypeMeta{}
+		if err := yaml.Unmarshal(doc, &typeMeta); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		if typeMeta.APIVersion != rbacoperatorv1alpha1.GroupVersion.String() {
+			continue
+		}
+		objectName, objectFindings, err := lintDocument(linter, format, typeMeta.Kind, doc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		for _, finding := range objectFindings {
+			findings = append(findings, objectFinding{Finding: finding, file: name, kind: typeMeta.Kind, name: objectName})
+		}
+	}
+}
+
+// lintDocument lints a document of the kind and returns the name of the object with the findings. The application
+// objects of roles are linted as rendered in the format.
+func lintDocument(linter *lint.Linter, format policy.ObjectFormat, kind string, doc []byte) (string, []lint.Finding, error) {
+	switch kind {
+	case "ArgoCDRole":
+		role := rbacoperatorv1alpha1.ArgoCDRole{}
+		if err := yaml.Unmarshal(doc, &role); err != nil {
+			return "", nil, err
+		}
+		rules, errs := format.GlobalRules(role.Spec.Rules)
+		if len(errs) > 0 {
+			return "", nil, fmt.Errorf("ArgoCDRole %s: %v", role.Name, errs.ToAggregate())
+		}
+		return role.Name, linter.GlobalRules(rules), nil
+	case "ArgoCDRoleBinding":
+		rb := rbacoperatorv1alpha1.ArgoCDRoleBinding{}
+		if err := yaml.Unmarshal(doc, &rb); err != nil {
+			return "", nil, err
+		}
+		return rb.Name, linter.RoleBinding(&rb), nil
+	case "ArgoCDProjectRole":
+		role := rbacoperatorv1alpha1.ArgoCDProjectRole{}
+		if err := yaml.Unmarshal(doc, &role); err != nil {
+			return "", nil, err
+		}
+		return role.Name, linter.ProjectRules(role.Spec.Rules), nil
+	case "ArgoCDProjectRoleBinding":
+		rb := rbacoperatorv1alpha1.ArgoCDProjectRoleBinding{}
+		if err := yaml.Unmarshal(doc, &rb); err != nil {
+			return "", nil, err
+		}
+		return rb.Name, linter.ProjectRoleBinding(&rb), nil
+	}
+	return "", nil, nil
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package accessreport writes access reviews of the Argo CD RBAC policy, who has what access to which Argo CD objects,
+// as CSV, Markdown or JSON tables, and the changes between two reports.
+package accessreport
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Formats a report can be written in.
+const (
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+	FormatJSON     = "json"
+)
+
+// Row is a rule a user, group or account holds, and the resource granting it.
+type Row struct {
+	Subject string `json:"subject"`
+	// SubjectKind is sso, local or group. Empty if the subject is granted by policy not managed by the operator.
+	SubjectKind string `json:"subjectKind"`
+	Resource    string `json:"resource"`
+	Verb        string `json:"verb"`
+	Object      string `json:"object"`
+	Effect      string `json:"effect"`
+	// Source is the resource granting the rule to the subject, as "<kind>/<name>".
+	Source    string `json:"source"`
+	Namespace string `json:"namespace"`
+	// Expiry is the time the rule is revoked from the subject, RFC 3339. Empty if never.
+	Expiry string `json:"expiry"`
+}
+
+// columns are the columns of the CSV and Markdown tables.
+var columns = []string{"subject", "subject kind", "resource", "verb", "object", "effect", "source", "namespace", "expiry"}
+
+func (r Row) values() []string {
+	return []string{r.Subject, r.SubjectKind, r.Resource, r.Verb, r.Object, r.Effect, r.Source, r.Namespace, r.Expiry}
+}
+
+// Sort sorts the rows by their columns.
+func Sort(rows []Row) {
+	slices.SortFunc(rows, func(a, b Row) int {
+		return slices.Compare(a.values(), b.values())
+	})
+}
+
+// Changes are the rows added and removed since a previous report. A row whose expiry changed is removed and added.
+type Changes struct {
+	Added   []Row `json:"added"`
+	Removed []Row `json:"removed"`
+}
+
+// Diff returns the changes from the previous rows to the current ones.
+func Diff(previous, current []Row) Changes {
+	changes := Changes{Added: []Row{}, Removed: []Row{}}
+	for _, row := range current {
+		if !slices.Contains(previous, row) {
+			changes.Added = append(changes.Added, row)
+		}
+	}
+	for _, row := range previous {
+		if !slices.Contains(current, row) {
+			changes.Removed = append(changes.Removed, row)
+		}
+	}
+	return changes
+}
+
+// Extension returns the file extension of the format.
+func Extension(format string) string {
+	if format == FormatMarkdown {
+		return "md"
+	}
+	return format
+}
+
+// ValidateFormat returns an error if the format is not csv, markdown or json.
+func ValidateFormat(format string) error {
+	if format != FormatCSV && format != FormatMarkdown && format != FormatJSON {
+		return fmt.Errorf("unknown format %q, must be csv, markdown or json", format)
+	}
+	return nil
+}
+
+// Write writes the rows as a table in the format.
+func Write(w io.Writer, format string, rows []Row) error {
+	if format == FormatJSON {
+		return writeJSON(w, rows)
+	}
+	table := [][]string{}
+	for _, row := range rows {
+		table = append(table, row.values())
+	}
+	return writeTable(w, format, columns, table)
+}
+
+// WriteChanges writes the changes in the format. Tables have an additional first column, added or removed.
+func WriteChanges(w io.Writer, format string, changes Changes) error {
+	if format == FormatJSON {
+		return writeJSON(w, changes)
+	}
+	table := [][]string{}
+	for _, row := range changes.Added {
+		table = append(table, append([]string{"added"}, row.values()...))
+	}
+	for _, row := range changes.Removed {
+		table = append(table, append([]string{"removed"}, row.values()...))
+	}
+	return writeTable(w, format, append([]string{"change"}, columns...), table)
+}
+
+// Parse returns the rows of a report written as JSON.
+func Parse(data []byte) ([]Row, error) {
+	rows := []Row{}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func writeTable(w io.Writer, format string, header []string, table [][]string) error {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+		if err := writer.WriteAll(table); err != nil {
+			return err
+		}
+		return writer.Error()
+	case FormatMarkdown:
+		var b strings.Builder
+		b.WriteString(markdownRow(header))
+		b.WriteString(strings.Repeat("| --- ", len(header)) + "|\n")
+		for _, row := range table {
+			b.WriteString(markdownRow(row))
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	return ValidateFormat(format)
+}
+
+// markdownRow returns a row of a Markdown table, pipes in the cells are escaped.
+func markdownRow(cells []string) string {
+	escaped := []string{}
+	for _, cell := range cells {
+		escaped = append(escaped, strings.ReplaceAll(cell, "|", `\|`))
+	}
+	return "| " + strings.Join(escaped, " | ") + " |\n"
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accessreport
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeTestRows() []Row {
+	return []Row{
+		{Subject: "bob", SubjectKind: "local", Resource: "applications", Verb: "sync", Object: "team-a/*", Effect: "allow",
+			Source: "ArgoCDRoleBinding/dev", Namespace: "team-a", Expiry: "2024-07-01T00:00:00Z"},
+		{Subject: "alice", SubjectKind: "sso", Resource: "logs", Verb: "get", Object: "team-a/*", Effect: "deny",
+			Source: "ArgoCDRoleBinding/dev", Namespace: "team-a"},
+		{Subject: "alice", SubjectKind: "sso", Resource: "applications", Verb: "get", Object: "team-a/*", Effect: "allow",
+			Source: "ArgoCDRoleBinding/dev", Namespace: "team-a"},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	rows := makeTestRows()
+	Sort(rows)
+	assert.Equal(t, []string{"alice", "alice", "bob"}, []string{rows[0].Subject, rows[1].Subject, rows[2].Subject})
+	assert.Equal(t, "applications", rows[0].Resource)
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, Write(out, FormatCSV, rows))
+	assert.Equal(t, `subject,subject kind,resource,verb,object,effect,source,namespace,expiry
+alice,sso,applications,get,team-a/*,allow,ArgoCDRoleBinding/dev,team-a,
+alice,sso,logs,get,team-a/*,deny,ArgoCDRoleBinding/dev,team-a,
+bob,local,applications,sync,team-a/*,allow,ArgoCDRoleBinding/dev,team-a,2024-07-01T00:00:00Z
+`, out.String())
+
+	out.Reset()
+	assert.NoError(t, Write(out, FormatMarkdown, rows[:1]))
+	assert.Equal(t, `| subject | subject kind | resource | verb | object | effect | source | namespace | expiry |
+| --- | --- | --- | --- | --- | --- | --- | --- | --- |
+| alice | sso | applications | get | team-a/* | allow | ArgoCDRoleBinding/dev | team-a |  |
+`, out.String())
+
+	out.Reset()
+	assert.NoError(t, Write(out, FormatJSON, rows))
+	parsed, err := Parse(out.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, rows, parsed)
+
+	assert.Error(t, Write(out, "xlsx", rows))
+}
+
+func TestDiff(t *testing.T) {
+	previous := makeTestRows()
+	current := makeTestRows()[1:]
+	current[0].Expiry = "2024-08-01T00:00:00Z"
+	changes := Diff(previous, current)
+	assert.Equal(t, []Row{current[0]}, changes.Added)
+	assert.Equal(t, []Row{previous[0], previous[1]}, changes.Removed)
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, WriteChanges(out, FormatCSV, Changes{Added: []Row{current[1]}, Removed: []Row{}}))
+	assert.Equal(t, `change,subject,subject kind,resource,verb,object,effect,source,namespace,expiry
+added,alice,sso,applications,get,team-a/*,allow,ArgoCDRoleBinding/dev,team-a,
+`, out.String())
+
+	assert.Equal(t, Changes{Added: []Row{}, Removed: []Row{}}, Diff(previous, previous))
+}
+
+func TestMarkdownRow(t *testing.T) {
+	assert.Equal(t, "| a\\|b | c |\n", markdownRow([]string{"a|b", "c"}))
+}
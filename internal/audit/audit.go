@@ -0,0 +1,201 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit writes an append-only trail of the changes to the Argo CD RBAC policy as JSON lines.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a change of the Argo CD RBAC ConfigMap or an AppProject written by the reconciliation of a resource.
+type Entry struct {
+	// Time the change was written.
+	Time time.Time `json:"time"`
+	// Actor is the identity that last changed the spec of the resource, empty if unknown.
+	Actor string `json:"actor,omitempty"`
+	// ActorSource is where the actor was taken from, ActorSourceWebhook or ActorSourceManagedFields, or
+	// ActorSourceReconcile if the change has no actor.
+	ActorSource string `json:"actorSource,omitempty"`
+	// Resource whose reconciliation wrote the change.
+	Resource Resource `json:"resource"`
+	// Target is the ConfigMap or AppProject written, as "<kind> <namespace>/<name>".
+	Target string `json:"target"`
+	// Changes are the keys of the ConfigMap or roles of the AppProject that changed.
+	Changes []Change `json:"changes"`
+	// Subjects are the users, groups and roles of the lines added or removed.
+	Subjects []string `json:"subjects,omitempty"`
+	// Error is set on the second entry of a change whose write failed after its entry was written, the change
+	// was not applied.
+	Error string `json:"error,omitempty"`
+}
+
+// Sources of the actor of an entry.
+const (
+	// ActorSourceWebhook actors were recorded by the mutating webhook from the user info of the request.
+	ActorSourceWebhook = "webhook"
+	// ActorSourceManagedFields actors are the field manager that last changed the spec.
+	ActorSourceManagedFields = "managedFields"
+	// ActorSourceReconcile entries have no actor, the spec of the resource was already reconciled and the change is
+	// caused by time, like an expiry or a schedule, or by a change of another resource.
+	ActorSourceReconcile = "reconcile"
+)
+
+// Resource references the resource whose reconciliation wrote a change.
+type Resource struct {
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	Generation int64  `json:"generation,omitempty"`
+}
+
+// Change is the content of a ConfigMap key or AppProject role before and after the write.
+type Change struct {
+	Key    string   `json:"key"`
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+}
+
+// NewChanges returns the changes of the keys whose lines differ. Keys are sorted.
+func NewChanges(before, after map[string][]string) []Change {
+	keys := []string{}
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	changes := []Change{}
+	for _, key := range keys {
+		if slices.Equal(before[key], after[key]) {
+			continue
+		}
+		changes = append(changes, Change{Key: key, Before: nonNil(before[key]), After: nonNil(after[key])})
+	}
+	return changes
+}
+
+func nonNil(lines []string) []string {
+	if lines == nil {
+		return []string{}
+	}
+	return lines
+}
+
+// Subjects returns the subjects of the lines added or removed by the changes, sorted.
+// The subject of a Casbin line is its second field, the subject of an AppProject role line "group <group>" the group.
+func Subjects(changes []Change) []string {
+	subjects := []string{}
+	add := func(line string) {
+		subject := ""
+		if group, ok := strings.CutPrefix(line, "group "); ok {
+			subject = group
+		} else if fields := strings.Split(line, ","); len(fields) > 2 && (fields[0] == "p" || fields[0] == "g") {
+			subject = strings.TrimSpace(fields[1])
+		}
+		if subject != "" && !slices.Contains(subjects, subject) {
+			subjects = append(subjects, subject)
+		}
+	}
+	for _, change := range changes {
+		for _, line := range change.Before {
+			if !slices.Contains(change.After, line) {
+				add(line)
+			}
+		}
+		for _, line := range change.After {
+			if !slices.Contains(change.Before, line) {
+				add(line)
+			}
+		}
+	}
+	slices.Sort(subjects)
+	return subjects
+}
+
+// Sink writes the audit entries.
+type Sink interface {
+	Write(entry Entry) error
+}
+
+// NewSink returns the sink writing to the target: "stdout", an http:// or https:// URL the entries are posted to,
+// or the path of a file the entries are appended to.
+func NewSink(target string) (Sink, error) {
+	switch {
+	case target == "":
+		return nil, fmt.Errorf("audit sink is empty")
+	case target == "stdout":
+		return &writerSink{w: os.Stdout}, nil
+	case strings.HasPrefix(target, "http://"), strings.HasPrefix(target, "https://"):
+		return &httpSink{url: target, client: &http.Client{Timeout: 10 * time.Second}}, nil
+	}
+	file, err := os.OpenFile(strings.TrimPrefix(target, "file://"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	return &writerSink{w: file}, nil
+}
+
+// writerSink writes every entry as a JSON line.
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *writerSink) Write(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// httpSink posts every entry as JSON.
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *httpSink) Write(entry Entry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("audit sink %s responded %s", s.url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewChanges(t *testing.T) {
+	before := map[string][]string{
+		"policy.csv":        {"p, role:team-a, applications, get, */*, allow", "g, team-a, role:team-a"},
+		"policy.team-b.csv": {"g, team-b, role:team-b"},
+	}
+	after := map[string][]string{
+		"policy.csv":        {"p, role:team-a, applications, get, */*, allow", "g, team-c, role:team-a"},
+		"policy.team-b.csv": {"g, team-b, role:team-b"},
+		"policy.team-d.csv": {"p, role:team-d, logs, get, */*, allow"},
+	}
+	changes := NewChanges(before, after)
+	assert.Equal(t, []Change{
+		{
+			Key:    "policy.csv",
+			Before: []string{"p, role:team-a, applications, get, */*, allow", "g, team-a, role:team-a"},
+			After:  []string{"p, role:team-a, applications, get, */*, allow", "g, team-c, role:team-a"},
+		},
+		{Key: "policy.team-d.csv", Before: []string{}, After: []string{"p, role:team-d, logs, get, */*, allow"}},
+	}, changes)
+	assert.Equal(t, []string{"role:team-d", "team-a", "team-c"}, Subjects(changes))
+
+	// AppProject roles are keyed by role name and grant groups
+	changes = NewChanges(map[string][]string{"role/dev": {"group team-a"}}, map[string][]string{})
+	assert.Equal(t, []string{"team-a"}, Subjects(changes))
+	assert.Empty(t, NewChanges(before, before))
+}
+
+func TestNewSink_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	entry := Entry{
+		Time:     time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+		Actor:    "alice",
+		Resource: Resource{Kind: "ArgoCDRoleBinding", Namespace: "team-a", Name: "dev", Generation: 2},
+		Target:   "ConfigMap argocd/argocd-rbac-cm",
+		Changes:  []Change{{Key: "policy.csv", Before: []string{}, After: []string{"g, alice, role:dev"}}},
+	}
+	for range 2 {
+		sink, err := NewSink("file://" + path)
+		assert.NoError(t, err)
+		assert.NoError(t, sink.Write(entry))
+	}
+
+	// Entries are appended as JSON lines
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close() //nolint:errcheck
+	scanner := bufio.NewScanner(file)
+	lines := 0
+	for scanner.Scan() {
+		var written Entry
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &written))
+		assert.Equal(t, entry, written)
+		lines++
+	}
+	assert.Equal(t, 2, lines)
+
+	_, err = NewSink("")
+	assert.Error(t, err)
+}
+
+func TestNewSink_HTTP(t *testing.T) {
+	var received []Entry
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var entry Entry
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&entry))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		received = append(received, entry)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(server.URL)
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Write(Entry{Target: "AppProject argocd/team-a"}))
+	assert.Equal(t, []Entry{{Target: "AppProject argocd/team-a"}}, received)
+
+	// Entries not accepted by the endpoint are errors
+	status = http.StatusInternalServerError
+	assert.Error(t, sink.Write(Entry{Target: "AppProject argocd/team-a"}))
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/accessreport"
+)
+
+// accessReportResources are the bindings and local accounts the kind and expiry of the subjects of a report are read from.
+type accessReportResources struct {
+	bindings        []rbacoperatorv1alpha1.ArgoCDRoleBinding
+	projectBindings []rbacoperatorv1alpha1.ArgoCDProjectRoleBinding
+	localAccounts   []rbacoperatorv1alpha1.ArgoCDLocalAccount
+}
+
+// LoadAccessReport returns the rows of the access report of the policy Argo CD enforces, sorted: every rule of every
+// subject, once per resource granting it to the subject. Rules held through roles are attributed to the resource
+// granting the first role to the subject, and expire with the first binding of the chain of roles that expires.
+func LoadAccessReport(ctx context.Context, c client.Reader, cmName, cmNamespace string) ([]accessreport.Row, error) {
+	p, err := LoadPolicy(ctx, c, cmName, cmNamespace)
+	if err != nil {
+		return nil, err
+	}
+	bindings := rbacoperatorv1alpha1.ArgoCDRoleBindingList{}
+	if err := c.List(ctx, &bindings); err != nil {
+		return nil, err
+	}
+	projectBindings := rbacoperatorv1alpha1.ArgoCDProjectRoleBindingList{}
+	if err := c.List(ctx, &projectBindings); err != nil {
+		return nil, err
+	}
+	localAccounts := rbacoperatorv1alpha1.ArgoCDLocalAccountList{}
+	if err := c.List(ctx, &localAccounts); err != nil {
+		return nil, err
+	}
+	resources := &accessReportResources{
+		bindings:        bindings.Items,
+		projectBindings: projectBindings.Items,
+		localAccounts:   localAccounts.Items,
+	}
+
+	rows := []accessreport.Row{}
+	for _, subject := range p.Subjects() {
+		permissions := p.Permissions(subject)
+		grants := append(slices.Clone(permissions.Roles), permissions.ProjectRoles...)
+		for _, rule := range permissions.Rules {
+			chains := [][]rbacoperatorv1alpha1.PermissionSource{{rule.Source}}
+			if rule.Role != "" {
+				chains = grantChains(grants, rule.Role)
+			}
+			for _, chain := range chains {
+				row := accessreport.Row{
+					Subject:     subject,
+					SubjectKind: resources.subjectKind(subject, chain[0]),
+					Resource:    rule.Resource,
+					Verb:        rule.Action,
+					Object:      rule.Object,
+					Effect:      rule.Effect,
+					Source:      chain[0].Kind,
+					Namespace:   chain[0].Namespace,
+				}
+				if chain[0].Name != "" {
+					row.Source += "/" + chain[0].Name
+				}
+				if expiry := resources.expiry(chain); !expiry.IsZero() {
+					row.Expiry = expiry.UTC().Format(time.RFC3339)
+				}
+				if !slices.Contains(rows, row) {
+					rows = append(rows, row)
+				}
+			}
+		}
+	}
+	accessreport.Sort(rows)
+	return rows, nil
+}
+
+// grantChains returns the sources of the grants leading from the subject to the role, one chain per role granted to
+// the subject directly through which the role is held. The first source of a chain grants a role to the subject.
+func grantChains(grants []rbacoperatorv1alpha1.RoleGrant, role string) [][]rbacoperatorv1alpha1.PermissionSource {
+	chains := [][]rbacoperatorv1alpha1.PermissionSource{}
+	for _, direct := range grants {
+		if direct.Via != "" {
+			continue
+		}
+		// Breadth first, so that the shortest chain is found
+		paths := [][]rbacoperatorv1alpha1.RoleGrant{{direct}}
+		visited := []string{direct.Role}
+		for i := 0; i < len(paths); i++ {
+			path := paths[i]
+			last := path[len(path)-1]
+			if last.Role == role {
+				chain := []rbacoperatorv1alpha1.PermissionSource{}
+				for _, grant := range path {
+					chain = append(chain, grant.Source)
+				}
+				chains = append(chains, chain)
+				break
+			}
+			for _, grant := range grants {
+				if grant.Via == last.Role && !slices.Contains(visited, grant.Role) {
+					visited = append(visited, grant.Role)
+					paths = append(paths, append(slices.Clone(path), grant))
+				}
+			}
+		}
+	}
+	return chains
+}
+
+// subjectKind returns the kind of the subject granted by the source: the kind of the subject of the binding,
+// group for the groups of AppProject roles, local for local accounts. Empty if the kind is unknown.
+func (r *accessReportResources) subjectKind(subject string, source rbacoperatorv1alpha1.PermissionSource) string {
+	switch source.Kind {
+	case "ArgoCDRoleBinding":
+		if rb := r.binding(source); rb != nil {
+			if i, ok := subjectIndex(source.Field, len(rb.Spec.Subjects)); ok {
+				return rb.Spec.Subjects[i].Kind
+			}
+		}
+	case "ArgoCDProjectRoleBinding":
+		if rb := r.projectBinding(source); rb != nil {
+			if i, ok := subjectIndex(source.Field, len(rb.Spec.Subjects)); ok {
+				for _, user := range rb.Spec.Subjects[i].Users {
+					if user.Name == subject {
+						return user.Kind
+					}
+				}
+				return "group"
+			}
+		}
+	case "AppProject":
+		return "group"
+	}
+	for _, account := range r.localAccounts {
+		if account.Name == subject {
+			return "local"
+		}
+	}
+	return ""
+}
+
+// expiry returns the earliest time one of the bindings of the chain stops granting access. Zero time if never.
+func (r *accessReportResources) expiry(chain []rbacoperatorv1alpha1.PermissionSource) time.Time {
+	expiry := time.Time{}
+	earliest := func(t *metav1.Time) {
+		if t != nil && (expiry.IsZero() || t.Time.Before(expiry)) {
+			expiry = t.Time
+		}
+	}
+	for _, source := range chain {
+		switch source.Kind {
+		case "ArgoCDRoleBinding":
+			rb := r.binding(source)
+			if rb == nil {
+				continue
+			}
+			earliest(rb.Status.BreakGlassDeadline)
+			if i, ok := subjectIndex(source.Field, len(rb.Spec.Subjects)); ok {
+				earliest(globalSubjectWindow(rb, rb.Spec.Subjects[i]).expiresAt)
+			} else {
+				earliest(roleBindingWindow(rb).expiresAt)
+			}
+		case "ArgoCDProjectRoleBinding":
+			rb := r.projectBinding(source)
+			if rb == nil {
+				continue
+			}
+			if i, ok := subjectIndex(source.Field, len(rb.Spec.Subjects)); ok {
+				earliest(appProjectSubjectWindow(rb, rb.Spec.Subjects[i]).expiresAt)
+			} else {
+				earliest(projectRoleBindingWindow(rb).expiresAt)
+			}
+		}
+	}
+	return expiry
+}
+
+func (r *accessReportResources) binding(source rbacoperatorv1alpha1.PermissionSource) *rbacoperatorv1alpha1.ArgoCDRoleBinding {
+	for i := range r.bindings {
+		if r.bindings[i].Namespace == source.Namespace && r.bindings[i].Name == source.Name {
+			return &r.bindings[i]
+		}
+	}
+	return nil
+}
+
+func (r *accessReportResources) projectBinding(source rbacoperatorv1alpha1.PermissionSource) *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding {
+	for i := range r.projectBindings {
+		if r.projectBindings[i].Namespace == source.Namespace && r.projectBindings[i].Name == source.Name {
+			return &r.projectBindings[i]
+		}
+	}
+	return nil
+}
+
+// subjectIndex returns the index of the subject of the field spec.subjects[<index>], false if the field is not
+// a subject of the binding.
+func subjectIndex(field string, subjects int) (int, bool) {
+	var i int
+	if _, err := fmt.Sscanf(field, "spec.subjects[%d]", &i); err != nil || i < 0 || i >= subjects {
+		return 0, false
+	}
+	return i, true
+}
@@ -3,27 +3,46 @@ package controller
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
 	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
 	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
 )
 
 func buildCasbinPolicyStrings(pr *rbacoperatorv1alpha1.ArgoCDProjectRole, appProject *argocdv1alpha.AppProject) []string {
 	policies := []string{}
-	for _, rule := range pr.Spec.Rules {
+	for _, line := range renderCasbinPolicies(pr, appProject) {
+		policies = append(policies, line.line)
+	}
+	return policies
+}
+
+// renderCasbinPolicies renders the rules of the project role for the AppProject, each line with the rule it was rendered from.
+func renderCasbinPolicies(pr *rbacoperatorv1alpha1.ArgoCDProjectRole, appProject *argocdv1alpha.AppProject) []renderedLine {
+	lines := []renderedLine{}
+	for i, rule := range pr.Spec.Rules {
 		resource := rule.Resource
 		for _, verb := range rule.Verbs {
 			for _, object := range rule.Objects {
-				policy := fmt.Sprintf("p, proj:%s:%s, %s, %s, %s, allow", appProject.Name, pr.Name, resource, verb, object)
-				policies = append(policies, policy)
+				if !isPolicyValue(verb) || !isPolicyValue(object) {
+					continue
+				}
+				lines = append(lines, renderedLine{
+					line:  fmt.Sprintf("p, proj:%s:%s, %s, %s, %s, allow", appProject.Name, appProjectRoleName(appProject.Namespace, pr.Namespace, pr.Name), resource, verb, object),
+					field: fmt.Sprintf("spec.rules[%d]", i),
+				})
 			}
 		}
 	}
-	return policies
+	return lines
 }
 
 func newAppProject(name, namespace string) *argocdv1alpha.AppProject {
@@ -35,10 +54,74 @@ func newAppProject(name, namespace string) *argocdv1alpha.AppProject {
 	}
 }
 
-func (r *ArgoCDProjectRoleBindingReconciler) patchAppProject(appProject *argocdv1alpha.AppProject, pr *rbacoperatorv1alpha1.ArgoCDProjectRole, groups *[]string) error {
+// resolveAppProjectNamespace returns the namespace of the AppProject referenced by the subject.
+// An explicit namespace on the subject takes precedence over the configured Argo CD namespace,
+// which in turn takes precedence over the namespace of the binding.
+func resolveAppProjectNamespace(subject rbacoperatorv1alpha1.AppProjectSubject, argoCDNamespace, bindingNamespace string) string {
+	if subject.Namespace != "" {
+		return subject.Namespace
+	}
+	if argoCDNamespace != "" {
+		return argoCDNamespace
+	}
+	return bindingNamespace
+}
+
+// appProjectStatusKey returns the key used to record a bound AppProject in the binding status.
+// AppProjects living in the binding's namespace are recorded by name only.
+func appProjectStatusKey(appProject types.NamespacedName, bindingNamespace string) string {
+	if appProject.Namespace == bindingNamespace {
+		return appProject.Name
+	}
+	return appProject.String()
+}
+
+// parseAppProjectStatusKey is the inverse of appProjectStatusKey.
+func parseAppProjectStatusKey(key, bindingNamespace string) types.NamespacedName {
+	if namespace, name, found := strings.Cut(key, "/"); found {
+		return types.NamespacedName{Namespace: namespace, Name: name}
+	}
+	return types.NamespacedName{Namespace: bindingNamespace, Name: key}
+}
+
+// appProjectRoleName returns the name of the ArgoCDProjectRole of the namespace in an AppProject of the given namespace.
+// Roles of other namespaces are prefixed with their namespace and "_", which Kubernetes names can't contain, so
+// namespaces sharing an AppProject can't overwrite each other's roles.
+func appProjectRoleName(appProjectNamespace, namespace, name string) string {
+	if appProjectNamespace == namespace {
+		return name
+	}
+	return namespace + "_" + name
+}
+
+// isNamespaceAllowed returns true if ArgoCDProjectRoleBindings of the given namespace may bind to the AppProject.
+// Bindings in the AppProject's own namespace are always allowed, others have to be listed
+// in the allowed-namespaces annotation of the AppProject.
+func isNamespaceAllowed(appProject *argocdv1alpha.AppProject, namespace string) bool {
+	if appProject.Namespace == namespace {
+		return true
+	}
+	allowed, ok := appProject.Annotations[common.AnnotationAllowedNamespaces]
+	if !ok {
+		return false
+	}
+	for _, ns := range strings.Split(allowed, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns == "*" || ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// patchAppProject will ensure that the role in the AppProject is up-to-date with the given ArgoCDProjectRole and the
+// groups of its ArgoCDProjectRoleBinding.
+func patchAppProject(rClient client.Client, appProject *argocdv1alpha.AppProject, pr *rbacoperatorv1alpha1.ArgoCDProjectRole,
+	rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding, groups *[]string) error {
 	changed := false
+	roleName := appProjectRoleName(appProject.Namespace, pr.Namespace, pr.Name)
 	apProjectRole := &argocdv1alpha.ProjectRole{
-		Name:        pr.Name,
+		Name:        roleName,
 		Description: pr.Spec.Description,
 		Groups:      *groups,
 		Policies:    buildCasbinPolicyStrings(pr, appProject),
@@ -46,21 +129,41 @@ func (r *ArgoCDProjectRoleBindingReconciler) patchAppProject(appProject *argocdv
 
 	ogAppProject := appProject.DeepCopy()
 
-	role, index := getRoleInAppProject(appProject, pr.Name)
+	role, index := getRoleInAppProject(appProject, roleName)
 	if role == nil {
 		appProject.Spec.Roles = append(appProject.Spec.Roles, *apProjectRole)
 		changed = true
 	}
 	if role != nil && !areProjectRolesEqual(role, apProjectRole) {
+		// JWT tokens are issued by ArgoCDProjectRoleTokens or by Argo CD itself, keep them
+		apProjectRole.JWTTokens = role.JWTTokens
 		appProject.Spec.Roles[index] = *apProjectRole
 		changed = true
 	}
+	if recordPolicySources(appProject, "role "+roleName, newPolicySource("ArgoCDProjectRole", pr, renderCasbinPolicies(pr, appProject)),
+		newPolicySource("ArgoCDProjectRoleBinding", rb, renderGroups(rb, appProject, roleName, *groups))) {
+		changed = true
+	}
 	if changed {
-		return r.Patch(context.TODO(), appProject, client.MergeFrom(ogAppProject))
+		return rClient.Patch(context.TODO(), appProject, client.MergeFrom(ogAppProject))
 	}
 	return nil
 }
 
+// renderGroups renders the groups of the role of the AppProject, each line with the subject of the binding it was rendered from.
+func renderGroups(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding, appProject *argocdv1alpha.AppProject, roleName string, groups []string) []renderedLine {
+	lines := []renderedLine{}
+	for _, group := range groups {
+		lines = append(lines, renderedLine{
+			line: fmt.Sprintf("g, %s, proj:%s:%s", group, appProject.Name, roleName),
+			field: projectSubjectField(rb, appProject.Name, func(subject rbacoperatorv1alpha1.AppProjectSubject) bool {
+				return slices.Contains(subject.Groups, group)
+			}),
+		})
+	}
+	return lines
+}
+
 func getRoleInAppProject(appProject *argocdv1alpha.AppProject, roleName string) (role *argocdv1alpha.ProjectRole, index int) {
 	for i, role := range appProject.Spec.Roles {
 		if role.Name == roleName {
@@ -100,8 +203,37 @@ func removeRoleFromAppProject(rClient client.Client, appProject *argocdv1alpha.A
 		return nil // Role not found in AppProject, nothing to delete
 	}
 	appProject.Spec.Roles = append(appProject.Spec.Roles[:index], appProject.Spec.Roles[index+1:]...)
+	recordPolicySources(appProject, "role "+roleName)
 	if err := rClient.Patch(context.TODO(), appProject, client.MergeFrom(ogAppProject)); err != nil {
 		return errors.Wrapf(err, "failed to patch AppProject %s/%s to remove role %s", appProject.Namespace, appProject.Name, roleName)
 	}
 	return nil
 }
+
+// mapAppProjectToProjectRoles maps the AppProject to the ArgoCDProjectRoles of its roles, including the roles removed
+// since the operator last wrote them, so the roles changed outside of the operator are written again.
+func mapAppProjectToProjectRoles(_ context.Context, obj client.Object) []reconcile.Request {
+	appProject, ok := obj.(*argocdv1alpha.AppProject)
+	if !ok {
+		return nil
+	}
+	roleNames := []string{}
+	for _, role := range appProject.Spec.Roles {
+		roleNames = append(roleNames, role.Name)
+	}
+	for key := range policyDigests(appProject) {
+		if roleName := strings.TrimPrefix(key, "role "); !slices.Contains(roleNames, roleName) {
+			roleNames = append(roleNames, roleName)
+		}
+	}
+	slices.Sort(roleNames)
+	requests := []reconcile.Request{}
+	for _, roleName := range roleNames {
+		role := types.NamespacedName{Namespace: appProject.Namespace, Name: roleName}
+		if namespace, name, found := strings.Cut(roleName, "_"); found {
+			role = types.NamespacedName{Namespace: namespace, Name: name}
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: role})
+	}
+	return requests
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+const (
+	eventReasonApprovalPending = "ApprovalPending"
+	eventReasonChangeApproved  = "ChangeApproved"
+)
+
+// projectPlaceholder is the AppProject name the pending changes of ArgoCDProjectRoles are rendered for.
+const projectPlaceholder = "<project>"
+
+// requiresApproval returns true if the spec changes of the role are only rendered once approved.
+func requiresApproval(obj client.Object) bool {
+	return obj.GetLabels()[common.LabelRequireApproval] == "true"
+}
+
+// isGated returns true if the spec changes of the role are staged. Removing the label keeps the gate on until the
+// removal is approved, the gate is on as long as an applied spec is recorded.
+func isGated(obj client.Object, appliedSpec bool) bool {
+	return requiresApproval(obj) || appliedSpec
+}
+
+// generationApprover returns the user that approved the current generation of the role, empty if it is not approved.
+// The approval is only valid if the webhook recorded the approver, so it fails closed without the webhooks, and if the
+// approver did not make the last change of the role. The error tells why an approval of the generation is not valid.
+func generationApprover(obj client.Object, trusted bool) (string, error) {
+	annotations := obj.GetAnnotations()
+	if annotations[common.AnnotationApprovedGeneration] != strconv.FormatInt(obj.GetGeneration(), 10) {
+		return "", nil
+	}
+	approver := annotations[common.AnnotationApprovedGenerationBy]
+	switch {
+	case !trusted:
+		return "", fmt.Errorf("the approval can't be verified, the webhooks are not enabled")
+	case approver == "":
+		return "", fmt.Errorf("the approver was not recorded")
+	case approver == annotations[common.AnnotationChangedBy]:
+		return "", fmt.Errorf("%s made the last change and can't approve it", approver)
+	}
+	return approver, nil
+}
+
+// approvalPendingMessage returns the message of the ApprovalPending condition of the role.
+func approvalPendingMessage(obj client.Object, appliedGeneration int64, err error) string {
+	generation := obj.GetGeneration()
+	message := fmt.Sprintf("generation %d waits for the %s annotation, generation %d is applied", generation, common.AnnotationApprovedGeneration, appliedGeneration)
+	if !requiresApproval(obj) {
+		message = fmt.Sprintf("the removal of the %s label waits for the %s annotation of generation %d", common.LabelRequireApproval, common.AnnotationApprovedGeneration, generation)
+	}
+	if err != nil {
+		message = fmt.Sprintf("%s, %v", message, err)
+	}
+	return message
+}
+
+// diffPolicyLines returns the lines added ("+") and removed ("-") from the applied to the desired policy.
+func diffPolicyLines(applied, desired []string) []string {
+	changes := []string{}
+	for _, line := range applied {
+		if !slices.Contains(desired, line) {
+			changes = append(changes, "-"+line)
+		}
+	}
+	for _, line := range desired {
+		if !slices.Contains(applied, line) {
+			changes = append(changes, "+"+line)
+		}
+	}
+	return changes
+}
+
+// stageRoleSpec keeps the spec changes of an ArgoCDRole requiring approval out of the policy until its generation is approved
+// by someone else than the last editor. The spec of the role at the time the label is added is applied as is, a role created
+// with the label or changed together with adding it renders no rules until approved. The applied spec replaces the spec of
+// the given role while changes are pending, so that only the applied spec is rendered.
+func stageRoleSpec(recorder record.EventRecorder, role *rbacoperatorv1alpha1.ArgoCDRole, trustApprovals bool) {
+	generation := role.GetGeneration()
+	if !isGated(role, role.Status.AppliedSpec != nil) {
+		role.Status.AppliedGeneration = generation
+		role.Status.PendingChanges = nil
+		return
+	}
+	approver, err := generationApprover(role, trustApprovals)
+	applied := role.Status.AppliedGeneration == generation
+	if approver != "" || (applied && requiresApproval(role)) {
+		if approver != "" && (!applied || !requiresApproval(role)) && recorder != nil {
+			recorder.Eventf(role, corev1.EventTypeNormal, eventReasonChangeApproved, "Applied generation %d approved by %s", generation, approver)
+		}
+		role.Status.AppliedGeneration = generation
+		role.Status.AppliedSpec = role.Spec.DeepCopy()
+		role.Status.PendingChanges = nil
+		role.SetConditions(rbacoperatorv1alpha1.Approved().WithObservedGeneration(generation))
+		if !requiresApproval(role) {
+			// The removal of the label is approved, the gate is off
+			role.Status.AppliedSpec = nil
+		}
+		return
+	}
+	appliedRole := role.DeepCopy()
+	appliedRole.Spec = rbacoperatorv1alpha1.ArgoCDRoleSpec{}
+	if role.Status.AppliedSpec != nil {
+		appliedRole.Spec = *role.Status.AppliedSpec
+	}
+	roleName := fmt.Sprintf("role:%s", role.Name)
+	changes := diffPolicyLines(policyLines(buildPolicyStringRules(appliedRole, roleName)), policyLines(buildPolicyStringRules(role, roleName)))
+	notifyApprovalPending(recorder, role, changes)
+	role.Status.PendingChanges = changes
+	role.SetConditions(rbacoperatorv1alpha1.ApprovalPending().
+		WithMessage(approvalPendingMessage(role, role.Status.AppliedGeneration, err)).
+		WithObservedGeneration(generation))
+	role.Spec = appliedRole.Spec
+}
+
+// stageProjectRoleSpec keeps the spec changes of an ArgoCDProjectRole requiring approval out of the AppProjects until its generation
+// is approved by someone else than the last editor, like stageRoleSpec. The applied spec replaces the spec of the given role
+// while changes are pending, so that only the applied spec is rendered.
+func stageProjectRoleSpec(recorder record.EventRecorder, projectRole *rbacoperatorv1alpha1.ArgoCDProjectRole, trustApprovals bool) {
+	generation := projectRole.GetGeneration()
+	if !isGated(projectRole, projectRole.Status.AppliedSpec != nil) {
+		projectRole.Status.AppliedGeneration = generation
+		projectRole.Status.PendingChanges = nil
+		return
+	}
+	approver, err := generationApprover(projectRole, trustApprovals)
+	applied := projectRole.Status.AppliedGeneration == generation
+	if approver != "" || (applied && requiresApproval(projectRole)) {
+		if approver != "" && (!applied || !requiresApproval(projectRole)) && recorder != nil {
+			recorder.Eventf(projectRole, corev1.EventTypeNormal, eventReasonChangeApproved, "Applied generation %d approved by %s", generation, approver)
+		}
+		projectRole.Status.AppliedGeneration = generation
+		projectRole.Status.AppliedSpec = projectRole.Spec.DeepCopy()
+		projectRole.Status.PendingChanges = nil
+		projectRole.SetConditions(rbacoperatorv1alpha1.Approved().WithObservedGeneration(generation))
+		if !requiresApproval(projectRole) {
+			// The removal of the label is approved, the gate is off
+			projectRole.Status.AppliedSpec = nil
+		}
+		return
+	}
+	appliedRole := projectRole.DeepCopy()
+	appliedRole.Spec = rbacoperatorv1alpha1.ArgoCDProjectRoleSpec{}
+	if projectRole.Status.AppliedSpec != nil {
+		appliedRole.Spec = *projectRole.Status.AppliedSpec
+	}
+	changes := diffPolicyLines(projectRoleLines(appliedRole, projectPlaceholder), projectRoleLines(projectRole, projectPlaceholder))
+	notifyApprovalPending(recorder, projectRole, changes)
+	projectRole.Status.PendingChanges = changes
+	projectRole.SetConditions(rbacoperatorv1alpha1.ApprovalPending().
+		WithMessage(approvalPendingMessage(projectRole, projectRole.Status.AppliedGeneration, err)).
+		WithObservedGeneration(generation))
+	projectRole.Spec = appliedRole.Spec
+}
+
+// projectRoleLines returns the description and policies of the ArgoCDProjectRole as rendered into the given AppProject.
+func projectRoleLines(projectRole *rbacoperatorv1alpha1.ArgoCDProjectRole, appProjectName string) []string {
+	lines := []string{}
+	if projectRole.Spec.Description != "" {
+		lines = append(lines, "description "+projectRole.Spec.Description)
+	}
+	return append(lines, buildCasbinPolicyStrings(projectRole, newAppProject(appProjectName, projectRole.Namespace))...)
+}
+
+// notifyApprovalPending emits an event for the pending changes of the role, once per generation.
+func notifyApprovalPending(recorder record.EventRecorder, obj client.Object, changes []string) {
+	var pending []string
+	switch o := obj.(type) {
+	case *rbacoperatorv1alpha1.ArgoCDRole:
+		pending = o.Status.PendingChanges
+	case *rbacoperatorv1alpha1.ArgoCDProjectRole:
+		pending = o.Status.PendingChanges
+	}
+	if recorder == nil || slices.Equal(pending, changes) {
+		return
+	}
+	recorder.Eventf(obj, corev1.EventTypeNormal, eventReasonApprovalPending,
+		"Generation %d waits for approval with the %s annotation:\n%s", obj.GetGeneration(), common.AnnotationApprovedGeneration, strings.Join(changes, "\n"))
+}
+
+// applyApprovedRoleSpec replaces the spec of the ArgoCDRole with its applied spec while changes wait for approval,
+// with an empty spec if none was applied yet.
+func applyApprovedRoleSpec(role *rbacoperatorv1alpha1.ArgoCDRole) {
+	if !isGated(role, role.Status.AppliedSpec != nil) || role.Status.AppliedGeneration == role.GetGeneration() {
+		return
+	}
+	role.Spec = rbacoperatorv1alpha1.ArgoCDRoleSpec{}
+	if role.Status.AppliedSpec != nil {
+		role.Spec = *role.Status.AppliedSpec.DeepCopy()
+	}
+}
+
+// applyApprovedProjectRoleSpec replaces the spec of the ArgoCDProjectRole with its applied spec while changes wait for approval,
+// with an empty spec if none was applied yet.
+func applyApprovedProjectRoleSpec(projectRole *rbacoperatorv1alpha1.ArgoCDProjectRole) {
+	if !isGated(projectRole, projectRole.Status.AppliedSpec != nil) || projectRole.Status.AppliedGeneration == projectRole.GetGeneration() {
+		return
+	}
+	projectRole.Spec = rbacoperatorv1alpha1.ArgoCDProjectRoleSpec{}
+	if projectRole.Status.AppliedSpec != nil {
+		projectRole.Spec = *projectRole.Status.AppliedSpec.DeepCopy()
+	}
+}
+
+// policyLines splits a rendered policy string into its lines.
+func policyLines(policy string) []string {
+	return strings.FieldsFunc(policy, func(r rune) bool { return r == '\n' })
+}
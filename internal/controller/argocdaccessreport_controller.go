@@ -0,0 +1,289 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/accessreport"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+const eventReasonReportGenerated = "ReportGenerated"
+
+// Keys of the ConfigMap or Secret an access report is written to.
+const (
+	accessReportKeyRows = "rows.json"
+	// accessReportKeyReport and accessReportKeyChanges are followed by the extension of the format.
+	accessReportKeyReport  = "report."
+	accessReportKeyChanges = "changes."
+)
+
+// ArgoCDAccessReportReconciler reconciles a ArgoCDAccessReport object
+type ArgoCDAccessReportReconciler struct {
+	client.Client
+	// APIReader reads the Secrets, which are not cached unless labeled, see SecretCacheSelector.
+	APIReader                    client.Reader
+	Log                          logr.Logger
+	Scheme                       *runtime.Scheme
+	Recorder                     record.EventRecorder
+	ArgoCDRBACConfigMapName      string
+	ArgoCDRBACConfigMapNamespace string
+}
+
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdaccessreports,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdaccessreports/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdroles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrolebindings,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectroles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectrolebindings,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdlocalaccounts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=argoproj.io,resources=appprojects,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile generates the access report when it was never generated, when its spec changed and when it is due
+// according to its schedule, and requeues the report at the next time it is due.
+func (r *ArgoCDAccessReportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	_ = r.Log.WithValues("argocdaccessreport", req.NamespacedName)
+
+	r.Log.Info("Reconciling ArgoCDAccessReport", "name", req.Name, "namespace", req.Namespace)
+
+	report := rbacoperatorv1alpha1.ArgoCDAccessReport{}
+	if err := r.Get(ctx, req.NamespacedName, &report); err != nil {
+		if errors.IsNotFound(err) {
+			r.Log.Info("ArgoCDAccessReport not found, skipping reconcile", "name", req.Name)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	now := timeNow()
+	next, err := nextReportTime(&report, now)
+	if err != nil {
+		// Not retried, the schedule is fixed by a change of the spec
+		report.SetConditions(rbacoperatorv1alpha1.ReconcileError(err).WithObservedGeneration(report.Generation))
+		return ctrl.Result{}, r.Status().Update(ctx, &report)
+	}
+	if report.Status.LastReportTime != nil && report.Status.ObservedGeneration == report.Generation &&
+		(next.IsZero() || now.Before(next)) {
+		return reportRequeue(next, now), nil
+	}
+
+	changes, rows, err := r.generate(ctx, &report)
+	if err != nil {
+		report.SetConditions(rbacoperatorv1alpha1.ReconcileError(err).WithObservedGeneration(report.Generation))
+		if err := r.Status().Update(ctx, &report); err != nil {
+			r.Log.Error(err, "Failed to update ArgoCDAccessReport status", "name", req.Name)
+		}
+		return ctrl.Result{}, fmt.Errorf("error when generating the access report: %v", err)
+	}
+
+	lastReportTime := metav1.NewTime(now.UTC().Truncate(time.Second))
+	report.Status.ObservedGeneration = report.Generation
+	report.Status.LastReportTime = &lastReportTime
+	report.Status.NextReportTime = nil
+	if next, _ = nextReportTime(&report, now); !next.IsZero() {
+		nextReportTime := metav1.NewTime(next.UTC())
+		report.Status.NextReportTime = &nextReportTime
+	}
+	report.Status.Rows = rows
+	report.Status.Added = len(changes.Added)
+	report.Status.Removed = len(changes.Removed)
+	report.SetConditions(rbacoperatorv1alpha1.ReconcileSuccess().WithObservedGeneration(report.Generation))
+	if err := r.Status().Update(ctx, &report); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.Recorder.Eventf(&report, corev1.EventTypeNormal, eventReasonReportGenerated, "Wrote %d rows to %s %s, %d added and %d removed since the previous report",
+		rows, accessReportOutputKind(&report), report.Spec.Output.Name, len(changes.Added), len(changes.Removed))
+	return reportRequeue(next, now), nil
+}
+
+// nextReportTime returns the time the report is next due after its last report. Zero time if the report is not scheduled.
+func nextReportTime(report *rbacoperatorv1alpha1.ArgoCDAccessReport, now time.Time) (time.Time, error) {
+	if report.Spec.Schedule == "" {
+		return time.Time{}, nil
+	}
+	schedule, location, err := parseSchedule(rbacoperatorv1alpha1.Schedule{Cron: report.Spec.Schedule, TimeZone: report.Spec.TimeZone})
+	if err != nil {
+		return time.Time{}, err
+	}
+	last := now
+	if report.Status.LastReportTime != nil {
+		last = report.Status.LastReportTime.Time
+	}
+	return schedule.Next(last.In(location)), nil
+}
+
+// reportRequeue returns the result requeuing the report at the next time it is due, if it is scheduled.
+func reportRequeue(next, now time.Time) ctrl.Result {
+	if next.IsZero() {
+		return ctrl.Result{}
+	}
+	return ctrl.Result{RequeueAfter: max(next.Sub(now), time.Second)}
+}
+
+// generate writes the access report and the changes since the report previously written to the output.
+// Returns the changes and the number of rows.
+func (r *ArgoCDAccessReportReconciler) generate(ctx context.Context, report *rbacoperatorv1alpha1.ArgoCDAccessReport) (accessreport.Changes, int, error) {
+	format := report.Spec.Format
+	if format == "" {
+		format = accessreport.FormatCSV
+	}
+	if err := accessreport.ValidateFormat(format); err != nil {
+		return accessreport.Changes{}, 0, err
+	}
+	rows, err := LoadAccessReport(ctx, r.Client, r.ArgoCDRBACConfigMapName, r.ArgoCDRBACConfigMapNamespace)
+	if err != nil {
+		return accessreport.Changes{}, 0, err
+	}
+	// Reports outside the Argo CD namespace only list the rules granted by the resources of their namespace,
+	// readers of a tenant namespace must not see the access of the other tenants.
+	if report.Namespace != r.ArgoCDRBACConfigMapNamespace {
+		rows = slices.DeleteFunc(rows, func(row accessreport.Row) bool {
+			return row.Namespace != report.Namespace
+		})
+	}
+
+	obj, data, err := r.getOutput(ctx, report)
+	if err != nil {
+		return accessreport.Changes{}, 0, err
+	}
+	previous := []accessreport.Row{}
+	if data[accessReportKeyRows] != "" {
+		if previous, err = accessreport.Parse([]byte(data[accessReportKeyRows])); err != nil {
+			return accessreport.Changes{}, 0, fmt.Errorf("invalid %s of the previous report: %v", accessReportKeyRows, err)
+		}
+	}
+	changes := accessreport.Diff(previous, rows)
+
+	out := &bytes.Buffer{}
+	if err := accessreport.Write(out, format, rows); err != nil {
+		return accessreport.Changes{}, 0, err
+	}
+	data[accessReportKeyReport+accessreport.Extension(format)] = out.String()
+	out.Reset()
+	if err := accessreport.WriteChanges(out, format, changes); err != nil {
+		return accessreport.Changes{}, 0, err
+	}
+	data[accessReportKeyChanges+accessreport.Extension(format)] = out.String()
+	rowsJSON, err := json.Marshal(rows)
+	if err != nil {
+		return accessreport.Changes{}, 0, err
+	}
+	data[accessReportKeyRows] = string(rowsJSON)
+
+	if err := r.writeOutput(ctx, report, obj, data); err != nil {
+		return accessreport.Changes{}, 0, err
+	}
+	return changes, len(rows), nil
+}
+
+// accessReportOutputKind returns the kind of the output of the report, ConfigMap if not set.
+func accessReportOutputKind(report *rbacoperatorv1alpha1.ArgoCDAccessReport) string {
+	if report.Spec.Output.Kind == "" {
+		return "ConfigMap"
+	}
+	return report.Spec.Output.Kind
+}
+
+// getOutput returns the ConfigMap or Secret the report is written to and its data. The object is nil if it does not exist.
+// Returns an error if it exists and is not controlled by the report, the report must not overwrite data of others.
+func (r *ArgoCDAccessReportReconciler) getOutput(ctx context.Context, report *rbacoperatorv1alpha1.ArgoCDAccessReport) (client.Object, map[string]string, error) {
+	key := client.ObjectKey{Name: report.Spec.Output.Name, Namespace: report.Namespace}
+	data := map[string]string{}
+	if accessReportOutputKind(report) == "Secret" {
+		secret := &corev1.Secret{}
+		if err := r.APIReader.Get(ctx, key, secret); err != nil {
+			return nil, data, client.IgnoreNotFound(err)
+		}
+		if !metav1.IsControlledBy(secret, report) {
+			return nil, data, fmt.Errorf("Secret %s exists and is not controlled by the ArgoCDAccessReport", key.Name)
+		}
+		for k, v := range secret.Data {
+			data[k] = string(v)
+		}
+		return secret, data, nil
+	}
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, key, cm); err != nil {
+		return nil, data, client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(cm, report) {
+		return nil, data, fmt.Errorf("ConfigMap %s exists and is not controlled by the ArgoCDAccessReport", key.Name)
+	}
+	for k, v := range cm.Data {
+		data[k] = v
+	}
+	return cm, data, nil
+}
+
+// writeOutput writes the data to the ConfigMap or Secret of the report, created and owned by the report if obj is nil.
+func (r *ArgoCDAccessReportReconciler) writeOutput(ctx context.Context, report *rbacoperatorv1alpha1.ArgoCDAccessReport, obj client.Object, data map[string]string) error {
+	if obj == nil {
+		meta := metav1.ObjectMeta{Name: report.Spec.Output.Name, Namespace: report.Namespace}
+		if accessReportOutputKind(report) == "Secret" {
+			obj = &corev1.Secret{ObjectMeta: meta}
+		} else {
+			obj = &corev1.ConfigMap{ObjectMeta: meta}
+		}
+		if err := ctrl.SetControllerReference(report, obj, r.Scheme); err != nil {
+			return err
+		}
+	}
+	switch output := obj.(type) {
+	case *corev1.Secret:
+		if output.Labels == nil {
+			output.Labels = map[string]string{}
+		}
+		output.Labels[common.LabelSecret] = common.LabelSecretAccessReport
+		output.Data = map[string][]byte{}
+		for k, v := range data {
+			output.Data[k] = []byte(v)
+		}
+	case *corev1.ConfigMap:
+		output.Data = data
+	}
+	if obj.GetResourceVersion() == "" {
+		return r.Create(ctx, obj)
+	}
+	return r.Update(ctx, obj)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ArgoCDAccessReportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&rbacoperatorv1alpha1.ArgoCDAccessReport{}).
+		Named("argocdaccessreport").
+		Complete(r)
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/accessreport"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+var _ reconcile.Reconciler = &ArgoCDAccessReportReconciler{}
+
+func TestLoadAccessReport(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	expiresAt := metav1.NewTime(now.Add(24 * time.Hour))
+	argocdRole := makeTestRole()
+	argocdRoleBinding := makeTestRoleBindingWithSSOSubject(setRoleBindingWindow(nil, &expiresAt))
+	cm := recordTestPolicySources(makeTestCM_ArgoCDRole_WithRoleBindingSSOSubject_Expected(), argocdRole, argocdRoleBinding)
+	cm.Data["policy.csv"] = "p, ci, applications, sync, */*, allow\n"
+	appProject := makeTestAppProject(func(ap *argocdv1alpha.AppProject) {
+		ap.Spec.Roles[0].Groups = []string{"team-a"}
+		ap.Spec.Roles[0].Policies = []string{fmt.Sprintf("p, proj:%s:existing-role, applications, get, %s/*, allow", ap.Name, ap.Name)}
+	})
+	localAccount := &rbacoperatorv1alpha1.ArgoCDLocalAccount{ObjectMeta: metav1.ObjectMeta{Name: "ci", Namespace: testRBACCMNamespace}}
+
+	resObjs := []client.Object{argocdRole, argocdRoleBinding, cm, appProject, localAccount}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	client := makeTestReconcilerClient(scheme, resObjs, nil)
+
+	rows, err := LoadAccessReport(context.TODO(), client, testRBACCMName, testRBACCMNamespace)
+	assert.NoError(t, err)
+
+	// Rules held through roles are attributed to the binding granting the role
+	binding := accessreport.Row{Subject: "gosha", SubjectKind: "sso", Resource: "applications", Object: "*/*", Effect: "allow",
+		Source: "ArgoCDRoleBinding/" + testRoleBindingName, Namespace: testNamespace, Expiry: "2024-06-02T12:00:00Z"}
+	get, list := binding, binding
+	get.Verb, list.Verb = "get", "list"
+	assert.Contains(t, rows, get)
+	assert.Contains(t, rows, list)
+
+	assert.Contains(t, rows, accessreport.Row{Subject: "ci", SubjectKind: "local", Resource: "applications", Verb: "sync", Object: "*/*",
+		Effect: "allow", Source: "ConfigMap/" + testRBACCMName, Namespace: testRBACCMNamespace})
+	assert.Contains(t, rows, accessreport.Row{Subject: "team-a", SubjectKind: "group", Resource: "applications", Verb: "get",
+		Object: testAppProjectName + "/*", Effect: "allow", Source: "AppProject/" + testAppProjectName, Namespace: testNamespace})
+
+	// Rules of the built-in policy are attributed to it
+	assert.Contains(t, rows, accessreport.Row{Subject: "admin", Resource: "applications", Verb: "sync", Object: "*/*",
+		Effect: "allow", Source: "BuiltinPolicy"})
+}
+
+func TestArgoCDAccessReportReconciler_Reconcile(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	argocdRole := makeTestRole()
+	argocdRoleBinding := makeTestRoleBindingWithSSOSubject()
+	cm := recordTestPolicySources(makeTestCM_ArgoCDRole_WithRoleBindingSSOSubject_Expected(), argocdRole, argocdRoleBinding)
+	cm.Data["policy.csv"] = "p, ci, applications, sync, */*, allow\n"
+	report := &rbacoperatorv1alpha1.ArgoCDAccessReport{
+		ObjectMeta: metav1.ObjectMeta{Name: "access-review", Namespace: testRBACCMNamespace, Generation: 1},
+		Spec: rbacoperatorv1alpha1.ArgoCDAccessReportSpec{
+			Schedule: "0 * * * *",
+			Format:   accessreport.FormatMarkdown,
+			Output:   rbacoperatorv1alpha1.AccessReportOutput{Kind: "ConfigMap", Name: "access-review"},
+		},
+	}
+
+	resObjs := []client.Object{argocdRole, argocdRoleBinding, cm, report}
+	subresObjs := []client.Object{report}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := &ArgoCDAccessReportReconciler{
+		Client:                       client,
+		APIReader:                    client,
+		Scheme:                       scheme,
+		Log:                          ZapLogger(true),
+		Recorder:                     record.NewFakeRecorder(10),
+		ArgoCDRBACConfigMapName:      testRBACCMName,
+		ArgoCDRBACConfigMapNamespace: testRBACCMNamespace,
+	}
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: report.Name, Namespace: report.Namespace}}
+	res, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, res.RequeueAfter)
+	assert.Contains(t, <-reconciler.Recorder.(*record.FakeRecorder).Events, eventReasonReportGenerated)
+
+	// The report, the changes and the rows are written to the ConfigMap owned by the report
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, report))
+	assert.Equal(t, "2024-06-01T13:00:00Z", report.Status.NextReportTime.UTC().Format(time.RFC3339))
+	assert.Equal(t, report.Status.Rows, report.Status.Added)
+	assert.Zero(t, report.Status.Removed)
+	output := &corev1.ConfigMap{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, output))
+	assert.Len(t, output.OwnerReferences, 1)
+	assert.Contains(t, output.Data["report.md"], "| gosha | sso | applications | get | */* | allow | ArgoCDRoleBinding/test-role-binding | default |  |\n")
+	assert.Contains(t, output.Data["changes.md"], "| added | ci |  | applications | sync | */* | allow | ConfigMap/argocd-rbac-cm | argocd |  |\n")
+	rows, err := accessreport.Parse([]byte(output.Data["rows.json"]))
+	assert.NoError(t, err)
+	assert.Len(t, rows, report.Status.Rows)
+
+	// Not generated again before it is due
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	cm.Data["policy.csv"] = ""
+	assert.NoError(t, reconciler.Update(context.TODO(), cm))
+	now = now.Add(30 * time.Minute)
+	res, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, res.RequeueAfter)
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, report))
+	assert.Zero(t, report.Status.Removed)
+
+	// The changes since the previous report
+	now = now.Add(30 * time.Minute)
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, report))
+	assert.Zero(t, report.Status.Added)
+	assert.Equal(t, 1, report.Status.Removed)
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, output))
+	assert.Contains(t, output.Data["changes.md"], "| removed | ci |")
+	assert.NotContains(t, output.Data["changes.md"], "| added |")
+
+	// Invalid schedules are reported and not retried
+	report.Spec.Schedule = "every hour"
+	report.Generation = 2
+	assert.NoError(t, reconciler.Update(context.TODO(), report))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, report))
+	assert.Contains(t, conditionReasons(report.Status.Conditions), rbacoperatorv1alpha1.ReasonReconcileError)
+
+	// Reports outside the Argo CD namespace only list the rules granted in their namespace
+	tenantReport := &rbacoperatorv1alpha1.ArgoCDAccessReport{
+		ObjectMeta: metav1.ObjectMeta{Name: "tenant-review", Namespace: testNamespace, Generation: 1},
+		Spec:       rbacoperatorv1alpha1.ArgoCDAccessReportSpec{Output: rbacoperatorv1alpha1.AccessReportOutput{Name: "tenant-review"}},
+	}
+	assert.NoError(t, reconciler.Create(context.TODO(), tenantReport))
+	tenantReq := reconcile.Request{NamespacedName: types.NamespacedName{Name: tenantReport.Name, Namespace: tenantReport.Namespace}}
+	_, err = reconciler.Reconcile(context.TODO(), tenantReq)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), tenantReq.NamespacedName, output))
+	rows, err = accessreport.Parse([]byte(output.Data["rows.json"]))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, rows)
+	for _, row := range rows {
+		assert.Equal(t, testNamespace, row.Namespace)
+	}
+
+	// Secrets are labeled, so that they are found again in the cache
+	assert.NoError(t, reconciler.Get(context.TODO(), tenantReq.NamespacedName, tenantReport))
+	tenantReport.Spec.Output.Kind = "Secret"
+	tenantReport.Generation = 2
+	assert.NoError(t, reconciler.Update(context.TODO(), tenantReport))
+	_, err = reconciler.Reconcile(context.TODO(), tenantReq)
+	assert.NoError(t, err)
+	secret := &corev1.Secret{}
+	assert.NoError(t, reconciler.Get(context.TODO(), tenantReq.NamespacedName, secret))
+	assert.Equal(t, common.LabelSecretAccessReport, secret.Labels[common.LabelSecret])
+	assert.NotEmpty(t, secret.Data["rows.json"])
+
+	// ConfigMaps not created by the report are not written to
+	foreign := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-config", Namespace: testNamespace},
+		Data:       map[string]string{"key": "value"},
+	}
+	assert.NoError(t, reconciler.Create(context.TODO(), foreign))
+	assert.NoError(t, reconciler.Get(context.TODO(), tenantReq.NamespacedName, tenantReport))
+	tenantReport.Spec.Output = rbacoperatorv1alpha1.AccessReportOutput{Name: foreign.Name}
+	tenantReport.Generation = 3
+	assert.NoError(t, reconciler.Update(context.TODO(), tenantReport))
+	_, err = reconciler.Reconcile(context.TODO(), tenantReq)
+	assert.ErrorContains(t, err, "ConfigMap team-config exists and is not controlled by the ArgoCDAccessReport")
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: foreign.Name, Namespace: foreign.Namespace}, output))
+	assert.Equal(t, map[string]string{"key": "value"}, output.Data)
+	assert.NoError(t, reconciler.Get(context.TODO(), tenantReq.NamespacedName, tenantReport))
+	assert.Contains(t, conditionReasons(tenantReport.Status.Conditions), rbacoperatorv1alpha1.ReasonReconcileError)
+}
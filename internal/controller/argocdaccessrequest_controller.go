@@ -0,0 +1,262 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+const (
+	eventReasonAccessApproved = "AccessApproved"
+	eventReasonAccessRevoked  = "AccessRevoked"
+)
+
+// ArgoCDAccessRequestReconciler reconciles a ArgoCDAccessRequest object
+type ArgoCDAccessRequestReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// TrustApprovals takes the requester and the approver from the annotations of the request. Only set if the
+	// webhooks are served, which record them, otherwise anyone able to annotate the request could approve it.
+	TrustApprovals bool
+}
+
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdaccessrequests,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdaccessrequests/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdapprovalpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrolebindings,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *ArgoCDAccessRequestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	_ = r.Log.WithValues("argocdaccessrequest", req.NamespacedName)
+
+	r.Log.Info("Reconciling ArgoCDAccessRequest", "name", req.Name, "namespace", req.Namespace)
+
+	accessRequest := rbacoperatorv1alpha1.ArgoCDAccessRequest{}
+	if err := r.Get(ctx, req.NamespacedName, &accessRequest); err != nil {
+		if errors.IsNotFound(err) {
+			r.Log.Info("ArgoCDAccessRequest not found, skipping reconcile", "name", req.Name)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if !accessRequest.DeletionTimestamp.IsZero() {
+		// The ArgoCDRoleBinding is owned by the request and garbage collected
+		return ctrl.Result{}, nil
+	}
+
+	now := metav1.NewTime(timeNow().UTC().Truncate(time.Second))
+	if accessRequest.Status.Phase == "" {
+		requestedAt := accessRequest.CreationTimestamp
+		if requestedAt.IsZero() {
+			requestedAt = now
+		}
+		accessRequest.AddHistory(rbacoperatorv1alpha1.AccessRequestPhaseRequested, requestedAt, accessRequest.Annotations[common.AnnotationRequestedBy])
+	}
+
+	if accessRequest.Status.Phase == rbacoperatorv1alpha1.AccessRequestPhaseRequested {
+		approver, ok := accessRequest.Annotations[common.AnnotationApprovedBy]
+		if !ok {
+			r.Log.Info("ArgoCDAccessRequest is waiting for approval", "name", req.Name)
+			accessRequest.SetConditions(rbacoperatorv1alpha1.Pending(fmt.Errorf("waiting for approval")))
+			if err := r.Status().Update(ctx, &accessRequest); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
+		if !r.TrustApprovals {
+			r.Log.Info("Approval of ArgoCDAccessRequest can't be verified", "name", req.Name)
+			accessRequest.SetConditions(rbacoperatorv1alpha1.Pending(fmt.Errorf("approval can't be verified, the ArgoCDAccessRequest webhooks are not enabled")))
+			if err := r.Status().Update(ctx, &accessRequest); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
+		if err := r.checkApproval(ctx, &accessRequest, approver); err != nil {
+			r.Log.Info("Approval of ArgoCDAccessRequest is not valid", "name", req.Name, "reason", err.Error())
+			accessRequest.SetConditions(rbacoperatorv1alpha1.Pending(err))
+			if err := r.Status().Update(ctx, &accessRequest); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
+		expiresAt := metav1.NewTime(now.Add(accessRequest.Spec.Duration.Duration))
+		accessRequest.Status.ApprovedBy = approver
+		accessRequest.Status.ApprovedAt = &now
+		accessRequest.Status.ExpiresAt = &expiresAt
+		accessRequest.AddHistory(rbacoperatorv1alpha1.AccessRequestPhaseApproved, now, approver)
+		r.Recorder.Eventf(&accessRequest, corev1.EventTypeNormal, eventReasonAccessApproved, "Access to ArgoCDRole %s approved by %s until %s",
+			accessRequest.Spec.ArgoCDRoleRef.Name, approver, expiresAt.Format(time.RFC3339))
+	}
+
+	if accessRequest.Status.Phase == rbacoperatorv1alpha1.AccessRequestPhaseApproved && now.Before(accessRequest.Status.ExpiresAt) {
+		if err := r.reconcileRoleBinding(ctx, &accessRequest); err != nil {
+			accessRequest.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+			if err := r.Status().Update(ctx, &accessRequest); err != nil {
+				r.Log.Error(err, "Failed to update ArgoCDAccessRequest status", "name", req.Name)
+			}
+			return ctrl.Result{}, fmt.Errorf("error when reconciling ArgoCDRoleBinding: %v", err)
+		}
+		accessRequest.SetConditions(
+			rbacoperatorv1alpha1.ReconcileSuccess().WithObservedGeneration(accessRequest.GetGeneration()),
+			rbacoperatorv1alpha1.Active(),
+		)
+		if err := r.Status().Update(ctx, &accessRequest); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: accessRequest.Status.ExpiresAt.Sub(now.Time)}, nil
+	}
+
+	// The request expired, revoke the access
+	if err := r.deleteRoleBinding(ctx, &accessRequest); err != nil {
+		accessRequest.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := r.Status().Update(ctx, &accessRequest); err != nil {
+			r.Log.Error(err, "Failed to update ArgoCDAccessRequest status", "name", req.Name)
+		}
+		return ctrl.Result{}, fmt.Errorf("error when deleting ArgoCDRoleBinding: %v", err)
+	}
+	if accessRequest.Status.Phase != rbacoperatorv1alpha1.AccessRequestPhaseExpired {
+		accessRequest.AddHistory(rbacoperatorv1alpha1.AccessRequestPhaseExpired, *accessRequest.Status.ExpiresAt, "")
+		r.Recorder.Eventf(&accessRequest, corev1.EventTypeNormal, eventReasonAccessRevoked, "Access to ArgoCDRole %s expired", accessRequest.Spec.ArgoCDRoleRef.Name)
+	}
+	accessRequest.SetConditions(
+		rbacoperatorv1alpha1.ReconcileSuccess().WithObservedGeneration(accessRequest.GetGeneration()),
+		rbacoperatorv1alpha1.Expired().WithMessage(fmt.Sprintf("expired at %s", accessRequest.Status.ExpiresAt.Format(time.RFC3339))),
+	)
+	if err := r.Status().Update(ctx, &accessRequest); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// checkApproval returns an error if the approver is not allowed to approve the request
+// by any ArgoCDApprovalPolicy in the namespace of the request.
+func (r *ArgoCDAccessRequestReconciler) checkApproval(ctx context.Context, accessRequest *rbacoperatorv1alpha1.ArgoCDAccessRequest, approver string) error {
+	policies := rbacoperatorv1alpha1.ArgoCDApprovalPolicyList{}
+	if err := r.List(ctx, &policies, client.InNamespace(accessRequest.Namespace)); err != nil {
+		return err
+	}
+	var groups []string
+	if value := accessRequest.Annotations[common.AnnotationApprovedByGroups]; value != "" {
+		groups = strings.Split(value, ",")
+	}
+	return accessRequest.CheckApproval(policies.Items, accessRequest.Annotations[common.AnnotationRequestedBy], approver, groups)
+}
+
+// reconcileRoleBinding will create or update the ArgoCDRoleBinding granting the requested role until the request expires.
+func (r *ArgoCDAccessRequestReconciler) reconcileRoleBinding(ctx context.Context, accessRequest *rbacoperatorv1alpha1.ArgoCDAccessRequest) error {
+	rb := &rbacoperatorv1alpha1.ArgoCDRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      accessRequest.Name,
+			Namespace: accessRequest.Namespace,
+		},
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, rb, func() error {
+		if !rb.CreationTimestamp.IsZero() && !metav1.IsControlledBy(rb, accessRequest) {
+			return fmt.Errorf("ArgoCDRoleBinding %s already exists and is not owned by the request", rb.Name)
+		}
+		// The binding plans its changes if the request is in dry-run mode
+		if value, ok := accessRequest.Annotations[common.AnnotationDryRun]; ok {
+			metav1.SetMetaDataAnnotation(&rb.ObjectMeta, common.AnnotationDryRun, value)
+		} else {
+			delete(rb.Annotations, common.AnnotationDryRun)
+		}
+		rb.Spec = rbacoperatorv1alpha1.ArgoCDRoleBindingSpec{
+			ArgoCDRoleRef: accessRequest.Spec.ArgoCDRoleRef,
+			Subjects: []rbacoperatorv1alpha1.GlobalSubject{{
+				Kind: accessRequest.Spec.Subject.Kind,
+				Name: accessRequest.Spec.Subject.Name,
+			}},
+			ExpiresAt: accessRequest.Status.ExpiresAt,
+		}
+		return controllerutil.SetControllerReference(accessRequest, rb, r.Scheme)
+	}); err != nil {
+		return err
+	}
+	accessRequest.Status.ArgoCDRoleBinding = rb.Name
+	return nil
+}
+
+// deleteRoleBinding will delete the ArgoCDRoleBinding of the request, if it exists.
+func (r *ArgoCDAccessRequestReconciler) deleteRoleBinding(ctx context.Context, accessRequest *rbacoperatorv1alpha1.ArgoCDAccessRequest) error {
+	if accessRequest.Status.ArgoCDRoleBinding == "" {
+		return nil
+	}
+	rb := &rbacoperatorv1alpha1.ArgoCDRoleBinding{}
+	if err := r.Get(ctx, client.ObjectKey{Name: accessRequest.Status.ArgoCDRoleBinding, Namespace: accessRequest.Namespace}, rb); err != nil {
+		if errors.IsNotFound(err) {
+			accessRequest.Status.ArgoCDRoleBinding = ""
+			return nil
+		}
+		return err
+	}
+	if metav1.IsControlledBy(rb, accessRequest) {
+		if err := r.Delete(ctx, rb); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	accessRequest.Status.ArgoCDRoleBinding = ""
+	return nil
+}
+
+// mapApprovalPolicyToRequests returns the pending ArgoCDAccessRequests in the namespace of the ArgoCDApprovalPolicy.
+func (r *ArgoCDAccessRequestReconciler) mapApprovalPolicyToRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	accessRequests := rbacoperatorv1alpha1.ArgoCDAccessRequestList{}
+	if err := r.List(ctx, &accessRequests, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "Failed to list ArgoCDAccessRequests", "namespace", obj.GetNamespace())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, accessRequest := range accessRequests.Items {
+		if accessRequest.Status.Phase == rbacoperatorv1alpha1.AccessRequestPhaseRequested {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&accessRequest)})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ArgoCDAccessRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&rbacoperatorv1alpha1.ArgoCDAccessRequest{}).
+		Owns(&rbacoperatorv1alpha1.ArgoCDRoleBinding{}).
+		Watches(&rbacoperatorv1alpha1.ArgoCDApprovalPolicy{}, handler.EnqueueRequestsFromMapFunc(r.mapApprovalPolicyToRequests)).
+		Named("argocdaccessrequest").
+		Complete(r)
+}
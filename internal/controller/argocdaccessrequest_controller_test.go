@@ -0,0 +1,177 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+var _ reconcile.Reconciler = &ArgoCDAccessRequestReconciler{}
+
+func TestArgoCDAccessRequestReconciler_Reconcile(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	accessRequest := makeTestAccessRequest()
+	approvalPolicy := makeTestApprovalPolicy(rbacoperatorv1alpha1.Approver{Kind: rbacoperatorv1alpha1.ApproverKindGroup, Name: "leads"})
+
+	resObjs := []client.Object{accessRequest, approvalPolicy}
+	subresObjs := []client.Object{accessRequest}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDAccessRequestReconciler(client, scheme)
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      accessRequest.Name,
+			Namespace: accessRequest.Namespace,
+		},
+	}
+
+	// The request is inert until approved
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	res := &rbacoperatorv1alpha1.ArgoCDAccessRequest{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, res))
+	assert.Equal(t, rbacoperatorv1alpha1.AccessRequestPhaseRequested, res.Status.Phase)
+	assert.Equal(t, rbacoperatorv1alpha1.TypePending, res.Status.Conditions[0].Type)
+	assert.False(t, IsObjectFound(reconciler.Client, testNamespace, testAccessRequestName, &rbacoperatorv1alpha1.ArgoCDRoleBinding{}))
+
+	// Approval by a member of an approver group creates the binding
+	approveAccessRequest(testApprover, "leads")(res)
+	assert.NoError(t, reconciler.Update(context.TODO(), res))
+
+	result, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, result.RequeueAfter)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, res))
+	assert.Equal(t, rbacoperatorv1alpha1.AccessRequestPhaseApproved, res.Status.Phase)
+	assert.Equal(t, testApprover, res.Status.ApprovedBy)
+	assert.Equal(t, metav1.NewTime(now.Add(time.Hour)).Unix(), res.Status.ExpiresAt.Unix())
+
+	rb := &rbacoperatorv1alpha1.ArgoCDRoleBinding{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testAccessRequestName, Namespace: testNamespace}, rb))
+	assert.Equal(t, testRoleName, rb.Spec.ArgoCDRoleRef.Name)
+	assert.Equal(t, []rbacoperatorv1alpha1.GlobalSubject{{Kind: "sso", Name: "gosha"}}, rb.Spec.Subjects)
+	assert.Equal(t, res.Status.ExpiresAt.Unix(), rb.Spec.ExpiresAt.Unix())
+	assert.True(t, metav1.IsControlledBy(rb, res))
+
+	// The binding is removed at expiry
+	now = now.Add(time.Hour)
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, res))
+	assert.Equal(t, rbacoperatorv1alpha1.AccessRequestPhaseExpired, res.Status.Phase)
+	assert.Empty(t, res.Status.ArgoCDRoleBinding)
+	assert.False(t, IsObjectFound(reconciler.Client, testNamespace, testAccessRequestName, &rbacoperatorv1alpha1.ArgoCDRoleBinding{}))
+
+	phases := []rbacoperatorv1alpha1.AccessRequestPhase{}
+	for _, event := range res.Status.History {
+		phases = append(phases, event.Phase)
+	}
+	assert.Equal(t, []rbacoperatorv1alpha1.AccessRequestPhase{
+		rbacoperatorv1alpha1.AccessRequestPhaseRequested,
+		rbacoperatorv1alpha1.AccessRequestPhaseApproved,
+		rbacoperatorv1alpha1.AccessRequestPhaseExpired,
+	}, phases)
+	assert.Equal(t, testRequester, res.Status.History[0].By)
+	assert.Equal(t, testApprover, res.Status.History[1].By)
+}
+
+func TestArgoCDAccessRequestReconciler_InvalidApproval(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+
+	tests := []struct {
+		name     string
+		approver string
+		duration time.Duration
+		policy   bool
+		message  string
+	}{
+		{"approver not listed", "someone", time.Hour, true, "someone is not allowed to approve 1h0m0s of ArgoCDRole test-role"},
+		{"self approval", testRequester, time.Hour, true, "requester can't approve its own request"},
+		{"duration above max", testApprover, 8 * time.Hour, true, "approver is not allowed to approve 8h0m0s of ArgoCDRole test-role"},
+		{"no policy", testApprover, time.Hour, false, "no ArgoCDApprovalPolicy applies to ArgoCDRole test-role"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			accessRequest := makeTestAccessRequest(approveAccessRequest(test.approver), func(r *rbacoperatorv1alpha1.ArgoCDAccessRequest) {
+				r.Spec.Duration = metav1.Duration{Duration: test.duration}
+			})
+			resObjs := []client.Object{accessRequest}
+			if test.policy {
+				resObjs = append(resObjs, makeTestApprovalPolicy(
+					rbacoperatorv1alpha1.Approver{Kind: rbacoperatorv1alpha1.ApproverKindUser, Name: testApprover},
+					rbacoperatorv1alpha1.Approver{Kind: rbacoperatorv1alpha1.ApproverKindUser, Name: testRequester},
+				))
+			}
+			scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+			client := makeTestReconcilerClient(scheme, resObjs, []client.Object{accessRequest})
+			reconciler := makeTestArgoCDAccessRequestReconciler(client, scheme)
+
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: accessRequest.Name, Namespace: accessRequest.Namespace}}
+			_, err := reconciler.Reconcile(context.TODO(), req)
+			assert.NoError(t, err)
+
+			res := &rbacoperatorv1alpha1.ArgoCDAccessRequest{}
+			assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, res))
+			assert.Equal(t, rbacoperatorv1alpha1.AccessRequestPhaseRequested, res.Status.Phase)
+			assert.Equal(t, test.message, res.Status.Conditions[0].Message)
+			assert.False(t, IsObjectFound(reconciler.Client, testNamespace, testAccessRequestName, &rbacoperatorv1alpha1.ArgoCDRoleBinding{}))
+		})
+	}
+}
+
+func TestArgoCDAccessRequestReconciler_UntrustedApproval(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+
+	accessRequest := makeTestAccessRequest(approveAccessRequest(testApprover, "leads"))
+	approvalPolicy := makeTestApprovalPolicy(rbacoperatorv1alpha1.Approver{Kind: rbacoperatorv1alpha1.ApproverKindGroup, Name: "leads"})
+
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, []client.Object{accessRequest, approvalPolicy}, []client.Object{accessRequest})
+	reconciler := makeTestArgoCDAccessRequestReconciler(client, scheme)
+	reconciler.TrustApprovals = false
+
+	// Without the webhooks anyone could set the approver annotations, the request is never approved
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: accessRequest.Name, Namespace: accessRequest.Namespace}}
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	res := &rbacoperatorv1alpha1.ArgoCDAccessRequest{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, res))
+	assert.Equal(t, rbacoperatorv1alpha1.AccessRequestPhaseRequested, res.Status.Phase)
+	assert.Equal(t, rbacoperatorv1alpha1.TypePending, res.Status.Conditions[0].Type)
+	assert.False(t, IsObjectFound(reconciler.Client, testNamespace, testAccessRequestName, &rbacoperatorv1alpha1.ArgoCDRoleBinding{}))
+}
@@ -0,0 +1,549 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+const (
+	argoCDRoleBindingKind        = "ArgoCDRoleBinding"
+	argoCDProjectRoleBindingKind = "ArgoCDProjectRoleBinding"
+	argoCDAdminAccountName       = "admin"
+)
+
+// ArgoCDLocalAccountReconciler reconciles a ArgoCDLocalAccount object
+type ArgoCDLocalAccountReconciler struct {
+	client.Client
+	// APIReader reads the Secrets, which are not cached unless labeled, see SecretCacheSelector.
+	APIReader                client.Reader
+	Log                      logr.Logger
+	Scheme                   *runtime.Scheme
+	ArgoCDConfigMapName      string
+	ArgoCDConfigMapNamespace string
+	ArgoCDSecretName         string
+	ArgoCDSecretNamespace    string
+	// DryRun holds all objects instead of reconciling them, their changes can't be planned.
+	DryRun bool
+	// Audit writes an audit entry for every change to the Argo CD ConfigMap. Nothing is audited if nil.
+	Audit *AuditLog
+}
+
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdlocalaccounts,verbs=*
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdlocalaccounts/status,verbs=*
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdlocalaccounts/finalizers,verbs=*
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrolebindings,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectrolebindings,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdroles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectroles,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;delete
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *ArgoCDLocalAccountReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	_ = r.Log.WithValues("argocdlocalaccount", req.NamespacedName)
+
+	r.Log.Info("Reconciling ArgoCDLocalAccount", "name", req.Name, "namespace", req.Namespace)
+
+	account := rbacoperatorv1alpha1.ArgoCDLocalAccount{}
+	if err := r.Get(ctx, req.NamespacedName, &account); err != nil {
+		if errors.IsNotFound(err) {
+			r.Log.Info("ArgoCDLocalAccount not found, skipping reconcile", "name", req.Name)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	r = r.audited(&account)
+	if isDryRun(r.DryRun, &account) {
+		r.Log.Info("ArgoCDLocalAccount is in dry-run mode, skipping reconcile", "name", req.Name)
+		account.SetConditions(rbacoperatorv1alpha1.Pending(errNotPlanned("ArgoCDLocalAccount")))
+		return ctrl.Result{}, r.Status().Update(ctx, &account)
+	}
+
+	if account.IsBeingDeleted() {
+		if err := r.handleFinalizer(ctx, &account); err != nil {
+			if errors.IsConflict(err) {
+				r.Log.Info("Conflict while handling finalizer for ArgoCDLocalAccount", "name", req.Name)
+				return ctrl.Result{RequeueAfter: time.Second}, nil
+			}
+			account.SetConditions(rbacoperatorv1alpha1.Deleting().WithMessage(err.Error()))
+			if err := r.Status().Update(ctx, &account); err != nil {
+				r.Log.Error(err, "Failed to update ArgoCDLocalAccount status during finalizer handling", "name", req.Name)
+			}
+			return ctrl.Result{}, fmt.Errorf("error when handling finalizer: %v", err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !account.HasFinalizer(rbacoperatorv1alpha1.ArgoCDLocalAccountFinalizerName) {
+		if err := r.addFinalizer(ctx, &account); err != nil {
+			return ctrl.Result{}, fmt.Errorf("error when adding finalizer: %v", err)
+		}
+		return ctrl.Result{RequeueAfter: time.Second}, nil
+	}
+
+	if err := r.validateAccount(ctx, &account); err != nil {
+		r.Log.Info("ArgoCDLocalAccount is not valid", "name", req.Name, "reason", err.Error())
+		account.SetConditions(rbacoperatorv1alpha1.Pending(err))
+		if err := r.Status().Update(ctx, &account); err != nil {
+			r.Log.Error(err, "Failed to update ArgoCDLocalAccount status", "name", req.Name)
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	cm := newConfigMap(r.ArgoCDConfigMapName, r.ArgoCDConfigMapNamespace)
+	secret := &corev1.Secret{}
+	if !IsObjectFound(r.Client, r.ArgoCDConfigMapNamespace, r.ArgoCDConfigMapName, cm) ||
+		!IsObjectFound(r.APIReader, r.ArgoCDSecretNamespace, r.ArgoCDSecretName, secret) {
+		err := fmt.Errorf("ConfigMap %s/%s or Secret %s/%s not found",
+			r.ArgoCDConfigMapNamespace, r.ArgoCDConfigMapName, r.ArgoCDSecretNamespace, r.ArgoCDSecretName)
+		r.Log.Info("Argo CD ConfigMap or Secret not found", "name", req.Name)
+		account.SetConditions(rbacoperatorv1alpha1.Pending(err))
+		if err := r.Status().Update(ctx, &account); err != nil {
+			r.Log.Error(err, "Failed to update ArgoCDLocalAccount status", "name", req.Name)
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	if err := validateAccountManager(cm, &account); err != nil {
+		r.Log.Info("ArgoCDLocalAccount is not valid", "name", req.Name, "reason", err.Error())
+		account.SetConditions(rbacoperatorv1alpha1.Pending(err))
+		if err := r.Status().Update(ctx, &account); err != nil {
+			r.Log.Error(err, "Failed to update ArgoCDLocalAccount status", "name", req.Name)
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	if reconcileAccountConfigMap(cm, &account) {
+		r.Log.Info("Updating account in Argo CD ConfigMap", "account", account.Name)
+		if err := r.Update(ctx, cm); err != nil {
+			return r.reconcileError(ctx, &account, err)
+		}
+	}
+
+	now := timeNow().UTC().Truncate(time.Second)
+	secretChanged, err := r.reconcilePassword(ctx, &account, secret, now)
+	if err != nil {
+		return r.reconcileError(ctx, &account, err)
+	}
+	tokensChanged, err := r.reconcileTokens(ctx, &account, secret, now)
+	if err != nil {
+		return r.reconcileError(ctx, &account, err)
+	}
+	if secretChanged || tokensChanged {
+		r.Log.Info("Updating account in Argo CD Secret", "account", account.Name)
+		if err := r.Update(ctx, secret); err != nil {
+			return r.reconcileError(ctx, &account, err)
+		}
+	}
+
+	bindings, err := r.getBindingsStatus(ctx, &account)
+	if err != nil {
+		return r.reconcileError(ctx, &account, err)
+	}
+	account.Status.Bindings = bindings
+
+	account.SetConditions(rbacoperatorv1alpha1.ReconcileSuccess().WithObservedGeneration(account.GetGeneration()))
+	if err := r.Status().Update(ctx, &account); err != nil {
+		r.Log.Error(err, "Failed to update ArgoCDLocalAccount status", "name", req.Name)
+	}
+
+	requeueAfter := time.Minute * 10
+	for _, token := range account.Status.Tokens {
+		if token.ExpiresAt != nil {
+			requeueAfter = min(requeueAfter, max(token.ExpiresAt.Sub(now), time.Second))
+		}
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+func (r *ArgoCDLocalAccountReconciler) reconcileError(ctx context.Context, account *rbacoperatorv1alpha1.ArgoCDLocalAccount, err error) (ctrl.Result, error) {
+	if errors.IsConflict(err) {
+		r.Log.Info("Conflict while reconciling ArgoCDLocalAccount, requeuing", "name", account.Name)
+		return ctrl.Result{RequeueAfter: time.Second}, nil
+	}
+	account.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+	if err := r.Status().Update(ctx, account); err != nil {
+		r.Log.Error(err, "Failed to update ArgoCDLocalAccount status", "name", account.Name)
+	}
+	return ctrl.Result{}, err
+}
+
+// validateAccount returns an error if the account can not be managed by the ArgoCDLocalAccount.
+// As local accounts are global in Argo CD, the oldest ArgoCDLocalAccount with the name owns the account.
+func (r *ArgoCDLocalAccountReconciler) validateAccount(ctx context.Context, account *rbacoperatorv1alpha1.ArgoCDLocalAccount) error {
+	if account.Name == argoCDAdminAccountName {
+		return fmt.Errorf("the %s account is built-in and can not be managed", argoCDAdminAccountName)
+	}
+	if len(account.Spec.Tokens) > 0 && !account.HasCapability(rbacoperatorv1alpha1.AccountCapabilityAPIKey) {
+		return fmt.Errorf("tokens require the %s capability", rbacoperatorv1alpha1.AccountCapabilityAPIKey)
+	}
+	if owner, err := r.getAccountOwner(ctx, account.Name); err != nil {
+		return err
+	} else if owner != nil && owner.Namespace != account.Namespace {
+		return fmt.Errorf("account %s is already managed by ArgoCDLocalAccount %s/%s", account.Name, owner.Namespace, owner.Name)
+	}
+	return nil
+}
+
+// validateAccountManager returns an error if the local account exists in Argo CD, but is not managed by the
+// ArgoCDLocalAccount. Only accounts created by an ArgoCDLocalAccount of the namespace, or listed for the namespace
+// in the managed accounts annotation of the Argo CD ConfigMap by an administrator, are changed.
+func validateAccountManager(cm *corev1.ConfigMap, account *rbacoperatorv1alpha1.ArgoCDLocalAccount) error {
+	switch manager := accountManager(cm, account.Name); {
+	case manager == "" && accountExists(cm, account.Name):
+		return fmt.Errorf("account %s already exists in Argo CD and is not managed by an ArgoCDLocalAccount, see the %s annotation of ConfigMap %s",
+			account.Name, common.AnnotationManagedAccounts, cm.Name)
+	case manager != "" && manager != account.Namespace:
+		return fmt.Errorf("account %s is already managed by an ArgoCDLocalAccount in namespace %s", account.Name, manager)
+	}
+	return nil
+}
+
+// getAccountOwner returns the oldest ArgoCDLocalAccount with the name, which owns the account in Argo CD.
+func (r *ArgoCDLocalAccountReconciler) getAccountOwner(ctx context.Context, name string) (*rbacoperatorv1alpha1.ArgoCDLocalAccount, error) {
+	accounts := rbacoperatorv1alpha1.ArgoCDLocalAccountList{}
+	if err := r.List(ctx, &accounts); err != nil {
+		return nil, err
+	}
+	var owner *rbacoperatorv1alpha1.ArgoCDLocalAccount
+	for i, account := range accounts.Items {
+		if account.Name != name {
+			continue
+		}
+		if owner == nil || account.CreationTimestamp.Before(&owner.CreationTimestamp) ||
+			(account.CreationTimestamp.Equal(&owner.CreationTimestamp) && account.Namespace < owner.Namespace) {
+			owner = &accounts.Items[i]
+		}
+	}
+	return owner, nil
+}
+
+// reconcilePassword will set the password from the referenced Secret in the Argo CD Secret.
+func (r *ArgoCDLocalAccountReconciler) reconcilePassword(ctx context.Context, account *rbacoperatorv1alpha1.ArgoCDLocalAccount, secret *corev1.Secret, now time.Time) (bool, error) {
+	var password []byte
+	if ref := account.Spec.PasswordSecretRef; ref != nil {
+		passwordSecret := &corev1.Secret{}
+		if err := r.APIReader.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: account.Namespace}, passwordSecret); err != nil {
+			return false, fmt.Errorf("failed to get password Secret %s: %w", ref.Name, err)
+		}
+		key := ref.Key
+		if key == "" {
+			key = common.PasswordSecretKey
+		}
+		var ok bool
+		if password, ok = passwordSecret.Data[key]; !ok || len(password) == 0 {
+			return false, fmt.Errorf("key %s is missing in password Secret %s", key, ref.Name)
+		}
+	}
+
+	changed, err := reconcileAccountPassword(secret, account.Name, password, now)
+	if err != nil {
+		return false, err
+	}
+	account.Status.PasswordMtime = nil
+	if mtime, ok := secret.Data[accountKey(account.Name, common.ArgoCDKeyAccountPasswordMtimeSuffix)]; ok {
+		if t, err := time.Parse(time.RFC3339, string(mtime)); err == nil {
+			passwordMtime := metav1.NewTime(t)
+			account.Status.PasswordMtime = &passwordMtime
+		}
+	}
+	return changed, nil
+}
+
+// reconcileTokens will issue the API tokens of the account, reissue expired or revoked tokens
+// and revoke the tokens removed from the spec.
+func (r *ArgoCDLocalAccountReconciler) reconcileTokens(ctx context.Context, account *rbacoperatorv1alpha1.ArgoCDLocalAccount, secret *corev1.Secret, now time.Time) (bool, error) {
+	tokens, err := getAccountTokens(secret, account.Name)
+	if err != nil {
+		return false, err
+	}
+	changed := false
+
+	issued := map[string]rbacoperatorv1alpha1.LocalAccountTokenStatus{}
+	for _, status := range account.Status.Tokens {
+		issued[status.ID] = status
+	}
+
+	tokensStatus := []rbacoperatorv1alpha1.LocalAccountTokenStatus{}
+	for _, spec := range account.Spec.Tokens {
+		status, ok := issued[spec.ID]
+		delete(issued, spec.ID)
+		if ok && !r.needsIssue(ctx, account, spec, status, tokens, now) {
+			tokensStatus = append(tokensStatus, status)
+			continue
+		}
+		if ok {
+			tokens = withoutAccountToken(tokens, status.TokenID)
+		}
+
+		r.Log.Info("Issuing API token", "account", account.Name, "id", spec.ID)
+		newStatus, token, err := r.issueToken(ctx, account, secret, spec, now)
+		if err != nil {
+			return false, err
+		}
+		tokens = append(tokens, token)
+		tokensStatus = append(tokensStatus, newStatus)
+		changed = true
+	}
+
+	// Tokens left are not in the spec anymore, revoke them
+	for _, status := range issued {
+		r.Log.Info("Revoking API token", "account", account.Name, "id", status.ID)
+		tokens = withoutAccountToken(tokens, status.TokenID)
+		if status.SecretName != "" {
+			if err := r.Delete(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: status.SecretName, Namespace: account.Namespace}}); err != nil && !errors.IsNotFound(err) {
+				return false, err
+			}
+		}
+		changed = true
+	}
+
+	account.Status.Tokens = tokensStatus
+	if !changed {
+		return false, nil
+	}
+	return true, setAccountTokens(secret, account.Name, tokens)
+}
+
+// needsIssue returns true if the token has to be issued again, because it was revoked in Argo CD,
+// the Secret is missing, the token expired or the spec of the token changed.
+func (r *ArgoCDLocalAccountReconciler) needsIssue(ctx context.Context, account *rbacoperatorv1alpha1.ArgoCDLocalAccount, spec rbacoperatorv1alpha1.LocalAccountToken, status rbacoperatorv1alpha1.LocalAccountTokenStatus, tokens []accountToken, now time.Time) bool {
+	if !hasAccountToken(tokens, status.TokenID) || status.SecretName != spec.SecretName {
+		return true
+	}
+	if err := r.APIReader.Get(ctx, types.NamespacedName{Name: spec.SecretName, Namespace: account.Namespace}, &corev1.Secret{}); err != nil {
+		return true
+	}
+	if status.ExpiresAt == nil {
+		return spec.ExpiresIn != nil
+	}
+	if spec.ExpiresIn == nil || status.ExpiresAt.Sub(status.IssuedAt.Time) != spec.ExpiresIn.Duration {
+		return true
+	}
+	return !now.Before(status.ExpiresAt.Time)
+}
+
+// issueToken will sign a new API token for the account and write it into the output Secret.
+func (r *ArgoCDLocalAccountReconciler) issueToken(ctx context.Context, account *rbacoperatorv1alpha1.ArgoCDLocalAccount, secret *corev1.Secret, spec rbacoperatorv1alpha1.LocalAccountToken, now time.Time) (rbacoperatorv1alpha1.LocalAccountTokenStatus, accountToken, error) {
+	signingKey, ok := secret.Data[common.ArgoCDKeyServerSignature]
+	if !ok || len(signingKey) == 0 {
+		return rbacoperatorv1alpha1.LocalAccountTokenStatus{}, accountToken{},
+			fmt.Errorf("%s is missing in Secret %s/%s", common.ArgoCDKeyServerSignature, secret.Namespace, secret.Name)
+	}
+
+	id := fmt.Sprintf("%s-%d", spec.ID, now.Unix())
+	var expiresIn time.Duration
+	if spec.ExpiresIn != nil {
+		expiresIn = spec.ExpiresIn.Duration
+	}
+	jwtToken, err := signJWTToken(signingKey, accountTokenSubject(account.Name), id, now, expiresIn)
+	if err != nil {
+		return rbacoperatorv1alpha1.LocalAccountTokenStatus{}, accountToken{}, err
+	}
+
+	outputSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      spec.SecretName,
+			Namespace: account.Namespace,
+		},
+	}
+	if err := createOrUpdateSecret(ctx, r.APIReader, r.Client, outputSecret, common.LabelSecretToken, func() error {
+		outputSecret.Type = corev1.SecretTypeOpaque
+		outputSecret.Data = map[string][]byte{
+			common.TokenSecretKey: []byte(jwtToken),
+		}
+		return controllerutil.SetControllerReference(account, outputSecret, r.Scheme)
+	}); err != nil {
+		return rbacoperatorv1alpha1.LocalAccountTokenStatus{}, accountToken{}, err
+	}
+
+	token := accountToken{ID: id, IssuedAt: now.Unix()}
+	status := rbacoperatorv1alpha1.LocalAccountTokenStatus{
+		ID:         spec.ID,
+		TokenID:    id,
+		SecretName: spec.SecretName,
+		IssuedAt:   metav1.NewTime(now),
+	}
+	if expiresIn > 0 {
+		token.ExpiresAt = now.Add(expiresIn).Unix()
+		expiresAt := metav1.NewTime(now.Add(expiresIn))
+		status.ExpiresAt = &expiresAt
+	}
+	return status, token, nil
+}
+
+// getBindingsStatus returns the ArgoCDRoleBindings and ArgoCDProjectRoleBindings referencing the account
+// as local subject and whether they are valid.
+func (r *ArgoCDLocalAccountReconciler) getBindingsStatus(ctx context.Context, account *rbacoperatorv1alpha1.ArgoCDLocalAccount) ([]rbacoperatorv1alpha1.LocalAccountBindingStatus, error) {
+	bindings := []rbacoperatorv1alpha1.LocalAccountBindingStatus{}
+
+	roleBindings := rbacoperatorv1alpha1.ArgoCDRoleBindingList{}
+	if err := r.List(ctx, &roleBindings); err != nil {
+		return nil, err
+	}
+	for _, rb := range roleBindings.Items {
+		if !roleBindingReferencesAccount(&rb, account.Name) {
+			continue
+		}
+		roleName := rb.Spec.ArgoCDRoleRef.Name
+		roleFound := roleName == "admin" || roleName == "readonly" ||
+			IsObjectFound(r.Client, rb.Namespace, roleName, &rbacoperatorv1alpha1.ArgoCDRole{})
+		bindings = append(bindings, makeBindingStatus(account, argoCDRoleBindingKind, &rb, "ArgoCDRole", roleName, roleFound))
+	}
+
+	projectRoleBindings := rbacoperatorv1alpha1.ArgoCDProjectRoleBindingList{}
+	if err := r.List(ctx, &projectRoleBindings); err != nil {
+		return nil, err
+	}
+	for _, rb := range projectRoleBindings.Items {
+		if !projectRoleBindingReferencesAccount(&rb, account.Name) {
+			continue
+		}
+		roleName := rb.Spec.ArgoCDProjectRoleRef.Name
+		roleFound := IsObjectFound(r.Client, rb.Namespace, roleName, &rbacoperatorv1alpha1.ArgoCDProjectRole{})
+		bindings = append(bindings, makeBindingStatus(account, argoCDProjectRoleBindingKind, &rb, "ArgoCDProjectRole", roleName, roleFound))
+	}
+
+	sort.Slice(bindings, func(i, j int) bool {
+		if bindings[i].Kind != bindings[j].Kind {
+			return bindings[i].Kind < bindings[j].Kind
+		}
+		return bindings[i].Name < bindings[j].Name
+	})
+	return bindings, nil
+}
+
+func makeBindingStatus(account *rbacoperatorv1alpha1.ArgoCDLocalAccount, kind string, binding client.Object, roleKind, roleName string, roleFound bool) rbacoperatorv1alpha1.LocalAccountBindingStatus {
+	status := rbacoperatorv1alpha1.LocalAccountBindingStatus{
+		Kind:  kind,
+		Name:  client.ObjectKeyFromObject(binding).String(),
+		Valid: true,
+	}
+	switch {
+	case !account.IsEnabled():
+		status.Valid = false
+		status.Message = fmt.Sprintf("account %s is disabled", account.Name)
+	case !roleFound:
+		status.Valid = false
+		status.Message = fmt.Sprintf("%s %s/%s not found", roleKind, binding.GetNamespace(), roleName)
+	}
+	return status
+}
+
+func roleBindingReferencesAccount(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, name string) bool {
+	for _, subject := range rb.Spec.Subjects {
+		if subject.Kind == "local" && subject.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func projectRoleBindingReferencesAccount(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding, name string) bool {
+	for _, subject := range rb.Spec.Subjects {
+		for _, user := range subject.Users {
+			if user.Kind == "local" && user.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ArgoCDLocalAccountReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&rbacoperatorv1alpha1.ArgoCDLocalAccount{}).
+		Owns(&corev1.Secret{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.mapPasswordSecretToLocalAccounts)).
+		Watches(&rbacoperatorv1alpha1.ArgoCDRoleBinding{}, handler.EnqueueRequestsFromMapFunc(r.mapBindingToLocalAccounts)).
+		Watches(&rbacoperatorv1alpha1.ArgoCDProjectRoleBinding{}, handler.EnqueueRequestsFromMapFunc(r.mapBindingToLocalAccounts)).
+		Named("argocdlocalaccount").
+		Complete(r)
+}
+
+// mapPasswordSecretToLocalAccounts enqueues the ArgoCDLocalAccounts referencing the Secret as password Secret,
+// so that password changes are applied immediately. Only labeled Secrets are watched, see SecretCacheSelector.
+func (r *ArgoCDLocalAccountReconciler) mapPasswordSecretToLocalAccounts(ctx context.Context, obj client.Object) []reconcile.Request {
+	accounts := rbacoperatorv1alpha1.ArgoCDLocalAccountList{}
+	if err := r.List(ctx, &accounts, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "Failed to list ArgoCDLocalAccounts", "namespace", obj.GetNamespace())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, account := range accounts.Items {
+		if account.Spec.PasswordSecretRef != nil && account.Spec.PasswordSecretRef.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&account)})
+		}
+	}
+	return requests
+}
+
+// mapBindingToLocalAccounts enqueues the ArgoCDLocalAccounts referenced by the binding as local subjects,
+// so that the status of the bindings in the accounts is kept up to date.
+func (r *ArgoCDLocalAccountReconciler) mapBindingToLocalAccounts(ctx context.Context, obj client.Object) []reconcile.Request {
+	accounts := rbacoperatorv1alpha1.ArgoCDLocalAccountList{}
+	if err := r.List(ctx, &accounts); err != nil {
+		r.Log.Error(err, "Failed to list ArgoCDLocalAccounts")
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, account := range accounts.Items {
+		referenced := false
+		switch rb := obj.(type) {
+		case *rbacoperatorv1alpha1.ArgoCDRoleBinding:
+			referenced = roleBindingReferencesAccount(rb, account.Name)
+		case *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding:
+			referenced = projectRoleBindingReferencesAccount(rb, account.Name)
+		}
+		// Accounts which are not referenced anymore have to be enqueued too, to remove the binding from the status
+		if referenced || hasBindingInStatus(&account, client.ObjectKeyFromObject(obj).String()) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&account)})
+		}
+	}
+	return requests
+}
+
+func hasBindingInStatus(account *rbacoperatorv1alpha1.ArgoCDLocalAccount, name string) bool {
+	for _, binding := range account.Status.Bindings {
+		if binding.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/audit"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+var _ reconcile.Reconciler = &ArgoCDLocalAccountReconciler{}
+
+func TestArgoCDLocalAccountReconciler_Reconcile(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	account := makeTestLocalAccount(addFinalizerLocalAccount(), addPasswordToLocalAccount("password"), addTokenToLocalAccount("ci", "ci-token"))
+
+	resObjs := []client.Object{
+		account,
+		makeTestArgoCDConfigMap(),
+		makeTestArgoCDSecret(),
+		makeTestPasswordSecret("password", "s3cr3t"),
+		makeTestRoleBindingWithLocalSubject(),
+	}
+	subresObjs := []client.Object{account}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDLocalAccountReconciler(client, scheme)
+	sink := &testAuditSink{}
+	reconciler.Audit = &AuditLog{Sink: sink}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      account.Name,
+			Namespace: account.Namespace,
+		},
+	}
+
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testArgoCDCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, "login,apiKey", cm.Data["accounts.localUser"])
+	assert.Equal(t, "true", cm.Data["accounts.localUser.enabled"])
+	assert.Equal(t, "login", cm.Data["accounts.other"])
+
+	// The account written to the ConfigMap is audited
+	assert.Len(t, sink.entries, 1)
+	assert.Equal(t, "ArgoCDLocalAccount", sink.entries[0].Resource.Kind)
+	assert.Contains(t, sink.entries[0].Changes, audit.Change{Key: "accounts.localUser", Before: []string{}, After: []string{"login,apiKey"}})
+
+	secret := &corev1.Secret{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testArgoCDSecretName, Namespace: testRBACCMNamespace}, secret))
+	assert.NoError(t, bcrypt.CompareHashAndPassword(secret.Data["accounts.localUser.password"], []byte("s3cr3t")))
+	assert.NotEmpty(t, secret.Data["accounts.localUser.passwordMtime"])
+
+	accountRes := &rbacoperatorv1alpha1.ArgoCDLocalAccount{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, accountRes))
+	assert.NotNil(t, accountRes.Status.PasswordMtime)
+	assert.Len(t, accountRes.Status.Tokens, 1)
+
+	tokens, err := getAccountTokens(secret, account.Name)
+	assert.NoError(t, err)
+	assert.True(t, hasAccountToken(tokens, accountRes.Status.Tokens[0].TokenID))
+
+	tokenSecret := &corev1.Secret{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: "ci-token", Namespace: testNamespace}, tokenSecret))
+	assert.NotEmpty(t, tokenSecret.Data[common.TokenSecretKey])
+	assert.Equal(t, common.LabelSecretToken, tokenSecret.Labels[common.LabelSecret])
+
+	// The ArgoCDRole referenced by the binding does not exist
+	assert.Equal(t, []rbacoperatorv1alpha1.LocalAccountBindingStatus{{
+		Kind:    argoCDRoleBindingKind,
+		Name:    testNamespace + "/" + testRoleBindingName,
+		Valid:   false,
+		Message: "ArgoCDRole default/test-role not found",
+	}}, accountRes.Status.Bindings)
+
+	// A second reconcile keeps the password and the token
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	secretRes := &corev1.Secret{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testArgoCDSecretName, Namespace: testRBACCMNamespace}, secretRes))
+	assert.Equal(t, secret.Data, secretRes.Data)
+}
+
+func TestArgoCDLocalAccountReconciler_Delete(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	account := makeTestLocalAccount(addFinalizerLocalAccount(), addPasswordToLocalAccount("password"))
+
+	resObjs := []client.Object{account, makeTestArgoCDConfigMap(), makeTestArgoCDSecret(), makeTestPasswordSecret("password", "s3cr3t")}
+	subresObjs := []client.Object{account}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDLocalAccountReconciler(client, scheme)
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      account.Name,
+			Namespace: account.Namespace,
+		},
+	}
+
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	accountRes := &rbacoperatorv1alpha1.ArgoCDLocalAccount{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, accountRes))
+	assert.NoError(t, reconciler.Delete(context.TODO(), accountRes))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testArgoCDCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, map[string]string{"accounts.other": "login"}, cm.Data)
+	assert.NotContains(t, cm.Annotations, common.AnnotationManagedAccounts)
+
+	secret := &corev1.Secret{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testArgoCDSecretName, Namespace: testRBACCMNamespace}, secret))
+	assert.Equal(t, makeTestArgoCDSecret().Data, secret.Data)
+
+	err = reconciler.Get(context.TODO(), req.NamespacedName, accountRes)
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestArgoCDLocalAccountReconciler_AccountManagedInOtherNamespace(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	now := time.Now()
+	owner := makeTestLocalAccount(addFinalizerLocalAccount(), setLocalAccountNamespace("team-a"), setLocalAccountCreatedAt(now.Add(-time.Hour)))
+	account := makeTestLocalAccount(addFinalizerLocalAccount(), setLocalAccountCreatedAt(now))
+
+	resObjs := []client.Object{owner, account, makeTestArgoCDConfigMap(), makeTestArgoCDSecret()}
+	subresObjs := []client.Object{owner, account}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDLocalAccountReconciler(client, scheme)
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      account.Name,
+			Namespace: account.Namespace,
+		},
+	}
+
+	res, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, res.RequeueAfter)
+
+	accountRes := &rbacoperatorv1alpha1.ArgoCDLocalAccount{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, accountRes))
+	assert.Equal(t, rbacoperatorv1alpha1.TypePending, accountRes.Status.Conditions[0].Type)
+
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testArgoCDCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.NotContains(t, cm.Data, "accounts.localUser")
+}
+
+func TestArgoCDLocalAccountReconciler_ExistingAccount(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	account := makeTestLocalAccount(addFinalizerLocalAccount(), setLocalAccountName("other"))
+
+	resObjs := []client.Object{account, makeTestArgoCDConfigMap(), makeTestArgoCDSecret()}
+	subresObjs := []client.Object{account}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDLocalAccountReconciler(client, scheme)
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      account.Name,
+			Namespace: account.Namespace,
+		},
+	}
+
+	// Accounts not created by an ArgoCDLocalAccount are not taken over
+	res, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, res.RequeueAfter)
+	accountRes := &rbacoperatorv1alpha1.ArgoCDLocalAccount{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, accountRes))
+	assert.Equal(t, rbacoperatorv1alpha1.TypePending, accountRes.Status.Conditions[0].Type)
+	assert.Contains(t, accountRes.Status.Conditions[0].Message, "is not managed by an ArgoCDLocalAccount")
+
+	// nor removed when the ArgoCDLocalAccount is deleted
+	assert.NoError(t, reconciler.Delete(context.TODO(), accountRes))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testArgoCDCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, makeTestArgoCDConfigMap().Data, cm.Data)
+}
+
+func TestAccountManager(t *testing.T) {
+	cm := makeTestArgoCDConfigMap()
+	assert.Equal(t, "", accountManager(cm, "ci-bot"))
+	assert.True(t, setAccountManager(cm, "ci-bot", "team-b"))
+	assert.True(t, setAccountManager(cm, "deployer", "team-a"))
+	assert.False(t, setAccountManager(cm, "ci-bot", "team-b"))
+	assert.Equal(t, "team-a/deployer,team-b/ci-bot", cm.Annotations[common.AnnotationManagedAccounts])
+	assert.Equal(t, "team-b", accountManager(cm, "ci-bot"))
+	assert.True(t, setAccountManager(cm, "ci-bot", ""))
+	assert.True(t, setAccountManager(cm, "deployer", ""))
+	assert.NotContains(t, cm.Annotations, common.AnnotationManagedAccounts)
+}
@@ -21,13 +21,20 @@ import (
 	"fmt"
 	"time"
 
+	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/lint"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
 )
 
 // ArgoCDProjectRoleReconciler reconciles a ArgoCDProjectRole object
@@ -35,13 +42,30 @@ type ArgoCDProjectRoleReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+	// ArgoCDNamespace is the namespace AppProjects are looked up in, if the subject
+	// does not specify one. If empty, the namespace of the role is used.
+	ArgoCDNamespace string
+	// Linter reports risky grants in the PolicyRisk condition. Nothing is linted if nil.
+	Linter   *lint.Linter
+	Recorder record.EventRecorder
+	// DryRun plans the changes to the Argo CD RBAC ConfigMap and AppProjects of all objects instead of writing them.
+	DryRun bool
+	// TrustApprovals accepts the approvals of role changes, recorded by the mutating webhook.
+	TrustApprovals bool
+	// History records the policy written as ArgoCDRBACRevisions. No revisions are recorded if nil.
+	History *RevisionHistory
+	// Audit writes an audit entry for every change to the policy. Nothing is audited if nil.
+	Audit *AuditLog
+	// Notifications sends events of the changes to the policy. No events are sent if nil.
+	Notifications *Notifications
 }
 
 // +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectroles,verbs=*
 // +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectroles/status,verbs=*
 // +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectroles/finalizers,verbs=*
-// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectrolebindings,verbs=get;list
-// +kubebuilder:rbac:groups=argoproj.io,resources=appprojects,verbs=get;list;patch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectrolebindings,verbs=get;list;watch
+// +kubebuilder:rbac:groups=argoproj.io,resources=appprojects,verbs=get;list;patch;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrbactenantpolicies,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -63,11 +87,19 @@ func (r *ArgoCDProjectRoleReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		return ctrl.Result{}, err
 	}
 
+	r = r.recorded(&projectRole)
+	reconciler, plan := r.planned(&projectRole)
+	defer func() {
+		if err := reportPlan(ctx, reconciler.Client, reconciler.Recorder, &projectRole, &projectRole.Status.PlannedChanges, plan); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDProjectRole status", "name", req.Name)
+		}
+	}()
+
 	if projectRole.IsBeingDeleted() {
-		if err := r.handleFinalizer(ctx, &projectRole); err != nil {
+		if err := reconciler.handleFinalizer(ctx, &projectRole); err != nil {
 			projectRole.SetConditions(rbacoperatorv1alpha1.Deleting())
-			if err := r.Status().Update(ctx, &projectRole); err != nil {
-				r.Log.Error(err, "Failed to update ArgoCDProjectRole status during finalizer handling", "name", req.Name)
+			if err := reconciler.Status().Update(ctx, &projectRole); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDProjectRole status during finalizer handling", "name", req.Name)
 			}
 			return ctrl.Result{}, fmt.Errorf("error when handling finalizer: %v", err)
 		}
@@ -75,16 +107,29 @@ func (r *ArgoCDProjectRoleReconciler) Reconcile(ctx context.Context, req ctrl.Re
 	}
 
 	if !projectRole.HasFinalizer(rbacoperatorv1alpha1.ArgoCDProjectRoleFinalizerName) {
-		if err := r.addFinalizer(ctx, &projectRole); err != nil {
+		if err := reconciler.addFinalizer(ctx, &projectRole); err != nil {
 			projectRole.SetConditions(rbacoperatorv1alpha1.Deleting().WithMessage(err.Error()))
-			if err := r.Status().Update(ctx, &projectRole); err != nil {
-				r.Log.Error(err, "Failed to update ArgoCDProjectRole status after adding finalizer", "name", req.Name)
+			if err := reconciler.Status().Update(ctx, &projectRole); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDProjectRole status after adding finalizer", "name", req.Name)
 			}
 			return ctrl.Result{}, fmt.Errorf("error when adding finalizer: %v", err)
 		}
 		return ctrl.Result{RequeueAfter: time.Second}, nil
 	}
 
+	var pause *rbacoperatorv1alpha1.Condition
+	if condition, paused, err := pausedCondition(ctx, reconciler.Client, &projectRole); err != nil {
+		projectRole.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := reconciler.Status().Update(ctx, &projectRole); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDProjectRole status", "name", req.Name)
+		}
+		return ctrl.Result{}, err
+	} else if paused {
+		reconciler.Log.Info("ArgoCDProjectRole is paused, only writing revocations", "name", req.Name)
+		reconciler = reconciler.revokeOnly()
+		pause = &condition
+	}
+
 	if projectRole.HasArgoCDProjectRoleBindingRef() {
 		projectRb := rbacoperatorv1alpha1.ArgoCDProjectRoleBinding{}
 
@@ -93,30 +138,145 @@ func (r *ArgoCDProjectRoleReconciler) Reconcile(ctx context.Context, req ctrl.Re
 			Namespace: req.Namespace,
 		}
 
-		if err := r.Get(ctx, projectRBObjectKey, &projectRb); err != nil {
+		if err := reconciler.Get(ctx, projectRBObjectKey, &projectRb); err != nil {
 			if errors.IsNotFound(err) {
-				r.Log.Info("ArgoCDProjectRoleBinding not found", "name", projectRBObjectKey.Name)
+				reconciler.Log.Info("ArgoCDProjectRoleBinding not found", "name", projectRBObjectKey.Name)
 				projectRole.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
 				projectRole.Status.ArgoCDProjectRoleBindingRef = ""
-				if err := r.Status().Update(ctx, &projectRole); err != nil {
-					r.Log.Error(err, "Failed to update ArgoCDProjectRole status after binding not found", "name", req.Name)
+				if err := reconciler.Status().Update(ctx, &projectRole); err != nil {
+					reconciler.Log.Error(err, "Failed to update ArgoCDProjectRole status after binding not found", "name", req.Name)
 				}
 				return ctrl.Result{RequeueAfter: time.Minute * 2}, nil
 			}
 			projectRole.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
-			if err := r.Status().Update(ctx, &projectRole); err != nil {
-				r.Log.Error(err, "Failed to update ArgoCDProjectRole status", "name", req.Name)
+			if err := reconciler.Status().Update(ctx, &projectRole); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDProjectRole status", "name", req.Name)
 			}
 			return ctrl.Result{}, fmt.Errorf("error fetching ArgoCDProjectRoleBinding: %v", err)
 		}
+
+		if condition, paused, err := pausedCondition(ctx, reconciler.Client, &projectRb); err != nil {
+			projectRole.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+			if err := reconciler.Status().Update(ctx, &projectRole); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDProjectRole status", "name", req.Name)
+			}
+			return ctrl.Result{}, err
+		} else if paused && pause == nil {
+			reconciler.Log.Info("ArgoCDProjectRole is paused, only writing revocations", "name", req.Name)
+			reconciler = reconciler.revokeOnly()
+			pause = &condition
+		}
+		// The groups of the binding are written with the role, they are only planned if it is in dry-run mode
+		if plan == nil {
+			reconciler, plan = reconciler.planned(&projectRb)
+		}
+
+		stageProjectRoleSpec(reconciler.Recorder, &projectRole, reconciler.TrustApprovals)
+
+		policies, err := policy.LoadTenantPolicies(ctx, reconciler.Client, projectRole.Namespace)
+		if err != nil {
+			projectRole.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+			if err := reconciler.Status().Update(ctx, &projectRole); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDProjectRole status", "name", req.Name)
+			}
+			return ctrl.Result{}, err
+		}
+		enforceProjectRoleTenantPolicies(policies, &projectRole)
+		enforceProjectRoleBindingTenantPolicies(policies, &projectRb)
+		lintProjectRole(reconciler.Linter, &projectRole)
+		boundProjectRole(&projectRole, &projectRb)
+
+		reconciler.Log.Info("Syncing ArgoCDProjectRole to bound AppProjects", "name", req.Name)
+		if err := reconciler.syncAppProjects(&projectRole, &projectRb); err != nil {
+			projectRole.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+			if err := reconciler.Status().Update(ctx, &projectRole); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDProjectRole status", "name", req.Name)
+			}
+			if errors.IsConflict(err) {
+				reconciler.Log.Info("Conflict while patching AppProject, requeuing", "name", req.Name)
+				return ctrl.Result{RequeueAfter: time.Second}, nil
+			}
+			return ctrl.Result{}, fmt.Errorf("error when syncing AppProjects: %v", err)
+		}
+
+		projectRole.SetConditions(syncedCondition(pause, projectRole.GetGeneration()))
+		if err := reconciler.Status().Update(ctx, &projectRole); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDProjectRole status", "name", req.Name)
+		}
+		return ctrl.Result{RequeueAfter: time.Minute * 5}, nil
+	}
+
+	boundProjectRole(&projectRole, nil)
+	if pause != nil {
+		projectRole.SetConditions(*pause)
+	}
+	if err := reconciler.Status().Update(ctx, &projectRole); err != nil {
+		reconciler.Log.Error(err, "Failed to update ArgoCDProjectRole status", "name", req.Name)
 	}
 	return ctrl.Result{RequeueAfter: time.Minute * 5}, nil
 }
 
+// syncAppProjects will re-render the role and patch it into every AppProject the binding is bound to.
+// The sync state of every AppProject is recorded in the status of the role, the first error is returned.
+func (r *ArgoCDProjectRoleReconciler) syncAppProjects(projectRole *rbacoperatorv1alpha1.ArgoCDProjectRole, projectRb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) error {
+	var syncErr error
+	appProjectSubjectSet := makeAppProjectSubjectsSet(activeAppProjectSubjects(projectRb, timeNow()), r.ArgoCDNamespace, projectRb.Namespace)
+	for _, boundAppProject := range projectRb.Status.AppProjectsBound {
+		groups, exists := appProjectSubjectSet[boundAppProject]
+		if !exists {
+			continue // AppProject is being unbound by the ArgoCDProjectRoleBinding
+		}
+		syncStatus := rbacoperatorv1alpha1.AppProjectSyncStatus{
+			AppProject:         boundAppProject,
+			Synced:             true,
+			ObservedGeneration: projectRole.GetGeneration(),
+		}
+		appProjectKey := parseAppProjectStatusKey(boundAppProject, projectRb.Namespace)
+		appProject := newAppProject(appProjectKey.Name, appProjectKey.Namespace)
+		err := FetchObject(r.Client, appProject.Namespace, appProject.Name, appProject)
+		if err == nil && !isNamespaceAllowed(appProject, projectRb.Namespace) {
+			err = fmt.Errorf("namespace %s is not allowed to bind to AppProject %s", projectRb.Namespace, boundAppProject)
+		}
+		if err == nil {
+			err = patchAppProject(r.Client, appProject, projectRole, projectRb, &groups)
+		}
+		if err != nil {
+			r.Log.Error(err, "Failed to sync AppProject", "appProject", boundAppProject, "role", projectRole.Name)
+			syncStatus.Synced = false
+			syncStatus.Message = err.Error()
+			if syncErr == nil {
+				syncErr = err
+			}
+		}
+		projectRole.SetAppProjectSyncStatus(syncStatus)
+	}
+	projectRole.RemoveStaleAppProjectSyncStatus(projectRb.Status.AppProjectsBound)
+	return syncErr
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ArgoCDProjectRoleReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&rbacoperatorv1alpha1.ArgoCDProjectRole{}).
+		Watches(&rbacoperatorv1alpha1.ArgoCDRBACTenantPolicy{}, handler.EnqueueRequestsFromMapFunc(mapTenantPolicyToObjects(r.Client,
+			func() client.ObjectList { return &rbacoperatorv1alpha1.ArgoCDProjectRoleList{} }))).
+		Watches(&rbacoperatorv1alpha1.ArgoCDProjectRoleBinding{}, handler.EnqueueRequestsFromMapFunc(mapProjectRoleBindingToProjectRole)).
+		Watches(&argocdv1alpha.AppProject{}, handler.EnqueueRequestsFromMapFunc(mapAppProjectToProjectRoles)).
 		Named("argocdprojectrole").
 		Complete(r)
 }
+
+// mapProjectRoleBindingToProjectRole enqueues the ArgoCDProjectRole referenced by the ArgoCDProjectRoleBinding,
+// so that changes to the bound AppProjects are reflected in the status of the role.
+func mapProjectRoleBindingToProjectRole(_ context.Context, obj client.Object) []reconcile.Request {
+	projectRb, ok := obj.(*rbacoperatorv1alpha1.ArgoCDProjectRoleBinding)
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{
+			Name:      projectRb.Spec.ArgoCDProjectRoleRef.Name,
+			Namespace: projectRb.Namespace,
+		},
+	}}
+}
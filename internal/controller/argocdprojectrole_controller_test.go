@@ -18,10 +18,12 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -59,7 +61,10 @@ func TestArgoCDProjectRoleReconciler_Reconcile(t *testing.T) {
 	projectRoleRes := &rbacoperatorv1alpha1.ArgoCDProjectRole{}
 	err = reconciler.Get(context.TODO(), req.NamespacedName, projectRoleRes)
 	assert.NoError(t, err)
-	assert.Equal(t, projectRoleRes, argocdProjectRole)
+	assert.Equal(t, argocdProjectRole.Spec, projectRoleRes.Spec)
+	// Without binding the role is only reported as unbound
+	assert.Equal(t, []rbacoperatorv1alpha1.ConditionReason{rbacoperatorv1alpha1.ReasonUnbound}, conditionReasons(projectRoleRes.Status.Conditions))
+	assert.Equal(t, float64(1), testutil.ToFloat64(unboundResources.WithLabelValues("ArgoCDProjectRole", projectRoleRes.Namespace, projectRoleRes.Name)))
 }
 
 func TestArgoCDProjectRoleReconciler_AddFinalizer(t *testing.T) {
@@ -176,3 +181,123 @@ func TestArgoCDProjectRole_RoleBindingMissing(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, projectRoleRes.Status.ArgoCDProjectRoleBindingRef, "")
 }
+
+func TestArgoCDProjectRole_PropagatesSpecChanges(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	argocdProjectRole := makeTestProjectRole(addFinalizerProjectRole(), addProjectRoleBinding(testProjectRoleBindingName), func(r *rbacoperatorv1alpha1.ArgoCDProjectRole) {
+		r.Spec.Description = "Changed Project Role"
+		r.Spec.Rules = r.Spec.Rules[:1]
+	})
+	argocdProjectRoleBinding := makeTestProjectRoleBinding(addFinalizerProjectRoleBinding(), addBoundAppProjects([]string{testAppProjectName}))
+
+	resObjs := []client.Object{argocdProjectRole, argocdProjectRoleBinding}
+	subresObjs := []client.Object{argocdProjectRole, argocdProjectRoleBinding}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDProjectRoleReconciler(client, scheme)
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestAppProject(addTestRoleToAppProject())))
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      argocdProjectRole.Name,
+			Namespace: argocdProjectRole.Namespace,
+		},
+	}
+
+	res, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	if res.RequeueAfter < 5*time.Minute {
+		t.Fatalf("reconcile requeued request after %s", res.RequeueAfter)
+	}
+
+	appProject := &argocdv1alpha.AppProject{}
+	err = reconciler.Get(context.TODO(), types.NamespacedName{Name: testAppProjectName, Namespace: testNamespace}, appProject)
+	assert.NoError(t, err)
+	role, _ := getRoleInAppProject(appProject, testProjectRoleName)
+	assert.NotNil(t, role)
+	assert.Equal(t, "Changed Project Role", role.Description)
+	assert.Equal(t, []string{
+		fmt.Sprintf("p, proj:%s:%s, applications, get, */*, allow", testAppProjectName, testProjectRoleName),
+		fmt.Sprintf("p, proj:%s:%s, applications, list, */*, allow", testAppProjectName, testProjectRoleName),
+	}, role.Policies)
+	assert.Equal(t, []string{"group1", "group2"}, role.Groups)
+
+	projectRoleRes := &rbacoperatorv1alpha1.ArgoCDProjectRole{}
+	err = reconciler.Get(context.TODO(), req.NamespacedName, projectRoleRes)
+	assert.NoError(t, err)
+	assert.Equal(t, []rbacoperatorv1alpha1.AppProjectSyncStatus{
+		{
+			AppProject:         testAppProjectName,
+			Synced:             true,
+			ObservedGeneration: projectRoleRes.Generation,
+		},
+	}, projectRoleRes.Status.AppProjects)
+	assert.Contains(t, conditionReasons(projectRoleRes.Status.Conditions), rbacoperatorv1alpha1.ReasonBound)
+}
+
+func TestArgoCDProjectRole_PropagateAppProjectNotFound(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	argocdProjectRole := makeTestProjectRole(addFinalizerProjectRole(), addProjectRoleBinding(testProjectRoleBindingName))
+	argocdProjectRoleBinding := makeTestProjectRoleBinding(addFinalizerProjectRoleBinding(), addBoundAppProjects([]string{testAppProjectName}))
+
+	resObjs := []client.Object{argocdProjectRole, argocdProjectRoleBinding}
+	subresObjs := []client.Object{argocdProjectRole, argocdProjectRoleBinding}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDProjectRoleReconciler(client, scheme)
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      argocdProjectRole.Name,
+			Namespace: argocdProjectRole.Namespace,
+		},
+	}
+
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.Error(t, err)
+
+	projectRoleRes := &rbacoperatorv1alpha1.ArgoCDProjectRole{}
+	err = reconciler.Get(context.TODO(), req.NamespacedName, projectRoleRes)
+	assert.NoError(t, err)
+	assert.Len(t, projectRoleRes.Status.AppProjects, 1)
+	assert.False(t, projectRoleRes.Status.AppProjects[0].Synced)
+	assert.NotEmpty(t, projectRoleRes.Status.AppProjects[0].Message)
+}
+
+func TestArgoCDProjectRole_DryRun(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	argocdProjectRole := makeTestProjectRole(addFinalizerProjectRole(), addProjectRoleBinding(testProjectRoleBindingName), func(r *rbacoperatorv1alpha1.ArgoCDProjectRole) {
+		r.Spec.Description = "Changed Project Role"
+	})
+	argocdProjectRoleBinding := makeTestProjectRoleBinding(addFinalizerProjectRoleBinding(), addBoundAppProjects([]string{testAppProjectName}))
+
+	resObjs := []client.Object{argocdProjectRole, argocdProjectRoleBinding}
+	subresObjs := []client.Object{argocdProjectRole, argocdProjectRoleBinding}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDProjectRoleReconciler(client, scheme)
+	reconciler.DryRun = true
+
+	liveAppProject := makeTestAppProject(addTestRoleToAppProject())
+	assert.NoError(t, reconciler.Create(context.TODO(), liveAppProject))
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      argocdProjectRole.Name,
+			Namespace: argocdProjectRole.Namespace,
+		},
+	}
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	// The AppProject is not patched
+	appProject := &argocdv1alpha.AppProject{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testAppProjectName, Namespace: testNamespace}, appProject))
+	assert.Equal(t, liveAppProject.Spec.Roles, appProject.Spec.Roles)
+
+	projectRoleRes := &rbacoperatorv1alpha1.ArgoCDProjectRole{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, projectRoleRes))
+	assert.Contains(t, projectRoleRes.Status.PlannedChanges,
+		fmt.Sprintf("AppProject %s/%s role %s: +description Changed Project Role", testNamespace, testAppProjectName, testProjectRoleName))
+}
@@ -23,12 +23,19 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 
 	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/lint"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
 )
 
 // ArgoCDProjectRoleBindingReconciler reconciles a ArgoCDProjectRoleBinding object
@@ -36,6 +43,25 @@ type ArgoCDProjectRoleBindingReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+	// ArgoCDNamespace is the namespace AppProjects are looked up in, if the subject
+	// does not specify one. If empty, the namespace of the binding is used.
+	ArgoCDNamespace              string
+	ArgoCDRBACConfigMapName      string
+	ArgoCDRBACConfigMapNamespace string
+	Recorder                     record.EventRecorder
+	// Linter reports risky grants in the PolicyRisk condition. Nothing is linted if nil.
+	Linter *lint.Linter
+	// TrustAttestations takes the attester from the attested-by annotation. Only set if the mutating webhooks are served,
+	// otherwise attestations are ignored.
+	TrustAttestations bool
+	// DryRun plans the changes to the Argo CD RBAC ConfigMap and AppProjects of all objects instead of writing them.
+	DryRun bool
+	// History records the policy written as ArgoCDRBACRevisions. No revisions are recorded if nil.
+	History *RevisionHistory
+	// Audit writes an audit entry for every change to the policy. Nothing is audited if nil.
+	Audit *AuditLog
+	// Notifications sends events of the changes to the policy. No events are sent if nil.
+	Notifications *Notifications
 }
 
 // +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectrolebindings,verbs=*
@@ -44,6 +70,10 @@ type ArgoCDProjectRoleBindingReconciler struct {
 // +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectroles,verbs=get;list
 // +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectroles/status,verbs=get;list;update
 // +kubebuilder:rbac:groups=argoproj.io,resources=appprojects,verbs=get;list;patch;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrecertificationpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrbactenantpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -65,11 +95,19 @@ func (r *ArgoCDProjectRoleBindingReconciler) Reconcile(ctx context.Context, req
 		}
 	}
 
+	r = r.recorded(&projectRoleBinding)
+	reconciler, plan := r.planned(&projectRoleBinding)
+	defer func() {
+		if err := reportPlan(ctx, reconciler.Client, reconciler.Recorder, &projectRoleBinding, &projectRoleBinding.Status.PlannedChanges, plan); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status", "name", req.Name)
+		}
+	}()
+
 	if projectRoleBinding.IsBeingDeleted() {
-		if err := r.handleFinalizer(ctx, &projectRoleBinding); err != nil {
+		if err := reconciler.handleFinalizer(ctx, &projectRoleBinding); err != nil {
 			projectRoleBinding.SetConditions(rbacoperatorv1alpha1.Deleting())
-			if err := r.Status().Update(ctx, &projectRoleBinding); err != nil {
-				r.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status during finalizer handling", "name", req.Name)
+			if err := reconciler.Status().Update(ctx, &projectRoleBinding); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status during finalizer handling", "name", req.Name)
 			}
 			return ctrl.Result{}, fmt.Errorf("error when handling finalizer: %v", err)
 		}
@@ -77,13 +115,15 @@ func (r *ArgoCDProjectRoleBindingReconciler) Reconcile(ctx context.Context, req
 	}
 
 	if !projectRoleBinding.HasFinalizer(rbacoperatorv1alpha1.ArgoCDProjectRoleBindingFinalizerName) {
-		if err := r.addFinalizer(ctx, &projectRoleBinding); err != nil {
+		if err := reconciler.addFinalizer(ctx, &projectRoleBinding); err != nil {
 			projectRoleBinding.SetConditions(rbacoperatorv1alpha1.Deleting().WithMessage(err.Error()))
-			if err := r.Status().Update(ctx, &projectRoleBinding); err != nil {
-				r.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status after adding finalizer", "name", req.Name)
+			if err := reconciler.Status().Update(ctx, &projectRoleBinding); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status after adding finalizer", "name", req.Name)
 			}
 			return ctrl.Result{}, fmt.Errorf("error when adding finalizer: %v", err)
 		}
+		reconciler.Notifications.bindingCreated("ArgoCDProjectRoleBinding", &projectRoleBinding,
+			projectRoleBinding.Spec.ArgoCDProjectRoleRef.Name, projectRoleBindingSubjects(&projectRoleBinding))
 		return ctrl.Result{RequeueAfter: time.Second}, nil
 	}
 
@@ -93,106 +133,237 @@ func (r *ArgoCDProjectRoleBindingReconciler) Reconcile(ctx context.Context, req
 		Name:      projectRoleName,
 		Namespace: req.Namespace,
 	}
-	if err := r.Get(ctx, projectRoleObjectKey, &projectRole); err != nil {
+	if err := reconciler.Get(ctx, projectRoleObjectKey, &projectRole); err != nil {
 		if errors.IsNotFound(err) {
-			r.Log.Info("ArgoCDProjectRole not found, skipping reconcile", "name", projectRoleName)
+			reconciler.Log.Info("ArgoCDProjectRole not found, skipping reconcile", "name", projectRoleName)
 			projectRoleBinding.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
-			if err := r.Status().Update(ctx, &projectRoleBinding); err != nil {
-				r.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status", "name", req.Name)
+			if err := reconciler.Status().Update(ctx, &projectRoleBinding); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status", "name", req.Name)
 			}
 			return ctrl.Result{RequeueAfter: time.Second}, nil
 		}
 		projectRoleBinding.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
-		if err := r.Status().Update(ctx, &projectRoleBinding); err != nil {
-			r.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status after project role not found", "name", req.Name)
+		if err := reconciler.Status().Update(ctx, &projectRoleBinding); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status after project role not found", "name", req.Name)
 		}
 		return ctrl.Result{}, fmt.Errorf("error when getting ArgoCDProjectRole: %v", err)
 	}
 
 	if !projectRole.HasArgoCDProjectRoleBindingRef() {
 		projectRole.SetArgoCDProjectRoleBindingRef(projectRoleBinding.Name)
-		if err := r.Status().Update(ctx, &projectRole); err != nil {
-			r.Log.Error(err, "Failed to update ArgoCDProjectRole status with binding reference", "name", projectRole.Name)
+		if err := reconciler.Status().Update(ctx, &projectRole); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDProjectRole status with binding reference", "name", projectRole.Name)
 		}
 	}
 
-	appProjectSubjectSet := makeAppProjectSubjectsSet(projectRoleBinding.Spec.Subjects)
-	for _, boundAppProject := range projectRoleBinding.Status.AppProjectsBound {
-		if _, exists := appProjectSubjectSet[boundAppProject]; !exists {
-			appProject := newAppProject(boundAppProject, req.Namespace)
-			if !IsObjectFound(r.Client, appProject.Namespace, appProject.Name, appProject) {
-				r.Log.Info("AppProject not found", "name", boundAppProject)
-				continue
-			}
-			r.Log.Info("Removing Role from AppProject", "appProject", boundAppProject, "role", projectRoleName)
-			if err := removeRoleFromAppProject(r.Client, appProject, projectRoleName); err != nil {
-				if errors.IsConflict(err) {
-					r.Log.Info("Conflict while patching AppProject, requeuing", "appProject", appProject.Name)
-					return ctrl.Result{RequeueAfter: time.Second}, nil
-				}
-				r.Log.Error(err, "Failed to remove role from AppProject", "appProject", boundAppProject, "role", projectRoleName)
-				projectRoleBinding.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
-				return ctrl.Result{}, fmt.Errorf("error when removing role from AppProject: %v", err)
+	var pause *rbacoperatorv1alpha1.Condition
+	if condition, paused, err := pausedCondition(ctx, reconciler.Client, &projectRoleBinding, &projectRole); err != nil {
+		projectRoleBinding.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := reconciler.Status().Update(ctx, &projectRoleBinding); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status", "name", req.Name)
+		}
+		return ctrl.Result{}, err
+	} else if paused {
+		reconciler.Log.Info("ArgoCDProjectRoleBinding is paused, only writing revocations", "name", req.Name)
+		reconciler = reconciler.revokeOnly()
+		pause = &condition
+	}
+	// The rules of the role are written with the binding, they are only planned if it is in dry-run mode
+	if plan == nil {
+		reconciler, plan = reconciler.planned(&projectRole)
+	}
+
+	if err := validateSchedules(projectRoleBinding.Spec.Schedules, timeNow()); err != nil {
+		reconciler.Log.Info("Invalid schedule", "name", req.Name, "reason", err.Error())
+		projectRoleBinding.SetConditions(rbacoperatorv1alpha1.Pending(err))
+		if err := reconciler.Client.Status().Update(ctx, &projectRoleBinding); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status", "name", req.Name)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	now := timeNow()
+	if err := reconciler.reconcileRecertification(ctx, &projectRoleBinding, now); err != nil {
+		projectRoleBinding.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := reconciler.Status().Update(ctx, &projectRoleBinding); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status", "name", req.Name)
+		}
+		return ctrl.Result{}, err
+	}
+
+	policies, err := policy.LoadTenantPolicies(ctx, reconciler.Client, projectRoleBinding.Namespace)
+	if err != nil {
+		projectRoleBinding.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := reconciler.Status().Update(ctx, &projectRoleBinding); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status", "name", req.Name)
+		}
+		return ctrl.Result{}, err
+	}
+	enforceProjectRoleBindingTenantPolicies(policies, &projectRoleBinding)
+	applyApprovedProjectRoleSpec(&projectRole)
+	enforceProjectRoleTenantPolicies(policies, &projectRole)
+	lintProjectRoleBinding(reconciler.Linter, &projectRoleBinding)
+
+	appProjectSubjectSet := makeAppProjectSubjectsSet(activeAppProjectSubjects(&projectRoleBinding, now), reconciler.ArgoCDNamespace, req.Namespace)
+	for _, boundAppProject := range slices.Clone(projectRoleBinding.Status.AppProjectsBound) {
+		appProjectKey := parseAppProjectStatusKey(boundAppProject, req.Namespace)
+		appProject := newAppProject(appProjectKey.Name, appProjectKey.Namespace)
+		if !IsObjectFound(reconciler.Client, appProject.Namespace, appProject.Name, appProject) {
+			reconciler.Log.Info("AppProject not found", "name", boundAppProject)
+			continue
+		}
+		// The role is removed from AppProjects no longer referenced and from AppProjects that revoked the namespace
+		if _, exists := appProjectSubjectSet[boundAppProject]; exists && isNamespaceAllowed(appProject, req.Namespace) {
+			continue
+		}
+		roleName := appProjectRoleName(appProject.Namespace, req.Namespace, projectRoleName)
+		reconciler.Log.Info("Removing Role from AppProject", "appProject", boundAppProject, "role", roleName)
+		if err := removeRoleFromAppProject(reconciler.Client, appProject, roleName); err != nil {
+			if errors.IsConflict(err) {
+				reconciler.Log.Info("Conflict while patching AppProject, requeuing", "appProject", appProject.Name)
+				return ctrl.Result{RequeueAfter: time.Second}, nil
 			}
-			r.Log.Info("Role removed from AppProject", "appProject", boundAppProject, "role", projectRoleName)
-			projectRoleBinding.Status.AppProjectsBound = removeStringFromSlice(projectRoleBinding.Status.AppProjectsBound, boundAppProject)
+			reconciler.Log.Error(err, "Failed to remove role from AppProject", "appProject", boundAppProject, "role", roleName)
+			projectRoleBinding.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+			return ctrl.Result{}, fmt.Errorf("error when removing role from AppProject: %v", err)
 		}
+		reconciler.Log.Info("Role removed from AppProject", "appProject", boundAppProject, "role", roleName)
+		projectRoleBinding.Status.AppProjectsBound = removeStringFromSlice(projectRoleBinding.Status.AppProjectsBound, boundAppProject)
 	}
-	if err := r.Status().Update(ctx, &projectRoleBinding); err != nil {
-		r.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status after removing roles from AppProjects", "name", req.Name)
+	if err := reconciler.Status().Update(ctx, &projectRoleBinding); err != nil {
+		reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status after removing roles from AppProjects", "name", req.Name)
 	}
 
-	r.Log.Info("Reconciling AppProjects with ArgoCDProjectRoleBinding", "name", req.Name)
+	reconciler.Log.Info("Reconciling AppProjects with ArgoCDProjectRoleBinding", "name", req.Name)
 
 	for appProjectRef, groups := range appProjectSubjectSet {
-		appProject := newAppProject(appProjectRef, req.Namespace)
-		if !IsObjectFound(r.Client, appProject.Namespace, appProject.Name, appProject) {
+		appProjectKey := parseAppProjectStatusKey(appProjectRef, req.Namespace)
+		appProject := newAppProject(appProjectKey.Name, appProjectKey.Namespace)
+		if !IsObjectFound(reconciler.Client, appProject.Namespace, appProject.Name, appProject) {
 			projectRoleBinding.SetConditions(rbacoperatorv1alpha1.Pending(fmt.Errorf("AppProject %s not found", appProjectRef)))
-			if err := r.Status().Update(ctx, &projectRoleBinding); err != nil {
-				r.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status", "name", req.Name)
+			if err := reconciler.Status().Update(ctx, &projectRoleBinding); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status", "name", req.Name)
 			}
 			continue
 		}
-		r.Log.Info("Reconciling AppProject", "appProject", appProjectRef)
-		if err := r.patchAppProject(appProject, &projectRole, &groups); err != nil {
+		if !isNamespaceAllowed(appProject, req.Namespace) {
+			reconciler.Log.Info("Namespace not allowed to bind to AppProject", "appProject", appProjectRef, "namespace", req.Namespace)
+			projectRoleBinding.SetConditions(rbacoperatorv1alpha1.Pending(fmt.Errorf("namespace %s is not allowed to bind to AppProject %s", req.Namespace, appProjectRef)))
+			if err := reconciler.Status().Update(ctx, &projectRoleBinding); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status", "name", req.Name)
+			}
+			continue
+		}
+		if pause != nil && !isAppProjectInStatus(projectRoleBinding.Status.AppProjectsBound, appProjectRef) {
+			continue // a paused binding is not bound to new AppProjects
+		}
+		reconciler.Log.Info("Reconciling AppProject", "appProject", appProjectRef)
+		if err := patchAppProject(reconciler.Client, appProject, &projectRole, &projectRoleBinding, &groups); err != nil {
 			if errors.IsConflict(err) {
-				r.Log.Info("Conflict while patching AppProject, requeuing", "appProject", appProjectRef)
+				reconciler.Log.Info("Conflict while patching AppProject, requeuing", "appProject", appProjectRef)
 				return ctrl.Result{RequeueAfter: time.Second}, nil
 			}
 			projectRoleBinding.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
-			if err := r.Status().Update(ctx, &projectRoleBinding); err != nil {
-				r.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status after patching AppProject", "name", req.Name)
+			if err := reconciler.Status().Update(ctx, &projectRoleBinding); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status after patching AppProject", "name", req.Name)
 			}
 			return ctrl.Result{}, fmt.Errorf("error when patching AppProject: %v", err)
 		}
-		r.Log.Info("AppProject patched successfully", "appProject", appProjectRef)
+		reconciler.Log.Info("AppProject patched successfully", "appProject", appProjectRef)
 		if !isAppProjectInStatus(projectRoleBinding.Status.AppProjectsBound, appProjectRef) {
 			projectRoleBinding.Status.AppProjectsBound = append(projectRoleBinding.Status.AppProjectsBound, appProjectRef)
-			r.Log.Info("AppProject added to status", "appProject", appProjectRef)
-			if err := r.Status().Update(ctx, &projectRoleBinding); err != nil {
-				r.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status after patching AppProject", "name", req.Name)
+			reconciler.Log.Info("AppProject added to status", "appProject", appProjectRef)
+			if err := reconciler.Status().Update(ctx, &projectRoleBinding); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status after patching AppProject", "name", req.Name)
 				return ctrl.Result{}, fmt.Errorf("error when updating status: %v", err)
 			}
 		}
 	}
-	r.Log.Info("ArgoCDProjectRoleBinding reconciliation completed", "name", req.Name)
+	cm := newConfigMap(reconciler.ArgoCDRBACConfigMapName, reconciler.ArgoCDRBACConfigMapNamespace)
+	if IsObjectFound(reconciler.Client, cm.Namespace, cm.Name, cm) {
+		reconciler.Log.Info("Reconciling RBAC ConfigMap")
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			cm := newConfigMap(reconciler.ArgoCDRBACConfigMapName, reconciler.ArgoCDRBACConfigMapNamespace)
+			if err := reconciler.Get(ctx, client.ObjectKeyFromObject(cm), cm); err != nil {
+				return err
+			}
+			return reconciler.reconcileRBACConfigMap(cm, &projectRoleBinding)
+		})
+		if err != nil {
+			projectRoleBinding.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+			if err := reconciler.Status().Update(ctx, &projectRoleBinding); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status", "name", req.Name)
+			}
+			return ctrl.Result{}, err
+		}
+	} else if hasAppProjectUsers(projectRoleBinding.Spec.Subjects) {
+		projectRoleBinding.SetConditions(rbacoperatorv1alpha1.Pending(fmt.Errorf("ConfigMap %s not found", cm.Name)))
+		if err := reconciler.Status().Update(ctx, &projectRoleBinding); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status", "name", req.Name)
+		}
+		return ctrl.Result{}, fmt.Errorf("ConfigMap not found")
+	}
 
-	projectRoleBinding.SetConditions(rbacoperatorv1alpha1.ReconcileSuccess().WithObservedGeneration(projectRoleBinding.GetGeneration()))
-	if err := r.Status().Update(ctx, &projectRoleBinding); err != nil {
-		r.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status after reconciliation", "name", req.Name)
+	reconciler.Log.Info("ArgoCDProjectRoleBinding reconciliation completed", "name", req.Name)
+
+	reconciler.updateActiveSubjects(&projectRoleBinding, now)
+	boundProjectRoleBinding(&projectRoleBinding, now)
+	projectRoleBinding.SetConditions(syncedCondition(pause, projectRoleBinding.GetGeneration()))
+	if err := reconciler.Status().Update(ctx, &projectRoleBinding); err != nil {
+		reconciler.Log.Error(err, "Failed to update ArgoCDProjectRoleBinding status after reconciliation", "name", req.Name)
 	}
 
-	return ctrl.Result{RequeueAfter: time.Minute * 5}, nil
+	return ctrl.Result{RequeueAfter: projectRoleBindingRequeueAfter(&projectRoleBinding, now, time.Minute*5)}, nil
+}
+
+// updateActiveSubjects will set the Active condition and the AppProjects of the active subjects in the status of the binding.
+// An Event is emitted for every subject whose access expired since the last reconciliation.
+func (r *ArgoCDProjectRoleBindingReconciler) updateActiveSubjects(projectRoleBinding *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding, now time.Time) {
+	active := []string{}
+	for _, subject := range activeAppProjectSubjects(projectRoleBinding, now) {
+		active = append(active, appProjectSubjectKey(subject, r.ArgoCDNamespace, projectRoleBinding.Namespace))
+	}
+	for _, subject := range projectRoleBinding.Spec.Subjects {
+		key := appProjectSubjectKey(subject, r.ArgoCDNamespace, projectRoleBinding.Namespace)
+		if slices.Contains(projectRoleBinding.Status.ActiveSubjects, key) && !slices.Contains(active, key) &&
+			appProjectSubjectWindow(projectRoleBinding, subject).isExpired(now) {
+			r.Recorder.Eventf(projectRoleBinding, corev1.EventTypeNormal, eventReasonAccessExpired,
+				"Access to ArgoCDProjectRole %s in AppProject %s expired", projectRoleBinding.Spec.ArgoCDProjectRoleRef.Name, key)
+			r.Notifications.bindingExpired("ArgoCDProjectRoleBinding", projectRoleBinding, projectRoleBinding.Spec.ArgoCDProjectRoleRef.Name, key)
+		}
+	}
+	projectRoleBinding.Status.ActiveSubjects = active
+	projectRoleBinding.Status.NextTransition, projectRoleBinding.Status.UpcomingWindows = transitionStatus(now,
+		projectRoleBinding.Spec.Schedules, projectRoleBindingWindows(projectRoleBinding)...)
+	if projectRoleBinding.Status.Recertification.IsSuspended(now) {
+		projectRoleBinding.SetConditions(rbacoperatorv1alpha1.Suspended().WithMessage("binding was not re-attested in time"))
+		return
+	}
+	projectRoleBinding.SetConditions(projectRoleBindingWindow(projectRoleBinding).condition(now))
 }
 
-func makeAppProjectSubjectsSet(appProjectSubjects []rbacoperatorv1alpha1.AppProjectSubject) map[string][]string {
+// makeAppProjectSubjectsSet returns the groups of each subject keyed by the AppProject status key.
+func makeAppProjectSubjectsSet(appProjectSubjects []rbacoperatorv1alpha1.AppProjectSubject, argoCDNamespace, bindingNamespace string) map[string][]string {
 	appProjectSubjectSet := make(map[string][]string, len(appProjectSubjects))
 	for _, subject := range appProjectSubjects {
-		appProjectSubjectSet[subject.AppProjectRef] = subject.Groups
+		appProject := types.NamespacedName{
+			Name:      subject.AppProjectRef,
+			Namespace: resolveAppProjectNamespace(subject, argoCDNamespace, bindingNamespace),
+		}
+		appProjectSubjectSet[appProjectStatusKey(appProject, bindingNamespace)] = subject.Groups
 	}
 	return appProjectSubjectSet
 }
 
+func hasAppProjectUsers(appProjectSubjects []rbacoperatorv1alpha1.AppProjectSubject) bool {
+	for _, subject := range appProjectSubjects {
+		if len(subject.Users) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func removeStringFromSlice(slice []string, item string) []string {
 	for i, v := range slice {
 		if v == item {
@@ -210,6 +381,10 @@ func isAppProjectInStatus(appProjects []string, appProject string) bool {
 func (r *ArgoCDProjectRoleBindingReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&rbacoperatorv1alpha1.ArgoCDProjectRoleBinding{}).
+		Watches(&rbacoperatorv1alpha1.ArgoCDRBACTenantPolicy{}, handler.EnqueueRequestsFromMapFunc(mapTenantPolicyToObjects(r.Client,
+			func() client.ObjectList { return &rbacoperatorv1alpha1.ArgoCDProjectRoleBindingList{} }))).
+		Watches(&rbacoperatorv1alpha1.ArgoCDRecertificationPolicy{}, handler.EnqueueRequestsFromMapFunc(r.mapRecertificationPolicyToBindings)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.mapRBACConfigMapToProjectRoleBindings)).
 		Named("argocdprojectrolebinding").
 		Complete(r)
 }
@@ -25,6 +25,7 @@ import (
 	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -240,3 +241,241 @@ func TestArgoCDProjectRoleBindingReconciler_BoundAppProjectNotInSpec(t *testing.
 	wantAppProject := makeTestAppProject(setAppProjectName("another-app-project"))
 	assert.Equal(t, wantAppProject.Spec.Roles, appProject.Spec.Roles)
 }
+
+func TestArgoCDProjectRoleBindingReconciler_AppProjectInArgoCDNamespace(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	argocdProjectRoleBinding := makeTestProjectRoleBinding(addFinalizerProjectRoleBinding())
+	argocdProjectRole := makeTestProjectRole()
+
+	resObjs := []client.Object{argocdProjectRoleBinding, argocdProjectRole}
+	subresObjs := []client.Object{argocdProjectRoleBinding, argocdProjectRole}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDProjectRoleBindingReconciler(client, scheme)
+	reconciler.ArgoCDNamespace = testRBACCMNamespace
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestAppProject(setAppProjectNamespace(testRBACCMNamespace), addAllowedNamespacesToAppProject(testNamespace))))
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      argocdProjectRoleBinding.Name,
+			Namespace: argocdProjectRoleBinding.Namespace,
+		},
+	}
+
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	projectRoleBindingRes := &rbacoperatorv1alpha1.ArgoCDProjectRoleBinding{}
+	err = reconciler.Get(context.TODO(), req.NamespacedName, projectRoleBindingRes)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{fmt.Sprintf("%s/%s", testRBACCMNamespace, testAppProjectName)}, projectRoleBindingRes.Status.AppProjectsBound)
+
+	// The role is prefixed with the namespace of the binding in AppProjects of other namespaces
+	appProject := &argocdv1alpha.AppProject{}
+	err = reconciler.Get(context.TODO(), types.NamespacedName{Name: testAppProjectName, Namespace: testRBACCMNamespace}, appProject)
+	assert.NoError(t, err)
+	wantAppProject := makeTestAppProject(addNamedTestRoleToAppProject(testNamespace + "_" + testProjectRoleName))
+	assert.Equal(t, wantAppProject.Spec.Roles, appProject.Spec.Roles)
+
+	// Revoking the namespace removes the role from the AppProject
+	appProject.Annotations = nil
+	assert.NoError(t, reconciler.Update(context.TODO(), appProject))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, projectRoleBindingRes))
+	assert.Empty(t, projectRoleBindingRes.Status.AppProjectsBound)
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testAppProjectName, Namespace: testRBACCMNamespace}, appProject))
+	assert.Equal(t, makeTestAppProject().Spec.Roles, appProject.Spec.Roles)
+}
+
+func TestArgoCDProjectRoleBindingReconciler_AppProjectNamespaceNotAllowed(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	argocdProjectRoleBinding := makeTestProjectRoleBinding(addFinalizerProjectRoleBinding(), setAppProjectSubjectNamespace(testRBACCMNamespace))
+	argocdProjectRole := makeTestProjectRole()
+
+	resObjs := []client.Object{argocdProjectRoleBinding, argocdProjectRole}
+	subresObjs := []client.Object{argocdProjectRoleBinding, argocdProjectRole}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDProjectRoleBindingReconciler(client, scheme)
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestAppProject(setAppProjectNamespace(testRBACCMNamespace), addAllowedNamespacesToAppProject("team-a, team-b"))))
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      argocdProjectRoleBinding.Name,
+			Namespace: argocdProjectRoleBinding.Namespace,
+		},
+	}
+
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	projectRoleBindingRes := &rbacoperatorv1alpha1.ArgoCDProjectRoleBinding{}
+	err = reconciler.Get(context.TODO(), req.NamespacedName, projectRoleBindingRes)
+	assert.NoError(t, err)
+	assert.Empty(t, projectRoleBindingRes.Status.AppProjectsBound)
+	assert.Contains(t, projectRoleBindingRes.Status.Conditions, rbacoperatorv1alpha1.Condition{
+		Type:               rbacoperatorv1alpha1.TypePending,
+		Status:             corev1.ConditionFalse,
+		Reason:             rbacoperatorv1alpha1.ReasonReconcileError,
+		Message:            fmt.Sprintf("namespace %s is not allowed to bind to AppProject %s/%s", testNamespace, testRBACCMNamespace, testAppProjectName),
+		LastTransitionTime: projectRoleBindingRes.Status.Conditions[0].LastTransitionTime,
+	})
+
+	appProject := &argocdv1alpha.AppProject{}
+	err = reconciler.Get(context.TODO(), types.NamespacedName{Name: testAppProjectName, Namespace: testRBACCMNamespace}, appProject)
+	assert.NoError(t, err)
+	wantAppProject := makeTestAppProject()
+	assert.Equal(t, wantAppProject.Spec.Roles, appProject.Spec.Roles)
+}
+
+func TestArgoCDProjectRoleBindingReconciler_UsersBoundViaGlobalPolicy(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	argocdProjectRoleBinding := makeTestProjectRoleBinding(addFinalizerProjectRoleBinding(), addUsersToAppProjectSubject(
+		rbacoperatorv1alpha1.AppProjectUser{Kind: "sso", Name: "gosha"},
+		rbacoperatorv1alpha1.AppProjectUser{Kind: "local", Name: "ci-bot"},
+		// Values breaking out of their line are not rendered
+		rbacoperatorv1alpha1.AppProjectUser{Kind: "sso", Name: "x, role:admin\ng, x"},
+	))
+	argocdProjectRole := makeTestProjectRole()
+
+	resObjs := []client.Object{argocdProjectRoleBinding, argocdProjectRole, makeTestRBACConfigMap()}
+	subresObjs := []client.Object{argocdProjectRoleBinding, argocdProjectRole}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDProjectRoleBindingReconciler(client, scheme)
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestAppProject()))
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      argocdProjectRoleBinding.Name,
+			Namespace: argocdProjectRoleBinding.Namespace,
+		},
+	}
+
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	overlayKey := fmt.Sprintf("policy.%s.projectrolebinding_%s.csv", testNamespace, testProjectRoleBindingName)
+	cm := &corev1.ConfigMap{}
+	err = reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("g, gosha, proj:%s:%s\ng, ci-bot, proj:%s:%s\n", testAppProjectName, testProjectRoleName, testAppProjectName, testProjectRoleName), cm.Data[overlayKey])
+
+	assert.NoError(t, reconciler.Delete(context.TODO(), argocdProjectRoleBinding))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	err = reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm)
+	assert.NoError(t, err)
+	assert.NotContains(t, cm.Data, overlayKey)
+}
+
+func TestArgoCDProjectRoleBindingReconciler_NotBefore(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+
+	now := time.Now().Truncate(time.Second)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	notBefore := metav1.NewTime(now.Add(time.Minute))
+	argocdProjectRoleBinding := makeTestProjectRoleBinding(addFinalizerProjectRoleBinding(), setAppProjectSubjectWindow(&notBefore, nil))
+	argocdProjectRole := makeTestProjectRole()
+
+	resObjs := []client.Object{argocdProjectRoleBinding, argocdProjectRole, makeTestAppProject()}
+	subresObjs := []client.Object{argocdProjectRoleBinding, argocdProjectRole}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDProjectRoleBindingReconciler(client, scheme)
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      argocdProjectRoleBinding.Name,
+			Namespace: argocdProjectRoleBinding.Namespace,
+		},
+	}
+
+	res, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, res.RequeueAfter)
+
+	appProject := &argocdv1alpha.AppProject{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testAppProjectName, Namespace: testNamespace}, appProject))
+	assert.Equal(t, makeTestAppProject().Spec.Roles, appProject.Spec.Roles)
+
+	projectRoleBindingRes := &rbacoperatorv1alpha1.ArgoCDProjectRoleBinding{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, projectRoleBindingRes))
+	assert.Empty(t, projectRoleBindingRes.Status.AppProjectsBound)
+	assert.Empty(t, projectRoleBindingRes.Status.ActiveSubjects)
+
+	// The role is bound to the AppProject once the window starts
+	now = notBefore.Time
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testAppProjectName, Namespace: testNamespace}, appProject))
+	assert.Equal(t, makeTestAppProject(addTestRoleToAppProject()).Spec.Roles, appProject.Spec.Roles)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, projectRoleBindingRes))
+	assert.Equal(t, []string{testAppProjectName}, projectRoleBindingRes.Status.ActiveSubjects)
+	assert.Contains(t, conditionReasons(projectRoleBindingRes.Status.Conditions), rbacoperatorv1alpha1.ReasonActive)
+}
+
+func TestArgoCDProjectRoleBindingReconciler_Schedule(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+
+	// Saturday, outside of business hours
+	now := time.Date(2025, 1, 11, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	argocdProjectRoleBinding := makeTestProjectRoleBinding(addFinalizerProjectRoleBinding(), func(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) {
+		rb.Spec.Schedules = []rbacoperatorv1alpha1.Schedule{makeTestBusinessHoursSchedule()}
+	})
+	argocdProjectRole := makeTestProjectRole()
+
+	resObjs := []client.Object{argocdProjectRoleBinding, argocdProjectRole, makeTestAppProject()}
+	subresObjs := []client.Object{argocdProjectRoleBinding, argocdProjectRole}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDProjectRoleBindingReconciler(client, scheme)
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      argocdProjectRoleBinding.Name,
+			Namespace: argocdProjectRoleBinding.Namespace,
+		},
+	}
+
+	res, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, res.RequeueAfter)
+
+	appProject := &argocdv1alpha.AppProject{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testAppProjectName, Namespace: testNamespace}, appProject))
+	assert.Equal(t, makeTestAppProject().Spec.Roles, appProject.Spec.Roles)
+
+	projectRoleBindingRes := &rbacoperatorv1alpha1.ArgoCDProjectRoleBinding{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, projectRoleBindingRes))
+	monday := time.Date(2025, 1, 13, 7, 0, 0, 0, time.UTC)
+	assert.Equal(t, monday, projectRoleBindingRes.Status.NextTransition.UTC())
+	assert.Len(t, projectRoleBindingRes.Status.UpcomingWindows, upcomingWindowsCount)
+	assert.Equal(t, monday, projectRoleBindingRes.Status.UpcomingWindows[0].Start.UTC())
+	assert.Contains(t, conditionReasons(projectRoleBindingRes.Status.Conditions), rbacoperatorv1alpha1.ReasonScheduled)
+
+	// The role is bound while the window is open and requeued at closing
+	now = monday.Add(time.Hour)
+	res, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, res.RequeueAfter)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testAppProjectName, Namespace: testNamespace}, appProject))
+	assert.Equal(t, makeTestAppProject(addTestRoleToAppProject()).Spec.Roles, appProject.Spec.Roles)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, projectRoleBindingRes))
+	assert.Equal(t, monday.Add(10*time.Hour), projectRoleBindingRes.Status.NextTransition.UTC())
+	assert.Contains(t, conditionReasons(projectRoleBindingRes.Status.Conditions), rbacoperatorv1alpha1.ReasonActive)
+}
@@ -0,0 +1,332 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+// ArgoCDProjectRoleTokenReconciler reconciles a ArgoCDProjectRoleToken object
+type ArgoCDProjectRoleTokenReconciler struct {
+	client.Client
+	// APIReader reads the Secrets, which are not cached unless labeled, see SecretCacheSelector.
+	APIReader client.Reader
+	Log       logr.Logger
+	Scheme    *runtime.Scheme
+	// ArgoCDNamespace is the namespace AppProjects are looked up in, if the token
+	// does not specify one. If empty, the namespace of the token is used.
+	ArgoCDNamespace       string
+	ArgoCDSecretName      string
+	ArgoCDSecretNamespace string
+	// DryRun holds all objects instead of reconciling them, their changes can't be planned.
+	DryRun bool
+	// Audit writes an audit entry for every change to the AppProjects. Nothing is audited if nil.
+	Audit *AuditLog
+}
+
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectroletokens,verbs=*
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectroletokens/status,verbs=*
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectroletokens/finalizers,verbs=*
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectrolebindings,verbs=get;list;watch
+// +kubebuilder:rbac:groups=argoproj.io,resources=appprojects,verbs=get;list;patch;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;delete
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *ArgoCDProjectRoleTokenReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	_ = r.Log.WithValues("argocdprojectroletoken", req.NamespacedName)
+
+	r.Log.Info("Reconciling ArgoCDProjectRoleToken", "name", req.Name, "namespace", req.Namespace)
+
+	token := rbacoperatorv1alpha1.ArgoCDProjectRoleToken{}
+	if err := r.Get(ctx, req.NamespacedName, &token); err != nil {
+		if errors.IsNotFound(err) {
+			r.Log.Info("ArgoCDProjectRoleToken not found, skipping reconcile", "name", req.Name)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	r = r.audited(&token)
+	if isDryRun(r.DryRun, &token) {
+		r.Log.Info("ArgoCDProjectRoleToken is in dry-run mode, skipping reconcile", "name", req.Name)
+		token.SetConditions(rbacoperatorv1alpha1.Pending(errNotPlanned("ArgoCDProjectRoleToken")))
+		return ctrl.Result{}, r.Status().Update(ctx, &token)
+	}
+
+	if token.IsBeingDeleted() {
+		if err := r.handleFinalizer(ctx, &token); err != nil {
+			if errors.IsConflict(err) {
+				r.Log.Info("Conflict while handling finalizer for ArgoCDProjectRoleToken", "name", req.Name)
+				return ctrl.Result{RequeueAfter: time.Second}, nil
+			}
+			token.SetConditions(rbacoperatorv1alpha1.Deleting().WithMessage(err.Error()))
+			if err := r.Status().Update(ctx, &token); err != nil {
+				r.Log.Error(err, "Failed to update ArgoCDProjectRoleToken status during finalizer handling", "name", req.Name)
+			}
+			return ctrl.Result{}, fmt.Errorf("error when handling finalizer: %v", err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !token.HasFinalizer(rbacoperatorv1alpha1.ArgoCDProjectRoleTokenFinalizerName) {
+		if err := r.addFinalizer(ctx, &token); err != nil {
+			return ctrl.Result{}, fmt.Errorf("error when adding finalizer: %v", err)
+		}
+		return ctrl.Result{RequeueAfter: time.Second}, nil
+	}
+
+	projectRb := rbacoperatorv1alpha1.ArgoCDProjectRoleBinding{}
+	projectRbObjectKey := client.ObjectKey{
+		Name:      token.Spec.ArgoCDProjectRoleBindingRef,
+		Namespace: req.Namespace,
+	}
+	if err := r.Get(ctx, projectRbObjectKey, &projectRb); err != nil {
+		if errors.IsNotFound(err) {
+			r.Log.Info("ArgoCDProjectRoleBinding not found", "name", projectRbObjectKey.Name)
+			token.SetConditions(rbacoperatorv1alpha1.Pending(err))
+			if err := r.Status().Update(ctx, &token); err != nil {
+				r.Log.Error(err, "Failed to update ArgoCDProjectRoleToken status", "name", req.Name)
+			}
+			return ctrl.Result{RequeueAfter: time.Minute}, nil
+		}
+		token.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := r.Status().Update(ctx, &token); err != nil {
+			r.Log.Error(err, "Failed to update ArgoCDProjectRoleToken status", "name", req.Name)
+		}
+		return ctrl.Result{}, err
+	}
+
+	appProjectKey := types.NamespacedName{
+		Name: token.Spec.AppProjectRef,
+		Namespace: resolveAppProjectNamespace(rbacoperatorv1alpha1.AppProjectSubject{
+			AppProjectRef: token.Spec.AppProjectRef,
+			Namespace:     token.Spec.Namespace,
+		}, r.ArgoCDNamespace, req.Namespace),
+	}
+	if !isAppProjectInStatus(projectRb.Status.AppProjectsBound, appProjectStatusKey(appProjectKey, req.Namespace)) {
+		r.Log.Info("AppProject not bound by ArgoCDProjectRoleBinding", "appProject", appProjectKey, "binding", projectRb.Name)
+		token.SetConditions(rbacoperatorv1alpha1.Pending(fmt.Errorf("AppProject %s is not bound by ArgoCDProjectRoleBinding %s", appProjectKey, projectRb.Name)))
+		if err := r.Status().Update(ctx, &token); err != nil {
+			r.Log.Error(err, "Failed to update ArgoCDProjectRoleToken status", "name", req.Name)
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	appProject := newAppProject(appProjectKey.Name, appProjectKey.Namespace)
+	if err := FetchObject(r.Client, appProject.Namespace, appProject.Name, appProject); err != nil {
+		token.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := r.Status().Update(ctx, &token); err != nil {
+			r.Log.Error(err, "Failed to update ArgoCDProjectRoleToken status", "name", req.Name)
+		}
+		return ctrl.Result{}, err
+	}
+
+	roleName := appProjectRoleName(appProject.Namespace, req.Namespace, projectRb.Spec.ArgoCDProjectRoleRef.Name)
+	now := timeNow().UTC().Truncate(time.Second)
+	if r.needsIssue(&token, appProject, roleName, now) {
+		r.Log.Info("Issuing token", "name", req.Name, "appProject", appProjectKey, "role", roleName)
+		if err := r.issueToken(ctx, &token, appProject, roleName, now); err != nil {
+			if errors.IsConflict(err) {
+				r.Log.Info("Conflict while issuing token, requeuing", "name", req.Name)
+				return ctrl.Result{RequeueAfter: time.Second}, nil
+			}
+			token.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+			if err := r.Status().Update(ctx, &token); err != nil {
+				r.Log.Error(err, "Failed to update ArgoCDProjectRoleToken status", "name", req.Name)
+			}
+			return ctrl.Result{}, fmt.Errorf("error when issuing token: %v", err)
+		}
+	}
+
+	token.SetConditions(rbacoperatorv1alpha1.ReconcileSuccess().WithObservedGeneration(token.GetGeneration()))
+	if err := r.Status().Update(ctx, &token); err != nil {
+		r.Log.Error(err, "Failed to update ArgoCDProjectRoleToken status", "name", req.Name)
+	}
+
+	if renewAt := tokenRenewTime(&token); !renewAt.IsZero() {
+		return ctrl.Result{RequeueAfter: max(renewAt.Sub(now), time.Second)}, nil
+	}
+	return ctrl.Result{RequeueAfter: time.Minute * 10}, nil
+}
+
+// needsIssue returns true if a new token has to be issued, because there is none yet, the spec changed,
+// the token was revoked in the AppProject, the Secret is missing or the token is about to expire.
+func (r *ArgoCDProjectRoleTokenReconciler) needsIssue(token *rbacoperatorv1alpha1.ArgoCDProjectRoleToken, appProject *argocdv1alpha.AppProject, roleName string, now time.Time) bool {
+	if token.Status.TokenID == "" || token.Status.ObservedGeneration != token.Generation {
+		return true
+	}
+	if role, _ := getRoleInAppProject(appProject, roleName); role == nil || !hasJWTToken(role.JWTTokens, token.Status.TokenID) {
+		return true
+	}
+	if !IsObjectFound(r.APIReader, token.Namespace, token.Spec.SecretName, &corev1.Secret{}) {
+		return true
+	}
+	renewAt := tokenRenewTime(token)
+	return !renewAt.IsZero() && !now.Before(renewAt)
+}
+
+// issueToken will sign a new token, record it in the AppProject role, revoke the previous token, write the new
+// token into the output Secret and record it in the status. The new token is revoked again if it can't be recorded,
+// so that no valid token is left behind unknown to the ArgoCDProjectRoleToken.
+func (r *ArgoCDProjectRoleTokenReconciler) issueToken(ctx context.Context, token *rbacoperatorv1alpha1.ArgoCDProjectRoleToken, appProject *argocdv1alpha.AppProject, roleName string, now time.Time) error {
+	if err := r.checkSecretOwner(ctx, token); err != nil {
+		return err
+	}
+	signingKey, err := getServerSignature(r.APIReader, r.ArgoCDSecretName, r.ArgoCDSecretNamespace)
+	if err != nil {
+		return err
+	}
+
+	id := token.Spec.ID
+	if id == "" {
+		id = token.Name
+	}
+	id = fmt.Sprintf("%s-%d", id, now.Unix())
+
+	var expiresIn time.Duration
+	if token.Spec.ExpiresIn != nil {
+		expiresIn = token.Spec.ExpiresIn.Duration
+	}
+	jwtToken, err := signJWTToken(signingKey, projectTokenSubject(appProject.Name, roleName), id, now, expiresIn)
+	if err != nil {
+		return err
+	}
+
+	// The token was issued for another AppProject before the spec changed, revoke it there
+	appProjectKey := client.ObjectKeyFromObject(appProject).String()
+	if token.Status.AppProject != "" && token.Status.AppProject != appProjectKey {
+		if err := r.revokeToken(token); err != nil {
+			return err
+		}
+	}
+
+	argoCDToken := argocdv1alpha.JWTToken{IssuedAt: now.Unix(), ID: id}
+	if expiresIn > 0 {
+		argoCDToken.ExpiresAt = now.Add(expiresIn).Unix()
+	}
+	if err := addJWTTokenToAppProject(r.Client, appProject, roleName, argoCDToken, token.Status.TokenID); err != nil {
+		return err
+	}
+
+	previous := token.Status.DeepCopy()
+	if err := r.recordToken(ctx, token, jwtToken, id, appProjectKey, now, expiresIn); err != nil {
+		token.Status = *previous
+		if revokeErr := removeJWTTokenFromAppProject(r.Client, appProject, id); revokeErr != nil {
+			return fmt.Errorf("%v, failed to revoke the unrecorded token %s: %v", err, id, revokeErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// recordToken will write the token into the output Secret and record it in the status of the ArgoCDProjectRoleToken.
+func (r *ArgoCDProjectRoleTokenReconciler) recordToken(ctx context.Context, token *rbacoperatorv1alpha1.ArgoCDProjectRoleToken, jwtToken, id, appProjectKey string, now time.Time, expiresIn time.Duration) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      token.Spec.SecretName,
+			Namespace: token.Namespace,
+		},
+	}
+	if err := createOrUpdateSecret(ctx, r.APIReader, r.Client, secret, common.LabelSecretToken, func() error {
+		if !secret.CreationTimestamp.IsZero() && !metav1.IsControlledBy(secret, token) {
+			return fmt.Errorf("Secret %s is not controlled by the ArgoCDProjectRoleToken", secret.Name)
+		}
+		secret.Type = corev1.SecretTypeOpaque
+		secret.Data = map[string][]byte{
+			common.TokenSecretKey: []byte(jwtToken),
+		}
+		return controllerutil.SetControllerReference(token, secret, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	issuedAt := metav1.NewTime(now)
+	token.Status.TokenID = id
+	token.Status.AppProject = appProjectKey
+	token.Status.IssuedAt = &issuedAt
+	token.Status.ExpiresAt = nil
+	if expiresIn > 0 {
+		expiresAt := metav1.NewTime(now.Add(expiresIn))
+		token.Status.ExpiresAt = &expiresAt
+	}
+	token.Status.ObservedGeneration = token.Generation
+	return r.Status().Update(ctx, token)
+}
+
+// checkSecretOwner returns an error if the output Secret exists, but is not controlled by the ArgoCDProjectRoleToken.
+// Secrets of others are never overwritten or deleted.
+func (r *ArgoCDProjectRoleTokenReconciler) checkSecretOwner(ctx context.Context, token *rbacoperatorv1alpha1.ArgoCDProjectRoleToken) error {
+	secret := &corev1.Secret{}
+	if err := r.APIReader.Get(ctx, client.ObjectKey{Name: token.Spec.SecretName, Namespace: token.Namespace}, secret); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(secret, token) {
+		return fmt.Errorf("Secret %s is not controlled by the ArgoCDProjectRoleToken", secret.Name)
+	}
+	return nil
+}
+
+// revokeToken will remove the currently issued token from the AppProject it was issued for.
+func (r *ArgoCDProjectRoleTokenReconciler) revokeToken(token *rbacoperatorv1alpha1.ArgoCDProjectRoleToken) error {
+	if token.Status.TokenID == "" || token.Status.AppProject == "" {
+		return nil // No token issued, nothing to revoke
+	}
+	appProjectKey := parseAppProjectStatusKey(token.Status.AppProject, token.Namespace)
+	appProject := newAppProject(appProjectKey.Name, appProjectKey.Namespace)
+	if !IsObjectFound(r.Client, appProject.Namespace, appProject.Name, appProject) {
+		return nil // AppProject does not exist, nothing to revoke
+	}
+	return removeJWTTokenFromAppProject(r.Client, appProject, token.Status.TokenID)
+}
+
+// tokenRenewTime returns the time the current token has to be rotated at, or zero time if it does not expire.
+func tokenRenewTime(token *rbacoperatorv1alpha1.ArgoCDProjectRoleToken) time.Time {
+	if token.Status.ExpiresAt == nil {
+		return time.Time{}
+	}
+	renewAt := token.Status.ExpiresAt.Time
+	if token.Spec.RenewBefore != nil {
+		renewAt = renewAt.Add(-token.Spec.RenewBefore.Duration)
+	}
+	return renewAt
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ArgoCDProjectRoleTokenReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&rbacoperatorv1alpha1.ArgoCDProjectRoleToken{}).
+		Owns(&corev1.Secret{}).
+		Named("argocdprojectroletoken").
+		Complete(r)
+}
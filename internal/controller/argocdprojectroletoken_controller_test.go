@@ -0,0 +1,245 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+var _ reconcile.Reconciler = &ArgoCDProjectRoleTokenReconciler{}
+
+func TestArgoCDProjectRoleTokenReconciler_IssueAndRevoke(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	token := makeTestProjectRoleToken(addFinalizerProjectRoleToken(), setProjectRoleTokenExpiresIn(time.Hour, 10*time.Minute))
+	projectRoleBinding := makeTestProjectRoleBinding(addBoundAppProjects([]string{testAppProjectName}))
+
+	resObjs := []client.Object{token, projectRoleBinding, makeTestAppProject(addTestRoleToAppProject()), makeTestArgoCDSecret()}
+	subresObjs := []client.Object{token}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDProjectRoleTokenReconciler(client, scheme)
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      token.Name,
+			Namespace: token.Namespace,
+		},
+	}
+
+	res, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	if res.RequeueAfter > 50*time.Minute {
+		t.Fatalf("reconcile requeued request after %s, want before renewal", res.RequeueAfter)
+	}
+
+	tokenRes := &rbacoperatorv1alpha1.ArgoCDProjectRoleToken{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, tokenRes))
+	assert.NotEmpty(t, tokenRes.Status.TokenID)
+	assert.Equal(t, testNamespace+"/"+testAppProjectName, tokenRes.Status.AppProject)
+	assert.NotNil(t, tokenRes.Status.ExpiresAt)
+
+	appProject := &argocdv1alpha.AppProject{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testAppProjectName, Namespace: testNamespace}, appProject))
+	role, _ := getRoleInAppProject(appProject, testProjectRoleName)
+	assert.NotNil(t, role)
+	assert.True(t, hasJWTToken(role.JWTTokens, tokenRes.Status.TokenID))
+	assert.True(t, hasJWTToken(appProject.Status.JWTTokensByRole[testProjectRoleName].Items, tokenRes.Status.TokenID))
+
+	secret := &corev1.Secret{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: token.Spec.SecretName, Namespace: testNamespace}, secret))
+	// The output Secret is labeled, so that it is cached and watched
+	assert.Equal(t, common.LabelSecretToken, secret.Labels[common.LabelSecret])
+	claims := jwt.RegisteredClaims{}
+	_, err = jwt.ParseWithClaims(string(secret.Data[common.TokenSecretKey]), &claims, func(*jwt.Token) (any, error) {
+		return []byte("test-signature"), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, projectTokenSubject(testAppProjectName, testProjectRoleName), claims.Subject)
+	assert.Equal(t, tokenRes.Status.TokenID, claims.ID)
+
+	// The token is revoked when the ArgoCDProjectRoleToken is deleted
+	assert.NoError(t, reconciler.Delete(context.TODO(), tokenRes))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testAppProjectName, Namespace: testNamespace}, appProject))
+	role, _ = getRoleInAppProject(appProject, testProjectRoleName)
+	assert.False(t, hasJWTToken(role.JWTTokens, tokenRes.Status.TokenID))
+	assert.False(t, hasJWTToken(appProject.Status.JWTTokensByRole[testProjectRoleName].Items, tokenRes.Status.TokenID))
+
+	err = reconciler.Get(context.TODO(), types.NamespacedName{Name: token.Spec.SecretName, Namespace: testNamespace}, secret)
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestArgoCDProjectRoleTokenReconciler_RenewToken(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	token := makeTestProjectRoleToken(addFinalizerProjectRoleToken(), setProjectRoleTokenExpiresIn(time.Hour, 2*time.Hour))
+	projectRoleBinding := makeTestProjectRoleBinding(addBoundAppProjects([]string{testAppProjectName}))
+
+	resObjs := []client.Object{token, projectRoleBinding, makeTestAppProject(addTestRoleToAppProject()), makeTestArgoCDSecret()}
+	subresObjs := []client.Object{token}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDProjectRoleTokenReconciler(client, scheme)
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      token.Name,
+			Namespace: token.Namespace,
+		},
+	}
+
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	tokenRes := &rbacoperatorv1alpha1.ArgoCDProjectRoleToken{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, tokenRes))
+	firstID := tokenRes.Status.TokenID
+
+	// RenewBefore exceeds the lifetime of the token, so every reconcile after a second rotates the token
+	time.Sleep(time.Second)
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, tokenRes))
+	assert.NotEqual(t, firstID, tokenRes.Status.TokenID)
+
+	appProject := &argocdv1alpha.AppProject{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testAppProjectName, Namespace: testNamespace}, appProject))
+	role, _ := getRoleInAppProject(appProject, testProjectRoleName)
+	assert.False(t, hasJWTToken(role.JWTTokens, firstID))
+	assert.True(t, hasJWTToken(role.JWTTokens, tokenRes.Status.TokenID))
+}
+
+func TestArgoCDProjectRoleTokenReconciler_AppProjectNotBound(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	token := makeTestProjectRoleToken(addFinalizerProjectRoleToken())
+	projectRoleBinding := makeTestProjectRoleBinding()
+
+	resObjs := []client.Object{token, projectRoleBinding, makeTestAppProject(), makeTestArgoCDSecret()}
+	subresObjs := []client.Object{token}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDProjectRoleTokenReconciler(client, scheme)
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      token.Name,
+			Namespace: token.Namespace,
+		},
+	}
+
+	res, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, res.RequeueAfter)
+
+	tokenRes := &rbacoperatorv1alpha1.ArgoCDProjectRoleToken{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, tokenRes))
+	assert.Empty(t, tokenRes.Status.TokenID)
+	assert.Equal(t, rbacoperatorv1alpha1.TypePending, tokenRes.Status.Conditions[0].Type)
+}
+
+func TestArgoCDProjectRoleTokenReconciler_ForeignSecret(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	token := makeTestProjectRoleToken(addFinalizerProjectRoleToken())
+	projectRoleBinding := makeTestProjectRoleBinding(addBoundAppProjects([]string{testAppProjectName}))
+	foreign := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: token.Spec.SecretName, Namespace: testNamespace},
+		Data:       map[string][]byte{"password": []byte("keep")},
+	}
+
+	resObjs := []client.Object{token, projectRoleBinding, makeTestAppProject(addTestRoleToAppProject()), makeTestArgoCDSecret(), foreign}
+	subresObjs := []client.Object{token}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDProjectRoleTokenReconciler(client, scheme)
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      token.Name,
+			Namespace: token.Namespace,
+		},
+	}
+
+	// No token is issued into a Secret of someone else
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.ErrorContains(t, err, "not controlled by the ArgoCDProjectRoleToken")
+	appProject := &argocdv1alpha.AppProject{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testAppProjectName, Namespace: testNamespace}, appProject))
+	role, _ := getRoleInAppProject(appProject, testProjectRoleName)
+	assert.Empty(t, role.JWTTokens)
+
+	// and the Secret is kept when the ArgoCDProjectRoleToken is deleted
+	tokenRes := &rbacoperatorv1alpha1.ArgoCDProjectRoleToken{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, tokenRes))
+	assert.NoError(t, reconciler.Delete(context.TODO(), tokenRes))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	secret := &corev1.Secret{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: foreign.Name, Namespace: testNamespace}, secret))
+	assert.Equal(t, []byte("keep"), secret.Data["password"])
+}
+
+func TestArgoCDProjectRoleTokenReconciler_StatusUpdateFailed(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	token := makeTestProjectRoleToken(addFinalizerProjectRoleToken())
+	projectRoleBinding := makeTestProjectRoleBinding(addBoundAppProjects([]string{testAppProjectName}))
+
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(token, projectRoleBinding, makeTestAppProject(addTestRoleToAppProject()), makeTestArgoCDSecret()).
+		WithStatusSubresource(token).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourceUpdate: func(_ context.Context, _ client.Client, _ string, _ client.Object, _ ...client.SubResourceUpdateOption) error {
+				return fmt.Errorf("status update failed")
+			},
+		}).Build()
+	reconciler := makeTestArgoCDProjectRoleTokenReconciler(c, scheme)
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      token.Name,
+			Namespace: token.Namespace,
+		},
+	}
+
+	// The token is revoked again if it can't be recorded in the status
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.ErrorContains(t, err, "status update failed")
+	appProject := &argocdv1alpha.AppProject{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testAppProjectName, Namespace: testNamespace}, appProject))
+	role, _ := getRoleInAppProject(appProject, testProjectRoleName)
+	assert.Empty(t, role.JWTTokens)
+	assert.Empty(t, appProject.Status.JWTTokensByRole[testProjectRoleName].Items)
+}
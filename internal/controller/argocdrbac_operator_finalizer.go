@@ -22,11 +22,14 @@ import (
 
 	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
-	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
 )
 
 func (r *ArgoCDRoleReconciler) addFinalizer(ctx context.Context, role *rbacoperatorv1alpha1.ArgoCDRole) error {
@@ -42,22 +45,19 @@ func (r *ArgoCDRoleReconciler) handleFinalizer(ctx context.Context, role *rbacop
 	if err := r.delete(role); err != nil {
 		return err
 	}
+	if isPlanning(r.Client) {
+		return nil // The finalizer is kept in dry-run mode, so the removal can be written once it is turned off
+	}
 
 	role.RemoveFinalizer(rbacoperatorv1alpha1.ArgoCDRoleFinalizerName)
 	return r.Update(ctx, role)
 }
 
 func (r *ArgoCDRoleReconciler) delete(role *rbacoperatorv1alpha1.ArgoCDRole) error {
-	cm := newConfigMap(r.ArgoCDRBACConfigMapName, r.ArgoCDRBACConfigMapNamespace)
+	policyRiskSeverity.DeleteLabelValues("ArgoCDRole", role.Namespace, role.Name)
+	unboundResources.DeleteLabelValues("ArgoCDRole", role.Namespace, role.Name)
 	overlayKey := fmt.Sprintf("policy.%s.%s.csv", role.Namespace, role.Name)
-	if IsObjectFound(r.Client, cm.Namespace, cm.Name, cm) {
-		delete(cm.Data, overlayKey)
-		if err := r.Update(context.TODO(), cm); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return deleteConfigMapKeys(r.Client, r.ArgoCDRBACConfigMapName, r.ArgoCDRBACConfigMapNamespace, overlayKey)
 }
 
 func (r *ArgoCDProjectRoleReconciler) addFinalizer(ctx context.Context, projectRole *rbacoperatorv1alpha1.ArgoCDProjectRole) error {
@@ -73,12 +73,17 @@ func (r *ArgoCDProjectRoleReconciler) handleFinalizer(ctx context.Context, proje
 	if err := r.delete(projectRole); err != nil {
 		return err
 	}
+	if isPlanning(r.Client) {
+		return nil // The finalizer is kept in dry-run mode, so the removal can be written once it is turned off
+	}
 
 	projectRole.RemoveFinalizer(rbacoperatorv1alpha1.ArgoCDProjectRoleFinalizerName)
 	return r.Update(ctx, projectRole)
 }
 
 func (r *ArgoCDProjectRoleReconciler) delete(projectRole *rbacoperatorv1alpha1.ArgoCDProjectRole) error {
+	policyRiskSeverity.DeleteLabelValues("ArgoCDProjectRole", projectRole.Namespace, projectRole.Name)
+	unboundResources.DeleteLabelValues("ArgoCDProjectRole", projectRole.Namespace, projectRole.Name)
 	rbName := projectRole.Status.ArgoCDProjectRoleBindingRef
 	if rbName == "" {
 		return nil // Role not bound to any AppProject, nothing to delete
@@ -93,12 +98,9 @@ func (r *ArgoCDProjectRoleReconciler) delete(projectRole *rbacoperatorv1alpha1.A
 		// RoleBinding does not exist, nothing to delete
 		return nil
 	}
-	appProjectNames := []string{}
 	// get all AppProjects this role is bound to
-	for _, subject := range rb.Spec.Subjects {
-		appProjectNames = append(appProjectNames, subject.AppProjectRef)
-	}
-	return deleteProjectRoles(r.Client, appProjectNames, projectRole.Name, projectRole.Namespace)
+	appProjects := boundAppProjects(rb.Spec.Subjects, r.ArgoCDNamespace, rb.Namespace)
+	return deleteProjectRoles(r.Client, appProjects, projectRole.Name, projectRole.Namespace)
 }
 
 func (r *ArgoCDRoleBindingReconciler) addFinalizer(ctx context.Context, rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) error {
@@ -114,6 +116,9 @@ func (r *ArgoCDRoleBindingReconciler) handleFinalizer(ctx context.Context, rb *r
 	if err := r.delete(rb); err != nil {
 		return err
 	}
+	if isPlanning(r.Client) {
+		return nil // The finalizer is kept in dry-run mode, so the removal can be written once it is turned off
+	}
 
 	rb.RemoveFinalizer(rbacoperatorv1alpha1.ArgoCDRoleBindingFinalizerName)
 	return r.Update(ctx, rb)
@@ -121,16 +126,16 @@ func (r *ArgoCDRoleBindingReconciler) handleFinalizer(ctx context.Context, rb *r
 
 func (r *ArgoCDRoleBindingReconciler) delete(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) error {
 	roleRefName := rb.Spec.ArgoCDRoleRef.Name
-	if roleRefName == common.ArgoCDRoleAdmin || roleRefName == common.ArgoCDRoleReadOnly {
-		cm := newConfigMap(r.ArgoCDRBACConfigMapName, r.ArgoCDRBACConfigMapNamespace)
-		overlayKey := fmt.Sprintf("policy.%s.%s.csv", rb.Namespace, roleRefName)
-		if IsObjectFound(r.Client, cm.Namespace, cm.Name, cm) {
-			delete(cm.Data, overlayKey)
-			if err := r.Update(context.TODO(), cm); err != nil {
-				return err
-			}
-		}
-		return nil
+	breakGlassDeadlineSeconds.DeleteLabelValues(rb.Namespace, rb.Name, roleRefName)
+	policyRiskSeverity.DeleteLabelValues("ArgoCDRoleBinding", rb.Namespace, rb.Name)
+	unboundResources.DeleteLabelValues("ArgoCDRoleBinding", rb.Namespace, rb.Name)
+	// The own key of the binding is removed even if its role does not exist anymore
+	keys := []string{ownOverlayKey(rb)}
+	if isBuiltInRole(roleRefName) {
+		keys = append(keys, roleBindingOverlayKey(rb, r.createBuiltInRole(rb.Namespace, roleRefName)))
+	}
+	if err := deleteConfigMapKeys(r.Client, r.ArgoCDRBACConfigMapName, r.ArgoCDRBACConfigMapNamespace, keys...); err != nil {
+		return err
 	}
 
 	role := &rbacoperatorv1alpha1.ArgoCDRole{
@@ -139,7 +144,8 @@ func (r *ArgoCDRoleBindingReconciler) delete(rb *rbacoperatorv1alpha1.ArgoCDRole
 			Namespace: rb.Namespace,
 		},
 	}
-	if IsObjectFound(r.Client, role.Namespace, role.Name, role) {
+	// Only the binding the role is bound to unbinds it, other bindings to the role don't revoke its subjects
+	if !isBuiltInRole(roleRefName) && IsObjectFound(r.Client, role.Namespace, role.Name, role) && role.Status.ArgoCDRoleBindingRef == rb.Name {
 		role.Status.ArgoCDRoleBindingRef = ""
 
 		if err := r.Status().Update(context.TODO(), role); err != nil {
@@ -162,35 +168,173 @@ func (r *ArgoCDProjectRoleBindingReconciler) handleFinalizer(ctx context.Context
 	if err := r.delete(projectRoleBinding); err != nil {
 		return err
 	}
+	if isPlanning(r.Client) {
+		return nil // The finalizer is kept in dry-run mode, so the removal can be written once it is turned off
+	}
 
 	projectRoleBinding.RemoveFinalizer(rbacoperatorv1alpha1.ArgoCDProjectRoleBindingFinalizerName)
 	return r.Update(ctx, projectRoleBinding)
 }
 
 func (r *ArgoCDProjectRoleBindingReconciler) delete(projectRoleBinding *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) error {
+	policyRiskSeverity.DeleteLabelValues("ArgoCDProjectRoleBinding", projectRoleBinding.Namespace, projectRoleBinding.Name)
+	unboundResources.DeleteLabelValues("ArgoCDProjectRoleBinding", projectRoleBinding.Namespace, projectRoleBinding.Name)
 	roleName := projectRoleBinding.Spec.ArgoCDProjectRoleRef.Name
 
-	appProjectNames := []string{}
-	for _, subject := range projectRoleBinding.Spec.Subjects {
-		appProjectNames = append(appProjectNames, subject.AppProjectRef)
+	if err := updateConfigMap(r.Client, r.ArgoCDRBACConfigMapName, r.ArgoCDRBACConfigMapNamespace, func(cm *corev1.ConfigMap) bool {
+		return removeConfigMapKeys(cm, projectRoleBindingOverlayKey(projectRoleBinding))
+	}); err != nil {
+		return err
 	}
-	return deleteProjectRoles(r.Client, appProjectNames, roleName, projectRoleBinding.Namespace)
+
+	appProjects := boundAppProjects(projectRoleBinding.Spec.Subjects, r.ArgoCDNamespace, projectRoleBinding.Namespace)
+	return deleteProjectRoles(r.Client, appProjects, roleName, projectRoleBinding.Namespace)
 }
 
-func deleteProjectRoles(rClient client.Client, appProjects []string, roleName string, namespace string) error {
-	for _, appProjectName := range appProjects {
+// boundAppProjects returns the AppProjects referenced by the given subjects.
+func boundAppProjects(subjects []rbacoperatorv1alpha1.AppProjectSubject, argoCDNamespace, bindingNamespace string) []types.NamespacedName {
+	appProjects := []types.NamespacedName{}
+	for _, subject := range subjects {
+		appProjects = append(appProjects, types.NamespacedName{
+			Name:      subject.AppProjectRef,
+			Namespace: resolveAppProjectNamespace(subject, argoCDNamespace, bindingNamespace),
+		})
+	}
+	return appProjects
+}
+
+func deleteProjectRoles(rClient client.Client, appProjects []types.NamespacedName, roleName string, namespace string) error {
+	for _, appProjectKey := range appProjects {
 		appProject := &argocdv1alpha.AppProject{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      appProjectName,
-				Namespace: namespace,
+				Name:      appProjectKey.Name,
+				Namespace: appProjectKey.Namespace,
 			},
 		}
 		if !IsObjectFound(rClient, appProject.Namespace, appProject.Name, appProject) {
 			continue // AppProject does not exist, nothing to delete
 		}
-		if err := removeRoleFromAppProject(rClient, appProject, roleName); err != nil {
-			return errors.Wrapf(err, "failed to remove role %s from AppProject %s", roleName, appProjectName)
+		// Roles of other namespaces are prefixed with the namespace, so the role is removed even if the
+		// AppProject revoked the namespace since
+		if err := removeRoleFromAppProject(rClient, appProject, appProjectRoleName(appProject.Namespace, namespace, roleName)); err != nil {
+			return errors.Wrapf(err, "failed to remove role %s from AppProject %s", roleName, appProjectKey)
 		}
 	}
 	return nil
 }
+
+func (r *ArgoCDProjectRoleTokenReconciler) addFinalizer(ctx context.Context, token *rbacoperatorv1alpha1.ArgoCDProjectRoleToken) error {
+	token.AddFinalizer(rbacoperatorv1alpha1.ArgoCDProjectRoleTokenFinalizerName)
+	return r.Update(ctx, token)
+}
+
+func (r *ArgoCDProjectRoleTokenReconciler) handleFinalizer(ctx context.Context, token *rbacoperatorv1alpha1.ArgoCDProjectRoleToken) error {
+	if !token.HasFinalizer(rbacoperatorv1alpha1.ArgoCDProjectRoleTokenFinalizerName) {
+		return nil
+	}
+
+	if err := r.delete(token); err != nil {
+		return err
+	}
+
+	token.RemoveFinalizer(rbacoperatorv1alpha1.ArgoCDProjectRoleTokenFinalizerName)
+	return r.Update(ctx, token)
+}
+
+func (r *ArgoCDProjectRoleTokenReconciler) delete(token *rbacoperatorv1alpha1.ArgoCDProjectRoleToken) error {
+	if err := r.revokeToken(token); err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.APIReader.Get(context.TODO(), client.ObjectKey{Name: token.Spec.SecretName, Namespace: token.Namespace}, secret); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(secret, token) {
+		return nil // Secret belongs to someone else, nothing to delete
+	}
+	if err := r.Delete(context.TODO(), secret); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (r *ArgoCDLocalAccountReconciler) addFinalizer(ctx context.Context, account *rbacoperatorv1alpha1.ArgoCDLocalAccount) error {
+	account.AddFinalizer(rbacoperatorv1alpha1.ArgoCDLocalAccountFinalizerName)
+	return r.Update(ctx, account)
+}
+
+func (r *ArgoCDLocalAccountReconciler) handleFinalizer(ctx context.Context, account *rbacoperatorv1alpha1.ArgoCDLocalAccount) error {
+	if !account.HasFinalizer(rbacoperatorv1alpha1.ArgoCDLocalAccountFinalizerName) {
+		return nil
+	}
+
+	if err := r.delete(ctx, account); err != nil {
+		return err
+	}
+
+	account.RemoveFinalizer(rbacoperatorv1alpha1.ArgoCDLocalAccountFinalizerName)
+	return r.Update(ctx, account)
+}
+
+func (r *ArgoCDLocalAccountReconciler) delete(ctx context.Context, account *rbacoperatorv1alpha1.ArgoCDLocalAccount) error {
+	cm := newConfigMap(r.ArgoCDConfigMapName, r.ArgoCDConfigMapNamespace)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(cm), cm); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if account.Name == argoCDAdminAccountName || accountManager(cm, account.Name) != account.Namespace {
+		return nil // Account is not managed by the ArgoCDLocalAccount, nothing to delete
+	}
+
+	// The Secret is cleaned up first, the account stays marked as managed until it is removed from the ConfigMap
+	secret := &corev1.Secret{}
+	if IsObjectFound(r.APIReader, r.ArgoCDSecretNamespace, r.ArgoCDSecretName, secret) && removeAccountFromSecret(secret, account.Name) {
+		if err := r.Update(ctx, secret); err != nil {
+			return err
+		}
+	}
+
+	return updateConfigMap(r.Client, r.ArgoCDConfigMapName, r.ArgoCDConfigMapNamespace, func(cm *corev1.ConfigMap) bool {
+		if accountManager(cm, account.Name) != account.Namespace {
+			return false
+		}
+		removeAccountFromConfigMap(cm, account.Name)
+		setAccountManager(cm, account.Name, "")
+		return true
+	})
+}
+
+// deleteConfigMapKeys will remove the keys from the ConfigMap, see updateConfigMap.
+func deleteConfigMapKeys(rClient client.Client, name, namespace string, keys ...string) error {
+	return updateConfigMap(rClient, name, namespace, func(cm *corev1.ConfigMap) bool {
+		return removeConfigMapKeys(cm, keys...)
+	})
+}
+
+// removeConfigMapKeys removes the keys from the ConfigMap and returns true if any of them existed.
+func removeConfigMapKeys(cm *corev1.ConfigMap, keys ...string) bool {
+	removed := false
+	for _, key := range keys {
+		if _, exists := cm.Data[key]; exists {
+			delete(cm.Data, key)
+			recordPolicySources(cm, key)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// updateConfigMap will apply the change to the latest version of the ConfigMap and update it if the change returns true,
+// retrying on conflicts. A missing ConfigMap is not changed.
+func updateConfigMap(rClient client.Client, name, namespace string, change func(*corev1.ConfigMap) bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := newConfigMap(name, namespace)
+		if err := rClient.Get(context.TODO(), client.ObjectKeyFromObject(cm), cm); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if !change(cm) {
+			return nil
+		}
+		return rClient.Update(context.TODO(), cm)
+	})
+}
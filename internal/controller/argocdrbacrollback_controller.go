@@ -0,0 +1,290 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+const eventReasonRolledBack = "RolledBack"
+
+// ArgoCDRBACRollbackReconciler reconciles a ArgoCDRBACRollback object
+type ArgoCDRBACRollbackReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// History records the policy restored as ArgoCDRBACRevisions. No revisions are recorded if nil.
+	History *RevisionHistory
+	// Audit writes an audit entry for every change to the policy. Nothing is audited if nil.
+	Audit *AuditLog
+	// Notifications sends events of the changes to the policy. No events are sent if nil.
+	Notifications *Notifications
+	// DryRun holds all objects instead of reconciling them, their changes can't be planned.
+	DryRun bool
+}
+
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrbacrollbacks,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrbacrollbacks/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrbacrevisions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=argoproj.io,resources=appprojects,verbs=get;list;patch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectroles,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *ArgoCDRBACRollbackReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	_ = r.Log.WithValues("argocdrbacrollback", req.NamespacedName)
+
+	r.Log.Info("Reconciling ArgoCDRBACRollback", "name", req.Name, "namespace", req.Namespace)
+
+	rollback := rbacoperatorv1alpha1.ArgoCDRBACRollback{}
+	if err := r.Get(ctx, req.NamespacedName, &rollback); err != nil {
+		if errors.IsNotFound(err) {
+			r.Log.Info("ArgoCDRBACRollback not found, skipping reconcile", "name", req.Name)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if rollback.Status.Phase != "" {
+		// A rollback is applied once, create a new one to roll back again
+		return ctrl.Result{}, nil
+	}
+	if isDryRun(r.DryRun, &rollback) {
+		r.Log.Info("ArgoCDRBACRollback is in dry-run mode, skipping reconcile", "name", req.Name)
+		rollback.SetConditions(rbacoperatorv1alpha1.Pending(errNotPlanned("ArgoCDRBACRollback")))
+		return ctrl.Result{}, r.Status().Update(ctx, &rollback)
+	}
+
+	revision := rbacoperatorv1alpha1.ArgoCDRBACRevision{}
+	if err := r.Get(ctx, types.NamespacedName{Name: rollback.Spec.Revision, Namespace: rollback.Namespace}, &revision); err != nil {
+		rollback.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if errors.IsNotFound(err) {
+			rollback.Status.Phase = rbacoperatorv1alpha1.RollbackPhaseFailed
+			err = nil
+		}
+		if err := r.Status().Update(ctx, &rollback); err != nil {
+			r.Log.Error(err, "Failed to update ArgoCDRBACRollback status", "name", req.Name)
+		}
+		return ctrl.Result{}, err
+	}
+	revisions, err := listRevisions(ctx, r.Client, rollback.Namespace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	recorded := *r
+	recorded.Client = recordedClient(r.Client, "ArgoCDRBACRollback", &rollback, r.History, r.Audit, r.Notifications)
+	if err := recorded.rollback(ctx, &rollback, &revision, revisions); err != nil {
+		rollback.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := r.Status().Update(ctx, &rollback); err != nil {
+			r.Log.Error(err, "Failed to update ArgoCDRBACRollback status", "name", req.Name)
+		}
+		return ctrl.Result{}, fmt.Errorf("error when rolling back to revision %s: %v", revision.Name, err)
+	}
+
+	now := metav1.Now()
+	rollback.Status.Phase = rbacoperatorv1alpha1.RollbackPhaseCompleted
+	rollback.Status.CompletedAt = &now
+	rollback.SetConditions(rbacoperatorv1alpha1.ReconcileSuccess())
+	if err := r.Status().Update(ctx, &rollback); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.Recorder.Eventf(&rollback, corev1.EventTypeNormal, eventReasonRolledBack, "Restored revision %s of %s, paused %s",
+		revision.Name, strings.Join(rollback.Status.Restored, ", "), strings.Join(rollback.Status.Paused, ", "))
+	return ctrl.Result{}, nil
+}
+
+// rollback restores the ConfigMap and AppProjects written since the revision to their latest revision at or before it.
+// The resources that wrote them since are paused first, so that they do not write their policy again until fixed.
+func (r *ArgoCDRBACRollbackReconciler) rollback(ctx context.Context, rollback *rbacoperatorv1alpha1.ArgoCDRBACRollback,
+	revision *rbacoperatorv1alpha1.ArgoCDRBACRevision, revisions []rbacoperatorv1alpha1.ArgoCDRBACRevision) error {
+	latest := map[string]*rbacoperatorv1alpha1.ArgoCDRBACRevision{}
+	written := []string{}
+	triggers := []rbacoperatorv1alpha1.RevisionTrigger{}
+	for i := range revisions {
+		rev := &revisions[i]
+		if !revision.RecordedBefore(rev) {
+			latest[rev.Target()] = rev
+			continue
+		}
+		if !slices.Contains(written, rev.Target()) {
+			written = append(written, rev.Target())
+		}
+		if rev.Spec.Trigger.Kind != "ArgoCDRBACRollback" && !slices.Contains(triggers, rev.Spec.Trigger) {
+			triggers = append(triggers, rev.Spec.Trigger)
+		}
+	}
+	sort.Strings(written)
+
+	pausedBy := rollback.Namespace + "/" + rollback.Name
+	rollback.Status.Paused = nil
+	for _, trigger := range triggers {
+		if slices.Contains(rollback.Status.Paused, trigger.String()) {
+			continue // a resource has a revision per generation
+		}
+		paused, err := r.pause(ctx, trigger, pausedBy)
+		if err != nil {
+			return err
+		}
+		if paused {
+			rollback.Status.Paused = append(rollback.Status.Paused, trigger.String())
+		}
+	}
+	// The paused annotations are only honored once the rollback lists the resources, before the policy is restored
+	if err := r.Status().Update(ctx, rollback); err != nil {
+		return err
+	}
+
+	rollback.Status.Restored = nil
+	rollback.Status.Skipped = nil
+	for _, target := range written {
+		rev, ok := latest[target]
+		if !ok {
+			rollback.Status.Skipped = append(rollback.Status.Skipped, target)
+			continue
+		}
+		if err := r.restore(ctx, rev); err != nil {
+			return err
+		}
+		rollback.Status.Restored = append(rollback.Status.Restored, target)
+	}
+	return nil
+}
+
+// pause annotates the resource that triggered a revision as paused. Returns false if the resource does not exist anymore.
+func (r *ArgoCDRBACRollbackReconciler) pause(ctx context.Context, trigger rbacoperatorv1alpha1.RevisionTrigger, pausedBy string) (bool, error) {
+	var obj client.Object
+	switch trigger.Kind {
+	case "ArgoCDRole":
+		obj = &rbacoperatorv1alpha1.ArgoCDRole{}
+	case "ArgoCDRoleBinding":
+		obj = &rbacoperatorv1alpha1.ArgoCDRoleBinding{}
+	case "ArgoCDProjectRole":
+		obj = &rbacoperatorv1alpha1.ArgoCDProjectRole{}
+	case "ArgoCDProjectRoleBinding":
+		obj = &rbacoperatorv1alpha1.ArgoCDProjectRoleBinding{}
+	default:
+		return false, nil
+	}
+	if err := r.Get(ctx, types.NamespacedName{Name: trigger.Name, Namespace: trigger.Namespace}, obj); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	original := obj.DeepCopyObject().(client.Object)
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[common.AnnotationPaused] = pausedBy
+	obj.SetAnnotations(annotations)
+	return true, r.Patch(ctx, obj, client.MergeFrom(original))
+}
+
+// restore writes the policy recorded in the revision to its ConfigMap or AppProject. Only the policy.<namespace>.<name>.csv
+// keys and AppProject roles written by the operator are restored, other keys and roles are left as they are.
+func (r *ArgoCDRBACRollbackReconciler) restore(ctx context.Context, revision *rbacoperatorv1alpha1.ArgoCDRBACRevision) error {
+	if revision.Spec.AppProject != "" {
+		appProject := newAppProject(splitNamespacedName(revision.Spec.AppProject))
+		if err := r.Get(ctx, client.ObjectKeyFromObject(appProject), appProject); err != nil {
+			return err
+		}
+		original := appProject.DeepCopy()
+		roles := []argocdv1alpha.ProjectRole{}
+		for _, live := range appProject.Spec.Roles {
+			owned, err := isOperatorProjectRole(ctx, r.Client, appProject, live.Name)
+			if err != nil {
+				return err
+			}
+			if !owned {
+				roles = append(roles, live)
+			}
+		}
+		for _, recorded := range revision.Spec.ProjectRoles {
+			owned, err := isOperatorProjectRole(ctx, r.Client, appProject, recorded.Name)
+			if err != nil {
+				return err
+			}
+			if !owned {
+				continue
+			}
+			role := argocdv1alpha.ProjectRole{
+				Name:        recorded.Name,
+				Description: recorded.Description,
+				Groups:      recorded.Groups,
+				Policies:    recorded.Policies,
+			}
+			// JWT tokens are not recorded, keep the ones of the live role
+			if live, _ := getRoleInAppProject(original, recorded.Name); live != nil {
+				role.JWTTokens = live.JWTTokens
+			}
+			roles = append(roles, role)
+		}
+		appProject.Spec.Roles = roles
+		return r.Patch(ctx, appProject, client.MergeFrom(original))
+	}
+
+	cm := newConfigMap(splitNamespacedName(revision.Spec.ConfigMap))
+	if err := r.Get(ctx, client.ObjectKeyFromObject(cm), cm); err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	for key := range cm.Data {
+		if _, ok := revision.Spec.Policies[key]; isOperatorPolicyKey(key) && !ok {
+			delete(cm.Data, key)
+		}
+	}
+	for key, value := range revision.Spec.Policies {
+		if isOperatorPolicyKey(key) {
+			cm.Data[key] = value
+		}
+	}
+	return r.Update(ctx, cm)
+}
+
+// splitNamespacedName returns the name and namespace of "<namespace>/<name>".
+func splitNamespacedName(namespacedName string) (string, string) {
+	namespace, name, _ := strings.Cut(namespacedName, "/")
+	return name, namespace
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ArgoCDRBACRollbackReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&rbacoperatorv1alpha1.ArgoCDRBACRollback{}).
+		Named("argocdrbacrollback").
+		Complete(r)
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+var _ reconcile.Reconciler = &ArgoCDRBACRollbackReconciler{}
+
+func TestArgoCDRBACRollbackReconciler_Reconcile(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	argocdRole := makeTestRole(addFinalizerRole(), func(r *rbacoperatorv1alpha1.ArgoCDRole) {
+		r.Generation = 1
+	})
+	rollback := &rbacoperatorv1alpha1.ArgoCDRBACRollback{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-rollback", Namespace: testRBACCMNamespace},
+	}
+
+	resObjs := []client.Object{argocdRole}
+	subresObjs := []client.Object{argocdRole, rollback}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	history := &RevisionHistory{Namespace: testRBACCMNamespace, Limit: 3}
+	reconciler := makeTestArgoCDRoleReconciler(client, scheme)
+	reconciler.History = history
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestArgoCDNamespace()))
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestRBACConfigMap()))
+
+	// Every write of the ConfigMap is recorded
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: argocdRole.Name, Namespace: argocdRole.Namespace}}
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	role := &rbacoperatorv1alpha1.ArgoCDRole{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	role.Spec.Rules[0].Verbs = append(role.Spec.Rules[0].Verbs, "delete")
+	role.Generation = 2
+	assert.NoError(t, reconciler.Update(context.TODO(), role))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	revisions, err := listRevisions(context.TODO(), reconciler.Client, testRBACCMNamespace)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, rbacoperatorv1alpha1.RevisionTrigger{Kind: "ArgoCDRole", Namespace: role.Namespace, Name: role.Name, Generation: 1}, revisions[0].Spec.Trigger)
+	assert.Equal(t, testRBACCMNamespace+"/"+testRBACCMName, revisions[0].Spec.ConfigMap)
+	overlayKey := fmt.Sprintf("policy.%s.%s.csv", role.Namespace, role.Name)
+	assert.Equal(t, makeTestCMArgoCDRoleExpected().Data[overlayKey], revisions[0].Spec.Policies[overlayKey])
+	assert.Equal(t, int64(2), revisions[1].Spec.Trigger.Generation)
+
+	// Policy keys not written by the operator are not restored
+	cm := &corev1.ConfigMap{}
+	cmKey := types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}
+	assert.NoError(t, reconciler.Get(context.TODO(), cmKey, cm))
+	cm.Data["policy.overlay.csv"] = "p, role:overlay, applications, get, */*, allow\n"
+	assert.NoError(t, reconciler.Update(context.TODO(), cm))
+
+	// The rollback restores the first revision and pauses the role
+	rollback.Spec.Revision = revisions[0].Name
+	assert.NoError(t, reconciler.Create(context.TODO(), rollback))
+	rollbackReconciler := &ArgoCDRBACRollbackReconciler{
+		Client:   reconciler.Client,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+		History:  history,
+	}
+	rollbackReq := reconcile.Request{NamespacedName: types.NamespacedName{Name: rollback.Name, Namespace: rollback.Namespace}}
+	_, err = rollbackReconciler.Reconcile(context.TODO(), rollbackReq)
+	assert.NoError(t, err)
+
+	expected := makeTestCMArgoCDRoleExpected().Data
+	expected["policy.overlay.csv"] = "p, role:overlay, applications, get, */*, allow\n"
+	assert.NoError(t, reconciler.Get(context.TODO(), cmKey, cm))
+	assert.Equal(t, expected, cm.Data)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), rollbackReq.NamespacedName, rollback))
+	assert.Equal(t, rbacoperatorv1alpha1.RollbackPhaseCompleted, rollback.Status.Phase)
+	assert.Equal(t, []string{"ConfigMap " + testRBACCMNamespace + "/" + testRBACCMName}, rollback.Status.Restored)
+	assert.Equal(t, []string{"ArgoCDRole " + role.Namespace + "/" + role.Name}, rollback.Status.Paused)
+	assert.Contains(t, <-rollbackReconciler.Recorder.(*record.FakeRecorder).Events, eventReasonRolledBack)
+
+	// The restore is recorded as well, the oldest revisions beyond the limit are pruned
+	revisions, err = listRevisions(context.TODO(), reconciler.Client, testRBACCMNamespace)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 3)
+	assert.Equal(t, "ArgoCDRBACRollback", revisions[2].Spec.Trigger.Kind)
+
+	// The paused role does not write its additions until the annotation is removed
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.Equal(t, rollback.Namespace+"/"+rollback.Name, role.Annotations[common.AnnotationPaused])
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), cmKey, cm))
+	assert.Equal(t, expected, cm.Data)
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.Contains(t, conditionReasons(role.Status.Conditions), rbacoperatorv1alpha1.ReasonPaused)
+
+	// Revocations of the paused role are written
+	role.Spec.Rules[0].Verbs = []string{"get", "delete"}
+	role.Generation = 3
+	assert.NoError(t, reconciler.Update(context.TODO(), role))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), cmKey, cm))
+	assert.Equal(t, fmt.Sprintf("p, role:%s, applications, get, */*, allow\n", role.Name), cm.Data[overlayKey])
+
+	// A pause not listed by the rollback it names is not honored
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	role.Annotations[common.AnnotationPaused] = rollback.Namespace + "/forged"
+	assert.NoError(t, reconciler.Update(context.TODO(), role))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), cmKey, cm))
+	assert.Contains(t, cm.Data[overlayKey], fmt.Sprintf("p, role:%s, applications, delete, */*, allow", role.Name))
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.NotContains(t, conditionReasons(role.Status.Conditions), rbacoperatorv1alpha1.ReasonPaused)
+}
+
+func TestArgoCDRBACRollbackReconciler_RevisionNotFound(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	rollback := &rbacoperatorv1alpha1.ArgoCDRBACRollback{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-rollback", Namespace: testRBACCMNamespace},
+		Spec:       rbacoperatorv1alpha1.ArgoCDRBACRollbackSpec{Revision: "revision-missing"},
+	}
+
+	resObjs := []client.Object{rollback}
+	subresObjs := []client.Object{rollback}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := &ArgoCDRBACRollbackReconciler{Client: client, Scheme: scheme, Recorder: record.NewFakeRecorder(10)}
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: rollback.Name, Namespace: rollback.Namespace}}
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, rollback))
+	assert.Equal(t, rbacoperatorv1alpha1.RollbackPhaseFailed, rollback.Status.Phase)
+	assert.Contains(t, conditionReasons(rollback.Status.Conditions), rbacoperatorv1alpha1.ReasonReconcileError)
+}
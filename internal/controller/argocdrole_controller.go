@@ -22,15 +22,20 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/lint"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
 )
 
 // blank assignment to verify that RoleReconciler implements reconcile.Reconciler
@@ -43,12 +48,29 @@ type ArgoCDRoleReconciler struct {
 	Scheme                       *runtime.Scheme
 	ArgoCDRBACConfigMapName      string
 	ArgoCDRBACConfigMapNamespace string
+	// Linter reports risky grants in the PolicyRisk condition. Nothing is linted if nil.
+	Linter   *lint.Linter
+	Recorder record.EventRecorder
+	// DryRun plans the changes to the Argo CD RBAC ConfigMap and AppProjects of all objects instead of writing them.
+	DryRun bool
+	// TrustApprovals accepts the approvals of role changes, recorded by the mutating webhook.
+	TrustApprovals bool
+	// History records the policy written as ArgoCDRBACRevisions. No revisions are recorded if nil.
+	History *RevisionHistory
+	// Audit writes an audit entry for every change to the policy. Nothing is audited if nil.
+	Audit *AuditLog
+	// Notifications sends events of the changes to the policy. No events are sent if nil.
+	Notifications *Notifications
+	// SkipUnboundRoles removes the rules of roles no ArgoCDRoleBinding references from the policy instead of rendering them.
+	SkipUnboundRoles bool
 }
 
 // +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdroles,verbs=*
 // +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdroles/status,verbs=*
 // +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdroles/finalizers,verbs=*
-// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrolebindings,verbs=get;list
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrolebindings,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrbactenantpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -71,15 +93,23 @@ func (r *ArgoCDRoleReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
+	r = r.recorded(&role)
+	reconciler, plan := r.planned(&role)
+	defer func() {
+		if err := reportPlan(ctx, reconciler.Client, reconciler.Recorder, &role, &role.Status.PlannedChanges, plan); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
+		}
+	}()
+
 	if role.IsBeingDeleted() {
-		if err := r.handleFinalizer(ctx, &role); err != nil {
+		if err := reconciler.handleFinalizer(ctx, &role); err != nil {
 			if errors.IsConflict(err) {
-				r.Log.Info("Conflict while handling finalizer for ArgoCDRole", "name", req.Name)
+				reconciler.Log.Info("Conflict while handling finalizer for ArgoCDRole", "name", req.Name)
 				return ctrl.Result{Requeue: true, RequeueAfter: time.Second}, nil
 			}
 			role.SetConditions(rbacoperatorv1alpha1.Deleting().WithMessage(err.Error()))
-			if err := r.Client.Status().Update(ctx, &role); err != nil {
-				r.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
+			if err := reconciler.Client.Status().Update(ctx, &role); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
 			}
 			return ctrl.Result{}, fmt.Errorf("error when handling finalizer: %v", err)
 		}
@@ -87,27 +117,79 @@ func (r *ArgoCDRoleReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 
 	if !role.HasFinalizer(rbacoperatorv1alpha1.ArgoCDRoleFinalizerName) {
-		if err := r.addFinalizer(ctx, &role); err != nil {
+		if err := reconciler.addFinalizer(ctx, &role); err != nil {
 			role.SetConditions(rbacoperatorv1alpha1.Deleting().WithMessage(err.Error()))
-			if err := r.Client.Status().Update(ctx, &role); err != nil {
-				r.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
+			if err := reconciler.Client.Status().Update(ctx, &role); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
 			}
 			return ctrl.Result{}, fmt.Errorf("error when adding finalizer: %v", err)
 		}
 		return ctrl.Result{}, nil
 	}
 
-	cm := newConfigMap(r.ArgoCDRBACConfigMapName, r.ArgoCDRBACConfigMapNamespace)
+	var pause *rbacoperatorv1alpha1.Condition
+	if condition, paused, err := pausedCondition(ctx, reconciler.Client, &role); err != nil {
+		role.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := reconciler.Client.Status().Update(ctx, &role); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
+		}
+		return ctrl.Result{}, err
+	} else if paused {
+		reconciler.Log.Info("ArgoCDRole is paused, only writing revocations", "name", req.Name)
+		reconciler = reconciler.revokeOnly()
+		pause = &condition
+	}
+
+	cm := newConfigMap(reconciler.ArgoCDRBACConfigMapName, reconciler.ArgoCDRBACConfigMapNamespace)
 
-	r.Log.Info("Checking if ConfigMap exists")
-	if !IsObjectFound(r.Client, cm.Namespace, cm.Name, cm) {
+	reconciler.Log.Info("Checking if ConfigMap exists")
+	if !IsObjectFound(reconciler.Client, cm.Namespace, cm.Name, cm) {
 		role.SetConditions(rbacoperatorv1alpha1.Pending(fmt.Errorf("ConfigMap %s not found", cm.Name)))
-		if err := r.Client.Status().Update(ctx, &role); err != nil {
-			r.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
+		if err := reconciler.Client.Status().Update(ctx, &role); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
 		}
 		return ctrl.Result{}, fmt.Errorf("ConfigMap not found")
 	}
 
+	stageRoleSpec(reconciler.Recorder, &role, reconciler.TrustApprovals)
+
+	format, err := policy.LoadObjectFormat(ctx, reconciler.Client, reconciler.ArgoCDRBACConfigMapNamespace)
+	if err != nil {
+		role.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := reconciler.Client.Status().Update(ctx, &role); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
+		}
+		return ctrl.Result{}, err
+	}
+	if err := renderRoleObjects(format, &role); err != nil {
+		// Not retried before the next resync, the objects are fixed by a change of the spec or of the application namespaces
+		role.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := reconciler.Client.Status().Update(ctx, &role); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
+		}
+		return ctrl.Result{RequeueAfter: time.Minute * 10}, nil
+	}
+
+	policies, err := policy.LoadTenantPolicies(ctx, reconciler.Client, role.Namespace)
+	if err != nil {
+		role.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := reconciler.Client.Status().Update(ctx, &role); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
+		}
+		return ctrl.Result{}, err
+	}
+	enforceRoleTenantPolicies(policies, &role)
+	lintRole(reconciler.Linter, &role)
+	bindings := rbacoperatorv1alpha1.ArgoCDRoleBindingList{}
+	if err := reconciler.List(ctx, &bindings, client.InNamespace(role.Namespace)); err != nil {
+		role.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := reconciler.Client.Status().Update(ctx, &role); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
+		}
+		return ctrl.Result{}, err
+	}
+	boundRole(&role, bindings.Items, reconciler.SkipUnboundRoles)
+
 	if role.HasArgoCDRoleBindingRef() {
 		var rb rbacoperatorv1alpha1.ArgoCDRoleBinding
 
@@ -115,64 +197,90 @@ func (r *ArgoCDRoleReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			Name:      role.Status.ArgoCDRoleBindingRef,
 			Namespace: req.Namespace,
 		}
-		if err := r.Get(ctx, typeNamespacedNameRoleBinding, &rb); err != nil {
+		if err := reconciler.Get(ctx, typeNamespacedNameRoleBinding, &rb); err != nil {
 			if errors.IsNotFound(err) {
-				r.Log.Info("ArgoCDRoleBinding not found.", "name", role.Status.ArgoCDRoleBindingRef)
+				reconciler.Log.Info("ArgoCDRoleBinding not found.", "name", role.Status.ArgoCDRoleBindingRef)
 				role.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
-				if err := r.Client.Status().Update(ctx, &role); err != nil {
-					r.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
+				if err := reconciler.Client.Status().Update(ctx, &role); err != nil {
+					reconciler.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
 				}
 				return ctrl.Result{}, err
 			}
 		}
+		if condition, paused, err := pausedCondition(ctx, reconciler.Client, &rb); err != nil {
+			role.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+			if err := reconciler.Client.Status().Update(ctx, &role); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
+			}
+			return ctrl.Result{}, err
+		} else if paused && pause == nil {
+			reconciler.Log.Info("ArgoCDRole is paused, only writing revocations", "name", req.Name)
+			reconciler = reconciler.revokeOnly()
+			pause = &condition
+		}
+		// The lines of the binding are written with the role, they are only planned if it is in dry-run mode
+		if plan == nil {
+			reconciler, plan = reconciler.planned(&rb)
+		}
+		enforceRoleBindingTenantPolicies(policies, &rb)
+		graph, err := policy.LoadRoleGraph(ctx, reconciler.Client)
+		if err != nil {
+			role.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+			if err := reconciler.Client.Status().Update(ctx, &role); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
+			}
+			return ctrl.Result{}, err
+		}
+		resolveRoleSubjects(graph, &rb)
 
-		r.Log.Info("Reconciling RBAC ConfigMap")
-		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		reconciler.Log.Info("Reconciling RBAC ConfigMap")
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
 			// Fetch the latest version of the ConfigMap
-			cm := newConfigMap(r.ArgoCDRBACConfigMapName, r.ArgoCDRBACConfigMapNamespace)
-			if err := r.Get(ctx, client.ObjectKeyFromObject(cm), cm); err != nil {
+			cm := newConfigMap(reconciler.ArgoCDRBACConfigMapName, reconciler.ArgoCDRBACConfigMapNamespace)
+			if err := reconciler.Get(ctx, client.ObjectKeyFromObject(cm), cm); err != nil {
 				return err
 			}
-			return r.reconcileRBACConfigMapWithRoleBinding(cm, &role, &rb)
+			return reconciler.reconcileRBACConfigMapWithRoleBinding(cm, &role, &rb)
 		})
 
 		if err != nil {
 			role.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
-			if updateErr := r.Client.Status().Update(ctx, &role); updateErr != nil {
-				r.Log.Error(updateErr, "Failed to update ArgoCDRole status", "name", req.Name)
+			if updateErr := reconciler.Client.Status().Update(ctx, &role); updateErr != nil {
+				reconciler.Log.Error(updateErr, "Failed to update ArgoCDRole status", "name", req.Name)
 			}
 			return ctrl.Result{}, err
 		}
 
-		role.SetConditions(rbacoperatorv1alpha1.ReconcileSuccess().WithObservedGeneration(role.GetGeneration()))
-		if err := r.Client.Status().Update(ctx, &role); err != nil {
-			r.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
+		role.SetConditions(syncedCondition(pause, role.GetGeneration()))
+		if err := reconciler.Client.Status().Update(ctx, &role); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
 		}
-		return ctrl.Result{RequeueAfter: time.Minute * 10}, nil
+		// The policy rendered with the binding changes at every window boundary of the binding
+		return ctrl.Result{RequeueAfter: roleBindingRequeueAfter(&rb, timeNow(), time.Minute*10)}, nil
 	}
 
-	r.Log.Info("Reconciling RBAC ConfigMap")
-	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+	reconciler.Log.Info("Reconciling RBAC ConfigMap")
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		// Fetch the latest version of the ConfigMap
-		cm := newConfigMap(r.ArgoCDRBACConfigMapName, r.ArgoCDRBACConfigMapNamespace)
-		if err := r.Get(ctx, client.ObjectKeyFromObject(cm), cm); err != nil {
+		cm := newConfigMap(reconciler.ArgoCDRBACConfigMapName, reconciler.ArgoCDRBACConfigMapNamespace)
+		if err := reconciler.Get(ctx, client.ObjectKeyFromObject(cm), cm); err != nil {
 			return err
 		}
 
-		return r.reconcileRBACConfigMap(cm, &role)
+		return reconciler.reconcileRBACConfigMap(cm, &role)
 	})
 
 	if err != nil {
 		role.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
-		if updateErr := r.Client.Status().Update(ctx, &role); updateErr != nil {
-			r.Log.Error(updateErr, "Failed to update ArgoCDRole status", "name", req.Name)
+		if updateErr := reconciler.Client.Status().Update(ctx, &role); updateErr != nil {
+			reconciler.Log.Error(updateErr, "Failed to update ArgoCDRole status", "name", req.Name)
 		}
 		return ctrl.Result{}, err
 	}
 
-	role.SetConditions(rbacoperatorv1alpha1.ReconcileSuccess().WithObservedGeneration(role.GetGeneration()))
-	if err := r.Client.Status().Update(ctx, &role); err != nil {
-		r.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
+	role.SetConditions(syncedCondition(pause, role.GetGeneration()))
+	if err := reconciler.Client.Status().Update(ctx, &role); err != nil {
+		reconciler.Log.Error(err, "Failed to update ArgoCDRole status", "name", req.Name)
 	}
 	return ctrl.Result{RequeueAfter: time.Minute * 10}, nil
 }
@@ -181,5 +289,24 @@ func (r *ArgoCDRoleReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 func (r *ArgoCDRoleReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&rbacoperatorv1alpha1.ArgoCDRole{}).
+		Watches(&rbacoperatorv1alpha1.ArgoCDRBACTenantPolicy{}, handler.EnqueueRequestsFromMapFunc(mapTenantPolicyToObjects(r.Client,
+			func() client.ObjectList { return &rbacoperatorv1alpha1.ArgoCDRoleList{} }))).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.mapRBACConfigMapToRoles)).
+		Watches(&rbacoperatorv1alpha1.ArgoCDRoleBinding{}, handler.EnqueueRequestsFromMapFunc(mapRoleBindingToRole)).
 		Complete(r)
 }
+
+// mapRoleBindingToRole enqueues the ArgoCDRole referenced by the ArgoCDRoleBinding, so that bindings created, changed
+// or deleted are reflected in the Bound condition of the role.
+func mapRoleBindingToRole(_ context.Context, obj client.Object) []reconcile.Request {
+	rb, ok := obj.(*rbacoperatorv1alpha1.ArgoCDRoleBinding)
+	if !ok || isBuiltInRole(rb.Spec.ArgoCDRoleRef.Name) {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{
+			Name:      rb.Spec.ArgoCDRoleRef.Name,
+			Namespace: rb.Namespace,
+		},
+	}}
+}
@@ -18,17 +18,24 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/audit"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/lint"
 )
 
 var _ reconcile.Reconciler = &ArgoCDRoleReconciler{}
@@ -222,6 +229,110 @@ func TestArgoCDRoleReconciler_RoleHasRoleBinding(t *testing.T) {
 	assert.NoError(t, err)
 	resCM := makeTestCM_ArgoCDRole_WithRoleBindingRoleSubject_Expected()
 	assert.Equal(t, resCM.Data, cm.Data)
+
+	role := &rbacoperatorv1alpha1.ArgoCDRole{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.Contains(t, conditionReasons(role.Status.Conditions), rbacoperatorv1alpha1.ReasonBound)
+}
+
+func TestArgoCDRoleReconciler_ApplicationObjects(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	argocdRole := makeTestRole(addFinalizerRole(), func(r *rbacoperatorv1alpha1.ArgoCDRole) {
+		r.Spec.Rules = []rbacoperatorv1alpha1.GlobalRule{{Resource: "logs", Verbs: []string{"get"}, ApplicationObjects: []rbacoperatorv1alpha1.ApplicationObject{
+			{Project: "team-a", Name: "guestbook-?"},
+			{Project: "team-a", Namespace: "team-a-apps", Name: "guestbook"},
+		}}}
+	})
+
+	resObjs := []client.Object{argocdRole}
+	subresObjs := []client.Object{argocdRole}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDRoleReconciler(client, scheme)
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestArgoCDNamespace()))
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestRBACConfigMap()))
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: argocdRole.Name, Namespace: argocdRole.Namespace}}
+	overlayKey := fmt.Sprintf("policy.%s.%s.csv", argocdRole.Namespace, argocdRole.Name)
+
+	// Applications in other namespaces can't be matched without applications in any namespace
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.NotContains(t, cm.Data, overlayKey)
+	role := &rbacoperatorv1alpha1.ArgoCDRole{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.Contains(t, conditionReasons(role.Status.Conditions), rbacoperatorv1alpha1.ReasonReconcileError)
+
+	// And are rendered with their namespace once enabled, objects in the namespace of Argo CD not matching the others
+	params := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "argocd-cmd-params-cm", Namespace: testRBACCMNamespace},
+		Data:       map[string]string{"application.namespaces": "team-*"},
+	}
+	assert.NoError(t, reconciler.Create(context.TODO(), params))
+	assert.Equal(t, []reconcile.Request{req}, reconciler.mapRBACConfigMapToRoles(context.TODO(), params))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, fmt.Sprintf("p, role:%[1]s, logs, get, team-a/guestbook-[!/], allow\np, role:%[1]s, logs, get, team-a/team-a-apps/guestbook, allow\n", testRoleName),
+		cm.Data[overlayKey])
+}
+
+func TestArgoCDRoleReconciler_SkipUnboundRoles(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	argocdRole := makeTestRole(addFinalizerRole())
+
+	resObjs := []client.Object{argocdRole}
+	subresObjs := []client.Object{argocdRole}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDRoleReconciler(client, scheme)
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestArgoCDNamespace()))
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestRBACConfigMap()))
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: argocdRole.Name, Namespace: argocdRole.Namespace}}
+	overlayKey := fmt.Sprintf("policy.%s.%s.csv", argocdRole.Namespace, argocdRole.Name)
+
+	// Unbound roles are rendered by default
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Contains(t, cm.Data, overlayKey)
+
+	role := &rbacoperatorv1alpha1.ArgoCDRole{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.Contains(t, conditionReasons(role.Status.Conditions), rbacoperatorv1alpha1.ReasonUnbound)
+	assert.Equal(t, float64(1), testutil.ToFloat64(unboundResources.WithLabelValues("ArgoCDRole", role.Namespace, role.Name)))
+
+	// And removed from the policy if skipped
+	reconciler.SkipUnboundRoles = true
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.NotContains(t, cm.Data, overlayKey)
+	assert.Equal(t, getDefaultRBACPolicy(), cm.Data["policy.csv"])
+
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	for _, condition := range role.Status.Conditions {
+		if condition.Type == rbacoperatorv1alpha1.TypeBound {
+			assert.Equal(t, "no ArgoCDRoleBinding references the role, its rules are not rendered", condition.Message)
+		}
+	}
+
+	// Any binding referencing the role binds it, also one rendering into its own key
+	argocdRoleBinding := makeTestRoleBindingWithSSOSubject()
+	assert.NoError(t, reconciler.Create(context.TODO(), argocdRoleBinding))
+	assert.Equal(t, []reconcile.Request{req}, mapRoleBindingToRole(context.TODO(), argocdRoleBinding))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.Empty(t, role.Status.ArgoCDRoleBindingRef)
+	assert.Contains(t, conditionReasons(role.Status.Conditions), rbacoperatorv1alpha1.ReasonBound)
+	assert.Equal(t, float64(0), testutil.ToFloat64(unboundResources.WithLabelValues("ArgoCDRole", role.Namespace, role.Name)))
 }
 
 func TestArgoCDRoleReconciler_RoleBindingObjectMissing(t *testing.T) {
@@ -252,3 +363,379 @@ func TestArgoCDRoleReconciler_RoleBindingObjectMissing(t *testing.T) {
 		t.Fatalf("reconcile requeued request after %s", res.RequeueAfter)
 	}
 }
+
+func TestArgoCDRoleReconciler_PolicyRisk(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	argocdRole := makeTestRole(addFinalizerRole(), func(r *rbacoperatorv1alpha1.ArgoCDRole) {
+		r.Spec.Rules = append(r.Spec.Rules, rbacoperatorv1alpha1.GlobalRule{Resource: "exec", Verbs: []string{"*"}, Objects: []string{"*/*"}})
+	})
+
+	resObjs := []client.Object{argocdRole}
+	subresObjs := []client.Object{argocdRole}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDRoleReconciler(client, scheme)
+	linter, err := lint.New(lint.Config{})
+	assert.NoError(t, err)
+	reconciler.Linter = linter
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestArgoCDNamespace()))
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestRBACConfigMap()))
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: argocdRole.Name, Namespace: argocdRole.Namespace}}
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	role := &rbacoperatorv1alpha1.ArgoCDRole{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.Contains(t, conditionReasons(role.Status.Conditions), rbacoperatorv1alpha1.ReasonRiskFound)
+	for _, condition := range role.Status.Conditions {
+		if condition.Type == rbacoperatorv1alpha1.TypePolicyRisk {
+			assert.Equal(t, "critical exec-all-applications: exec * */* grants a shell in the pods of all applications (2 findings)", condition.Message)
+		}
+	}
+	assert.Equal(t, float64(lint.SeverityCritical.Rank()), testutil.ToFloat64(policyRiskSeverity.WithLabelValues("ArgoCDRole", role.Namespace, role.Name)))
+
+	// The risk is cleared once the rule is removed
+	role.Spec.Rules = role.Spec.Rules[:1]
+	assert.NoError(t, reconciler.Update(context.TODO(), role))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.Contains(t, conditionReasons(role.Status.Conditions), rbacoperatorv1alpha1.ReasonNoRisk)
+	assert.Equal(t, float64(0), testutil.ToFloat64(policyRiskSeverity.WithLabelValues("ArgoCDRole", role.Namespace, role.Name)))
+}
+
+func TestArgoCDRoleReconciler_DryRun(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	argocdRole := makeTestRole(addFinalizerRole(), func(r *rbacoperatorv1alpha1.ArgoCDRole) {
+		r.Annotations = map[string]string{common.AnnotationDryRun: "true"}
+	})
+
+	resObjs := []client.Object{argocdRole}
+	subresObjs := []client.Object{argocdRole}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDRoleReconciler(client, scheme)
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestArgoCDNamespace()))
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestRBACConfigMap()))
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: argocdRole.Name, Namespace: argocdRole.Namespace}}
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	// The ConfigMap is not written, the changes are planned instead
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, makeTestRBACConfigMap().Data, cm.Data)
+
+	role := &rbacoperatorv1alpha1.ArgoCDRole{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	overlayKey := fmt.Sprintf("policy.%s.%s.csv", role.Namespace, role.Name)
+	assert.Contains(t, role.Status.PlannedChanges, fmt.Sprintf("ConfigMap %s/%s %s: +p, role:%s, applications, get, */*, allow",
+		testRBACCMNamespace, testRBACCMName, overlayKey, role.Name))
+	assert.Contains(t, <-reconciler.Recorder.(*record.FakeRecorder).Events, eventReasonDryRun)
+
+	// The changes are written and the plan cleared once the annotation is removed
+	role.Annotations = nil
+	assert.NoError(t, reconciler.Update(context.TODO(), role))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, makeTestCMArgoCDRoleExpected().Data, cm.Data)
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.Empty(t, role.Status.PlannedChanges)
+
+	// Deleting the role in dry-run mode plans the removal and keeps the finalizer
+	role.Annotations = map[string]string{common.AnnotationDryRun: "true"}
+	assert.NoError(t, reconciler.Update(context.TODO(), role))
+	assert.NoError(t, reconciler.Delete(context.TODO(), role))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, makeTestCMArgoCDRoleExpected().Data, cm.Data)
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.Contains(t, role.Status.PlannedChanges, fmt.Sprintf("ConfigMap %s/%s %s: -p, role:%s, applications, get, */*, allow",
+		testRBACCMNamespace, testRBACCMName, overlayKey, role.Name))
+
+	// The role is deleted once the annotation is removed
+	role.Annotations = nil
+	assert.NoError(t, reconciler.Update(context.TODO(), role))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.False(t, IsObjectFound(reconciler.Client, role.Namespace, role.Name, &rbacoperatorv1alpha1.ArgoCDRole{}))
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.NotContains(t, cm.Data, overlayKey)
+}
+
+func TestArgoCDRoleReconciler_DryRunBound(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	argocdRole := makeTestRole(addFinalizerRole(), func(r *rbacoperatorv1alpha1.ArgoCDRole) {
+		r.Annotations = map[string]string{common.AnnotationDryRun: "true"}
+	})
+	argocdRoleBinding := makeTestRoleBindingWithSSOSubject(addFinalizerRoleBinding())
+
+	resObjs := []client.Object{argocdRole, argocdRoleBinding}
+	subresObjs := []client.Object{argocdRole, argocdRoleBinding}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDRoleReconciler(client, scheme)
+	bindingReconciler := makeTestArgoCDRoleBindingReconciler(client, scheme)
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestArgoCDNamespace()))
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestRBACConfigMap()))
+
+	// The binding doesn't write the rules of a role in dry-run mode, it plans them
+	bindingReq := reconcile.Request{NamespacedName: types.NamespacedName{Name: argocdRoleBinding.Name, Namespace: argocdRoleBinding.Namespace}}
+	_, err := bindingReconciler.Reconcile(context.TODO(), bindingReq)
+	assert.NoError(t, err)
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, makeTestRBACConfigMap().Data, cm.Data)
+	rb := &rbacoperatorv1alpha1.ArgoCDRoleBinding{}
+	assert.NoError(t, reconciler.Get(context.TODO(), bindingReq.NamespacedName, rb))
+	assert.NotEmpty(t, rb.Status.PlannedChanges)
+
+	// Neither does the role of a binding in dry-run mode
+	role := &rbacoperatorv1alpha1.ArgoCDRole{}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: argocdRole.Name, Namespace: argocdRole.Namespace}}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	role.Annotations = nil
+	assert.NoError(t, reconciler.Update(context.TODO(), role))
+	role.SetArgoCDRoleBindingRef(rb.Name)
+	assert.NoError(t, reconciler.Status().Update(context.TODO(), role))
+	rb.Annotations = map[string]string{common.AnnotationDryRun: "true"}
+	assert.NoError(t, reconciler.Update(context.TODO(), rb))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, makeTestRBACConfigMap().Data, cm.Data)
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.NotEmpty(t, role.Status.PlannedChanges)
+}
+
+func TestArgoCDRoleReconciler_ApprovalGate(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	argocdRole := makeTestRole(addFinalizerRole(), func(r *rbacoperatorv1alpha1.ArgoCDRole) {
+		r.Labels = map[string]string{common.LabelRequireApproval: "true"}
+		r.Annotations = map[string]string{common.AnnotationChangedBy: "alice"}
+		r.Generation = 1
+	})
+
+	resObjs := []client.Object{argocdRole}
+	subresObjs := []client.Object{argocdRole}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDRoleReconciler(client, scheme)
+	reconciler.TrustApprovals = true
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestArgoCDNamespace()))
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestRBACConfigMap()))
+
+	// A role created with the label renders no rules until approved
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: argocdRole.Name, Namespace: argocdRole.Namespace}}
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	overlayKey := fmt.Sprintf("policy.%s.%s.csv", argocdRole.Namespace, argocdRole.Name)
+	cm := &corev1.ConfigMap{}
+	cmKey := types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}
+	assert.NoError(t, reconciler.Get(context.TODO(), cmKey, cm))
+	assert.NotContains(t, cm.Data[overlayKey], "allow")
+
+	role := &rbacoperatorv1alpha1.ArgoCDRole{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.Nil(t, role.Status.AppliedSpec)
+	assert.Len(t, role.Status.PendingChanges, 2)
+	assert.Contains(t, conditionReasons(role.Status.Conditions), rbacoperatorv1alpha1.ReasonApprovalPending)
+	assert.Contains(t, <-reconciler.Recorder.(*record.FakeRecorder).Events, eventReasonApprovalPending)
+
+	// The last editor can't approve the generation
+	role.Annotations[common.AnnotationApprovedGeneration] = "1"
+	role.Annotations[common.AnnotationApprovedGenerationBy] = "alice"
+	assert.NoError(t, reconciler.Update(context.TODO(), role))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), cmKey, cm))
+	assert.NotContains(t, cm.Data[overlayKey], "allow")
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	for _, c := range role.Status.Conditions {
+		if c.Type == rbacoperatorv1alpha1.TypeApproved {
+			assert.Contains(t, c.Message, "alice made the last change and can't approve it")
+		}
+	}
+
+	// Approvals are not accepted without the webhooks
+	role.Annotations[common.AnnotationApprovedGenerationBy] = "bob"
+	assert.NoError(t, reconciler.Update(context.TODO(), role))
+	reconciler.TrustApprovals = false
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), cmKey, cm))
+	assert.NotContains(t, cm.Data[overlayKey], "allow")
+
+	// The generation is applied once approved by someone else
+	reconciler.TrustApprovals = true
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), cmKey, cm))
+	assert.Equal(t, makeTestCMArgoCDRoleExpected().Data, cm.Data)
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.Equal(t, int64(1), role.Status.AppliedGeneration)
+	assert.NotNil(t, role.Status.AppliedSpec)
+	assert.Contains(t, conditionReasons(role.Status.Conditions), rbacoperatorv1alpha1.ReasonApproved)
+	assert.Contains(t, <-reconciler.Recorder.(*record.FakeRecorder).Events, eventReasonChangeApproved)
+
+	// A widening change is staged until approved, an approval of another generation does not apply it
+	role.Spec.Rules[0].Verbs = append(role.Spec.Rules[0].Verbs, "delete")
+	role.Generation = 2
+	assert.NoError(t, reconciler.Update(context.TODO(), role))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), cmKey, cm))
+	assert.Equal(t, makeTestCMArgoCDRoleExpected().Data, cm.Data)
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.Equal(t, int64(1), role.Status.AppliedGeneration)
+	assert.Equal(t, []string{fmt.Sprintf("+p, role:%s, applications, delete, */*, allow", role.Name)}, role.Status.PendingChanges)
+	assert.Contains(t, conditionReasons(role.Status.Conditions), rbacoperatorv1alpha1.ReasonApprovalPending)
+	assert.Contains(t, <-reconciler.Recorder.(*record.FakeRecorder).Events, eventReasonApprovalPending)
+
+	// The change is applied once the generation is approved
+	role.Annotations[common.AnnotationApprovedGeneration] = "2"
+	assert.NoError(t, reconciler.Update(context.TODO(), role))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), cmKey, cm))
+	assert.Contains(t, cm.Data[overlayKey], fmt.Sprintf("p, role:%s, applications, delete, */*, allow", role.Name))
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.Equal(t, int64(2), role.Status.AppliedGeneration)
+	assert.Empty(t, role.Status.PendingChanges)
+	assert.Contains(t, conditionReasons(role.Status.Conditions), rbacoperatorv1alpha1.ReasonApproved)
+	assert.Contains(t, <-reconciler.Recorder.(*record.FakeRecorder).Events, eventReasonChangeApproved)
+
+	// Removing the label keeps the gate on until the removal is approved, the webhook drops the old approval
+	delete(role.Labels, common.LabelRequireApproval)
+	delete(role.Annotations, common.AnnotationApprovedGeneration)
+	delete(role.Annotations, common.AnnotationApprovedGenerationBy)
+	role.Spec.Rules[0].Verbs = append(role.Spec.Rules[0].Verbs, "sync")
+	role.Generation = 3
+	assert.NoError(t, reconciler.Update(context.TODO(), role))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), cmKey, cm))
+	assert.NotContains(t, cm.Data[overlayKey], "sync")
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.Equal(t, int64(2), role.Status.AppliedGeneration)
+	assert.Contains(t, conditionReasons(role.Status.Conditions), rbacoperatorv1alpha1.ReasonApprovalPending)
+	assert.Contains(t, <-reconciler.Recorder.(*record.FakeRecorder).Events, eventReasonApprovalPending)
+
+	// The approved removal turns the gate off
+	role.Annotations[common.AnnotationApprovedGeneration] = "3"
+	role.Annotations[common.AnnotationApprovedGenerationBy] = "bob"
+	assert.NoError(t, reconciler.Update(context.TODO(), role))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), cmKey, cm))
+	assert.Contains(t, cm.Data[overlayKey], fmt.Sprintf("p, role:%s, applications, sync, */*, allow", role.Name))
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	assert.Equal(t, int64(3), role.Status.AppliedGeneration)
+	assert.Nil(t, role.Status.AppliedSpec)
+}
+
+// testAuditSink collects the audit entries written.
+type testAuditSink struct {
+	entries []audit.Entry
+	err     error
+}
+
+func (s *testAuditSink) Write(entry audit.Entry) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func TestArgoCDRoleReconciler_AuditLog(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	argocdRole := makeTestRole(addFinalizerRole(), func(r *rbacoperatorv1alpha1.ArgoCDRole) {
+		r.Annotations = map[string]string{common.AnnotationChangedBy: "alice"}
+		r.ManagedFields = []metav1.ManagedFieldsEntry{
+			{Manager: "kubectl-edit", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:rules":{}}}`)}},
+			{Manager: "rbac-operator", Subresource: "status", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:status":{}}`)}},
+		}
+	})
+
+	resObjs := []client.Object{argocdRole}
+	subresObjs := []client.Object{argocdRole}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDRoleReconciler(client, scheme)
+	sink := &testAuditSink{}
+	reconciler.Audit = &AuditLog{Sink: sink, TrustChangedBy: true}
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestArgoCDNamespace()))
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestRBACConfigMap()))
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: argocdRole.Name, Namespace: argocdRole.Namespace}}
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	overlayKey := fmt.Sprintf("policy.%s.%s.csv", argocdRole.Namespace, argocdRole.Name)
+	lines := policyLines(makeTestCMArgoCDRoleExpected().Data[overlayKey])
+	assert.Len(t, sink.entries, 1)
+	entry := sink.entries[0]
+	assert.Equal(t, "alice", entry.Actor)
+	assert.Equal(t, audit.ActorSourceWebhook, entry.ActorSource)
+	assert.Equal(t, audit.Resource{Kind: "ArgoCDRole", Namespace: argocdRole.Namespace, Name: argocdRole.Name}, entry.Resource)
+	assert.Equal(t, fmt.Sprintf("ConfigMap %s/%s", testRBACCMNamespace, testRBACCMName), entry.Target)
+	assert.Equal(t, []audit.Change{{Key: overlayKey, Before: []string{}, After: lines}}, entry.Changes)
+	assert.Equal(t, []string{"role:" + argocdRole.Name}, entry.Subjects)
+
+	// Reconciliations not changing the policy are not audited
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Len(t, sink.entries, 1)
+
+	// Without the webhooks the annotation is not trusted, the actor is the field manager of the spec
+	reconciler.Audit.TrustChangedBy = false
+	role := &rbacoperatorv1alpha1.ArgoCDRole{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, role))
+	role.Spec.Rules[0].Verbs = []string{"sync"}
+	role.Generation = 2
+	assert.NoError(t, reconciler.Update(context.TODO(), role))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Len(t, sink.entries, 2)
+	entry = sink.entries[1]
+	assert.Equal(t, "kubectl-edit", entry.Actor)
+	assert.Equal(t, audit.ActorSourceManagedFields, entry.ActorSource)
+	assert.Equal(t, lines, entry.Changes[0].Before)
+
+	// Changes of a reconciled generation are not attributed to the user that last changed the spec
+	cm := &corev1.ConfigMap{}
+	cmKey := types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}
+	assert.NoError(t, reconciler.Get(context.TODO(), cmKey, cm))
+	delete(cm.Data, overlayKey)
+	assert.NoError(t, reconciler.Update(context.TODO(), cm))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Len(t, sink.entries, 3)
+	assert.Empty(t, sink.entries[2].Actor)
+	assert.Equal(t, audit.ActorSourceReconcile, sink.entries[2].ActorSource)
+
+	// The policy is not written if its audit entry can't be written
+	sink.err = fmt.Errorf("sink unavailable")
+	assert.NoError(t, reconciler.Get(context.TODO(), cmKey, cm))
+	delete(cm.Data, overlayKey)
+	assert.NoError(t, reconciler.Update(context.TODO(), cm))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.Error(t, err)
+	assert.NoError(t, reconciler.Get(context.TODO(), cmKey, cm))
+	assert.NotContains(t, cm.Data, overlayKey)
+}
@@ -19,18 +19,24 @@ package controller
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 
 	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
 	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/lint"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
 )
 
 // ArgoCDRoleBindingReconciler reconciles a ArgoCDRoleBinding object
@@ -40,14 +46,35 @@ type ArgoCDRoleBindingReconciler struct {
 	Scheme                       *runtime.Scheme
 	ArgoCDRBACConfigMapName      string
 	ArgoCDRBACConfigMapNamespace string
+	Recorder                     record.EventRecorder
+	// BreakGlassMaxDuration is the longest time a break-glass role can be bound for.
+	BreakGlassMaxDuration time.Duration
+	// BreakGlassAdmin makes the built-in admin role a break-glass role.
+	BreakGlassAdmin bool
+	// Linter reports risky grants in the PolicyRisk condition. Nothing is linted if nil.
+	Linter *lint.Linter
+	// TrustAttestations takes the attester from the attested-by annotation. Only set if the mutating webhooks are served,
+	// otherwise attestations are ignored.
+	TrustAttestations bool
+	// DryRun plans the changes to the Argo CD RBAC ConfigMap and AppProjects of all objects instead of writing them.
+	DryRun bool
+	// History records the policy written as ArgoCDRBACRevisions. No revisions are recorded if nil.
+	History *RevisionHistory
+	// Audit writes an audit entry for every change to the policy. Nothing is audited if nil.
+	Audit *AuditLog
+	// Notifications sends events of the changes to the policy. No events are sent if nil.
+	Notifications *Notifications
 }
 
 // +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrolebindings,verbs=*
 // +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrolebindings/status,verbs=*
 // +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrolebindings/finalizers,verbs=*
-// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdroles,verbs=get;list
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdroles,verbs=get;list;watch
 // +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdroles/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrecertificationpolicies,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrbactenantpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -69,15 +96,23 @@ func (r *ArgoCDRoleBindingReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		return ctrl.Result{}, err
 	}
 
+	r = r.recorded(&rb)
+	reconciler, plan := r.planned(&rb)
+	defer func() {
+		if err := reportPlan(ctx, reconciler.Client, reconciler.Recorder, &rb, &rb.Status.PlannedChanges, plan); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+		}
+	}()
+
 	if rb.IsBeingDeleted() {
-		if err := r.handleFinalizer(ctx, &rb); err != nil {
+		if err := reconciler.handleFinalizer(ctx, &rb); err != nil {
 			if errors.IsConflict(err) {
-				r.Log.Info("Conflict while handling finalizer, requeuing ArgoCDRoleBinding", "name", req.Name)
+				reconciler.Log.Info("Conflict while handling finalizer, requeuing ArgoCDRoleBinding", "name", req.Name)
 				return ctrl.Result{RequeueAfter: time.Second}, nil
 			}
 			rb.SetConditions(rbacoperatorv1alpha1.Deleting().WithMessage(err.Error()))
-			if err := r.Client.Status().Update(ctx, &rb); err != nil {
-				r.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+			if err := reconciler.Client.Status().Update(ctx, &rb); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
 			}
 			return ctrl.Result{}, fmt.Errorf("error when handling finalizer: %v", err)
 		}
@@ -85,23 +120,77 @@ func (r *ArgoCDRoleBindingReconciler) Reconcile(ctx context.Context, req ctrl.Re
 	}
 
 	if !rb.HasFinalizer(rbacoperatorv1alpha1.ArgoCDRoleBindingFinalizerName) {
-		if err := r.addFinalizer(ctx, &rb); err != nil {
+		if err := reconciler.addFinalizer(ctx, &rb); err != nil {
 			rb.SetConditions(rbacoperatorv1alpha1.Deleting().WithMessage(err.Error()))
-			if err := r.Client.Status().Update(ctx, &rb); err != nil {
-				r.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+			if err := reconciler.Client.Status().Update(ctx, &rb); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
 			}
 			return ctrl.Result{}, fmt.Errorf("error when adding finalizer: %v", err)
 		}
+		reconciler.Notifications.bindingCreated("ArgoCDRoleBinding", &rb, rb.Spec.ArgoCDRoleRef.Name, roleBindingSubjects(&rb))
+		return ctrl.Result{}, nil
+	}
+
+	var pause *rbacoperatorv1alpha1.Condition
+	if condition, paused, err := pausedCondition(ctx, reconciler.Client, &rb); err != nil {
+		rb.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := reconciler.Client.Status().Update(ctx, &rb); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+		}
+		return ctrl.Result{}, err
+	} else if paused {
+		reconciler.Log.Info("ArgoCDRoleBinding is paused, only writing revocations", "name", req.Name)
+		reconciler = reconciler.revokeOnly()
+		pause = &condition
+	}
+
+	if err := validateSchedules(rb.Spec.Schedules, timeNow()); err != nil {
+		reconciler.Log.Info("Invalid schedule", "name", req.Name, "reason", err.Error())
+		rb.SetConditions(rbacoperatorv1alpha1.Pending(err))
+		if err := reconciler.Client.Status().Update(ctx, &rb); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+		}
 		return ctrl.Result{}, nil
 	}
 
-	cm := newConfigMap(r.ArgoCDRBACConfigMapName, r.ArgoCDRBACConfigMapNamespace)
+	now := timeNow()
+	if err := reconciler.reconcileRecertification(ctx, &rb, now); err != nil {
+		rb.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := reconciler.Client.Status().Update(ctx, &rb); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+		}
+		return ctrl.Result{}, err
+	}
+
+	policies, err := policy.LoadTenantPolicies(ctx, reconciler.Client, rb.Namespace)
+	if err != nil {
+		rb.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := reconciler.Client.Status().Update(ctx, &rb); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+		}
+		return ctrl.Result{}, err
+	}
+	enforceRoleBindingTenantPolicies(policies, &rb)
+	lintRoleBinding(reconciler.Linter, &rb)
+
+	graph, err := policy.LoadRoleGraph(ctx, reconciler.Client)
+	if err != nil {
+		rb.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+		if err := reconciler.Client.Status().Update(ctx, &rb); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+		}
+		return ctrl.Result{}, err
+	}
+	resolveRoleSubjects(graph, &rb)
+	boundRoleBinding(graph, &rb, now)
+
+	cm := newConfigMap(reconciler.ArgoCDRBACConfigMapName, reconciler.ArgoCDRBACConfigMapNamespace)
 
-	r.Log.Info("Checking if ConfigMap exists")
-	if !IsObjectFound(r.Client, cm.Namespace, cm.Name, cm) {
+	reconciler.Log.Info("Checking if ConfigMap exists")
+	if !IsObjectFound(reconciler.Client, cm.Namespace, cm.Name, cm) {
 		rb.SetConditions(rbacoperatorv1alpha1.Pending(fmt.Errorf("ConfigMap %s not found", cm.Name)))
-		if err := r.Client.Status().Update(ctx, &rb); err != nil {
-			r.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+		if err := reconciler.Client.Status().Update(ctx, &rb); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
 		}
 		return ctrl.Result{}, fmt.Errorf("ConfigMap not found")
 	}
@@ -115,86 +204,163 @@ func (r *ArgoCDRoleBindingReconciler) Reconcile(ctx context.Context, req ctrl.Re
 			Namespace: req.Namespace,
 		}
 
-		if err := r.Get(ctx, typeNamespacedNameRole, &role); err != nil {
+		if err := reconciler.Get(ctx, typeNamespacedNameRole, &role); err != nil {
 			if errors.IsNotFound(err) {
-				r.Log.Info("ArgoCDRole not found.", "name", roleName)
+				reconciler.Log.Info("ArgoCDRole not found.", "name", roleName)
 				return ctrl.Result{RequeueAfter: time.Second}, nil
 			}
 			rb.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
-			if err := r.Client.Status().Update(ctx, &rb); err != nil {
-				r.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+			if err := reconciler.Client.Status().Update(ctx, &rb); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+			}
+			return ctrl.Result{}, err
+		}
+		if condition, paused, err := pausedCondition(ctx, reconciler.Client, &role); err != nil {
+			rb.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+			if err := reconciler.Client.Status().Update(ctx, &rb); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+			}
+			return ctrl.Result{}, err
+		} else if paused && pause == nil {
+			reconciler.Log.Info("ArgoCDRoleBinding is paused, only writing revocations", "name", req.Name)
+			reconciler = reconciler.revokeOnly()
+			pause = &condition
+		}
+		// The rules of the role are written with the binding, they are only planned if it is in dry-run mode
+		if plan == nil {
+			reconciler, plan = reconciler.planned(&role)
+		}
+		applyApprovedRoleSpec(&role)
+		format, err := policy.LoadObjectFormat(ctx, reconciler.Client, reconciler.ArgoCDRBACConfigMapNamespace)
+		if err != nil {
+			rb.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
+			if err := reconciler.Client.Status().Update(ctx, &rb); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+			}
+			return ctrl.Result{}, err
+		}
+		if err := renderRoleObjects(format, &role); err != nil {
+			rb.SetConditions(rbacoperatorv1alpha1.ReconcileError(fmt.Errorf("ArgoCDRole %s: %v", role.Name, err)))
+			if err := reconciler.Client.Status().Update(ctx, &rb); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
 			}
+			return ctrl.Result{RequeueAfter: time.Minute * 10}, nil
+		}
+		enforceRoleTenantPolicies(policies, &role)
+
+		if deleted, err := reconciler.reconcileBreakGlass(ctx, &rb, &role, now); err != nil || deleted {
 			return ctrl.Result{}, err
 		}
 
-		r.Log.Info("Reconciling RBAC ConfigMap")
-		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-			cm := newConfigMap(r.ArgoCDRBACConfigMapName, r.ArgoCDRBACConfigMapNamespace)
-			if err := r.Get(ctx, client.ObjectKeyFromObject(cm), cm); err != nil {
+		reconciler.Log.Info("Reconciling RBAC ConfigMap")
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			cm := newConfigMap(reconciler.ArgoCDRBACConfigMapName, reconciler.ArgoCDRBACConfigMapNamespace)
+			if err := reconciler.Get(ctx, client.ObjectKeyFromObject(cm), cm); err != nil {
 				return err
 			}
-			return r.reconcileRBACConfigMap(cm, &rb, &role)
+			return reconciler.reconcileRBACConfigMap(cm, &rb, &role)
 		})
 
 		if err != nil {
 			role.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
-			if updateErr := r.Client.Status().Update(ctx, &rb); updateErr != nil {
-				r.Log.Error(updateErr, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+			if updateErr := reconciler.Client.Status().Update(ctx, &rb); updateErr != nil {
+				reconciler.Log.Error(updateErr, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
 			}
 			return ctrl.Result{}, err
 		}
 
-		if !role.HasArgoCDRoleBindingRef() {
+		if !role.HasArgoCDRoleBindingRef() && !hasOwnOverlayKey(&rb, &role) {
 			role.SetArgoCDRoleBindingRef(rb.Name)
-			if err := r.Client.Status().Update(ctx, &role); err != nil {
-				r.Log.Error(err, "Failed to update ArgoCDRole status", "name", role.Name)
+			if err := reconciler.Client.Status().Update(ctx, &role); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDRole status", "name", role.Name)
+			}
+		} else if role.Status.ArgoCDRoleBindingRef == rb.Name && hasOwnOverlayKey(&rb, &role) {
+			// Bound by a previous version, the role renders its rules only from now on
+			role.SetArgoCDRoleBindingRef("")
+			if err := reconciler.Client.Status().Update(ctx, &role); err != nil {
+				reconciler.Log.Error(err, "Failed to update ArgoCDRole status", "name", role.Name)
 			}
 		}
 
-		rb.SetConditions(rbacoperatorv1alpha1.ReconcileSuccess().WithObservedGeneration(rb.GetGeneration()))
-		if err := r.Client.Status().Update(ctx, &rb); err != nil {
-			r.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+		reconciler.updateActiveSubjects(&rb, &role, now)
+		rb.SetConditions(syncedCondition(pause, rb.GetGeneration()))
+		if err := reconciler.Client.Status().Update(ctx, &rb); err != nil {
+			reconciler.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
 		}
-		return ctrl.Result{RequeueAfter: time.Minute * 10}, nil
+		return ctrl.Result{RequeueAfter: breakGlassRequeueAfter(&rb, now, roleBindingRequeueAfter(&rb, now, time.Minute*10))}, nil
 
 	}
 
-	role := &rbacoperatorv1alpha1.ArgoCDRole{}
+	role := reconciler.createBuiltInRole(rb.Namespace, roleName)
 
-	switch roleName {
-	case "admin":
-		role = r.createBuiltInAdminRole(rb.Namespace)
-	case "readonly":
-		role = r.createBuiltInReadOnlyRole(rb.Namespace)
+	if deleted, err := reconciler.reconcileBreakGlass(ctx, &rb, role, now); err != nil || deleted {
+		return ctrl.Result{}, err
 	}
 
-	r.Log.Info("Reconciling RBAC ConfigMap")
-	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		cm := newConfigMap(r.ArgoCDRBACConfigMapName, r.ArgoCDRBACConfigMapNamespace)
-		if err := r.Get(ctx, client.ObjectKeyFromObject(cm), cm); err != nil {
+	reconciler.Log.Info("Reconciling RBAC ConfigMap")
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := newConfigMap(reconciler.ArgoCDRBACConfigMapName, reconciler.ArgoCDRBACConfigMapNamespace)
+		if err := reconciler.Get(ctx, client.ObjectKeyFromObject(cm), cm); err != nil {
 			return err
 		}
-		return r.reconcileRBACConfigMapForBuiltInRole(cm, &rb, role)
+		return reconciler.reconcileRBACConfigMapForBuiltInRole(cm, &rb, role)
 	})
 
 	if err != nil {
 		role.SetConditions(rbacoperatorv1alpha1.ReconcileError(err))
-		if updateErr := r.Client.Status().Update(ctx, &rb); updateErr != nil {
-			r.Log.Error(updateErr, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+		if updateErr := reconciler.Client.Status().Update(ctx, &rb); updateErr != nil {
+			reconciler.Log.Error(updateErr, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
 		}
 		return ctrl.Result{}, err
 	}
 
-	rb.SetConditions(rbacoperatorv1alpha1.ReconcileSuccess().WithObservedGeneration(rb.GetGeneration()))
-	if err := r.Client.Status().Update(ctx, &rb); err != nil {
-		r.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+	reconciler.updateActiveSubjects(&rb, role, now)
+	rb.SetConditions(syncedCondition(pause, rb.GetGeneration()))
+	if err := reconciler.Client.Status().Update(ctx, &rb); err != nil {
+		reconciler.Log.Error(err, "Failed to update ArgoCDRoleBinding status", "name", req.Name)
+	}
+	return ctrl.Result{RequeueAfter: breakGlassRequeueAfter(&rb, now, roleBindingRequeueAfter(&rb, now, time.Minute*10))}, nil
+}
+
+// updateActiveSubjects will set the Active condition and the active subjects in the status of the binding.
+// An Event is emitted for every subject whose access expired since the last reconciliation.
+func (r *ArgoCDRoleBindingReconciler) updateActiveSubjects(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, role *rbacoperatorv1alpha1.ArgoCDRole, now time.Time) {
+	rb.Status.NextTransition, rb.Status.UpcomingWindows = transitionStatus(now, rb.Spec.Schedules, roleBindingWindows(rb)...)
+	if !isBreakGlassGranted(rb, role) {
+		rb.Status.ActiveSubjects = []string{}
+		rb.SetConditions(rbacoperatorv1alpha1.Rejected().WithMessage(breakGlassError(role).Error()))
+		return
+	}
+	if rb.Status.Recertification.IsSuspended(now) {
+		rb.Status.ActiveSubjects = []string{}
+		rb.SetConditions(rbacoperatorv1alpha1.Suspended().WithMessage("binding was not re-attested in time"))
+		return
+	}
+	active := []string{}
+	for _, subject := range activeGlobalSubjects(rb, now) {
+		active = append(active, globalSubjectKey(subject))
+	}
+	for _, subject := range rb.Spec.Subjects {
+		key := globalSubjectKey(subject)
+		if slices.Contains(rb.Status.ActiveSubjects, key) && !slices.Contains(active, key) && globalSubjectWindow(rb, subject).isExpired(now) {
+			r.Recorder.Eventf(rb, corev1.EventTypeNormal, eventReasonAccessExpired,
+				"Access of %s to ArgoCDRole %s expired", key, rb.Spec.ArgoCDRoleRef.Name)
+			r.Notifications.bindingExpired("ArgoCDRoleBinding", rb, rb.Spec.ArgoCDRoleRef.Name, key)
+		}
 	}
-	return ctrl.Result{RequeueAfter: time.Minute * 10}, nil
+	rb.Status.ActiveSubjects = active
+	rb.SetConditions(roleBindingWindow(rb).condition(now))
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ArgoCDRoleBindingReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&rbacoperatorv1alpha1.ArgoCDRoleBinding{}).
+		Watches(&rbacoperatorv1alpha1.ArgoCDRBACTenantPolicy{}, handler.EnqueueRequestsFromMapFunc(mapTenantPolicyToObjects(r.Client,
+			func() client.ObjectList { return &rbacoperatorv1alpha1.ArgoCDRoleBindingList{} }))).
+		Watches(&rbacoperatorv1alpha1.ArgoCDRecertificationPolicy{}, handler.EnqueueRequestsFromMapFunc(r.mapRecertificationPolicyToBindings)).
+		Watches(&rbacoperatorv1alpha1.ArgoCDRole{}, handler.EnqueueRequestsFromMapFunc(r.mapRoleGraphToBindings)).
+		Watches(&rbacoperatorv1alpha1.ArgoCDRoleBinding{}, handler.EnqueueRequestsFromMapFunc(r.mapRoleGraphToBindings)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.mapRBACConfigMapToBindings)).
 		Complete(r)
 }
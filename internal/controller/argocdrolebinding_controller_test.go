@@ -18,17 +18,28 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/lint"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/notify"
 )
 
 var _ reconcile.Reconciler = &ArgoCDRoleReconciler{}
@@ -205,6 +216,49 @@ func TestArgoCDRoleBindingReconciler_HandleFinalizer(t *testing.T) {
 	assert.Equal(t, wantCM.Data, cm.Data)
 }
 
+func TestArgoCDRoleBindingReconciler_AccessRequestBinding(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+
+	argocdRoleBinding := makeTestRoleBindingWithSSOSubject(addFinalizerRoleBinding(), setRoleBindingAccessRequest(testAccessRequestName))
+	argocdRole := makeTestRole(addFinalizerRole(), addRoleBinding("standing-binding"))
+	standingCM := makeTestCM_ArgoCDRole_WithRoleBindingRoleSubject_Expected()
+
+	resObjs := []client.Object{argocdRole, argocdRoleBinding, standingCM}
+	subresObjs := []client.Object{argocdRole, argocdRoleBinding}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDRoleBindingReconciler(client, scheme)
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestArgoCDNamespace()))
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: argocdRoleBinding.Name, Namespace: argocdRoleBinding.Namespace}}
+	overlayKey := fmt.Sprintf("policy.%s.rolebinding_%s.csv", testNamespace, argocdRoleBinding.Name)
+	standingKey := fmt.Sprintf("policy.%s.%s.csv", testNamespace, testRoleName)
+
+	// The binding of the request is written to its own key, the standing binding of the role is kept
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, getRBACPolicyCSV(argocdRole, argocdRoleBinding), cm.Data[overlayKey])
+	assert.Equal(t, standingCM.Data[standingKey], cm.Data[standingKey])
+
+	role := &rbacoperatorv1alpha1.ArgoCDRole{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRoleName, Namespace: testNamespace}, role))
+	assert.Equal(t, "standing-binding", role.Status.ArgoCDRoleBindingRef)
+
+	// Deleting it at expiry only removes its own key
+	assert.NoError(t, reconciler.Delete(context.TODO(), argocdRoleBinding))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.NotContains(t, cm.Data, overlayKey)
+	assert.Equal(t, standingCM.Data[standingKey], cm.Data[standingKey])
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRoleName, Namespace: testNamespace}, role))
+	assert.Equal(t, "standing-binding", role.Status.ArgoCDRoleBindingRef)
+}
+
 func TestArgoCDRoleBindingReconciler_RoleNotFound(t *testing.T) {
 	logf.SetLogger(ZapLogger(true))
 
@@ -307,7 +361,7 @@ func TestArgoCDRoleBindingReconciler_ReconcileLocalSubject(t *testing.T) {
 func TestArgoCDRoleBindingReconciler_ReconcileBuiltInAdmin(t *testing.T) {
 	logf.SetLogger(ZapLogger(true))
 
-	argocdRoleBinding := makeTestRoleBindingForBuiltInAdmin(addFinalizerRoleBinding())
+	argocdRoleBinding := makeTestRoleBindingForBuiltInAdmin(addFinalizerRoleBinding(), setBreakGlassReason("incident"))
 
 	resObjs := []client.Object{argocdRoleBinding}
 	subresObjs := []client.Object{argocdRoleBinding}
@@ -405,3 +459,454 @@ func TestArgoCDRoleBindingReconciler_HandleFinalizerBuiltInRole(t *testing.T) {
 	resCM := makeTestRBACConfigMap()
 	assert.Equal(t, resCM.Data, cm.Data)
 }
+
+func TestArgoCDRoleBindingReconciler_ExpiresAt(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+
+	now := time.Now().Truncate(time.Second)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	expiresAt := metav1.NewTime(now.Add(5 * time.Minute))
+	argocdRoleBinding := makeTestRoleBindingWithSSOSubject(addFinalizerRoleBinding(), setRoleBindingWindow(nil, &expiresAt))
+	argocdRole := makeTestRole()
+
+	resObjs := []client.Object{argocdRole, argocdRoleBinding}
+	subresObjs := []client.Object{argocdRole, argocdRoleBinding}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDRoleBindingReconciler(client, scheme)
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestArgoCDNamespace()))
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestRBACConfigMap()))
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      argocdRoleBinding.Name,
+			Namespace: argocdRoleBinding.Namespace,
+		},
+	}
+
+	res, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, res.RequeueAfter)
+
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, makeTestCM_ArgoCDRole_WithRoleBindingSSOSubject_Expected().Data, cm.Data)
+
+	rbRes := &rbacoperatorv1alpha1.ArgoCDRoleBinding{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, rbRes))
+	assert.Equal(t, []string{"sso:gosha"}, rbRes.Status.ActiveSubjects)
+	assert.Contains(t, conditionReasons(rbRes.Status.Conditions), rbacoperatorv1alpha1.ReasonActive)
+	assert.Contains(t, conditionReasons(rbRes.Status.Conditions), rbacoperatorv1alpha1.ReasonBound)
+
+	// The subject is removed once the binding expired
+	now = expiresAt.Add(time.Second)
+	res, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, res.RequeueAfter)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, makeTestCMArgoCDRoleExpected().Data, cm.Data)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, rbRes))
+	assert.Empty(t, rbRes.Status.ActiveSubjects)
+	assert.Contains(t, conditionReasons(rbRes.Status.Conditions), rbacoperatorv1alpha1.ReasonExpired)
+	assert.Contains(t, conditionReasons(rbRes.Status.Conditions), rbacoperatorv1alpha1.ReasonNoActiveSubjects)
+	assert.Equal(t, float64(1), testutil.ToFloat64(unboundResources.WithLabelValues("ArgoCDRoleBinding", rbRes.Namespace, rbRes.Name)))
+
+	recorder := reconciler.Recorder.(*record.FakeRecorder)
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, eventReasonAccessExpired)
+}
+
+func TestArgoCDRoleBindingReconciler_BreakGlass(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+
+	now := time.Now().Truncate(time.Second)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	// The deadline is counted from the activation, not from the creation
+	argocdRoleBinding := makeTestRoleBindingForBuiltInAdmin(addFinalizerRoleBinding(), setRoleBindingCreatedAt(now.Add(-2*time.Hour)))
+
+	resObjs := []client.Object{argocdRoleBinding}
+	subresObjs := []client.Object{argocdRoleBinding}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDRoleBindingReconciler(client, scheme)
+	reconciler.BreakGlassMaxDuration = time.Hour
+	reconciler.BreakGlassAdmin = true
+	overlayKey := fmt.Sprintf("policy.%s.rolebinding_%s.csv", testNamespace, testRoleBindingName)
+	recorder := reconciler.Recorder.(*record.FakeRecorder)
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestArgoCDNamespace()))
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestRBACConfigMap_WithChangedPolicyCSV()))
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      argocdRoleBinding.Name,
+			Namespace: argocdRoleBinding.Namespace,
+		},
+	}
+
+	// Without reason the admin role is not granted
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Empty(t, cm.Data[overlayKey])
+
+	rbRes := &rbacoperatorv1alpha1.ArgoCDRoleBinding{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, rbRes))
+	assert.Contains(t, conditionReasons(rbRes.Status.Conditions), rbacoperatorv1alpha1.ReasonRejected)
+	assert.Contains(t, <-recorder.Events, eventReasonBreakGlassRejected)
+
+	// With reason the admin role is granted until the deadline
+	setBreakGlassReason("incident 1234")(rbRes)
+	assert.NoError(t, reconciler.Update(context.TODO(), rbRes))
+
+	res, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, res.RequeueAfter)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, "g, role:rb-role-test, role:admin\n", cm.Data[overlayKey])
+	assert.NotContains(t, cm.Data, "policy.default.admin.csv")
+
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, rbRes))
+	assert.Equal(t, now.Add(time.Hour).Unix(), rbRes.Status.BreakGlassDeadline.Unix())
+	assert.Contains(t, <-recorder.Events, eventReasonBreakGlassActivated)
+
+	// The binding is deleted at the deadline and the admin role revoked
+	now = now.Add(time.Hour)
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Contains(t, <-recorder.Events, eventReasonBreakGlassExpired)
+
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.False(t, IsObjectFound(reconciler.Client, testNamespace, testRoleBindingName, &rbacoperatorv1alpha1.ArgoCDRoleBinding{}))
+
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.NotContains(t, cm.Data, overlayKey)
+}
+
+func TestArgoCDRoleBindingReconciler_Recertification(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+
+	const day = 24 * time.Hour
+	createdAt := time.Now().Truncate(time.Second)
+	now := createdAt.Add(90*day - time.Minute)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	argocdRoleBinding := makeTestRoleBindingWithSSOSubject(addFinalizerRoleBinding(), setRoleBindingCreatedAt(createdAt))
+	argocdRole := makeTestRole()
+
+	resObjs := []client.Object{argocdRole, argocdRoleBinding}
+	subresObjs := []client.Object{argocdRole, argocdRoleBinding}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDRoleBindingReconciler(client, scheme)
+	reconciler.TrustAttestations = true
+	recorder := reconciler.Recorder.(*record.FakeRecorder)
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestArgoCDNamespace()))
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestRBACConfigMap()))
+	// The global policy in the Argo CD namespace applies to the binding, a laxer policy of the namespace can't relax it
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestRecertificationPolicy(testRBACCMNamespace, 90*day, 7*day)))
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestRecertificationPolicy(testNamespace, 180*day, 7*day)))
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      argocdRoleBinding.Name,
+			Namespace: argocdRoleBinding.Namespace,
+		},
+	}
+
+	res, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, res.RequeueAfter)
+
+	rbRes := &rbacoperatorv1alpha1.ArgoCDRoleBinding{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, rbRes))
+	assert.Equal(t, testRBACCMNamespace+"/"+testRecertificationPolicyName, rbRes.Status.Recertification.Policy)
+	assert.Equal(t, createdAt.Add(90*day).Unix(), rbRes.Status.Recertification.DueAt.Unix())
+	assert.Contains(t, conditionReasons(rbRes.Status.Conditions), rbacoperatorv1alpha1.ReasonRecertified)
+
+	// The binding is due but its subjects keep access during the grace period
+	now = createdAt.Add(90 * day)
+	res, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, res.RequeueAfter)
+	assert.Contains(t, <-recorder.Events, eventReasonRecertificationDue)
+
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, makeTestCM_ArgoCDRole_WithRoleBindingSSOSubject_Expected().Data, cm.Data)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, rbRes))
+	assert.Contains(t, conditionReasons(rbRes.Status.Conditions), rbacoperatorv1alpha1.ReasonRecertificationDue)
+	assert.Contains(t, conditionReasons(rbRes.Status.Conditions), rbacoperatorv1alpha1.ReasonActive)
+
+	// After the grace period the subjects are suspended, the binding is kept
+	now = createdAt.Add(97 * day)
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Contains(t, <-recorder.Events, eventReasonAccessSuspended)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, makeTestCMArgoCDRoleExpected().Data, cm.Data)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, rbRes))
+	assert.Empty(t, rbRes.Status.ActiveSubjects)
+	assert.Contains(t, conditionReasons(rbRes.Status.Conditions), rbacoperatorv1alpha1.ReasonSuspended)
+
+	// The user that last changed the binding can't attest it
+	rbRes.Annotations = map[string]string{common.AnnotationChangedBy: "alice"}
+	setRoleBindingAttestedAt(now, "alice")(rbRes)
+	assert.NoError(t, reconciler.Update(context.TODO(), rbRes))
+
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Contains(t, <-recorder.Events, eventReasonInvalidAttestation)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, rbRes))
+	assert.Empty(t, rbRes.Status.ActiveSubjects)
+	assert.Contains(t, conditionReasons(rbRes.Status.Conditions), rbacoperatorv1alpha1.ReasonSuspended)
+
+	// Re-attesting the binding by another user restores the subjects
+	setRoleBindingAttestedAt(now, "bob")(rbRes)
+	assert.NoError(t, reconciler.Update(context.TODO(), rbRes))
+
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Contains(t, <-recorder.Events, eventReasonRecertified)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, makeTestCM_ArgoCDRole_WithRoleBindingSSOSubject_Expected().Data, cm.Data)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, rbRes))
+	assert.Equal(t, []string{"sso:gosha"}, rbRes.Status.ActiveSubjects)
+	assert.Equal(t, now.Add(90*day).Unix(), rbRes.Status.Recertification.DueAt.Unix())
+	assert.Equal(t, "bob", rbRes.Status.Recertification.LastAttestedBy)
+	assert.Contains(t, conditionReasons(rbRes.Status.Conditions), rbacoperatorv1alpha1.ReasonRecertified)
+	assert.Contains(t, conditionReasons(rbRes.Status.Conditions), rbacoperatorv1alpha1.ReasonActive)
+}
+
+func TestArgoCDRoleBindingReconciler_RecertificationNonPositiveInterval(t *testing.T) {
+	const day = 24 * time.Hour
+	lastAttestedAt := time.Now().Truncate(time.Second)
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	// Policies with an interval that is not positive would make every binding due at once, they are ignored
+	c := makeTestReconcilerClient(scheme, []client.Object{
+		makeTestRecertificationPolicy(testRBACCMNamespace, 90*day, -100*day),
+		makeTestRecertificationPolicy(testNamespace, 0, 7*day),
+	}, nil)
+
+	status, err := findRecertification(context.TODO(), c, testNamespace, testRBACCMNamespace, lastAttestedAt)
+	assert.NoError(t, err)
+	assert.Equal(t, testRBACCMNamespace+"/"+testRecertificationPolicyName, status.Policy)
+	assert.Equal(t, lastAttestedAt.Add(90*day).Unix(), status.DueAt.Unix())
+	// A negative grace period suspends the subjects when the binding is due, not before
+	assert.Equal(t, status.DueAt.Unix(), status.SuspendAt.Unix())
+
+	c = makeTestReconcilerClient(scheme, []client.Object{makeTestRecertificationPolicy(testNamespace, -day, 0)}, nil)
+	status, err = findRecertification(context.TODO(), c, testNamespace, testRBACCMNamespace, lastAttestedAt)
+	assert.NoError(t, err)
+	assert.Nil(t, status)
+}
+
+func TestArgoCDRoleBindingReconciler_TenantPolicy(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+
+	argocdRoleBinding := makeTestRoleBindingForBuiltInAdmin(addFinalizerRoleBinding(), setBreakGlassReason("incident 1234"))
+	tenantPolicy := &rbacoperatorv1alpha1.ArgoCDRBACTenantPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-tenant-policy"},
+		Spec: rbacoperatorv1alpha1.ArgoCDRBACTenantPolicySpec{
+			Namespaces: []string{testNamespace},
+		},
+	}
+
+	resObjs := []client.Object{argocdRoleBinding, tenantPolicy}
+	subresObjs := []client.Object{argocdRoleBinding}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDRoleBindingReconciler(client, scheme)
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestArgoCDNamespace()))
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestRBACConfigMap_WithChangedPolicyCSV()))
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      argocdRoleBinding.Name,
+			Namespace: argocdRoleBinding.Namespace,
+		},
+	}
+
+	// The built-in admin role is not allowed in the namespace, so no subject is bound
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Empty(t, cm.Data["policy.default.admin.csv"])
+
+	rbRes := &rbacoperatorv1alpha1.ArgoCDRoleBinding{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, rbRes))
+	assert.Len(t, rbRes.Spec.Subjects, 1)
+	assert.Empty(t, rbRes.Status.ActiveSubjects)
+	assert.Contains(t, conditionReasons(rbRes.Status.Conditions), rbacoperatorv1alpha1.ReasonPolicyViolation)
+
+	// Allowing built-in roles grants the role again
+	tenantPolicy.Spec.AllowBuiltInRoles = true
+	assert.NoError(t, reconciler.Update(context.TODO(), tenantPolicy))
+
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, makeTestCM_BuiltInAdmin_WithRoleBinding_Expected(testNamespace).Data, cm.Data)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, rbRes))
+	assert.Contains(t, conditionReasons(rbRes.Status.Conditions), rbacoperatorv1alpha1.ReasonCompliant)
+}
+
+func TestArgoCDRoleBindingReconciler_RoleGraph(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+
+	argocdRole := makeTestRole()
+	argocdRoleBinding := makeTestRoleBindingWithSSOSubject(addFinalizerRoleBinding(), func(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) {
+		rb.Spec.Subjects = append(rb.Spec.Subjects, rbacoperatorv1alpha1.GlobalSubject{Kind: "role", Name: "viewer"})
+	})
+	viewerBinding := &rbacoperatorv1alpha1.ArgoCDRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "viewer-binding", Namespace: testNamespace, Generation: 1},
+		Spec: rbacoperatorv1alpha1.ArgoCDRoleBindingSpec{
+			ArgoCDRoleRef: rbacoperatorv1alpha1.ArgoCDRoleRef{Name: "viewer"},
+			Subjects:      []rbacoperatorv1alpha1.GlobalSubject{{Kind: "role", Name: testRoleName}},
+		},
+	}
+	viewerBinding.SetConditions(rbacoperatorv1alpha1.Resolved().WithObservedGeneration(1))
+
+	resObjs := []client.Object{argocdRole, argocdRoleBinding, viewerBinding}
+	subresObjs := []client.Object{argocdRole, argocdRoleBinding}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDRoleBindingReconciler(client, scheme)
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestArgoCDNamespace()))
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestRBACConfigMap()))
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      argocdRoleBinding.Name,
+			Namespace: argocdRoleBinding.Namespace,
+		},
+	}
+
+	// The role subject closing the cycle with the resolved viewer binding is not rendered
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Equal(t, makeTestCM_ArgoCDRole_WithRoleBindingSSOSubject_Expected().Data, cm.Data)
+
+	rbRes := &rbacoperatorv1alpha1.ArgoCDRoleBinding{}
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, rbRes))
+	assert.Len(t, rbRes.Spec.Subjects, 2)
+	assert.Contains(t, conditionReasons(rbRes.Status.Conditions), rbacoperatorv1alpha1.ReasonRoleCycle)
+
+	// Without the cycle the role subject is rendered, but references no ArgoCDRole
+	assert.NoError(t, reconciler.Delete(context.TODO(), viewerBinding))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	assert.Contains(t, cm.Data[fmt.Sprintf("policy.%s.%s.csv", testNamespace, testRoleName)], "g, role:viewer, role:"+testRoleName)
+
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, rbRes))
+	assert.Contains(t, conditionReasons(rbRes.Status.Conditions), rbacoperatorv1alpha1.ReasonDanglingRole)
+
+	// The cycle and the dangling role trigger the reconciliation of the bindings involved
+	assert.Equal(t, []reconcile.Request{req}, reconciler.mapRoleGraphToBindings(context.TODO(), viewerBinding))
+}
+
+func TestArgoCDRoleBindingReconciler_Notifications(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	var mu sync.Mutex
+	events := []notify.Event{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ce struct {
+			Type string       `json:"type"`
+			Data notify.Event `json:"data"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&ce))
+		assert.Equal(t, notify.TypePrefix+ce.Data.Type, ce.Type)
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, ce.Data)
+	}))
+	defer server.Close()
+	notifier, err := notify.New(notify.Config{Sinks: []notify.Sink{{Name: "test", URL: server.URL}}})
+	assert.NoError(t, err)
+
+	argocdRoleBinding := makeTestRoleBindingForBuiltInAdmin(setBreakGlassReason("incident"))
+
+	resObjs := []client.Object{argocdRoleBinding}
+	subresObjs := []client.Object{argocdRoleBinding}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme)
+	client := makeTestReconcilerClient(scheme, resObjs, subresObjs)
+	reconciler := makeTestArgoCDRoleBindingReconciler(client, scheme)
+	reconciler.Notifications = &Notifications{Notifier: notifier}
+
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestArgoCDNamespace()))
+	assert.NoError(t, reconciler.Create(context.TODO(), makeTestRBACConfigMap()))
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: argocdRoleBinding.Name, Namespace: argocdRoleBinding.Namespace}}
+	reconcileEvents := func() []notify.Event {
+		_, err := reconciler.Reconcile(context.TODO(), req)
+		assert.NoError(t, err)
+		notifier.Wait()
+		mu.Lock()
+		defer mu.Unlock()
+		reconciled := events
+		events = []notify.Event{}
+		return reconciled
+	}
+
+	// The binding of the admin role is created, then its subjects are granted admin
+	reconciled := reconcileEvents()
+	assert.Len(t, reconciled, 1)
+	assert.Equal(t, notify.TypeBindingCreated, reconciled[0].Type)
+	assert.Equal(t, lint.SeverityCritical, reconciled[0].Severity)
+	assert.Equal(t, notify.Resource{Kind: "ArgoCDRoleBinding", Namespace: argocdRoleBinding.Namespace, Name: argocdRoleBinding.Name},
+		reconciled[0].Resource)
+	reconciled = reconcileEvents()
+	assert.NotEmpty(t, reconciled)
+	for _, event := range reconciled {
+		assert.Equal(t, notify.TypeSubjectAdded, event.Type)
+		assert.Equal(t, common.ArgoCDRoleAdmin, event.Role)
+		assert.Equal(t, lint.SeverityCritical, event.Severity)
+	}
+	assert.Empty(t, reconcileEvents())
+
+	// A grant added outside of the operator triggers the reconciliation of the binding and is reverted, also after a restart
+	cm := &corev1.ConfigMap{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}, cm))
+	overlayKey := fmt.Sprintf("policy.%s.%s.csv", argocdRoleBinding.Namespace, common.ArgoCDRoleAdmin)
+	assert.Contains(t, cm.Annotations[common.AnnotationPolicyDigests], overlayKey)
+	cm.Data[overlayKey] += "g, mallory, role:admin\n"
+	assert.NoError(t, reconciler.Update(context.TODO(), cm))
+	assert.Contains(t, reconciler.mapRBACConfigMapToBindings(context.TODO(), cm), req)
+	reconciler.Notifications = &Notifications{Notifier: notifier}
+	reconciled = reconcileEvents()
+	assert.Len(t, reconciled, 1)
+	assert.Equal(t, notify.TypeDriftReverted, reconciled[0].Type)
+	assert.Equal(t, lint.SeverityHigh, reconciled[0].Severity)
+	assert.Equal(t, overlayKey, reconciled[0].Key)
+	assert.Equal(t, []string{"g, mallory, role:admin"}, reconciled[0].Removed)
+}
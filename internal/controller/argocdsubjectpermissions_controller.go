@@ -0,0 +1,151 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+// ArgoCDSubjectPermissionsReconciler computes an ArgoCDSubjectPermissions report of the effective permissions
+// of every subject of the Argo CD RBAC policy, in the namespace of the Argo CD RBAC ConfigMap.
+type ArgoCDSubjectPermissionsReconciler struct {
+	client.Client
+	Log                          logr.Logger
+	Scheme                       *runtime.Scheme
+	ArgoCDRBACConfigMapName      string
+	ArgoCDRBACConfigMapNamespace string
+}
+
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdsubjectpermissions,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdroles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrolebindings,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectroles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdprojectrolebindings,verbs=get;list;watch
+// +kubebuilder:rbac:groups=argoproj.io,resources=appprojects,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+// Reconcile recomputes the reports of all subjects. The request is always the Argo CD RBAC ConfigMap.
+func (r *ArgoCDSubjectPermissionsReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	r.Log.Info("Reconciling ArgoCDSubjectPermissions", "configmap", req.NamespacedName)
+
+	p, err := LoadPolicy(ctx, r.Client, r.ArgoCDRBACConfigMapName, r.ArgoCDRBACConfigMapNamespace)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("error when loading the RBAC policy: %v", err)
+	}
+
+	reports := map[string]rbacoperatorv1alpha1.ArgoCDSubjectPermissionsSpec{}
+	for _, permissions := range p.SubjectPermissions() {
+		reports[subjectPermissionsName(permissions.Subject)] = permissions
+	}
+
+	list := rbacoperatorv1alpha1.ArgoCDSubjectPermissionsList{}
+	if err := r.List(ctx, &list, client.InNamespace(r.ArgoCDRBACConfigMapNamespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+	for i := range list.Items {
+		report := &list.Items[i]
+		spec, ok := reports[report.Name]
+		if !ok {
+			if err := r.Delete(ctx, report); client.IgnoreNotFound(err) != nil {
+				return ctrl.Result{}, err
+			}
+			continue
+		}
+		delete(reports, report.Name)
+		if equality.Semantic.DeepEqual(report.Spec, spec) {
+			continue
+		}
+		report.Spec = spec
+		if err := r.Update(ctx, report); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	for name, spec := range reports {
+		report := &rbacoperatorv1alpha1.ArgoCDSubjectPermissions{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: r.ArgoCDRBACConfigMapNamespace},
+			Spec:       spec,
+		}
+		if err := r.Create(ctx, report); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// subjectPermissionsName returns the name of the report of the subject: the subject made a valid name, followed by
+// a hash of the subject to tell apart subjects made the same name.
+func subjectPermissionsName(subject string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(subject))
+	if len(name) > 50 {
+		name = name[:50]
+	}
+	name = strings.Trim(name, "-.")
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(subject))
+	if name == "" {
+		return fmt.Sprintf("subject-%08x", hash.Sum32())
+	}
+	return fmt.Sprintf("%s-%08x", name, hash.Sum32())
+}
+
+// mapToRBACConfigMap maps every watched object to the Argo CD RBAC ConfigMap, the reports are recomputed as a whole.
+func (r *ArgoCDSubjectPermissionsReconciler) mapToRBACConfigMap(_ context.Context, obj client.Object) []reconcile.Request {
+	if cm, ok := obj.(*corev1.ConfigMap); ok && (cm.Name != r.ArgoCDRBACConfigMapName || cm.Namespace != r.ArgoCDRBACConfigMapNamespace) {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Name:      r.ArgoCDRBACConfigMapName,
+		Namespace: r.ArgoCDRBACConfigMapNamespace,
+	}}}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ArgoCDSubjectPermissionsReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	mapToRBACConfigMap := handler.EnqueueRequestsFromMapFunc(r.mapToRBACConfigMap)
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("argocdsubjectpermissions").
+		Watches(&corev1.ConfigMap{}, mapToRBACConfigMap).
+		Watches(&argocdv1alpha.AppProject{}, mapToRBACConfigMap).
+		Watches(&rbacoperatorv1alpha1.ArgoCDRole{}, mapToRBACConfigMap).
+		Watches(&rbacoperatorv1alpha1.ArgoCDRoleBinding{}, mapToRBACConfigMap).
+		Watches(&rbacoperatorv1alpha1.ArgoCDProjectRole{}, mapToRBACConfigMap).
+		Watches(&rbacoperatorv1alpha1.ArgoCDProjectRoleBinding{}, mapToRBACConfigMap).
+		Watches(&rbacoperatorv1alpha1.ArgoCDSubjectPermissions{}, mapToRBACConfigMap).
+		Complete(r)
+}
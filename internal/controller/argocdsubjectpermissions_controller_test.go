@@ -0,0 +1,118 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+var _ reconcile.Reconciler = &ArgoCDSubjectPermissionsReconciler{}
+
+func TestArgoCDSubjectPermissionsReconciler_Reconcile(t *testing.T) {
+	logf.SetLogger(ZapLogger(true))
+	argocdRole := makeTestRole()
+	argocdRoleBinding := makeTestRoleBindingWithSSOSubject()
+	cm := recordTestPolicySources(makeTestCM_ArgoCDRole_WithRoleBindingSSOSubject_Expected(), argocdRole, argocdRoleBinding)
+	cm.Data["policy.csv"] = "p, ci, applications, sync, */*, allow\n"
+	cm.Data["policy.default"] = "role:readonly"
+	appProject := makeTestAppProject(func(ap *argocdv1alpha.AppProject) {
+		ap.Spec.Roles[0].Groups = []string{"team-a"}
+		ap.Spec.Roles[0].Policies = []string{fmt.Sprintf("p, proj:%s:existing-role, applications, get, %s/*, allow", ap.Name, ap.Name)}
+	})
+
+	resObjs := []client.Object{argocdRole, argocdRoleBinding, cm, appProject}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	client := makeTestReconcilerClient(scheme, resObjs, nil)
+	reconciler := &ArgoCDSubjectPermissionsReconciler{
+		Client:                       client,
+		Scheme:                       scheme,
+		ArgoCDRBACConfigMapName:      testRBACCMName,
+		ArgoCDRBACConfigMapNamespace: testRBACCMNamespace,
+		Log:                          ZapLogger(true),
+	}
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: testRBACCMName, Namespace: testRBACCMNamespace}}
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+
+	list := rbacoperatorv1alpha1.ArgoCDSubjectPermissionsList{}
+	assert.NoError(t, reconciler.List(context.TODO(), &list))
+	subjects := []string{}
+	for _, report := range list.Items {
+		subjects = append(subjects, report.Spec.Subject)
+	}
+	assert.ElementsMatch(t, []string{"admin", "ci", "gosha", "team-a"}, subjects)
+
+	// Grants and rules are attributed to the resources and fields they were rendered from
+	report := &rbacoperatorv1alpha1.ArgoCDSubjectPermissions{}
+	key := types.NamespacedName{Name: subjectPermissionsName("gosha"), Namespace: testRBACCMNamespace}
+	assert.NoError(t, reconciler.Get(context.TODO(), key, report))
+	roleSource := rbacoperatorv1alpha1.PermissionSource{Kind: "ArgoCDRole", Namespace: testNamespace, Name: testRoleName, Field: "spec.rules[0]"}
+	bindingSource := rbacoperatorv1alpha1.PermissionSource{Kind: "ArgoCDRoleBinding", Namespace: testNamespace, Name: testRoleBindingName, Field: "spec.subjects[0]"}
+	defaultSource := rbacoperatorv1alpha1.PermissionSource{Kind: "ConfigMap", Namespace: testRBACCMNamespace, Name: testRBACCMName, Key: "policy.default"}
+	assert.Equal(t, []rbacoperatorv1alpha1.RoleGrant{
+		{Role: "role:" + testRoleName, Source: bindingSource},
+		{Role: "role:readonly", Source: defaultSource},
+	}, report.Spec.Roles)
+	assert.Contains(t, report.Spec.Rules, rbacoperatorv1alpha1.PermissionRule{
+		Resource: "applications", Action: "list", Object: "*/*", Effect: "allow", Role: "role:" + testRoleName, Source: roleSource,
+	})
+
+	key.Name = subjectPermissionsName("ci")
+	assert.NoError(t, reconciler.Get(context.TODO(), key, report))
+	assert.Contains(t, report.Spec.Rules, rbacoperatorv1alpha1.PermissionRule{
+		Resource: "applications", Action: "sync", Object: "*/*", Effect: "allow",
+		Source: rbacoperatorv1alpha1.PermissionSource{Kind: "ConfigMap", Namespace: testRBACCMNamespace, Name: testRBACCMName, Key: "policy.csv"},
+	})
+
+	// Project roles are held through the groups of the AppProject roles
+	key.Name = subjectPermissionsName("team-a")
+	assert.NoError(t, reconciler.Get(context.TODO(), key, report))
+	appProjectSource := rbacoperatorv1alpha1.PermissionSource{Kind: "AppProject", Namespace: testNamespace, Name: testAppProjectName}
+	projectRole := fmt.Sprintf("proj:%s:existing-role", testAppProjectName)
+	assert.Equal(t, []rbacoperatorv1alpha1.RoleGrant{{Role: projectRole, Source: appProjectSource}}, report.Spec.ProjectRoles)
+	assert.Contains(t, report.Spec.Rules, rbacoperatorv1alpha1.PermissionRule{
+		Resource: "applications", Action: "get", Object: testAppProjectName + "/*", Effect: "allow", Role: projectRole, Source: appProjectSource,
+	})
+
+	// Reports of subjects removed from the policy are deleted
+	assert.NoError(t, reconciler.Get(context.TODO(), req.NamespacedName, cm))
+	delete(cm.Data, "policy.csv")
+	assert.NoError(t, reconciler.Update(context.TODO(), cm))
+	_, err = reconciler.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.NoError(t, reconciler.List(context.TODO(), &list))
+	assert.Len(t, list.Items, 3)
+}
+
+func TestSubjectPermissionsName(t *testing.T) {
+	assert.Regexp(t, `^team-a-[0-9a-f]{8}$`, subjectPermissionsName("team-a"))
+	assert.Regexp(t, `^alice-example.com-[0-9a-f]{8}$`, subjectPermissionsName("Alice@example.com"))
+	assert.NotEqual(t, subjectPermissionsName("team_a"), subjectPermissionsName("team-a"))
+	assert.Regexp(t, `^subject-[0-9a-f]{8}$`, subjectPermissionsName("@@"))
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/audit"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+// AuditLog writes an audit entry for every change to the Argo CD RBAC ConfigMap and AppProjects.
+type AuditLog struct {
+	Sink audit.Sink
+	// TrustChangedBy takes the actor from the changed-by annotation. Only set if the mutating webhooks are served,
+	// otherwise the annotation can be set by anyone. The actor is taken from the managed fields if not set.
+	TrustChangedBy bool
+}
+
+// client returns a client writing an audit entry for the ConfigMaps and AppProjects written by the reconciliation
+// of the object. The client is returned as is if no audit log is written.
+func (l *AuditLog) client(c client.Client, kind string, obj client.Object) client.Client {
+	if l == nil {
+		return c
+	}
+	return &auditClient{Client: c, log: l, kind: kind, trigger: obj, reconciled: isGenerationReconciled(obj)}
+}
+
+// auditClient is a client writing an audit entry for every change to a ConfigMap or AppProject. The entry is written
+// before the change, so a change is never written without its entry.
+type auditClient struct {
+	client.Client
+	log     *AuditLog
+	kind    string
+	trigger client.Object
+	// reconciled is true if the spec of the trigger was already reconciled, its changes are not caused by a user.
+	reconciled bool
+}
+
+// Update writes an audit entry of the changes and the object if it is a ConfigMap or AppProject.
+func (c *auditClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	entry, err := c.entry(ctx, obj)
+	if err != nil {
+		return err
+	}
+	return c.write(entry, c.Client.Update(ctx, obj, opts...))
+}
+
+// Patch writes an audit entry of the changes and the object if it is a ConfigMap or AppProject.
+func (c *auditClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	entry, err := c.entry(ctx, obj)
+	if err != nil {
+		return err
+	}
+	return c.write(entry, c.Client.Patch(ctx, obj, patch, opts...))
+}
+
+// livePolicyLines returns the policy lines of the live ConfigMap or AppProject, empty if it is not found,
+// and nil for other objects.
+func livePolicyLines(ctx context.Context, c client.Client, obj client.Object) (map[string][]string, error) {
+	live, err := liveTarget(ctx, c, obj)
+	if err != nil || live == nil {
+		return nil, err
+	}
+	return objectPolicyLines(live), nil
+}
+
+// liveTarget returns the live ConfigMap or AppProject, empty if it is not found, and nil for other objects.
+func liveTarget(ctx context.Context, c client.Client, obj client.Object) (client.Object, error) {
+	var live client.Object
+	switch obj.(type) {
+	case *corev1.ConfigMap:
+		live = &corev1.ConfigMap{}
+	case *argocdv1alpha.AppProject:
+		live = &argocdv1alpha.AppProject{}
+	default:
+		return nil, nil
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), live); err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	return live, nil
+}
+
+// policyTarget returns the ConfigMap or AppProject written as "<kind> <namespace>/<name>".
+func policyTarget(obj client.Object) string {
+	kind := "ConfigMap"
+	if _, ok := obj.(*argocdv1alpha.AppProject); ok {
+		kind = "AppProject"
+	}
+	return fmt.Sprintf("%s %s/%s", kind, obj.GetNamespace(), obj.GetName())
+}
+
+// changedByKinds are the kinds whose changed-by annotation is set by the mutating webhook.
+var changedByKinds = []string{"ArgoCDRole", "ArgoCDRoleBinding", "ArgoCDProjectRole", "ArgoCDProjectRoleBinding"}
+
+// objectPolicyLines returns the policy and local account lines per key of a ConfigMap or role of an AppProject,
+// nil for other objects.
+func objectPolicyLines(obj client.Object) map[string][]string {
+	switch o := obj.(type) {
+	case *corev1.ConfigMap:
+		lines := map[string][]string{}
+		for key, value := range policyKeys(o) {
+			lines[key] = policyLines(value)
+		}
+		for key, value := range o.Data {
+			if strings.HasPrefix(key, common.ArgoCDKeyAccountPrefix+".") {
+				lines[key] = []string{value}
+			}
+		}
+		return lines
+	case *argocdv1alpha.AppProject:
+		return appProjectLines(o)
+	}
+	return nil
+}
+
+// entry writes the audit entry of the changes the object makes to the live ConfigMap or AppProject, before they are written.
+// Returns nil if the object is not a ConfigMap or AppProject or does not change the policy.
+func (c *auditClient) entry(ctx context.Context, obj client.Object) (*audit.Entry, error) {
+	before, err := livePolicyLines(ctx, c.Client, obj)
+	if err != nil || before == nil {
+		return nil, err
+	}
+	changes := audit.NewChanges(before, objectPolicyLines(obj))
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	entry := &audit.Entry{
+		Time: timeNow().UTC(),
+		Resource: audit.Resource{
+			Kind:       c.kind,
+			Namespace:  c.trigger.GetNamespace(),
+			Name:       c.trigger.GetName(),
+			Generation: c.trigger.GetGeneration(),
+		},
+		Target:   policyTarget(obj),
+		Changes:  changes,
+		Subjects: audit.Subjects(changes),
+	}
+	if c.reconciled {
+		// Expiries, schedules and changes of other resources are not caused by the user that last changed the spec
+		entry.ActorSource = audit.ActorSourceReconcile
+	} else {
+		entry.Actor, entry.ActorSource = c.log.actor(c.kind, c.trigger)
+	}
+	if err := c.log.Sink.Write(*entry); err != nil {
+		return nil, fmt.Errorf("audit entry not written, the policy is not written: %v", err)
+	}
+	return entry, nil
+}
+
+// write records the failure of the write of an audited change with a second entry, the change was not applied.
+func (c *auditClient) write(entry *audit.Entry, err error) error {
+	if entry == nil || err == nil {
+		return err
+	}
+	entry.Time = timeNow().UTC()
+	entry.Error = err.Error()
+	if sinkErr := c.log.Sink.Write(*entry); sinkErr != nil {
+		return fmt.Errorf("%v, and the audit entry of its failure not written: %v", err, sinkErr)
+	}
+	return err
+}
+
+// isGenerationReconciled returns true if the current generation of the object was already reconciled successfully.
+func isGenerationReconciled(obj client.Object) bool {
+	var conditions []rbacoperatorv1alpha1.Condition
+	switch o := obj.(type) {
+	case *rbacoperatorv1alpha1.ArgoCDRole:
+		conditions = o.Status.Conditions
+	case *rbacoperatorv1alpha1.ArgoCDRoleBinding:
+		conditions = o.Status.Conditions
+	case *rbacoperatorv1alpha1.ArgoCDProjectRole:
+		conditions = o.Status.Conditions
+	case *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding:
+		conditions = o.Status.Conditions
+	case *rbacoperatorv1alpha1.ArgoCDProjectRoleToken:
+		conditions = o.Status.Conditions
+	case *rbacoperatorv1alpha1.ArgoCDLocalAccount:
+		conditions = o.Status.Conditions
+	}
+	for _, condition := range conditions {
+		if condition.Type == rbacoperatorv1alpha1.TypeSynced && condition.Status == corev1.ConditionTrue {
+			return condition.ObservedGeneration == obj.GetGeneration()
+		}
+	}
+	return false
+}
+
+// actor returns the identity that last changed the spec of the object and where it was taken from: the changed-by
+// annotation if trusted and set by the webhook for the kind, otherwise the field manager that last updated the spec.
+func (l *AuditLog) actor(kind string, obj client.Object) (string, string) {
+	changedBy := obj.GetAnnotations()[common.AnnotationChangedBy]
+	if changedBy != "" && l.TrustChangedBy && slices.Contains(changedByKinds, kind) {
+		return changedBy, audit.ActorSourceWebhook
+	}
+	var latest *metav1.ManagedFieldsEntry
+	for i, entry := range obj.GetManagedFields() {
+		if entry.Subresource != "" || entry.FieldsV1 == nil || !bytes.Contains(entry.FieldsV1.Raw, []byte(`"f:spec"`)) {
+			continue
+		}
+		if latest == nil || (entry.Time != nil && (latest.Time == nil || latest.Time.Before(entry.Time))) {
+			latest = &obj.GetManagedFields()[i]
+		}
+	}
+	if latest == nil {
+		return "", ""
+	}
+	return strings.TrimSpace(latest.Manager), audit.ActorSourceManagedFields
+}
+
+// audited returns a copy of the reconciler writing an audit entry for the AppProjects written for the ArgoCDProjectRoleToken.
+func (r *ArgoCDProjectRoleTokenReconciler) audited(token *rbacoperatorv1alpha1.ArgoCDProjectRoleToken) *ArgoCDProjectRoleTokenReconciler {
+	if r.Audit == nil {
+		return r
+	}
+	audited := *r
+	audited.Client = r.Audit.client(r.Client, "ArgoCDProjectRoleToken", token)
+	return &audited
+}
+
+// audited returns a copy of the reconciler writing an audit entry for the ConfigMaps written for the ArgoCDLocalAccount.
+func (r *ArgoCDLocalAccountReconciler) audited(account *rbacoperatorv1alpha1.ArgoCDLocalAccount) *ArgoCDLocalAccountReconciler {
+	if r.Audit == nil {
+		return r
+	}
+	audited := *r
+	audited.Client = r.Audit.client(r.Client, "ArgoCDLocalAccount", account)
+	return &audited
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+const (
+	eventReasonBreakGlassActivated = "BreakGlassActivated"
+	eventReasonBreakGlassRejected  = "BreakGlassRejected"
+	eventReasonBreakGlassExpired   = "BreakGlassExpired"
+
+	// defaultBreakGlassMaxDuration is the maximum break-glass duration if the reconciler does not define one.
+	defaultBreakGlassMaxDuration = 4 * time.Hour
+)
+
+// breakGlassReason returns the reason the break-glass role is bound for, or an empty string if there is none.
+func breakGlassReason(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) string {
+	return rb.Annotations[common.AnnotationBreakGlassReason]
+}
+
+// isBreakGlassGranted returns false if the role is break-glass and the binding does not give a reason.
+// Subjects of such bindings are not rendered.
+func isBreakGlassGranted(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, role *rbacoperatorv1alpha1.ArgoCDRole) bool {
+	return !role.Spec.BreakGlass || breakGlassReason(rb) != ""
+}
+
+// breakGlassDeadline returns the time the binding to a break-glass role is deleted at, the max duration after the
+// binding was activated with a reason or its notBefore. Once activated the deadline is kept, so that bindings created
+// long before, or by previous versions, are not deleted right away and removing the reason can't extend it.
+func breakGlassDeadline(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, maxDuration time.Duration, now time.Time) time.Time {
+	if rb.Status.BreakGlassDeadline != nil {
+		return rb.Status.BreakGlassDeadline.Time
+	}
+	start := now
+	if rb.Spec.NotBefore != nil && rb.Spec.NotBefore.After(start) {
+		start = rb.Spec.NotBefore.Time
+	}
+	return start.Add(maxDuration).Truncate(time.Second)
+}
+
+// reconcileBreakGlass enforces the break-glass mode on a binding to a break-glass role.
+// Returns true if the binding was deleted because it reached its deadline.
+func (r *ArgoCDRoleBindingReconciler) reconcileBreakGlass(ctx context.Context, rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, role *rbacoperatorv1alpha1.ArgoCDRole, now time.Time) (bool, error) {
+	if !role.Spec.BreakGlass {
+		return false, nil
+	}
+
+	reason := breakGlassReason(rb)
+	if reason == "" {
+		r.Recorder.Eventf(rb, corev1.EventTypeWarning, eventReasonBreakGlassRejected,
+			"Binding to break-glass role %s requires the %s annotation", role.Name, common.AnnotationBreakGlassReason)
+		return false, nil
+	}
+
+	maxDuration := r.BreakGlassMaxDuration
+	if maxDuration == 0 {
+		maxDuration = defaultBreakGlassMaxDuration
+	}
+	deadline := breakGlassDeadline(rb, maxDuration, now)
+	if !now.Before(deadline) {
+		r.Log.Info("Break-glass binding reached its deadline, deleting", "name", rb.Name, "deadline", deadline)
+		r.Recorder.Eventf(rb, corev1.EventTypeWarning, eventReasonBreakGlassExpired,
+			"Binding to break-glass role %s reached its deadline %s and is deleted", role.Name, deadline.UTC().Format(time.RFC3339))
+		for _, subject := range roleBindingSubjects(rb) {
+			r.Notifications.bindingExpired("ArgoCDRoleBinding", rb, role.Name, subject)
+		}
+		breakGlassDeadlineSeconds.DeleteLabelValues(rb.Namespace, rb.Name, role.Name)
+		if err := r.Delete(ctx, rb); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if rb.Status.BreakGlassDeadline == nil {
+		r.Recorder.Eventf(rb, corev1.EventTypeWarning, eventReasonBreakGlassActivated,
+			"Break-glass role %s bound until %s: %s", role.Name, deadline.UTC().Format(time.RFC3339), reason)
+		breakGlassActivationsTotal.WithLabelValues(rb.Namespace, role.Name).Inc()
+	}
+	rbDeadline := metav1.NewTime(deadline)
+	rb.Status.BreakGlassDeadline = &rbDeadline
+	breakGlassDeadlineSeconds.WithLabelValues(rb.Namespace, rb.Name, role.Name).Set(float64(deadline.Unix()))
+	return false, nil
+}
+
+// breakGlassRequeueAfter returns the duration until the break-glass deadline, if it is earlier than requeueAfter.
+func breakGlassRequeueAfter(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, now time.Time, requeueAfter time.Duration) time.Duration {
+	if rb.Status.BreakGlassDeadline == nil {
+		return requeueAfter
+	}
+	return max(min(requeueAfter, rb.Status.BreakGlassDeadline.Sub(now)), time.Second)
+}
+
+// breakGlassError returns the error reported in the Rejected condition of a break-glass binding without reason.
+func breakGlassError(role *rbacoperatorv1alpha1.ArgoCDRole) error {
+	return fmt.Errorf("binding to break-glass role %s requires the %s annotation", role.Name, common.AnnotationBreakGlassReason)
+}
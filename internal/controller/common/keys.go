@@ -19,4 +19,136 @@ package common
 const (
 	// ArgoCDKeyRBACPolicyCSV is the configuration key for the Argo CD RBAC policy CSV.
 	ArgoCDKeyRBACPolicyCSV = "policy.csv"
+
+	// ArgoCDKeyRBACPolicyDefault is the configuration key for the default role of the Argo CD RBAC policy.
+	ArgoCDKeyRBACPolicyDefault = "policy.default"
+)
+
+const (
+	// AnnotationAllowedNamespaces is the AppProject annotation listing the namespaces (comma separated)
+	// whose ArgoCDProjectRoleBindings may bind to the AppProject. "*" allows all namespaces.
+	AnnotationAllowedNamespaces = "rbac-operator.argoproj-labs.io/allowed-namespaces"
+)
+
+const (
+	// ArgoCDKeyServerSignature is the key of the JWT signing key in the Argo CD Secret.
+	ArgoCDKeyServerSignature = "server.secretkey"
+
+	// ArgoCDJWTTokenIssuer is the issuer of the JWT tokens Argo CD accepts.
+	ArgoCDJWTTokenIssuer = "argocd"
+
+	// TokenSecretKey is the key the issued JWT token is written to in the output Secret.
+	TokenSecretKey = "token"
+)
+
+const (
+	// ArgoCDKeyAccountPrefix is the prefix of the local account keys in the Argo CD ConfigMap and Secret.
+	ArgoCDKeyAccountPrefix = "accounts"
+
+	// ArgoCDKeyAccountEnabledSuffix is the suffix of the key enabling a local account in the Argo CD ConfigMap.
+	ArgoCDKeyAccountEnabledSuffix = "enabled"
+
+	// ArgoCDKeyAccountPasswordSuffix is the suffix of the key holding the bcrypt password hash in the Argo CD Secret.
+	ArgoCDKeyAccountPasswordSuffix = "password"
+
+	// ArgoCDKeyAccountPasswordMtimeSuffix is the suffix of the key holding the password change time in the Argo CD Secret.
+	ArgoCDKeyAccountPasswordMtimeSuffix = "passwordMtime"
+
+	// ArgoCDKeyAccountTokensSuffix is the suffix of the key holding the issued API tokens in the Argo CD Secret.
+	ArgoCDKeyAccountTokensSuffix = "tokens"
+
+	// PasswordSecretKey is the default key of the password in the Secret referenced by an ArgoCDLocalAccount.
+	PasswordSecretKey = "password"
+
+	// AnnotationManagedAccounts is the Argo CD ConfigMap annotation listing the local accounts (comma separated
+	// <namespace>/<name>) managed by the ArgoCDLocalAccount of the namespace. Other accounts are never changed.
+	AnnotationManagedAccounts = "rbac-operator.argoproj-labs.io/managed-accounts"
+)
+
+const (
+	// AnnotationRequestedBy is the ArgoCDAccessRequest annotation holding the user that created the request.
+	AnnotationRequestedBy = "rbac-operator.argoproj-labs.io/requested-by"
+
+	// AnnotationApprovedBy is the ArgoCDAccessRequest annotation approving the request, holding the approving user.
+	AnnotationApprovedBy = "rbac-operator.argoproj-labs.io/approved-by"
+
+	// AnnotationApprovedByGroups is the ArgoCDAccessRequest annotation holding the groups (comma separated) of the approving user.
+	AnnotationApprovedByGroups = "rbac-operator.argoproj-labs.io/approved-by-groups"
+)
+
+const (
+	// AnnotationBreakGlassReason is the ArgoCDRoleBinding annotation holding the reason a break-glass role is bound.
+	AnnotationBreakGlassReason = "rbac-operator.argoproj-labs.io/break-glass-reason"
+)
+
+const (
+	// AnnotationAttestedAt is the binding annotation holding the time (RFC 3339) an owner last attested the binding.
+	AnnotationAttestedAt = "rbac-operator.argoproj-labs.io/attested-at"
+
+	// AnnotationAttestedBy is the binding annotation holding the user that set the attested-at annotation.
+	// It is set by the mutating webhook, the user can't set it.
+	AnnotationAttestedBy = "rbac-operator.argoproj-labs.io/attested-by"
+)
+
+const (
+	// AnnotationDryRun is the annotation of roles and bindings ("true") planning the changes to the Argo CD RBAC ConfigMap
+	// and AppProjects instead of writing them.
+	AnnotationDryRun = "rbac-operator.argoproj-labs.io/dry-run"
+)
+
+const (
+	// LabelRequireApproval is the label of roles ("true") whose spec changes are only rendered once approved.
+	LabelRequireApproval = "rbac-operator.argoproj-labs.io/require-approval"
+
+	// AnnotationApprovedGeneration is the role annotation approving the spec changes of the generation it holds.
+	AnnotationApprovedGeneration = "rbac-operator.argoproj-labs.io/approved-generation"
+
+	// AnnotationApprovedGenerationBy is the role annotation holding the user that set the approved-generation annotation.
+	// It is set by the mutating webhook, the user can't set it.
+	AnnotationApprovedGenerationBy = "rbac-operator.argoproj-labs.io/approved-generation-by"
+)
+
+const (
+	// AnnotationPaused is the annotation of roles and bindings whose additions to the policy are not written, holding the
+	// namespace/name of the ArgoCDRBACRollback that paused them. It is only honored if the rollback lists the resource in
+	// its status. Removing it resumes the reconciliation.
+	AnnotationPaused = "rbac-operator.argoproj-labs.io/paused"
+)
+
+const (
+	// AnnotationChangedBy is the annotation of roles and bindings holding the user that last changed their spec.
+	// It is set by the mutating webhook, the user can't set it.
+	AnnotationChangedBy = "rbac-operator.argoproj-labs.io/changed-by"
+)
+
+const (
+	// AnnotationPolicyDigests is the annotation of the Argo CD RBAC ConfigMap and AppProjects holding the SHA-256 of the
+	// lines last written by the operator per key or role (JSON), to detect the changes made outside of the operator.
+	AnnotationPolicyDigests = "rbac-operator.argoproj-labs.io/policy-digests"
+
+	// AnnotationPolicySources is the annotation of the Argo CD RBAC ConfigMap and AppProjects holding the resources and
+	// fields the lines of every key or role were rendered from (JSON), recorded when they are written.
+	AnnotationPolicySources = "rbac-operator.argoproj-labs.io/policy-sources"
+)
+
+const (
+	// ArgoCDCmdParamsConfigMapName is the name of the ConfigMap holding the parameters of the Argo CD components.
+	ArgoCDCmdParamsConfigMapName = "argocd-cmd-params-cm"
+
+	// ArgoCDKeyApplicationNamespaces is the key of the namespaces (comma separated globs or /regexps/) applications
+	// may be created in, besides the namespace of Argo CD, in the Argo CD parameters ConfigMap.
+	ArgoCDKeyApplicationNamespaces = "application.namespaces"
+)
+
+const (
+	// LabelSecret is the label of the Secrets written by the operator, e.g. "token" for the output Secrets of tokens.
+	// The manager only caches and watches Secrets with the label, users set it on password Secrets ("password")
+	// to apply password changes immediately.
+	LabelSecret = "rbac-operator.argoproj-labs.io/secret"
+
+	// LabelSecretToken is the value of LabelSecret of the output Secrets of tokens.
+	LabelSecretToken = "token"
+
+	// LabelSecretAccessReport is the value of LabelSecret of the Secrets access reports are written to.
+	LabelSecretAccessReport = "access-report"
 )
@@ -19,12 +19,16 @@ package controller
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
 	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
@@ -45,36 +49,87 @@ func getRBACPolicyCSV(role *rbacoperatorv1alpha1.ArgoCDRole, rb *rbacoperatorv1a
 	return policy
 }
 
+// rolePolicySources returns the sources of the lines of getRBACPolicyCSV: the rules of the role and the subjects of the binding.
+func rolePolicySources(role *rbacoperatorv1alpha1.ArgoCDRole, rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) []policySource {
+	return []policySource{
+		newPolicySource("ArgoCDRole", role, renderPolicyRules(role, fmt.Sprintf("role:%s", role.Name))),
+		newPolicySource("ArgoCDRoleBinding", rb, renderPolicySubjects(rb, role)),
+	}
+}
+
+// renderedLine is a line of the policy and the field of the resource it was rendered from.
+type renderedLine struct {
+	line  string
+	field string
+}
+
+// joinRenderedLines returns the policy string of the lines.
+func joinRenderedLines(lines []renderedLine) string {
+	policy := ""
+	for _, line := range lines {
+		policy += line.line + "\n"
+	}
+	return policy
+}
+
 // buildPolicyStringRules will build the policy string for Rules field of the given role.
 func buildPolicyStringRules(role *rbacoperatorv1alpha1.ArgoCDRole, roleName string) string {
-	policy := ""
-	for _, rule := range role.Spec.Rules {
+	return joinRenderedLines(renderPolicyRules(role, roleName))
+}
+
+// renderPolicyRules renders the rules of the given role, each line with the rule it was rendered from.
+func renderPolicyRules(role *rbacoperatorv1alpha1.ArgoCDRole, roleName string) []renderedLine {
+	lines := []renderedLine{}
+	for i, rule := range role.Spec.Rules {
 		resource := rule.Resource
 		for _, verb := range rule.Verbs {
 			for _, object := range rule.Objects {
-				policy += fmt.Sprintf("p, %s, %s, %s, %s, allow\n", roleName, resource, verb, object)
+				if !isPolicyValue(verb) || !isPolicyValue(object) {
+					continue
+				}
+				lines = append(lines, renderedLine{
+					line:  fmt.Sprintf("p, %s, %s, %s, %s, allow", roleName, resource, verb, object),
+					field: fmt.Sprintf("spec.rules[%d]", i),
+				})
 			}
 		}
 	}
-	return policy
+	return lines
 }
 
 // buildPolicyStringSubjects will build the policy string for Subjects field of the given role.
+// Only subjects within their notBefore/expiresAt window are included, and none if a break-glass role is bound without reason.
 func buildPolicyStringSubjects(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, role *rbacoperatorv1alpha1.ArgoCDRole) string {
-	policy := ""
+	return joinRenderedLines(renderPolicySubjects(rb, role))
+}
+
+// renderPolicySubjects renders the subjects of the given binding, each line with the subject it was rendered from.
+func renderPolicySubjects(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, role *rbacoperatorv1alpha1.ArgoCDRole) []renderedLine {
+	lines := []renderedLine{}
+	if !isBreakGlassGranted(rb, role) {
+		return lines
+	}
 	roleName := fmt.Sprintf("role:%s", role.Name)
-	for _, subject := range rb.Spec.Subjects {
+	for _, subject := range activeGlobalSubjects(rb, timeNow()) {
+		if !isPolicyValue(subject.Name) {
+			continue
+		}
+		field := fmt.Sprintf("spec.subjects[%d]", slices.IndexFunc(rb.Spec.Subjects, func(s rbacoperatorv1alpha1.GlobalSubject) bool {
+			return s.Kind == subject.Kind && s.Name == subject.Name
+		}))
 		switch subject.Kind {
 		case "sso":
-			policy += fmt.Sprintf("g, %s, %s\n", subject.Name, roleName)
+			lines = append(lines, renderedLine{line: fmt.Sprintf("g, %s, %s", subject.Name, roleName), field: field})
 		case "role":
 			subjectRoleName := fmt.Sprintf("role:%s", subject.Name)
-			policy += fmt.Sprintf("g, %s, %s\n", subjectRoleName, roleName)
+			lines = append(lines, renderedLine{line: fmt.Sprintf("g, %s, %s", subjectRoleName, roleName), field: field})
 		case "local":
-			policy += buildPolicyStringRules(role, subject.Name)
+			for _, line := range renderPolicyRules(role, subject.Name) {
+				lines = append(lines, renderedLine{line: line.line, field: field})
+			}
 		}
 	}
-	return policy
+	return lines
 }
 
 // newConfigMap will return a new ConfigMap resource.
@@ -102,11 +157,21 @@ func (r *ArgoCDRoleReconciler) reconcileRBACConfigMap(cm *corev1.ConfigMap, role
 		cm.Data[common.ArgoCDKeyRBACPolicyCSV] = getDefaultRBACPolicy()
 		changed = true
 	}
-	// Policy OverlayKey CSV
-	if cm.Data[overlayKey] != buildPolicyStringRules(role, roleName) {
+	// Policy OverlayKey CSV, removed for unbound roles if skipped
+	if _, exists := cm.Data[overlayKey]; exists && r.SkipUnboundRoles {
+		delete(cm.Data, overlayKey)
+		changed = true
+	} else if !r.SkipUnboundRoles && cm.Data[overlayKey] != buildPolicyStringRules(role, roleName) {
 		cm.Data[overlayKey] = buildPolicyStringRules(role, roleName)
 		changed = true
 	}
+	sources := []policySource{}
+	if !r.SkipUnboundRoles {
+		sources = append(sources, newPolicySource("ArgoCDRole", role, renderPolicyRules(role, roleName)))
+	}
+	if recordPolicySources(cm, overlayKey, sources...) {
+		changed = true
+	}
 
 	if changed {
 		return r.Update(context.TODO(), cm)
@@ -133,6 +198,9 @@ func (r *ArgoCDRoleReconciler) reconcileRBACConfigMapWithRoleBinding(cm *corev1.
 		cm.Data[overlayKey] = getRBACPolicyCSV(role, rb)
 		changed = true
 	}
+	if recordPolicySources(cm, overlayKey, rolePolicySources(role, rb)...) {
+		changed = true
+	}
 
 	if changed {
 		return r.Update(context.TODO(), cm)
@@ -140,10 +208,38 @@ func (r *ArgoCDRoleReconciler) reconcileRBACConfigMapWithRoleBinding(cm *corev1.
 	return nil
 }
 
+// roleBindingKeyPrefix prefixes the name of the ArgoCDRoleBinding in its own RBAC ConfigMap key. Kubernetes names
+// can't contain "_", so the key can't collide with the key of an ArgoCDRole.
+const roleBindingKeyPrefix = "rolebinding_"
+
+// hasOwnOverlayKey returns true if the policy of the binding is written to its own RBAC ConfigMap key instead of the
+// key of the role. Bindings of ArgoCDAccessRequests and to break-glass roles grant temporary access next to the
+// standing binding of the role, they must neither replace its subjects nor revoke them when they expire.
+func hasOwnOverlayKey(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, role *rbacoperatorv1alpha1.ArgoCDRole) bool {
+	if role != nil && role.Spec.BreakGlass {
+		return true
+	}
+	owner := metav1.GetControllerOf(rb)
+	return owner != nil && owner.Kind == "ArgoCDAccessRequest" && owner.APIVersion == rbacoperatorv1alpha1.GroupVersion.String()
+}
+
+// ownOverlayKey returns the own RBAC ConfigMap key of the binding, see hasOwnOverlayKey.
+func ownOverlayKey(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) string {
+	return fmt.Sprintf("policy.%s.%s%s.csv", rb.Namespace, roleBindingKeyPrefix, rb.Name)
+}
+
+// roleBindingOverlayKey returns the RBAC ConfigMap key holding the policy of the binding to the role.
+func roleBindingOverlayKey(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, role *rbacoperatorv1alpha1.ArgoCDRole) string {
+	if hasOwnOverlayKey(rb, role) {
+		return ownOverlayKey(rb)
+	}
+	return fmt.Sprintf("policy.%s.%s.csv", rb.Namespace, role.Name)
+}
+
 // reconcileRBACConfigMap will ensure that the ArgoCD RBAC ConfigMap is up-to-date.
 func (r *ArgoCDRoleBindingReconciler) reconcileRBACConfigMap(cm *corev1.ConfigMap, rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, role *rbacoperatorv1alpha1.ArgoCDRole) error {
 	changed := false
-	overlayKey := fmt.Sprintf("policy.%s.%s.csv", role.Namespace, role.Name)
+	overlayKey := roleBindingOverlayKey(rb, role)
 
 	if cm.Data == nil {
 		cm.Data = make(map[string]string)
@@ -159,6 +255,9 @@ func (r *ArgoCDRoleBindingReconciler) reconcileRBACConfigMap(cm *corev1.ConfigMa
 		cm.Data[overlayKey] = getRBACPolicyCSV(role, rb)
 		changed = true
 	}
+	if recordPolicySources(cm, overlayKey, rolePolicySources(role, rb)...) {
+		changed = true
+	}
 
 	if changed {
 		return r.Update(context.TODO(), cm)
@@ -169,7 +268,7 @@ func (r *ArgoCDRoleBindingReconciler) reconcileRBACConfigMap(cm *corev1.ConfigMa
 // reconcileRBACConfigMap will ensure that the ArgoCD RBAC ConfigMap is up-to-date.
 func (r *ArgoCDRoleBindingReconciler) reconcileRBACConfigMapForBuiltInRole(cm *corev1.ConfigMap, rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, role *rbacoperatorv1alpha1.ArgoCDRole) error {
 	changed := false
-	overlayKey := fmt.Sprintf("policy.%s.%s.csv", role.Namespace, role.Name)
+	overlayKey := roleBindingOverlayKey(rb, role)
 
 	if cm.Data == nil {
 		cm.Data = make(map[string]string)
@@ -180,11 +279,101 @@ func (r *ArgoCDRoleBindingReconciler) reconcileRBACConfigMapForBuiltInRole(cm *c
 		cm.Data[common.ArgoCDKeyRBACPolicyCSV] = getDefaultRBACPolicy()
 		changed = true
 	}
+	// The key of the built-in role is only left by previous versions if all its bindings are break-glass
+	if role.Spec.BreakGlass && removeConfigMapKeys(cm, fmt.Sprintf("policy.%s.%s.csv", rb.Namespace, role.Name)) {
+		changed = true
+	}
 	// Policy OverlayKey CSV
 	if cm.Data[overlayKey] != buildPolicyStringSubjects(rb, role) {
 		cm.Data[overlayKey] = buildPolicyStringSubjects(rb, role)
 		changed = true
 	}
+	if recordPolicySources(cm, overlayKey, newPolicySource("ArgoCDRoleBinding", rb, renderPolicySubjects(rb, role))) {
+		changed = true
+	}
+
+	if changed {
+		return r.Update(context.TODO(), cm)
+	}
+	return nil
+}
+
+// projectRoleBindingKeyPrefix prefixes the name of the ArgoCDProjectRoleBinding in its RBAC ConfigMap key. Kubernetes
+// names can't contain "_", so the key can't collide with the key of an ArgoCDRole.
+const projectRoleBindingKeyPrefix = "projectrolebinding_"
+
+// projectRoleBindingOverlayKey returns the RBAC ConfigMap key holding the global policy of the given ArgoCDProjectRoleBinding.
+func projectRoleBindingOverlayKey(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) string {
+	return fmt.Sprintf("policy.%s.%s%s.csv", rb.Namespace, projectRoleBindingKeyPrefix, rb.Name)
+}
+
+// buildPolicyStringProjectSubjects will build the policy string binding the users of the given
+// ArgoCDProjectRoleBinding to the project role of every bound AppProject.
+func buildPolicyStringProjectSubjects(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding, argoCDNamespace string) string {
+	return joinRenderedLines(renderPolicyProjectSubjects(rb, argoCDNamespace))
+}
+
+// renderPolicyProjectSubjects renders the users of the given ArgoCDProjectRoleBinding, each line with the subject it
+// was rendered from. Users and AppProjects with values that would break out of their line are left out, the CRD
+// rejects them but may be outdated.
+func renderPolicyProjectSubjects(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding, argoCDNamespace string) []renderedLine {
+	lines := []renderedLine{}
+	roleName := rb.Spec.ArgoCDProjectRoleRef.Name
+	for _, subject := range activeAppProjectSubjects(rb, timeNow()) {
+		appProject := types.NamespacedName{
+			Name:      subject.AppProjectRef,
+			Namespace: resolveAppProjectNamespace(subject, argoCDNamespace, rb.Namespace),
+		}
+		if !isAppProjectInStatus(rb.Status.AppProjectsBound, appProjectStatusKey(appProject, rb.Namespace)) {
+			continue // role is not bound to the AppProject (yet)
+		}
+		if !isPolicyValue(subject.AppProjectRef) {
+			continue
+		}
+		for _, user := range subject.Users {
+			if !isPolicyValue(user.Name) {
+				continue
+			}
+			lines = append(lines, renderedLine{
+				line: fmt.Sprintf("g, %s, proj:%s:%s", user.Name, subject.AppProjectRef, appProjectRoleName(appProject.Namespace, rb.Namespace, roleName)),
+				field: projectSubjectField(rb, subject.AppProjectRef, func(s rbacoperatorv1alpha1.AppProjectSubject) bool {
+					return slices.ContainsFunc(s.Users, func(u rbacoperatorv1alpha1.AppProjectUser) bool { return u.Name == user.Name })
+				}),
+			})
+		}
+	}
+	return lines
+}
+
+// isPolicyValue returns true if the value can be rendered as a field of a policy line: a comma would start another
+// field and a line break another line, e.g. "x, role:admin".
+func isPolicyValue(value string) bool {
+	return value != "" && !strings.ContainsAny(value, ",\r\n")
+}
+
+// reconcileRBACConfigMap will ensure that the global policy of the ArgoCDProjectRoleBinding in the ArgoCD RBAC ConfigMap is up-to-date.
+func (r *ArgoCDProjectRoleBindingReconciler) reconcileRBACConfigMap(cm *corev1.ConfigMap, rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) error {
+	changed := false
+	overlayKey := projectRoleBindingOverlayKey(rb)
+	rendered := renderPolicyProjectSubjects(rb, r.ArgoCDNamespace)
+	policy := joinRenderedLines(rendered)
+
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+
+	// Policy OverlayKey CSV
+	if _, exists := cm.Data[overlayKey]; exists && policy == "" {
+		delete(cm.Data, overlayKey)
+		changed = true
+	}
+	if policy != "" && cm.Data[overlayKey] != policy {
+		cm.Data[overlayKey] = policy
+		changed = true
+	}
+	if recordPolicySources(cm, overlayKey, newPolicySource("ArgoCDProjectRoleBinding", rb, rendered)) {
+		changed = true
+	}
 
 	if changed {
 		return r.Update(context.TODO(), cm)
@@ -194,17 +383,30 @@ func (r *ArgoCDRoleBindingReconciler) reconcileRBACConfigMapForBuiltInRole(cm *c
 
 // IsObjectFound will perform a basic check that the given object exists via the Kubernetes API.
 // If an error occurs as part of the check, the function will return false.
-func IsObjectFound(client client.Client, namespace string, name string, obj client.Object) bool {
+func IsObjectFound(client client.Reader, namespace string, name string, obj client.Object) bool {
 	return !apierrors.IsNotFound(FetchObject(client, namespace, name, obj))
 }
 
 // FetchObject will retrieve the object with the given namespace and name using the Kubernetes API.
 // The result will be stored in the given object.
-func FetchObject(client client.Client, namespace string, name string, obj client.Object) error {
+func FetchObject(client client.Reader, namespace string, name string, obj client.Object) error {
 	return client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, obj)
 }
 
-// createBuiltInAdminRole will return a new built-in ArgoCDRole with admin permissions.
+// isBuiltInRole returns true if the role is one of the built-in roles of Argo CD.
+func isBuiltInRole(name string) bool {
+	return name == common.ArgoCDRoleAdmin || name == common.ArgoCDRoleReadOnly
+}
+
+// createBuiltInRole will return the built-in ArgoCDRole of the given name.
+func (r *ArgoCDRoleBindingReconciler) createBuiltInRole(rbNamespace, name string) *rbacoperatorv1alpha1.ArgoCDRole {
+	if name == common.ArgoCDRoleAdmin {
+		return r.createBuiltInAdminRole(rbNamespace)
+	}
+	return r.createBuiltInReadOnlyRole(rbNamespace)
+}
+
+// createBuiltInAdminRole will return a new built-in ArgoCDRole with admin permissions, break-glass if BreakGlassAdmin is set.
 func (r *ArgoCDRoleBindingReconciler) createBuiltInAdminRole(rbNamespace string) *rbacoperatorv1alpha1.ArgoCDRole {
 	return &rbacoperatorv1alpha1.ArgoCDRole{
 		ObjectMeta: metav1.ObjectMeta{
@@ -212,6 +414,7 @@ func (r *ArgoCDRoleBindingReconciler) createBuiltInAdminRole(rbNamespace string)
 			Namespace: rbNamespace,
 		},
 		Spec: rbacoperatorv1alpha1.ArgoCDRoleSpec{
+			BreakGlass: r.BreakGlassAdmin,
 			Rules: []rbacoperatorv1alpha1.GlobalRule{
 				{
 					Resource: "applications",
@@ -321,3 +524,84 @@ func (r *ArgoCDRoleBindingReconciler) createBuiltInReadOnlyRole(rbNamespace stri
 		},
 	}
 }
+
+// rbacConfigMapKeys returns the namespace and name of the policy.<namespace>.<name>.csv keys of the Argo CD RBAC ConfigMap,
+// including the keys removed since the operator last wrote them, and nil for other ConfigMaps.
+func rbacConfigMapKeys(obj client.Object, name, namespace string) []types.NamespacedName {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok || cm.Name != name || cm.Namespace != namespace {
+		return nil
+	}
+	keys := slices.Collect(maps.Keys(cm.Data))
+	for key := range policyDigests(cm) {
+		if _, ok := cm.Data[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	names := []types.NamespacedName{}
+	for _, key := range keys {
+		if !isOperatorPolicyKey(key) {
+			continue
+		}
+		keyNamespace, keyName, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(key, "policy."), ".csv"), ".")
+		names = append(names, types.NamespacedName{Namespace: keyNamespace, Name: keyName})
+	}
+	return names
+}
+
+// mapRBACConfigMapToRoles maps the Argo CD RBAC ConfigMap to the ArgoCDRoles of its keys, so the keys changed outside of
+// the operator are written again, and the parameters ConfigMap of Argo CD to the roles with application objects.
+func (r *ArgoCDRoleReconciler) mapRBACConfigMapToRoles(ctx context.Context, obj client.Object) []reconcile.Request {
+	if isObjectFormatConfigMap(obj, r.ArgoCDRBACConfigMapNamespace) {
+		return mapObjectFormatToRoles(ctx, r.Client)
+	}
+	requests := []reconcile.Request{}
+	for _, key := range rbacConfigMapKeys(obj, r.ArgoCDRBACConfigMapName, r.ArgoCDRBACConfigMapNamespace) {
+		if !strings.Contains(key.Name, "_") {
+			requests = append(requests, reconcile.Request{NamespacedName: key})
+		}
+	}
+	return requests
+}
+
+// mapRBACConfigMapToBindings maps the Argo CD RBAC ConfigMap to the ArgoCDRoleBindings of its keys, the bindings with their
+// own key and the bindings of the roles of the other keys, so the keys changed outside of the operator are written again.
+// The parameters ConfigMap of Argo CD is mapped to the bindings of the roles with application objects.
+func (r *ArgoCDRoleBindingReconciler) mapRBACConfigMapToBindings(ctx context.Context, obj client.Object) []reconcile.Request {
+	if isObjectFormatConfigMap(obj, r.ArgoCDRBACConfigMapNamespace) {
+		return mapObjectFormatToBindings(ctx, r.Client)
+	}
+	requests := []reconcile.Request{}
+	for _, key := range rbacConfigMapKeys(obj, r.ArgoCDRBACConfigMapName, r.ArgoCDRBACConfigMapNamespace) {
+		if name, found := strings.CutPrefix(key.Name, roleBindingKeyPrefix); found {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: key.Namespace, Name: name}})
+			continue
+		}
+		if strings.Contains(key.Name, "_") {
+			continue
+		}
+		bindings := rbacoperatorv1alpha1.ArgoCDRoleBindingList{}
+		if err := r.List(ctx, &bindings, client.InNamespace(key.Namespace)); err != nil {
+			return nil
+		}
+		for _, rb := range bindings.Items {
+			if rb.Spec.ArgoCDRoleRef.Name == key.Name {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&rb)})
+			}
+		}
+	}
+	return requests
+}
+
+// mapRBACConfigMapToProjectRoleBindings maps the Argo CD RBAC ConfigMap to the ArgoCDProjectRoleBindings of its keys,
+// so the keys changed outside of the operator are written again.
+func (r *ArgoCDProjectRoleBindingReconciler) mapRBACConfigMapToProjectRoleBindings(_ context.Context, obj client.Object) []reconcile.Request {
+	requests := []reconcile.Request{}
+	for _, key := range rbacConfigMapKeys(obj, r.ArgoCDRBACConfigMapName, r.ArgoCDRBACConfigMapNamespace) {
+		if name, found := strings.CutPrefix(key.Name, projectRoleBindingKeyPrefix); found {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: key.Namespace, Name: name}})
+		}
+	}
+	return requests
+}
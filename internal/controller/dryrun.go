@@ -0,0 +1,274 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strings"
+
+	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+const eventReasonDryRun = "DryRun"
+
+// isDryRun returns true if the manager runs in dry-run mode or the object has the dry-run annotation.
+func isDryRun(dryRun bool, obj client.Object) bool {
+	return dryRun || obj.GetAnnotations()[common.AnnotationDryRun] == "true"
+}
+
+// errNotPlanned returns the reason a resource whose changes can't be planned is pending in dry-run mode.
+// Such resources are not reconciled at all while in dry-run mode, not even deleted, so nothing is written.
+func errNotPlanned(kind string) error {
+	return fmt.Errorf("not reconciled in dry-run mode, the changes of an %s can't be planned", kind)
+}
+
+// planClient is a client recording the changes to ConfigMaps, Secrets and AppProjects and the deletion of bindings
+// instead of writing them. All other writes, like the status and finalizers of the operator's own resources, are passed through.
+type planClient struct {
+	client.Client
+	changes []string
+}
+
+// isPlanning returns true if the client records the changes instead of writing them.
+func isPlanning(c client.Client) bool {
+	_, ok := c.(*planClient)
+	return ok
+}
+
+// Create records the ConfigMap, Secret or AppProject as added.
+func (c *planClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if !isPlanned(obj) {
+		return c.Client.Create(ctx, obj, opts...)
+	}
+	prefix, desired := planLines(obj)
+	c.changes = append(c.changes, diffLines(prefix, nil, desired)...)
+	return nil
+}
+
+// Delete records the ConfigMap, Secret or AppProject as removed, and ArgoCDRoleBindings and ArgoCDProjectRoleBindings as deleted.
+func (c *planClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	switch o := obj.(type) {
+	case *rbacoperatorv1alpha1.ArgoCDRoleBinding:
+		c.changes = append(c.changes, fmt.Sprintf("ArgoCDRoleBinding %s/%s: deleted", o.Namespace, o.Name))
+		return nil
+	case *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding:
+		c.changes = append(c.changes, fmt.Sprintf("ArgoCDProjectRoleBinding %s/%s: deleted", o.Namespace, o.Name))
+		return nil
+	}
+	if !isPlanned(obj) {
+		return c.Client.Delete(ctx, obj, opts...)
+	}
+	live, ok := obj.DeepCopyObject().(client.Object)
+	if !ok {
+		return fmt.Errorf("cannot copy %T", obj)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), live); err != nil {
+		return err
+	}
+	prefix, actual := planLines(live)
+	c.changes = append(c.changes, diffLines(prefix, actual, nil)...)
+	return nil
+}
+
+// Update records the changes of the ConfigMap, Secret or AppProject compared to the live object.
+func (c *planClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if !isPlanned(obj) {
+		return c.Client.Update(ctx, obj, opts...)
+	}
+	return c.plan(ctx, obj)
+}
+
+// Patch records the changes of the ConfigMap, Secret or AppProject compared to the live object.
+func (c *planClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if !isPlanned(obj) {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+	return c.plan(ctx, obj)
+}
+
+func isPlanned(obj client.Object) bool {
+	switch obj.(type) {
+	case *corev1.ConfigMap, *corev1.Secret, *argocdv1alpha.AppProject:
+		return true
+	}
+	return false
+}
+
+func (c *planClient) plan(ctx context.Context, obj client.Object) error {
+	live, ok := obj.DeepCopyObject().(client.Object)
+	if !ok {
+		return fmt.Errorf("cannot copy %T", obj)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), live); err != nil {
+		return err
+	}
+	prefix, desired := planLines(obj)
+	_, actual := planLines(live)
+	c.changes = append(c.changes, diffLines(prefix, actual, desired)...)
+	return nil
+}
+
+// planLines returns the prefix of the changes of the ConfigMap, Secret or AppProject and its lines per key.
+func planLines(obj client.Object) (string, map[string][]string) {
+	switch o := obj.(type) {
+	case *corev1.ConfigMap:
+		return fmt.Sprintf("ConfigMap %s/%s", o.Namespace, o.Name), configMapLines(o)
+	case *corev1.Secret:
+		return fmt.Sprintf("Secret %s/%s", o.Namespace, o.Name), secretLines(o)
+	case *argocdv1alpha.AppProject:
+		return fmt.Sprintf("AppProject %s/%s", o.Namespace, o.Name), appProjectLines(o)
+	}
+	return "", nil
+}
+
+func configMapLines(cm *corev1.ConfigMap) map[string][]string {
+	lines := map[string][]string{}
+	for key, value := range cm.Data {
+		lines[key] = strings.Split(strings.TrimSuffix(value, "\n"), "\n")
+	}
+	return lines
+}
+
+// secretLines returns the size and hash of the values, to tell changed values apart without showing them.
+func secretLines(secret *corev1.Secret) map[string][]string {
+	lines := map[string][]string{}
+	for key, value := range secret.Data {
+		hash := fnv.New32a()
+		_, _ = hash.Write(value)
+		lines[key] = []string{fmt.Sprintf("<%d bytes, fnv %x>", len(value), hash.Sum32())}
+	}
+	return lines
+}
+
+func appProjectLines(appProject *argocdv1alpha.AppProject) map[string][]string {
+	lines := map[string][]string{}
+	for _, role := range appProject.Spec.Roles {
+		roleLines := []string{"description " + role.Description}
+		for _, group := range role.Groups {
+			roleLines = append(roleLines, "group "+group)
+		}
+		roleLines = append(roleLines, role.Policies...)
+		for _, token := range role.JWTTokens {
+			roleLines = append(roleLines, fmt.Sprintf("token %s issued at %d", token.ID, token.IssuedAt))
+		}
+		lines["role "+role.Name] = roleLines
+	}
+	return lines
+}
+
+// diffLines returns the lines added ("+") and removed ("-") per key, as "<prefix> <key>: +<line>".
+func diffLines(prefix string, actual, desired map[string][]string) []string {
+	keys := []string{}
+	for key := range actual {
+		keys = append(keys, key)
+	}
+	for key := range desired {
+		if _, ok := actual[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	changes := []string{}
+	for _, key := range keys {
+		for _, line := range actual[key] {
+			if !slices.Contains(desired[key], line) {
+				changes = append(changes, fmt.Sprintf("%s %s: -%s", prefix, key, line))
+			}
+		}
+		for _, line := range desired[key] {
+			if !slices.Contains(actual[key], line) {
+				changes = append(changes, fmt.Sprintf("%s %s: +%s", prefix, key, line))
+			}
+		}
+	}
+	return changes
+}
+
+// reportPlan sets the planned changes of the object and emits an event if they changed.
+// The planned changes of objects no longer in dry-run mode are cleared. Objects deleted meanwhile are ignored.
+func reportPlan(ctx context.Context, c client.Client, recorder record.EventRecorder, obj client.Object, planned *[]string, plan *planClient) error {
+	changes := []string(nil)
+	if plan != nil {
+		changes = plan.changes
+	}
+	if slices.Equal(*planned, changes) {
+		return nil
+	}
+	*planned = changes
+	if len(changes) > 0 && recorder != nil {
+		recorder.Eventf(obj, corev1.EventTypeNormal, eventReasonDryRun, "Planned changes not written in dry-run mode:\n%s", strings.Join(changes, "\n"))
+	}
+	return client.IgnoreNotFound(c.Status().Update(ctx, obj))
+}
+
+// planned returns a copy of the reconciler writing through a planClient if the object, the ArgoCDRole or the
+// object it is rendered with, is in dry-run mode.
+func (r *ArgoCDRoleReconciler) planned(obj client.Object) (*ArgoCDRoleReconciler, *planClient) {
+	if !isDryRun(r.DryRun, obj) {
+		return r, nil
+	}
+	plan := &planClient{Client: r.Client}
+	planned := *r
+	planned.Client = plan
+	return &planned, plan
+}
+
+// planned returns a copy of the reconciler writing through a planClient if the object, the ArgoCDRoleBinding or the
+// object it is rendered with, is in dry-run mode.
+func (r *ArgoCDRoleBindingReconciler) planned(obj client.Object) (*ArgoCDRoleBindingReconciler, *planClient) {
+	if !isDryRun(r.DryRun, obj) {
+		return r, nil
+	}
+	plan := &planClient{Client: r.Client}
+	planned := *r
+	planned.Client = plan
+	return &planned, plan
+}
+
+// planned returns a copy of the reconciler writing through a planClient if the object, the ArgoCDProjectRole or the
+// object it is rendered with, is in dry-run mode.
+func (r *ArgoCDProjectRoleReconciler) planned(obj client.Object) (*ArgoCDProjectRoleReconciler, *planClient) {
+	if !isDryRun(r.DryRun, obj) {
+		return r, nil
+	}
+	plan := &planClient{Client: r.Client}
+	planned := *r
+	planned.Client = plan
+	return &planned, plan
+}
+
+// planned returns a copy of the reconciler writing through a planClient if the object, the ArgoCDProjectRoleBinding or the
+// object it is rendered with, is in dry-run mode.
+func (r *ArgoCDProjectRoleBindingReconciler) planned(obj client.Object) (*ArgoCDProjectRoleBindingReconciler, *planClient) {
+	if !isDryRun(r.DryRun, obj) {
+		return r, nil
+	}
+	plan := &planClient{Client: r.Client}
+	planned := *r
+	planned.Client = plan
+	return &planned, plan
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"net/http"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/graph"
+)
+
+// GraphPath is the path the graph of the Argo CD RBAC policy is served on.
+const GraphPath = "/rbac-graph"
+
+// graphContentTypes are the content types of the formats of the graph.
+var graphContentTypes = map[string]string{
+	graph.FormatDOT:     "text/vnd.graphviz; charset=utf-8",
+	graph.FormatMermaid: "text/plain; charset=utf-8",
+	graph.FormatJSON:    "application/json",
+}
+
+// GraphHandler serves the graph of the live Argo CD RBAC policy. The query parameters format (dot, mermaid or json,
+// dot if not set), namespace, subject and resource select the format and filter the graph.
+type GraphHandler struct {
+	Client                       client.Reader
+	ArgoCDRBACConfigMapName      string
+	ArgoCDRBACConfigMapNamespace string
+}
+
+func (h *GraphHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = graph.FormatDOT
+	}
+	contentType, ok := graphContentTypes[format]
+	if !ok {
+		http.Error(w, "format must be dot, mermaid or json", http.StatusBadRequest)
+		return
+	}
+
+	p, err := LoadPolicy(r.Context(), h.Client, h.ArgoCDRBACConfigMapName, h.ArgoCDRBACConfigMapNamespace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	g := graph.Build(p, graph.Filter{Namespace: query.Get("namespace"), Subject: query.Get("subject"), Resource: query.Get("resource")})
+	out := &bytes.Buffer{}
+	if err := g.Write(out, format); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(out.Bytes())
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/graph"
+)
+
+func TestGraphHandler(t *testing.T) {
+	argocdRole := makeTestRole()
+	argocdRoleBinding := makeTestRoleBindingWithSSOSubject()
+	cm := recordTestPolicySources(makeTestCM_ArgoCDRole_WithRoleBindingSSOSubject_Expected(), argocdRole, argocdRoleBinding)
+
+	resObjs := []client.Object{argocdRole, argocdRoleBinding, cm}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	handler := &GraphHandler{
+		Client:                       makeTestReconcilerClient(scheme, resObjs, nil),
+		ArgoCDRBACConfigMapName:      testRBACCMName,
+		ArgoCDRBACConfigMapNamespace: testRBACCMNamespace,
+	}
+
+	// The subject leads through the binding to the role and the objects of its rules
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, GraphPath+"?format=json&subject=gosha", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	g := &graph.Graph{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), g))
+	binding := "ArgoCDRoleBinding:" + testNamespace + "/" + testRoleBindingName
+	assert.Equal(t, []graph.Edge{
+		{From: "role:" + testRoleName, To: "resource:applications:*/*", Label: "get, list"},
+		{From: "subject:gosha", To: binding},
+		{From: binding, To: "role:" + testRoleName},
+	}, g.Edges)
+
+	// DOT by default
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, GraphPath+"?namespace="+testNamespace, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"subject:gosha" -> "`+binding+`";`)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, GraphPath+"?format=svg", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
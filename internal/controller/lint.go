@@ -0,0 +1,73 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/lint"
+)
+
+// The lint functions below set the PolicyRisk condition and the policy risk metric of the object from the findings
+// of the linter on what is rendered. Nothing is linted without linter.
+
+// lintRole lints the rules of the ArgoCDRole.
+func lintRole(linter *lint.Linter, role *rbacoperatorv1alpha1.ArgoCDRole) {
+	if linter != nil {
+		setPolicyRisk(role, "ArgoCDRole", role.Namespace, role.Name, linter.GlobalRules(role.Spec.Rules))
+	}
+}
+
+// lintRoleBinding lints the subjects of the ArgoCDRoleBinding.
+func lintRoleBinding(linter *lint.Linter, rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) {
+	if linter != nil {
+		setPolicyRisk(rb, "ArgoCDRoleBinding", rb.Namespace, rb.Name, linter.RoleBinding(rb))
+	}
+}
+
+// lintProjectRole lints the rules of the ArgoCDProjectRole.
+func lintProjectRole(linter *lint.Linter, role *rbacoperatorv1alpha1.ArgoCDProjectRole) {
+	if linter != nil {
+		setPolicyRisk(role, "ArgoCDProjectRole", role.Namespace, role.Name, linter.ProjectRules(role.Spec.Rules))
+	}
+}
+
+// lintProjectRoleBinding lints the groups and users of the ArgoCDProjectRoleBinding.
+func lintProjectRoleBinding(linter *lint.Linter, rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) {
+	if linter != nil {
+		setPolicyRisk(rb, "ArgoCDProjectRoleBinding", rb.Namespace, rb.Name, linter.ProjectRoleBinding(rb))
+	}
+}
+
+// setPolicyRisk sets the PolicyRisk condition to the top finding and the metric to its severity.
+func setPolicyRisk(obj interface {
+	SetConditions(...rbacoperatorv1alpha1.Condition)
+}, kind, namespace, name string, findings []lint.Finding) {
+	top := lint.Top(findings)
+	if top == nil {
+		obj.SetConditions(rbacoperatorv1alpha1.NoPolicyRisk())
+		policyRiskSeverity.WithLabelValues(kind, namespace, name).Set(0)
+		return
+	}
+	message := top.String()
+	if len(findings) > 1 {
+		message = fmt.Sprintf("%s (%d findings)", message, len(findings))
+	}
+	obj.SetConditions(rbacoperatorv1alpha1.PolicyRisk().WithMessage(message))
+	policyRiskSeverity.WithLabelValues(kind, namespace, name).Set(float64(top.Severity.Rank()))
+}
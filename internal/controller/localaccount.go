@@ -0,0 +1,221 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+// accountToken is an API token of a local account as stored by Argo CD in the Argo CD Secret.
+type accountToken struct {
+	ID        string `json:"id"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+}
+
+// accountKey returns the key of the local account in the Argo CD ConfigMap or Secret, e.g. "accounts.<name>.enabled".
+func accountKey(name string, suffix ...string) string {
+	return strings.Join(append([]string{common.ArgoCDKeyAccountPrefix, name}, suffix...), ".")
+}
+
+// accountTokenSubject returns the subject of an API token issued for the local account.
+func accountTokenSubject(name string) string {
+	return fmt.Sprintf("%s:%s", name, rbacoperatorv1alpha1.AccountCapabilityAPIKey)
+}
+
+// reconcileAccountConfigMap will set the capabilities and the enabled flag of the local account in the Argo CD ConfigMap.
+// Returns true if the ConfigMap was changed.
+func reconcileAccountConfigMap(cm *corev1.ConfigMap, account *rbacoperatorv1alpha1.ArgoCDLocalAccount) bool {
+	capabilities := make([]string, 0, len(account.Spec.Capabilities))
+	for _, capability := range account.Spec.Capabilities {
+		capabilities = append(capabilities, string(capability))
+	}
+
+	want := map[string]string{
+		accountKey(account.Name): strings.Join(capabilities, ","),
+		accountKey(account.Name, common.ArgoCDKeyAccountEnabledSuffix): strconv.FormatBool(account.IsEnabled()),
+	}
+	changed := setAccountManager(cm, account.Name, account.Namespace)
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	for key, value := range want {
+		if cm.Data[key] != value {
+			cm.Data[key] = value
+			changed = true
+		}
+	}
+	return changed
+}
+
+// accountManager returns the namespace of the ArgoCDLocalAccount managing the local account, empty if the account is
+// not managed by an ArgoCDLocalAccount.
+func accountManager(cm *corev1.ConfigMap, name string) string {
+	for _, managed := range strings.Split(cm.Annotations[common.AnnotationManagedAccounts], ",") {
+		if namespace, account, ok := strings.Cut(strings.TrimSpace(managed), "/"); ok && account == name {
+			return namespace
+		}
+	}
+	return ""
+}
+
+// accountExists returns true if the local account is configured in the Argo CD ConfigMap.
+func accountExists(cm *corev1.ConfigMap, name string) bool {
+	_, exists := cm.Data[accountKey(name)]
+	_, enabledExists := cm.Data[accountKey(name, common.ArgoCDKeyAccountEnabledSuffix)]
+	return exists || enabledExists
+}
+
+// setAccountManager will record the namespace of the ArgoCDLocalAccount managing the local account in the annotation
+// of the Argo CD ConfigMap. An empty namespace removes the account from the annotation. Returns true if the ConfigMap
+// was changed.
+func setAccountManager(cm *corev1.ConfigMap, name, namespace string) bool {
+	if accountManager(cm, name) == namespace {
+		return false
+	}
+	managed := []string{}
+	for _, entry := range strings.Split(cm.Annotations[common.AnnotationManagedAccounts], ",") {
+		if _, account, ok := strings.Cut(strings.TrimSpace(entry), "/"); ok && account != name {
+			managed = append(managed, strings.TrimSpace(entry))
+		}
+	}
+	if namespace != "" {
+		managed = append(managed, namespace+"/"+name)
+	}
+	slices.Sort(managed)
+	if cm.Annotations == nil {
+		cm.Annotations = map[string]string{}
+	}
+	cm.Annotations[common.AnnotationManagedAccounts] = strings.Join(managed, ",")
+	if len(managed) == 0 {
+		delete(cm.Annotations, common.AnnotationManagedAccounts)
+	}
+	return true
+}
+
+// removeAccountFromConfigMap will remove the local account from the Argo CD ConfigMap.
+// Returns true if the ConfigMap was changed.
+func removeAccountFromConfigMap(cm *corev1.ConfigMap, name string) bool {
+	changed := false
+	for _, key := range []string{accountKey(name), accountKey(name, common.ArgoCDKeyAccountEnabledSuffix)} {
+		if _, ok := cm.Data[key]; ok {
+			delete(cm.Data, key)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// reconcileAccountPassword will set the bcrypt hash of the password in the Argo CD Secret, if the password changed.
+// An empty password removes the password of the account. Returns true if the Secret was changed.
+func reconcileAccountPassword(secret *corev1.Secret, name string, password []byte, now time.Time) (bool, error) {
+	passwordKey := accountKey(name, common.ArgoCDKeyAccountPasswordSuffix)
+	mtimeKey := accountKey(name, common.ArgoCDKeyAccountPasswordMtimeSuffix)
+
+	if len(password) == 0 {
+		_, hasPassword := secret.Data[passwordKey]
+		_, hasMtime := secret.Data[mtimeKey]
+		delete(secret.Data, passwordKey)
+		delete(secret.Data, mtimeKey)
+		return hasPassword || hasMtime, nil
+	}
+
+	if hash, ok := secret.Data[passwordKey]; ok && bcrypt.CompareHashAndPassword(hash, password) == nil {
+		return false, nil // Password did not change
+	}
+	hash, err := bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
+	if err != nil {
+		return false, err
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[passwordKey] = hash
+	secret.Data[mtimeKey] = []byte(now.Format(time.RFC3339))
+	return true, nil
+}
+
+// getAccountTokens returns the API tokens of the local account stored in the Argo CD Secret.
+func getAccountTokens(secret *corev1.Secret, name string) ([]accountToken, error) {
+	data, ok := secret.Data[accountKey(name, common.ArgoCDKeyAccountTokensSuffix)]
+	if !ok || len(data) == 0 {
+		return nil, nil
+	}
+	var tokens []accountToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse tokens of account %s: %w", name, err)
+	}
+	return tokens, nil
+}
+
+// setAccountTokens will store the API tokens of the local account in the Argo CD Secret.
+func setAccountTokens(secret *corev1.Secret, name string, tokens []accountToken) error {
+	key := accountKey(name, common.ArgoCDKeyAccountTokensSuffix)
+	if len(tokens) == 0 {
+		delete(secret.Data, key)
+		return nil
+	}
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[key] = data
+	return nil
+}
+
+// removeAccountFromSecret will remove the password and the API tokens of the local account from the Argo CD Secret.
+// Returns true if the Secret was changed.
+func removeAccountFromSecret(secret *corev1.Secret, name string) bool {
+	changed := false
+	for _, suffix := range []string{
+		common.ArgoCDKeyAccountPasswordSuffix,
+		common.ArgoCDKeyAccountPasswordMtimeSuffix,
+		common.ArgoCDKeyAccountTokensSuffix,
+	} {
+		if _, ok := secret.Data[accountKey(name, suffix)]; ok {
+			delete(secret.Data, accountKey(name, suffix))
+			changed = true
+		}
+	}
+	return changed
+}
+
+func hasAccountToken(tokens []accountToken, id string) bool {
+	return slices.ContainsFunc(tokens, func(t accountToken) bool {
+		return t.ID == id
+	})
+}
+
+func withoutAccountToken(tokens []accountToken, id string) []accountToken {
+	return slices.DeleteFunc(slices.Clone(tokens), func(t accountToken) bool {
+		return t.ID == id
+	})
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// breakGlassActivationsTotal counts the bindings to break-glass roles that were activated.
+	breakGlassActivationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "argocd_rbac_operator_break_glass_activations_total",
+		Help: "Number of bindings to break-glass roles that were activated.",
+	}, []string{"namespace", "role"})
+
+	// breakGlassDeadlineSeconds is the deadline of every active binding to a break-glass role, as unix time.
+	breakGlassDeadlineSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "argocd_rbac_operator_break_glass_deadline_seconds",
+		Help: "Deadline of active bindings to break-glass roles in unix seconds.",
+	}, []string{"namespace", "binding", "role"})
+
+	// policyRiskSeverity is the severity of the top lint finding of every role and binding.
+	policyRiskSeverity = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "argocd_rbac_operator_policy_risk_severity",
+		Help: "Severity of the top lint finding of roles and bindings, from 1 (info) to 5 (critical), 0 without findings.",
+	}, []string{"kind", "namespace", "name"})
+
+	// unboundResources is 1 for every role nobody can hold and every binding without active subjects, 0 otherwise.
+	unboundResources = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "argocd_rbac_operator_unbound_resources",
+		Help: "Whether roles are not bound, or bindings have no active subjects (1) or not (0).",
+	}, []string{"kind", "namespace", "name"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(breakGlassActivationsTotal, breakGlassDeadlineSeconds, policyRiskSeverity, unboundResources)
+}
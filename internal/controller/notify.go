@@ -0,0 +1,350 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/audit"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/lint"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/notify"
+)
+
+// Notifications sends events of the changes to the Argo CD RBAC ConfigMap and AppProjects, and of bindings created
+// and expired. A change to a key or role differing from what was last written by the operator, as recorded in the
+// policy-digests annotation of the ConfigMap or AppProject, is reported as drift.
+type Notifications struct {
+	Notifier *notify.Notifier
+
+	// mu serializes the writes, so the digests written are known before the next write reads them.
+	mu sync.Mutex
+}
+
+// client returns a client sending the events of the ConfigMaps and AppProjects written by the reconciliation
+// of the object. The client is returned as is if no events are sent.
+func (n *Notifications) client(c client.Client, kind string, obj client.Object) client.Client {
+	if n == nil {
+		return c
+	}
+	return &notifyClient{Client: c, notifications: n, kind: kind, trigger: obj}
+}
+
+// send sends the event of the resource. Nothing is sent if n is nil.
+func (n *Notifications) send(kind string, obj client.Object, event notify.Event) {
+	if n == nil {
+		return
+	}
+	event.Time = timeNow().UTC()
+	event.Resource = notify.Resource{Kind: kind, Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	n.Notifier.Send(event)
+}
+
+// bindingCreated sends the event of a binding of the role to the subjects reconciled for the first time.
+func (n *Notifications) bindingCreated(kind string, obj client.Object, role string, subjects []string) {
+	n.send(kind, obj, notify.Event{
+		Type:     notify.TypeBindingCreated,
+		Severity: roleSeverity(role, lint.SeverityLow),
+		Role:     role,
+		Subjects: subjects,
+		Message:  fmt.Sprintf("%s %s/%s binds %s to %s", kind, obj.GetNamespace(), obj.GetName(), role, strings.Join(subjects, ", ")),
+	})
+}
+
+// bindingExpired sends the event of the access of a subject of a binding that expired.
+func (n *Notifications) bindingExpired(kind string, obj client.Object, role, subject string) {
+	n.send(kind, obj, notify.Event{
+		Type:     notify.TypeBindingExpired,
+		Severity: lint.SeverityInfo,
+		Role:     role,
+		Subjects: []string{subject},
+		Message:  fmt.Sprintf("Access of %s to %s expired", subject, role),
+	})
+}
+
+// roleBindingSubjects returns the subjects of the ArgoCDRoleBinding as "<kind>:<name>".
+func roleBindingSubjects(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) []string {
+	subjects := []string{}
+	for _, subject := range rb.Spec.Subjects {
+		subjects = append(subjects, globalSubjectKey(subject))
+	}
+	return subjects
+}
+
+// projectRoleBindingSubjects returns the groups of the ArgoCDProjectRoleBinding as "group:<name>" and the users
+// as "<kind>:<name>".
+func projectRoleBindingSubjects(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) []string {
+	subjects := []string{}
+	for _, subject := range rb.Spec.Subjects {
+		for _, group := range subject.Groups {
+			subjects = append(subjects, "group:"+group)
+		}
+		for _, user := range subject.Users {
+			subjects = append(subjects, fmt.Sprintf("%s:%s", user.Kind, user.Name))
+		}
+	}
+	return subjects
+}
+
+// roleSeverity returns critical for the built-in admin role, the given severity otherwise.
+func roleSeverity(role string, severity lint.Severity) lint.Severity {
+	if role == common.ArgoCDRoleAdmin {
+		return lint.SeverityCritical
+	}
+	return severity
+}
+
+// notifyClient is a client sending the events of every change to a ConfigMap or AppProject.
+type notifyClient struct {
+	client.Client
+	notifications *Notifications
+	kind          string
+	trigger       client.Object
+}
+
+// Update writes the object and sends the events of the changes if it is a ConfigMap or AppProject.
+func (c *notifyClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return c.write(ctx, obj, func() error { return c.Client.Update(ctx, obj, opts...) })
+}
+
+// Patch writes the object and sends the events of the changes if it is a ConfigMap or AppProject.
+func (c *notifyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.write(ctx, obj, func() error { return c.Client.Patch(ctx, obj, patch, opts...) })
+}
+
+func (c *notifyClient) write(ctx context.Context, obj client.Object, write func() error) error {
+	n := c.notifications
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	live, err := liveTarget(ctx, c.Client, obj)
+	if err != nil {
+		return err
+	}
+	if live == nil {
+		return write()
+	}
+	after := objectPolicyLines(obj)
+	changes := audit.NewChanges(objectPolicyLines(live), after)
+	written := policyDigests(live)
+	digests := maps.Clone(written)
+	for _, change := range changes {
+		if _, ok := after[change.Key]; ok {
+			digests[change.Key] = policyDigest(change.After)
+		} else {
+			delete(digests, change.Key)
+		}
+	}
+	if len(changes) > 0 {
+		if err := setPolicyDigests(obj, digests); err != nil {
+			return err
+		}
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	target := policyTarget(obj)
+	for _, change := range changes {
+		digest, known := written[change.Key]
+		for _, event := range c.events(obj, change, known && digest != policyDigest(change.Before)) {
+			event.Target = target
+			event.Key = change.Key
+			n.send(c.kind, c.trigger, event)
+		}
+	}
+	return nil
+}
+
+// policyDigests returns the digests of the lines last written by the operator per key or role of the ConfigMap or AppProject.
+func policyDigests(obj client.Object) map[string]string {
+	digests := map[string]string{}
+	if value, ok := obj.GetAnnotations()[common.AnnotationPolicyDigests]; ok {
+		// An invalid annotation is written again, the keys it held are not checked for drift until then
+		_ = json.Unmarshal([]byte(value), &digests)
+	}
+	return digests
+}
+
+// setPolicyDigests sets the policy-digests annotation of the ConfigMap or AppProject.
+func setPolicyDigests(obj client.Object, digests map[string]string) error {
+	value, err := json.Marshal(digests)
+	if err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[common.AnnotationPolicyDigests] = string(value)
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// policyDigest returns the SHA-256 of the lines of a key or role. The tokens of AppProject roles are issued by
+// ArgoCDProjectRoleTokens and Argo CD, not written with the role, and are left out.
+func policyDigest(lines []string) string {
+	hash := sha256.New()
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "token ") {
+			hash.Write([]byte(line + "\n"))
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// events returns the events of the change of a ConfigMap key or AppProject role.
+func (c *notifyClient) events(obj client.Object, change audit.Change, drifted bool) []notify.Event {
+	added, removed := lineDiff(change.Before, change.After)
+	if drifted {
+		return []notify.Event{{
+			Type:     notify.TypeDriftReverted,
+			Severity: lint.SeverityHigh,
+			Role:     triggerRole(c.trigger),
+			Added:    added,
+			Removed:  removed,
+			Message:  fmt.Sprintf("%s %s was changed outside of the operator and is reverted", policyTarget(obj), change.Key),
+		}}
+	}
+	if _, ok := obj.(*argocdv1alpha.AppProject); ok {
+		role := strings.TrimPrefix(change.Key, "role ")
+		return []notify.Event{{
+			Type:     notify.TypeAppProjectRolePatched,
+			Severity: lint.SeverityMedium,
+			Role:     role,
+			Subjects: audit.Subjects([]audit.Change{change}),
+			Added:    added,
+			Removed:  removed,
+			Message:  fmt.Sprintf("Role %s of %s is patched", role, policyTarget(obj)),
+		}}
+	}
+
+	events := []notify.Event{}
+	beforeSubjects, beforeRules := splitPolicyLines(change.Before, triggerRole(c.trigger))
+	afterSubjects, afterRules := splitPolicyLines(change.After, triggerRole(c.trigger))
+	for _, grant := range slices.Sorted(maps.Keys(afterSubjects)) {
+		if !beforeSubjects[grant] {
+			subject, role, _ := strings.Cut(grant, " ")
+			events = append(events, notify.Event{
+				Type:     notify.TypeSubjectAdded,
+				Severity: roleSeverity(role, lint.SeverityMedium),
+				Role:     role,
+				Subjects: []string{subject},
+				Message:  fmt.Sprintf("%s is granted %s", subject, role),
+			})
+		}
+	}
+	for _, grant := range slices.Sorted(maps.Keys(beforeSubjects)) {
+		if !afterSubjects[grant] {
+			subject, role, _ := strings.Cut(grant, " ")
+			events = append(events, notify.Event{
+				Type:     notify.TypeSubjectRemoved,
+				Severity: lint.SeverityInfo,
+				Role:     role,
+				Subjects: []string{subject},
+				Message:  fmt.Sprintf("%s is no longer granted %s", subject, role),
+			})
+		}
+	}
+	roles := slices.Sorted(maps.Keys(beforeRules))
+	for _, role := range slices.Sorted(maps.Keys(afterRules)) {
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	for _, role := range roles {
+		rulesAdded, rulesRemoved := lineDiff(beforeRules[role], afterRules[role])
+		if len(rulesAdded) == 0 && len(rulesRemoved) == 0 {
+			continue
+		}
+		events = append(events, notify.Event{
+			Type:     notify.TypeRoleRulesChanged,
+			Severity: lint.SeverityMedium,
+			Role:     role,
+			Added:    rulesAdded,
+			Removed:  rulesRemoved,
+			Message:  fmt.Sprintf("Rules of %s changed", role),
+		})
+	}
+	return events
+}
+
+// splitPolicyLines returns the grants of the lines as "<subject> <role>", and the rules of every role.
+// Group lines grant their role, policy lines of local accounts grant the bound role directly.
+func splitPolicyLines(lines []string, boundRole string) (map[string]bool, map[string][]string) {
+	grants := map[string]bool{}
+	rules := map[string][]string{}
+	for _, line := range lines {
+		fields := strings.Split(line, ",")
+		if len(fields) < 3 {
+			continue
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		switch {
+		case fields[0] == "g":
+			grants[fields[1]+" "+strings.TrimPrefix(fields[2], "role:")] = true
+		case fields[0] == "p" && strings.HasPrefix(fields[1], "role:"):
+			role := strings.TrimPrefix(fields[1], "role:")
+			rules[role] = append(rules[role], line)
+		case fields[0] == "p":
+			grants[fields[1]+" "+boundRole] = true
+		}
+	}
+	return grants, rules
+}
+
+// lineDiff returns the lines added and removed.
+func lineDiff(before, after []string) ([]string, []string) {
+	added := []string{}
+	for _, line := range after {
+		if !slices.Contains(before, line) {
+			added = append(added, line)
+		}
+	}
+	removed := []string{}
+	for _, line := range before {
+		if !slices.Contains(after, line) {
+			removed = append(removed, line)
+		}
+	}
+	return added, removed
+}
+
+// triggerRole returns the role of a role, or the role bound by a binding.
+func triggerRole(obj client.Object) string {
+	switch o := obj.(type) {
+	case *rbacoperatorv1alpha1.ArgoCDRoleBinding:
+		return o.Spec.ArgoCDRoleRef.Name
+	case *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding:
+		return o.Spec.ArgoCDProjectRoleRef.Name
+	case *rbacoperatorv1alpha1.ArgoCDRole, *rbacoperatorv1alpha1.ArgoCDProjectRole:
+		return obj.GetName()
+	}
+	return ""
+}
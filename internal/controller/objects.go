@@ -0,0 +1,93 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+)
+
+// renderRoleObjects renders the application objects of the rules of the ArgoCDRole into their objects, in the object
+// format of the Argo CD instance. Like the enforce functions, the role is only changed in memory, and must not be
+// written back with Update. Returns the application objects that can't be rendered, the role is left as is then.
+func renderRoleObjects(format policy.ObjectFormat, role *rbacoperatorv1alpha1.ArgoCDRole) error {
+	rules, errs := format.GlobalRules(role.Spec.Rules)
+	if len(errs) > 0 {
+		return errs.ToAggregate()
+	}
+	role.Spec.Rules = rules
+	return nil
+}
+
+// isObjectFormatConfigMap returns true if the object is the parameters ConfigMap of the Argo CD in the namespace, whose
+// application namespaces select the object format, see policy.LoadObjectFormat.
+func isObjectFormatConfigMap(obj client.Object, namespace string) bool {
+	cm, ok := obj.(*corev1.ConfigMap)
+	return ok && cm.Name == common.ArgoCDCmdParamsConfigMapName && cm.Namespace == namespace
+}
+
+// hasApplicationObjects returns true if rules of the ArgoCDRole have application objects.
+func hasApplicationObjects(role *rbacoperatorv1alpha1.ArgoCDRole) bool {
+	return slices.ContainsFunc(role.Spec.Rules, func(rule rbacoperatorv1alpha1.GlobalRule) bool {
+		return len(rule.ApplicationObjects) > 0
+	})
+}
+
+// mapObjectFormatToRoles enqueues the ArgoCDRoles with application objects, so they are rendered again when the
+// application namespaces of Argo CD change.
+func mapObjectFormatToRoles(ctx context.Context, c client.Reader) []reconcile.Request {
+	roles := rbacoperatorv1alpha1.ArgoCDRoleList{}
+	if err := c.List(ctx, &roles); err != nil {
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, role := range roles.Items {
+		if hasApplicationObjects(&role) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&role)})
+		}
+	}
+	return requests
+}
+
+// mapObjectFormatToBindings enqueues the ArgoCDRoleBindings of the ArgoCDRoles with application objects, so they are
+// rendered again when the application namespaces of Argo CD change.
+func mapObjectFormatToBindings(ctx context.Context, c client.Reader) []reconcile.Request {
+	roles := mapObjectFormatToRoles(ctx, c)
+	if len(roles) == 0 {
+		return nil
+	}
+	bindings := rbacoperatorv1alpha1.ArgoCDRoleBindingList{}
+	if err := c.List(ctx, &bindings); err != nil {
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, rb := range bindings.Items {
+		role := reconcile.Request{NamespacedName: client.ObjectKey{Namespace: rb.Namespace, Name: rb.Spec.ArgoCDRoleRef.Name}}
+		if slices.Contains(roles, role) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&rb)})
+		}
+	}
+	return requests
+}
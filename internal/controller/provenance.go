@@ -0,0 +1,234 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+)
+
+// LoadPolicy returns the policy Argo CD enforces: the built-in policy, the policy keys of the Argo CD RBAC ConfigMap
+// and the roles of all AppProjects. Every line is attributed to the resource and field it was rendered from, as recorded
+// in the policy-sources annotation of the ConfigMap or AppProject when it was written. Lines without recorded source are
+// attributed to the ConfigMap or AppProject.
+func LoadPolicy(ctx context.Context, c client.Reader, cmName, cmNamespace string) (*policy.Policy, error) {
+	cm := newConfigMap(cmName, cmNamespace)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(cm), cm); err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	appProjects := argocdv1alpha.AppProjectList{}
+	if err := c.List(ctx, &appProjects); err != nil {
+		return nil, err
+	}
+
+	p := &policy.Policy{Lines: policy.BuiltinLines()}
+	if role := cm.Data[common.ArgoCDKeyRBACPolicyDefault]; role != "" {
+		p.DefaultRole = role
+		p.DefaultRoleSource = rbacoperatorv1alpha1.PermissionSource{Kind: "ConfigMap", Namespace: cmNamespace, Name: cmName,
+			Key: common.ArgoCDKeyRBACPolicyDefault}
+	}
+	// Sorted, so that the lines are always in the same order
+	sources := policySources(cm)
+	policies := policyKeys(cm)
+	for _, key := range slices.Sorted(maps.Keys(policies)) {
+		configMapSource := rbacoperatorv1alpha1.PermissionSource{Kind: "ConfigMap", Namespace: cmNamespace, Name: cmName, Key: key}
+		for _, line := range policy.ParseLines(policies[key], configMapSource) {
+			if source, ok := lineSource(sources[key], line.Fields); ok {
+				line.Source = source
+			}
+			p.Lines = append(p.Lines, line)
+		}
+	}
+	for i := range appProjects.Items {
+		p.Lines = append(p.Lines, appProjectPolicyLines(&appProjects.Items[i])...)
+	}
+	return p, nil
+}
+
+// maxPolicySourcesSize is the size the policy-sources annotation is trimmed to. Kubernetes rejects objects whose
+// annotations exceed 256KiB together, the rest is left to the other annotations of the ConfigMap or AppProject.
+const maxPolicySourcesSize = 128 * 1024
+
+// policySource is a resource the lines of a ConfigMap key or AppProject role were rendered from.
+type policySource struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Fields are the fields of the resource the lines were rendered from, by digest of the line, see lineDigest.
+	Fields map[string]string `json:"fields"`
+}
+
+// newPolicySource returns the source of the lines rendered from the object.
+func newPolicySource(kind string, obj client.Object, rendered []renderedLine) policySource {
+	source := policySource{Kind: kind, Namespace: obj.GetNamespace(), Name: obj.GetName(), Fields: map[string]string{}}
+	for _, line := range rendered {
+		source.Fields[lineDigest(lineFields(line.line))] = line.field
+	}
+	return source
+}
+
+// lineSource returns the first of the sources the line was rendered from, and false if none was recorded.
+func lineSource(sources []policySource, fields []string) (rbacoperatorv1alpha1.PermissionSource, bool) {
+	digest := lineDigest(fields)
+	for _, source := range sources {
+		if field, ok := source.Fields[digest]; ok {
+			return rbacoperatorv1alpha1.PermissionSource{Kind: source.Kind, Namespace: source.Namespace, Name: source.Name, Field: field}, true
+		}
+	}
+	return rbacoperatorv1alpha1.PermissionSource{}, false
+}
+
+// lineFields returns the trimmed fields of a policy line.
+func lineFields(line string) []string {
+	fields := strings.Split(line, ",")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields
+}
+
+// lineDigest returns the first 8 bytes of the SHA-256 of the fields of a policy line, hex encoded. The lines are
+// recorded by digest to keep the policy-sources annotation small.
+func lineDigest(fields []string) string {
+	digest := sha256.Sum256([]byte(strings.Join(fields, ",")))
+	return hex.EncodeToString(digest[:8])
+}
+
+// policySources returns the sources recorded per key or role in the policy-sources annotation of the ConfigMap or AppProject.
+func policySources(obj client.Object) map[string][]policySource {
+	sources := map[string][]policySource{}
+	if value, ok := obj.GetAnnotations()[common.AnnotationPolicySources]; ok {
+		// An invalid annotation is written again, the lines it held are attributed to the ConfigMap or AppProject until then
+		_ = json.Unmarshal([]byte(value), &sources)
+	}
+	return sources
+}
+
+// recordPolicySources records the sources of the lines of the key or role of the ConfigMap or AppProject in its
+// policy-sources annotation, replacing those recorded before. The sources of the keys and roles the object does not
+// hold anymore are dropped, and those of the largest keys and roles if the annotation would exceed maxPolicySourcesSize:
+// their lines are attributed to the ConfigMap or AppProject, the policy is written anyway. Returns true if the annotation changed.
+func recordPolicySources(obj client.Object, key string, sources ...policySource) bool {
+	recorded := policySources(obj)
+	recorded[key] = sources
+	lines := objectPolicyLines(obj)
+	for recordedKey, recordedSources := range recorded {
+		if _, ok := lines[recordedKey]; !ok || len(recordedSources) == 0 {
+			delete(recorded, recordedKey)
+		}
+	}
+	// The sources are strings only, they can always be encoded
+	data, _ := json.Marshal(recorded)
+	if len(data) > maxPolicySourcesSize {
+		data = trimPolicySources(recorded)
+	}
+	annotations := obj.GetAnnotations()
+	value, exists := annotations[common.AnnotationPolicySources]
+	if len(recorded) == 0 {
+		if !exists {
+			return false
+		}
+		delete(annotations, common.AnnotationPolicySources)
+		obj.SetAnnotations(annotations)
+		return true
+	}
+	if exists && value == string(data) {
+		return false
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[common.AnnotationPolicySources] = string(data)
+	obj.SetAnnotations(annotations)
+	return true
+}
+
+// trimPolicySources drops the sources of the keys and roles with the most sources from recorded until it is encoded in
+// maxPolicySourcesSize, and returns it encoded.
+func trimPolicySources(recorded map[string][]policySource) []byte {
+	sizes := map[string]int{}
+	for key, sources := range recorded {
+		data, _ := json.Marshal(sources)
+		sizes[key] = len(data)
+	}
+	keys := slices.SortedFunc(maps.Keys(recorded), func(a, b string) int {
+		if sizes[a] != sizes[b] {
+			return sizes[b] - sizes[a]
+		}
+		return strings.Compare(a, b)
+	})
+	data, _ := json.Marshal(recorded)
+	for _, key := range keys {
+		if len(data) <= maxPolicySourcesSize {
+			break
+		}
+		delete(recorded, key)
+		data, _ = json.Marshal(recorded)
+	}
+	return data
+}
+
+// appProjectPolicyLines returns the policies and groups of the roles of the AppProject, attributed to the ArgoCDProjectRole
+// and ArgoCDProjectRoleBinding they were rendered from, other lines to the AppProject.
+func appProjectPolicyLines(appProject *argocdv1alpha.AppProject) []policy.Line {
+	lines := []policy.Line{}
+	sources := policySources(appProject)
+	appProjectSource := rbacoperatorv1alpha1.PermissionSource{Kind: "AppProject", Namespace: appProject.Namespace, Name: appProject.Name}
+	for _, role := range appProject.Spec.Roles {
+		roleLines := policy.ParseLines(strings.Join(role.Policies, "\n"), appProjectSource)
+		for _, group := range role.Groups {
+			roleLines = append(roleLines, policy.Line{
+				Fields: []string{"g", group, fmt.Sprintf("proj:%s:%s", appProject.Name, role.Name)},
+				Source: appProjectSource,
+			})
+		}
+		for _, line := range roleLines {
+			if source, ok := lineSource(sources["role "+role.Name], line.Fields); ok {
+				line.Source = source
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// projectSubjectField returns the field of the subject of the binding referencing the AppProject of the project role
+// "<appProject>:<role>" and matching, empty if none does.
+func projectSubjectField(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding, projectRole string,
+	match func(rbacoperatorv1alpha1.AppProjectSubject) bool) string {
+	appProject, _, _ := strings.Cut(projectRole, ":")
+	for i, subject := range rb.Spec.Subjects {
+		if subject.AppProjectRef == appProject && match(subject) {
+			return fmt.Sprintf("spec.subjects[%d]", i)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+)
+
+func TestLoadPolicy_Explain(t *testing.T) {
+	argocdRole := makeTestRole(func(r *rbacoperatorv1alpha1.ArgoCDRole) {
+		r.Spec.Rules = append(r.Spec.Rules, rbacoperatorv1alpha1.GlobalRule{Resource: "logs", Verbs: []string{"get"}, Objects: []string{"*/*"}})
+	})
+	argocdRoleBinding := makeTestRoleBindingWithSSOSubject()
+	cm := makeTestRBACConfigMap()
+	overlayKey := fmt.Sprintf("policy.%s.%s.csv", testNamespace, testRoleName)
+	cm.Data = map[string]string{overlayKey: getRBACPolicyCSV(argocdRole, argocdRoleBinding) + "g, mallory, role:" + testRoleName + "\n"}
+	assert.True(t, recordPolicySources(cm, overlayKey, rolePolicySources(argocdRole, argocdRoleBinding)...))
+	projectRole := makeTestProjectRole()
+	projectRoleBinding := makeTestProjectRoleBinding(func(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) {
+		rb.Status.AppProjectsBound = []string{testAppProjectName}
+	})
+	appProject := makeTestAppProject()
+	assert.NoError(t, patchAppProject(makeTestReconcilerClient(makeTestReconcilerScheme(addArgoCDPkgToScheme()), []client.Object{appProject}, nil),
+		appProject, projectRole, projectRoleBinding, &projectRoleBinding.Spec.Subjects[0].Groups))
+
+	resObjs := []client.Object{argocdRole, argocdRoleBinding, cm, projectRole, projectRoleBinding, appProject}
+	scheme := makeTestReconcilerScheme(rbacoperatorv1alpha1.AddToScheme, addArgoCDPkgToScheme())
+	client := makeTestReconcilerClient(scheme, resObjs, nil)
+
+	p, err := LoadPolicy(context.TODO(), client, testRBACCMName, testRBACCMNamespace)
+	assert.NoError(t, err)
+
+	// The chain leads from the user through the binding subject to the rule of the role
+	decision := p.Explain([]string{"gosha"}, "logs", "get", "default/guestbook")
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, []policy.Step{
+		{
+			Line:   "g, gosha, role:" + testRoleName,
+			Source: rbacoperatorv1alpha1.PermissionSource{Kind: "ArgoCDRoleBinding", Namespace: testNamespace, Name: testRoleBindingName, Field: "spec.subjects[0]"},
+		},
+		{
+			Line:   fmt.Sprintf("p, role:%s, logs, get, */*, allow", testRoleName),
+			Source: rbacoperatorv1alpha1.PermissionSource{Kind: "ArgoCDRole", Namespace: testNamespace, Name: testRoleName, Field: "spec.rules[1]"},
+		},
+	}, decision.Matches[decision.Decisive].Chain)
+
+	// Project roles lead from the group through the binding to the rule of the project role
+	decision = p.Explain([]string{"group2"}, "projects", "get", testAppProjectName)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, []policy.Step{
+		{
+			Line:   fmt.Sprintf("g, group2, proj:%s:%s", testAppProjectName, testProjectRoleName),
+			Source: rbacoperatorv1alpha1.PermissionSource{Kind: "ArgoCDProjectRoleBinding", Namespace: testNamespace, Name: testProjectRoleBindingName, Field: "spec.subjects[0]"},
+		},
+		{
+			Line:   fmt.Sprintf("p, proj:%s:%s, projects, get, *, allow", testAppProjectName, testProjectRoleName),
+			Source: rbacoperatorv1alpha1.PermissionSource{Kind: "ArgoCDProjectRole", Namespace: testNamespace, Name: testProjectRoleName, Field: "spec.rules[1]"},
+		},
+	}, decision.Matches[decision.Decisive].Chain)
+
+	// Lines are attributed to the resource they were rendered from when written, also if it changed since, e.g. pending approval
+	assert.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: argocdRole.Name, Namespace: argocdRole.Namespace}, argocdRole))
+	argocdRole.Spec.Rules = argocdRole.Spec.Rules[1:]
+	assert.NoError(t, client.Update(context.TODO(), argocdRole))
+	p, err = LoadPolicy(context.TODO(), client, testRBACCMName, testRBACCMNamespace)
+	assert.NoError(t, err)
+	decision = p.Explain([]string{"gosha"}, "logs", "get", "default/guestbook")
+	assert.Equal(t, "spec.rules[1]", decision.Matches[decision.Decisive].Chain[1].Source.Field)
+
+	// Lines not written by the operator are attributed to the ConfigMap
+	decision = p.Explain([]string{"mallory"}, "logs", "get", "default/guestbook")
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, rbacoperatorv1alpha1.PermissionSource{Kind: "ConfigMap", Namespace: testRBACCMNamespace, Name: testRBACCMName, Key: overlayKey},
+		decision.Matches[decision.Decisive].Chain[0].Source)
+}
+
+func TestRecordPolicySources_MaxSize(t *testing.T) {
+	cm := newConfigMap(testRBACCMName, testRBACCMNamespace)
+	bigKey, smallKey := "policy.team-a.big.csv", "policy.team-a.small.csv"
+	big := []renderedLine{}
+	for i := range 10000 {
+		big = append(big, renderedLine{line: fmt.Sprintf("p, role:big, applications, get, team-a/app-%d, allow", i), field: "spec.rules[0]"})
+	}
+	small := []renderedLine{{line: "p, role:small, applications, get, team-a/*, allow", field: "spec.rules[0]"}}
+	cm.Data = map[string]string{bigKey: joinRenderedLines(big), smallKey: joinRenderedLines(small)}
+	bigRole := makeTestRole(func(r *rbacoperatorv1alpha1.ArgoCDRole) { r.Name = "big" })
+	smallRole := makeTestRole(func(r *rbacoperatorv1alpha1.ArgoCDRole) { r.Name = "small" })
+
+	// The sources of the largest keys are dropped, the policy is still written
+	assert.True(t, recordPolicySources(cm, smallKey, newPolicySource("ArgoCDRole", smallRole, small)))
+	recordPolicySources(cm, bigKey, newPolicySource("ArgoCDRole", bigRole, big))
+	assert.LessOrEqual(t, len(cm.Annotations[common.AnnotationPolicySources]), maxPolicySourcesSize)
+	sources := policySources(cm)
+	assert.NotContains(t, sources, bigKey)
+	assert.Contains(t, sources, smallKey)
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+const (
+	eventReasonRecertificationDue = "RecertificationDue"
+	eventReasonAccessSuspended    = "AccessSuspended"
+	eventReasonRecertified        = "Recertified"
+	eventReasonInvalidAttestation = "InvalidAttestation"
+)
+
+// findRecertification returns the recertification status of a binding last attested at the given time.
+// The ArgoCDRecertificationPolicies in the namespace of the binding and in the Argo CD namespace apply,
+// the strictest one if there are several, so a namespace can't relax the global policy. Policies with an interval that
+// is not positive are ignored. Returns nil if no policy applies.
+func findRecertification(ctx context.Context, c client.Client, namespace, argoCDNamespace string, lastAttestedAt time.Time) (*rbacoperatorv1alpha1.RecertificationStatus, error) {
+	var strictest *rbacoperatorv1alpha1.RecertificationStatus
+	for i, ns := range []string{namespace, argoCDNamespace} {
+		if ns == "" || (i > 0 && ns == namespace) {
+			continue
+		}
+		policies := rbacoperatorv1alpha1.ArgoCDRecertificationPolicyList{}
+		if err := c.List(ctx, &policies, client.InNamespace(ns)); err != nil {
+			return nil, err
+		}
+		for _, policy := range policies.Items {
+			// A policy without interval would make every binding due at once, it is rejected by the API server
+			if policy.Spec.Interval.Duration <= 0 {
+				continue
+			}
+			status := policy.Recertification(lastAttestedAt)
+			if strictest == nil || status.DueAt.Before(&strictest.DueAt) ||
+				(status.DueAt.Equal(&strictest.DueAt) && status.SuspendAt.Before(&strictest.SuspendAt)) {
+				strictest = status
+			}
+		}
+	}
+	return strictest, nil
+}
+
+// lastAttestation returns the time and the user the binding was last attested at and by, its creation time if it never was.
+// Attestations in the future count as attested now. The attestation is only valid if the attester was recorded by the
+// webhook and is not the user that last changed the spec of the binding.
+func lastAttestation(obj client.Object, trusted bool, now time.Time) (time.Time, string, error) {
+	last := obj.GetCreationTimestamp().Time
+	if last.IsZero() {
+		last = now
+	}
+	annotations := obj.GetAnnotations()
+	value, ok := annotations[common.AnnotationAttestedAt]
+	if !ok {
+		return last, "", nil
+	}
+	attester := annotations[common.AnnotationAttestedBy]
+	switch {
+	case !trusted:
+		return last, "", fmt.Errorf("%s annotation ignored, the attester can't be verified without the webhooks", common.AnnotationAttestedAt)
+	case attester == "":
+		return last, "", fmt.Errorf("%s annotation ignored, the attester was not recorded", common.AnnotationAttestedAt)
+	case attester == annotations[common.AnnotationChangedBy]:
+		return last, "", fmt.Errorf("%s annotation ignored, %s last changed the binding and can't attest it", common.AnnotationAttestedAt, attester)
+	}
+	attestedAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return last, "", fmt.Errorf("invalid %s annotation: %v", common.AnnotationAttestedAt, err)
+	}
+	if attestedAt.After(now) {
+		attestedAt = now
+	}
+	if !attestedAt.After(last) {
+		return last.Truncate(time.Second), "", nil
+	}
+	return attestedAt.Truncate(time.Second), attester, nil
+}
+
+// recertify returns the recertification status of the binding and its Recertified condition at the given time.
+// The condition is nil if no ArgoCDRecertificationPolicy applies to the binding and none did before.
+// Events are emitted when the binding becomes due, when its subjects are suspended and when it is re-attested.
+func recertify(ctx context.Context, c client.Client, recorder record.EventRecorder, obj client.Object, conditions []rbacoperatorv1alpha1.Condition,
+	argoCDNamespace string, trustAttestations bool, now time.Time) (*rbacoperatorv1alpha1.RecertificationStatus, *rbacoperatorv1alpha1.Condition, error) {
+	lastAttestedAt, attester, err := lastAttestation(obj, trustAttestations, now)
+	if err != nil {
+		recorder.Event(obj, corev1.EventTypeWarning, eventReasonInvalidAttestation, err.Error())
+	}
+	status, err := findRecertification(ctx, c, obj.GetNamespace(), argoCDNamespace, lastAttestedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if status != nil {
+		status.LastAttestedBy = attester
+	}
+
+	wasDue := hasCondition(conditions, rbacoperatorv1alpha1.TypeRecertified, rbacoperatorv1alpha1.ReasonRecertificationDue)
+	wasSuspended := hasCondition(conditions, rbacoperatorv1alpha1.TypeActive, rbacoperatorv1alpha1.ReasonSuspended)
+	var condition rbacoperatorv1alpha1.Condition
+	switch {
+	case status.IsSuspended(now):
+		if !wasSuspended {
+			recorder.Eventf(obj, corev1.EventTypeWarning, eventReasonAccessSuspended,
+				"Subjects are suspended, the binding was not re-attested since %s", status.DueAt.UTC().Format(time.RFC3339))
+		}
+		condition = rbacoperatorv1alpha1.RecertificationDue().WithMessage(fmt.Sprintf("due since %s, subjects suspended since %s",
+			status.DueAt.UTC().Format(time.RFC3339), status.SuspendAt.UTC().Format(time.RFC3339)))
+	case status.IsDue(now):
+		if !wasDue {
+			recorder.Eventf(obj, corev1.EventTypeWarning, eventReasonRecertificationDue,
+				"Binding is due for recertification, subjects are suspended at %s unless it is re-attested with the %s annotation",
+				status.SuspendAt.UTC().Format(time.RFC3339), common.AnnotationAttestedAt)
+		}
+		condition = rbacoperatorv1alpha1.RecertificationDue().WithMessage(fmt.Sprintf("due since %s, subjects are suspended at %s",
+			status.DueAt.UTC().Format(time.RFC3339), status.SuspendAt.UTC().Format(time.RFC3339)))
+	case status != nil:
+		if wasDue {
+			recorder.Eventf(obj, corev1.EventTypeNormal, eventReasonRecertified,
+				"Binding re-attested at %s, next due at %s", status.LastAttestedAt.UTC().Format(time.RFC3339), status.DueAt.UTC().Format(time.RFC3339))
+		}
+		condition = rbacoperatorv1alpha1.Recertified().WithMessage(fmt.Sprintf("next due at %s", status.DueAt.UTC().Format(time.RFC3339)))
+	case hasCondition(conditions, rbacoperatorv1alpha1.TypeRecertified, ""):
+		condition = rbacoperatorv1alpha1.Recertified().WithMessage("no ArgoCDRecertificationPolicy applies")
+	default:
+		return nil, nil, nil
+	}
+	return status, &condition, nil
+}
+
+// hasCondition returns true if a condition of the type is set, with the given reason if not empty.
+func hasCondition(conditions []rbacoperatorv1alpha1.Condition, conditionType rbacoperatorv1alpha1.ConditionType, reason rbacoperatorv1alpha1.ConditionReason) bool {
+	for _, condition := range conditions {
+		if condition.Type == conditionType && (reason == "" || condition.Reason == reason) {
+			return true
+		}
+	}
+	return false
+}
+
+// recertificationRequeueAfter returns the duration until the binding becomes due or its subjects are suspended,
+// if it is earlier than requeueAfter.
+func recertificationRequeueAfter(status *rbacoperatorv1alpha1.RecertificationStatus, now time.Time, requeueAfter time.Duration) time.Duration {
+	if status == nil {
+		return requeueAfter
+	}
+	for _, boundary := range []time.Time{status.DueAt.Time, status.SuspendAt.Time} {
+		if boundary.After(now) {
+			requeueAfter = min(requeueAfter, boundary.Sub(now))
+		}
+	}
+	return requeueAfter
+}
+
+// reconcileRecertification sets the recertification status and the Recertified condition of the binding.
+func (r *ArgoCDRoleBindingReconciler) reconcileRecertification(ctx context.Context, rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, now time.Time) error {
+	status, condition, err := recertify(ctx, r.Client, r.Recorder, rb, rb.Status.Conditions, r.ArgoCDRBACConfigMapNamespace, r.TrustAttestations, now)
+	if err != nil {
+		return err
+	}
+	rb.Status.Recertification = status
+	if condition != nil {
+		rb.SetConditions(*condition)
+	}
+	return nil
+}
+
+// reconcileRecertification sets the recertification status and the Recertified condition of the binding.
+func (r *ArgoCDProjectRoleBindingReconciler) reconcileRecertification(ctx context.Context, rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding, now time.Time) error {
+	status, condition, err := recertify(ctx, r.Client, r.Recorder, rb, rb.Status.Conditions, r.ArgoCDRBACConfigMapNamespace, r.TrustAttestations, now)
+	if err != nil {
+		return err
+	}
+	rb.Status.Recertification = status
+	if condition != nil {
+		rb.SetConditions(*condition)
+	}
+	return nil
+}
+
+// mapRecertificationPolicyToBindings returns the ArgoCDRoleBindings the ArgoCDRecertificationPolicy may apply to.
+func (r *ArgoCDRoleBindingReconciler) mapRecertificationPolicyToBindings(ctx context.Context, obj client.Object) []reconcile.Request {
+	listOpts := []client.ListOption{}
+	if obj.GetNamespace() != r.ArgoCDRBACConfigMapNamespace {
+		listOpts = append(listOpts, client.InNamespace(obj.GetNamespace()))
+	}
+	bindings := rbacoperatorv1alpha1.ArgoCDRoleBindingList{}
+	if err := r.List(ctx, &bindings, listOpts...); err != nil {
+		r.Log.Error(err, "Failed to list ArgoCDRoleBindings", "namespace", obj.GetNamespace())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, binding := range bindings.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&binding)})
+	}
+	return requests
+}
+
+// mapRecertificationPolicyToBindings returns the ArgoCDProjectRoleBindings the ArgoCDRecertificationPolicy may apply to.
+func (r *ArgoCDProjectRoleBindingReconciler) mapRecertificationPolicyToBindings(ctx context.Context, obj client.Object) []reconcile.Request {
+	listOpts := []client.ListOption{}
+	if obj.GetNamespace() != r.ArgoCDRBACConfigMapNamespace {
+		listOpts = append(listOpts, client.InNamespace(obj.GetNamespace()))
+	}
+	bindings := rbacoperatorv1alpha1.ArgoCDProjectRoleBindingList{}
+	if err := r.List(ctx, &bindings, listOpts...); err != nil {
+		r.Log.Error(err, "Failed to list ArgoCDProjectRoleBindings", "namespace", obj.GetNamespace())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, binding := range bindings.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&binding)})
+	}
+	return requests
+}
@@ -0,0 +1,400 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+// +kubebuilder:rbac:groups=rbac-operator.argoproj-labs.io,resources=argocdrbacrevisions,verbs=get;list;watch;create;delete
+
+// RevisionHistory records the policy written to the Argo CD RBAC ConfigMap and AppProjects as ArgoCDRBACRevisions.
+type RevisionHistory struct {
+	// Namespace the revisions are kept in.
+	Namespace string
+	// Limit is the number of revisions kept, older ones are pruned. All revisions are kept if not positive.
+	Limit int
+}
+
+// client returns a client recording the ConfigMaps and AppProjects written by the reconciliation of the object.
+// The client is returned as is if no history is kept.
+func (h *RevisionHistory) client(c client.Client, kind string, obj client.Object) client.Client {
+	if h == nil {
+		return c
+	}
+	return &revisionClient{
+		Client:  c,
+		history: h,
+		trigger: rbacoperatorv1alpha1.RevisionTrigger{
+			Kind:       kind,
+			Namespace:  obj.GetNamespace(),
+			Name:       obj.GetName(),
+			Generation: obj.GetGeneration(),
+		},
+	}
+}
+
+// revisionClient is a client recording an ArgoCDRBACRevision for every write to a ConfigMap or AppProject.
+type revisionClient struct {
+	client.Client
+	history *RevisionHistory
+	trigger rbacoperatorv1alpha1.RevisionTrigger
+}
+
+// Update writes the object and records a revision if it is a ConfigMap or AppProject.
+func (c *revisionClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if err := c.Client.Update(ctx, obj, opts...); err != nil {
+		return err
+	}
+	return c.record(ctx, obj)
+}
+
+// Patch writes the object and records a revision if it is a ConfigMap or AppProject.
+func (c *revisionClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if err := c.Client.Patch(ctx, obj, patch, opts...); err != nil {
+		return err
+	}
+	return c.record(ctx, obj)
+}
+
+func (c *revisionClient) record(ctx context.Context, obj client.Object) error {
+	revision := &rbacoperatorv1alpha1.ArgoCDRBACRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "revision-",
+			Namespace:    c.history.Namespace,
+		},
+		Spec: rbacoperatorv1alpha1.ArgoCDRBACRevisionSpec{
+			Trigger:    c.trigger,
+			RecordedAt: metav1.NowMicro(),
+		},
+	}
+	switch o := obj.(type) {
+	case *corev1.ConfigMap:
+		revision.Spec.ConfigMap = o.Namespace + "/" + o.Name
+		revision.Spec.Policies = policyKeys(o)
+	case *argocdv1alpha.AppProject:
+		revision.Spec.AppProject = o.Namespace + "/" + o.Name
+		revision.Spec.ProjectRoles = revisionProjectRoles(o)
+	default:
+		return nil
+	}
+	if err := c.Client.Create(ctx, revision); err != nil {
+		return fmt.Errorf("policy written, but revision not recorded: %v", err)
+	}
+	return c.history.prune(ctx, c.Client)
+}
+
+// prune deletes the oldest revisions exceeding the limit.
+func (h *RevisionHistory) prune(ctx context.Context, c client.Client) error {
+	if h.Limit <= 0 {
+		return nil
+	}
+	revisions, err := listRevisions(ctx, c, h.Namespace)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(revisions)-h.Limit; i++ {
+		if err := c.Delete(ctx, &revisions[i]); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listRevisions returns the revisions in the namespace, oldest first.
+func listRevisions(ctx context.Context, c client.Client, namespace string) ([]rbacoperatorv1alpha1.ArgoCDRBACRevision, error) {
+	list := rbacoperatorv1alpha1.ArgoCDRBACRevisionList{}
+	if err := c.List(ctx, &list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].RecordedBefore(&list.Items[j]) })
+	return list.Items, nil
+}
+
+// isPolicyKey returns true if the ConfigMap key holds a policy CSV.
+func isPolicyKey(key string) bool {
+	return strings.HasPrefix(key, "policy.") && strings.HasSuffix(key, ".csv")
+}
+
+// policyKeys returns the policy.*.csv keys of the ConfigMap.
+func policyKeys(cm *corev1.ConfigMap) map[string]string {
+	policies := map[string]string{}
+	for key, value := range cm.Data {
+		if isPolicyKey(key) {
+			policies[key] = value
+		}
+	}
+	return policies
+}
+
+// revisionProjectRoles returns the roles of the AppProject without their JWT tokens.
+func revisionProjectRoles(appProject *argocdv1alpha.AppProject) []rbacoperatorv1alpha1.RevisionProjectRole {
+	roles := []rbacoperatorv1alpha1.RevisionProjectRole{}
+	for _, role := range appProject.Spec.Roles {
+		roles = append(roles, rbacoperatorv1alpha1.RevisionProjectRole{
+			Name:        role.Name,
+			Description: role.Description,
+			Groups:      role.Groups,
+			Policies:    role.Policies,
+		})
+	}
+	return roles
+}
+
+// pausedCondition returns the Synced condition of a resource whose additions to the policy are not written, because it
+// or a resource it is rendered with is paused by an ArgoCDRBACRollback. The paused annotation is only honored if the
+// ArgoCDRBACRollback it names lists the resource in its status, which only the operator writes, so it can't be forged.
+// Returns false if none of the resources is paused.
+func pausedCondition(ctx context.Context, c client.Client, objs ...client.Object) (rbacoperatorv1alpha1.Condition, bool, error) {
+	for _, obj := range objs {
+		by := obj.GetAnnotations()[common.AnnotationPaused]
+		if by == "" {
+			continue
+		}
+		rollback := &rbacoperatorv1alpha1.ArgoCDRBACRollback{}
+		name, namespace := splitNamespacedName(by)
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, rollback); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return rbacoperatorv1alpha1.Condition{}, false, err
+		}
+		if !slices.Contains(rollback.Status.Paused, pausedTrigger(obj)) {
+			continue
+		}
+		return rbacoperatorv1alpha1.Paused().WithMessage(fmt.Sprintf("%s is paused by ArgoCDRBACRollback %s, only revocations are written, remove the %s annotation to resume",
+			obj.GetName(), by, common.AnnotationPaused)), true, nil
+	}
+	return rbacoperatorv1alpha1.Condition{}, false, nil
+}
+
+// pausedTrigger returns the resource as listed in the paused resources of an ArgoCDRBACRollback.
+func pausedTrigger(obj client.Object) string {
+	trigger := rbacoperatorv1alpha1.RevisionTrigger{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	switch obj.(type) {
+	case *rbacoperatorv1alpha1.ArgoCDRole:
+		trigger.Kind = "ArgoCDRole"
+	case *rbacoperatorv1alpha1.ArgoCDRoleBinding:
+		trigger.Kind = "ArgoCDRoleBinding"
+	case *rbacoperatorv1alpha1.ArgoCDProjectRole:
+		trigger.Kind = "ArgoCDProjectRole"
+	case *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding:
+		trigger.Kind = "ArgoCDProjectRoleBinding"
+	}
+	return trigger.String()
+}
+
+// syncedCondition returns the Synced condition of a reconciled resource, the paused condition if it is paused.
+func syncedCondition(paused *rbacoperatorv1alpha1.Condition, generation int64) rbacoperatorv1alpha1.Condition {
+	if paused != nil {
+		return *paused
+	}
+	return rbacoperatorv1alpha1.ReconcileSuccess().WithObservedGeneration(generation)
+}
+
+// revokeClient is the client of a paused resource. It only writes the lines and AppProject roles removed from the policy,
+// so that a restored revision is not overwritten by additions while access is still revoked. All other writes are passed through.
+type revokeClient struct {
+	client.Client
+}
+
+// Update only writes the policy lines of the ConfigMap that are removed.
+func (c *revokeClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if cm, ok := obj.(*corev1.ConfigMap); ok {
+		live := &corev1.ConfigMap{}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(cm), live); err != nil {
+			return err
+		}
+		cm.Data = revokedPolicies(live.Data, cm.Data)
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+// Patch only writes the roles, groups and policies of the AppProject that are removed.
+func (c *revokeClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if appProject, ok := obj.(*argocdv1alpha.AppProject); ok {
+		live := &argocdv1alpha.AppProject{}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(appProject), live); err != nil {
+			return err
+		}
+		appProject.Spec.Roles = revokedProjectRoles(live.Spec.Roles, appProject.Spec.Roles)
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+// revokedPolicies returns the live ConfigMap data without the policy lines and keys missing from the desired data.
+func revokedPolicies(live, desired map[string]string) map[string]string {
+	data := map[string]string{}
+	for key, value := range live {
+		desiredValue, ok := desired[key]
+		switch {
+		case !isPolicyKey(key):
+			data[key] = value
+		case ok:
+			data[key] = ""
+			for _, line := range intersectLines(policyLines(value), policyLines(desiredValue)) {
+				data[key] += line + "\n"
+			}
+		}
+	}
+	return data
+}
+
+// revokedProjectRoles returns the live roles without the roles, groups and policies missing from the desired roles.
+func revokedProjectRoles(live, desired []argocdv1alpha.ProjectRole) []argocdv1alpha.ProjectRole {
+	desiredRoles := &argocdv1alpha.AppProject{Spec: argocdv1alpha.AppProjectSpec{Roles: desired}}
+	roles := []argocdv1alpha.ProjectRole{}
+	for _, role := range live {
+		desiredRole, _ := getRoleInAppProject(desiredRoles, role.Name)
+		if desiredRole == nil {
+			continue
+		}
+		role.Groups = intersectLines(role.Groups, desiredRole.Groups)
+		role.Policies = intersectLines(role.Policies, desiredRole.Policies)
+		roles = append(roles, role)
+	}
+	return roles
+}
+
+// intersectLines returns the lines also contained in the desired lines, in their order.
+func intersectLines(lines, desired []string) []string {
+	kept := []string{}
+	for _, line := range lines {
+		if slices.Contains(desired, line) {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+// isOperatorPolicyKey returns true if the ConfigMap key is a policy.<namespace>.<name>.csv key written by the operator.
+// Other policy keys, like policy.csv or the policy.<name>.csv overlays of Argo CD users, are never restored.
+func isOperatorPolicyKey(key string) bool {
+	if !isPolicyKey(key) {
+		return false
+	}
+	namespace, name, found := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(key, "policy."), ".csv"), ".")
+	if !found || len(validation.IsDNS1123Label(namespace)) > 0 {
+		return false
+	}
+	name = strings.TrimPrefix(strings.TrimPrefix(name, roleBindingKeyPrefix), projectRoleBindingKeyPrefix)
+	return len(validation.IsDNS1123Subdomain(name)) == 0
+}
+
+// isOperatorProjectRole returns true if the role of the AppProject is written by the operator: it is the role of an
+// ArgoCDProjectRole of another namespace, whose name holds a "_", or of an existing ArgoCDProjectRole of the namespace of the
+// AppProject. Other roles, like the roles defined in Argo CD, are never restored.
+func isOperatorProjectRole(ctx context.Context, c client.Client, appProject *argocdv1alpha.AppProject, roleName string) (bool, error) {
+	if strings.Contains(roleName, "_") {
+		return true, nil
+	}
+	err := c.Get(ctx, types.NamespacedName{Name: roleName, Namespace: appProject.Namespace}, &rbacoperatorv1alpha1.ArgoCDProjectRole{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// recordedClient returns the client recording the policy written for the object in the revision history, audit log
+// and notifications. The revision is recorded first, so an entry is only audited and notified once recorded.
+func recordedClient(c client.Client, kind string, obj client.Object, history *RevisionHistory, auditLog *AuditLog,
+	notifications *Notifications) client.Client {
+	return notifications.client(auditLog.client(history.client(c, kind, obj), kind, obj), kind, obj)
+}
+
+// revokeOnly returns a copy of the reconciler of a paused ArgoCDRole only writing revocations, see revokeClient.
+func (r *ArgoCDRoleReconciler) revokeOnly() *ArgoCDRoleReconciler {
+	revoking := *r
+	revoking.Client = &revokeClient{Client: r.Client}
+	return &revoking
+}
+
+// revokeOnly returns a copy of the reconciler of a paused ArgoCDRoleBinding only writing revocations, see revokeClient.
+func (r *ArgoCDRoleBindingReconciler) revokeOnly() *ArgoCDRoleBindingReconciler {
+	revoking := *r
+	revoking.Client = &revokeClient{Client: r.Client}
+	return &revoking
+}
+
+// revokeOnly returns a copy of the reconciler of a paused ArgoCDProjectRole only writing revocations, see revokeClient.
+func (r *ArgoCDProjectRoleReconciler) revokeOnly() *ArgoCDProjectRoleReconciler {
+	revoking := *r
+	revoking.Client = &revokeClient{Client: r.Client}
+	return &revoking
+}
+
+// revokeOnly returns a copy of the reconciler of a paused ArgoCDProjectRoleBinding only writing revocations, see revokeClient.
+func (r *ArgoCDProjectRoleBindingReconciler) revokeOnly() *ArgoCDProjectRoleBindingReconciler {
+	revoking := *r
+	revoking.Client = &revokeClient{Client: r.Client}
+	return &revoking
+}
+
+// recorded returns a copy of the reconciler recording the policy written for the ArgoCDRole in the revision history, audit log and notifications.
+func (r *ArgoCDRoleReconciler) recorded(role *rbacoperatorv1alpha1.ArgoCDRole) *ArgoCDRoleReconciler {
+	if r.History == nil && r.Audit == nil && r.Notifications == nil {
+		return r
+	}
+	recorded := *r
+	recorded.Client = recordedClient(r.Client, "ArgoCDRole", role, r.History, r.Audit, r.Notifications)
+	return &recorded
+}
+
+// recorded returns a copy of the reconciler recording the policy written for the ArgoCDRoleBinding in the revision history, audit log and notifications.
+func (r *ArgoCDRoleBindingReconciler) recorded(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) *ArgoCDRoleBindingReconciler {
+	if r.History == nil && r.Audit == nil && r.Notifications == nil {
+		return r
+	}
+	recorded := *r
+	recorded.Client = recordedClient(r.Client, "ArgoCDRoleBinding", rb, r.History, r.Audit, r.Notifications)
+	return &recorded
+}
+
+// recorded returns a copy of the reconciler recording the policy written for the ArgoCDProjectRole in the revision history, audit log and notifications.
+func (r *ArgoCDProjectRoleReconciler) recorded(role *rbacoperatorv1alpha1.ArgoCDProjectRole) *ArgoCDProjectRoleReconciler {
+	if r.History == nil && r.Audit == nil && r.Notifications == nil {
+		return r
+	}
+	recorded := *r
+	recorded.Client = recordedClient(r.Client, "ArgoCDProjectRole", role, r.History, r.Audit, r.Notifications)
+	return &recorded
+}
+
+// recorded returns a copy of the reconciler recording the policy written for the ArgoCDProjectRoleBinding in the revision history, audit log and notifications.
+func (r *ArgoCDProjectRoleBindingReconciler) recorded(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) *ArgoCDProjectRoleBindingReconciler {
+	if r.History == nil && r.Audit == nil && r.Notifications == nil {
+		return r
+	}
+	recorded := *r
+	recorded.Client = recordedClient(r.Client, "ArgoCDProjectRoleBinding", rb, r.History, r.Audit, r.Notifications)
+	return &recorded
+}
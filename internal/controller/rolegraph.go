@@ -0,0 +1,92 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+)
+
+// resolveRoleSubjects sets the Resolved condition of the ArgoCDRoleBinding from its analysis in the role graph, and
+// removes the role subjects closing a cycle from the spec. Only the subjects of the newest binding introducing a cycle
+// are removed, the conditions record the generation they were resolved for, see policy.RoleGraph. Like the enforce functions, the binding is only changed
+// in memory, and must not be written back with Update.
+func resolveRoleSubjects(graph *policy.RoleGraph, rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) {
+	analysis := graph.With(rb).Analyze(rb)
+	cyclic := []int{}
+	for _, cycle := range analysis.Cycles {
+		cyclic = append(cyclic, cycle.Subject)
+	}
+	subjects := []rbacoperatorv1alpha1.GlobalSubject{}
+	for i, subject := range rb.Spec.Subjects {
+		if !slices.Contains(cyclic, i) {
+			subjects = append(subjects, subject)
+		}
+	}
+	rb.Spec.Subjects = subjects
+
+	message := strings.Join(analysis.Messages(), "; ")
+	switch {
+	case len(analysis.Cycles) > 0:
+		rb.SetConditions(rbacoperatorv1alpha1.RoleCycle().WithMessage(message).WithObservedGeneration(rb.Generation))
+	case len(analysis.Dangling) > 0:
+		rb.SetConditions(rbacoperatorv1alpha1.DanglingRole().WithMessage(message).WithObservedGeneration(rb.Generation))
+	case analysis.Unreachable:
+		rb.SetConditions(rbacoperatorv1alpha1.UnreachableRole().WithMessage(message).WithObservedGeneration(rb.Generation))
+	default:
+		rb.SetConditions(rbacoperatorv1alpha1.Resolved().WithObservedGeneration(rb.Generation))
+	}
+}
+
+// mapRoleGraphToBindings enqueues the ArgoCDRoleBindings referencing the role of an ArgoCDRole or an ArgoCDRoleBinding,
+// or one of the roles of its role subjects, as role or as role subject. Their cycles, dangling references and reachability
+// may have changed. Bindings further away are re-analyzed when they are requeued.
+func (r *ArgoCDRoleBindingReconciler) mapRoleGraphToBindings(ctx context.Context, obj client.Object) []reconcile.Request {
+	roles := []string{}
+	switch o := obj.(type) {
+	case *rbacoperatorv1alpha1.ArgoCDRole:
+		roles = append(roles, o.Name)
+	case *rbacoperatorv1alpha1.ArgoCDRoleBinding:
+		roles = append(roles, o.Spec.ArgoCDRoleRef.Name)
+		for _, subject := range o.Spec.Subjects {
+			if subject.Kind == "role" {
+				roles = append(roles, subject.Name)
+			}
+		}
+	}
+
+	bindings := rbacoperatorv1alpha1.ArgoCDRoleBindingList{}
+	if err := r.List(ctx, &bindings); err != nil {
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, rb := range bindings.Items {
+		if slices.ContainsFunc(rb.Spec.Subjects, func(s rbacoperatorv1alpha1.GlobalSubject) bool {
+			return s.Kind == "role" && slices.Contains(roles, s.Name)
+		}) || slices.Contains(roles, rb.Spec.ArgoCDRoleRef.Name) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&rb)})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+// upcomingWindowsCount is the number of upcoming windows shown in the status of a binding.
+const upcomingWindowsCount = 5
+
+// maxScheduleIterations bounds the merging of overlapping windows, schedules that are always open have no end.
+const maxScheduleIterations = 1000
+
+// parseSchedule returns the cron schedule and the time zone of the schedule.
+func parseSchedule(schedule rbacoperatorv1alpha1.Schedule) (cron.Schedule, *time.Location, error) {
+	location := time.UTC
+	if schedule.TimeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(schedule.TimeZone); err != nil {
+			return nil, nil, fmt.Errorf("invalid time zone %q: %w", schedule.TimeZone, err)
+		}
+	}
+	cronSchedule, err := cron.ParseStandard(schedule.Cron)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid cron expression %q: %w", schedule.Cron, err)
+	}
+	return cronSchedule, location, nil
+}
+
+// validateSchedules returns an error if one of the schedules can't be parsed, has no duration or never fires after
+// the given time, e.g. "0 0 30 2 *".
+func validateSchedules(schedules []rbacoperatorv1alpha1.Schedule, now time.Time) error {
+	for _, schedule := range schedules {
+		cronSchedule, location, err := parseSchedule(schedule)
+		if err != nil {
+			return err
+		}
+		if schedule.Duration.Duration <= 0 {
+			return fmt.Errorf("duration of schedule %q must be positive", schedule.Cron)
+		}
+		if cronSchedule.Next(now.In(location)).IsZero() {
+			return fmt.Errorf("schedule %q never fires", schedule.Cron)
+		}
+	}
+	return nil
+}
+
+// scheduleWindowAt returns the window of the schedule open at the given time, or the next window if none is open.
+// Returns false if the schedule is not valid or has no window, cron returns zero time for schedules that never fire.
+func scheduleWindowAt(schedule rbacoperatorv1alpha1.Schedule, now time.Time) (time.Time, time.Time, bool) {
+	cronSchedule, location, err := parseSchedule(schedule)
+	if err != nil || schedule.Duration.Duration <= 0 {
+		return time.Time{}, time.Time{}, false
+	}
+	// The first window starting after now-duration is either open or the next one
+	start := cronSchedule.Next(now.In(location).Add(-schedule.Duration.Duration))
+	if start.IsZero() {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, start.Add(schedule.Duration.Duration), true
+}
+
+// schedulesWindowAt returns the window one of the schedules is open in at the given time, or the next window if none
+// is open. Like Argo CD sync windows, the schedules are combined, overlapping and adjacent windows form one window.
+// Returns false if there are no schedules or one of them is not valid or has no window.
+func schedulesWindowAt(schedules []rbacoperatorv1alpha1.Schedule, now time.Time) (time.Time, time.Time, bool) {
+	if len(schedules) == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+	var start, end time.Time
+	for i, schedule := range schedules {
+		scheduleStart, scheduleEnd, ok := scheduleWindowAt(schedule, now)
+		if !ok {
+			return time.Time{}, time.Time{}, false
+		}
+		if i == 0 || scheduleStart.Before(start) || (scheduleStart.Equal(start) && scheduleEnd.After(end)) {
+			start, end = scheduleStart, scheduleEnd
+		}
+	}
+	// Extend the window as long as another window is open when it closes
+	for range maxScheduleIterations {
+		extended := false
+		for _, schedule := range schedules {
+			scheduleStart, scheduleEnd, ok := scheduleWindowAt(schedule, end)
+			if ok && !scheduleStart.After(end) && scheduleEnd.After(end) {
+				end = scheduleEnd
+				extended = true
+			}
+		}
+		if !extended {
+			break
+		}
+	}
+	return start, end, true
+}
+
+// isScheduleOpen returns true if one of the windows of the schedules is open at the given time.
+func isScheduleOpen(schedules []rbacoperatorv1alpha1.Schedule, now time.Time) bool {
+	start, _, ok := schedulesWindowAt(schedules, now)
+	return ok && !start.After(now)
+}
+
+// nextScheduleTransition returns the time a window of the schedules opens next or the open windows close,
+// or zero time if none does.
+func nextScheduleTransition(schedules []rbacoperatorv1alpha1.Schedule, now time.Time) time.Time {
+	start, end, ok := schedulesWindowAt(schedules, now)
+	if !ok {
+		return time.Time{}
+	}
+	if !start.After(now) {
+		return end
+	}
+	return start
+}
+
+// upcomingWindows returns the next windows of the schedules, open or starting after the given time, sorted by start.
+func upcomingWindows(schedules []rbacoperatorv1alpha1.Schedule, now time.Time, count int) []rbacoperatorv1alpha1.TimeWindow {
+	windows := []rbacoperatorv1alpha1.TimeWindow{}
+	at := now
+	for range count {
+		start, end, ok := schedulesWindowAt(schedules, at)
+		if !ok {
+			break
+		}
+		windows = append(windows, rbacoperatorv1alpha1.TimeWindow{
+			Start: metav1.NewTime(start.UTC()),
+			End:   metav1.NewTime(end.UTC()),
+		})
+		at = end
+	}
+	return windows
+}
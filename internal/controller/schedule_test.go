@@ -0,0 +1,140 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+func makeTestBusinessHoursSchedule() rbacoperatorv1alpha1.Schedule {
+	return rbacoperatorv1alpha1.Schedule{
+		Cron:     "0 8 * * 1-5",
+		Duration: metav1.Duration{Duration: 10 * time.Hour},
+		TimeZone: "Europe/Berlin",
+	}
+}
+
+func TestSchedule_IsScheduleOpen(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	schedules := []rbacoperatorv1alpha1.Schedule{makeTestBusinessHoursSchedule()}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		open bool
+		next time.Time
+	}{
+		{"monday before opening", time.Date(2025, 1, 6, 7, 59, 0, 0, berlin), false, time.Date(2025, 1, 6, 8, 0, 0, 0, berlin)},
+		{"monday at opening", time.Date(2025, 1, 6, 8, 0, 0, 0, berlin), true, time.Date(2025, 1, 6, 18, 0, 0, 0, berlin)},
+		{"monday noon in UTC", time.Date(2025, 1, 6, 11, 0, 0, 0, time.UTC), true, time.Date(2025, 1, 6, 18, 0, 0, 0, berlin)},
+		{"monday at closing", time.Date(2025, 1, 6, 18, 0, 0, 0, berlin), false, time.Date(2025, 1, 7, 8, 0, 0, 0, berlin)},
+		{"saturday", time.Date(2025, 1, 11, 12, 0, 0, 0, berlin), false, time.Date(2025, 1, 13, 8, 0, 0, 0, berlin)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.open, isScheduleOpen(schedules, test.now))
+			assert.True(t, test.next.Equal(nextScheduleTransition(schedules, test.now)))
+		})
+	}
+}
+
+func TestSchedule_UpcomingWindows(t *testing.T) {
+	friday := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	windows := upcomingWindows([]rbacoperatorv1alpha1.Schedule{makeTestBusinessHoursSchedule()}, friday, 3)
+
+	assert.Len(t, windows, 3)
+	// The open window on friday is followed by monday and tuesday
+	assert.Equal(t, time.Date(2025, 1, 10, 7, 0, 0, 0, time.UTC), windows[0].Start.UTC())
+	assert.Equal(t, time.Date(2025, 1, 10, 17, 0, 0, 0, time.UTC), windows[0].End.UTC())
+	assert.Equal(t, time.Date(2025, 1, 13, 7, 0, 0, 0, time.UTC), windows[1].Start.UTC())
+	assert.Equal(t, time.Date(2025, 1, 14, 7, 0, 0, 0, time.UTC), windows[2].Start.UTC())
+}
+
+func TestSchedule_AnyScheduleOpen(t *testing.T) {
+	// Business hours from 7:00 to 17:00 UTC on weekdays and every afternoon from 15:00 to 19:00 UTC
+	schedules := []rbacoperatorv1alpha1.Schedule{
+		makeTestBusinessHoursSchedule(),
+		{Cron: "0 15 * * *", Duration: metav1.Duration{Duration: 4 * time.Hour}},
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		open bool
+		next time.Time
+	}{
+		{"monday noon", time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC), true, time.Date(2025, 1, 6, 19, 0, 0, 0, time.UTC)},
+		{"monday after business hours", time.Date(2025, 1, 6, 18, 0, 0, 0, time.UTC), true, time.Date(2025, 1, 6, 19, 0, 0, 0, time.UTC)},
+		{"monday night", time.Date(2025, 1, 6, 20, 0, 0, 0, time.UTC), false, time.Date(2025, 1, 7, 7, 0, 0, 0, time.UTC)},
+		{"saturday noon", time.Date(2025, 1, 11, 12, 0, 0, 0, time.UTC), false, time.Date(2025, 1, 11, 15, 0, 0, 0, time.UTC)},
+		{"saturday afternoon", time.Date(2025, 1, 11, 16, 0, 0, 0, time.UTC), true, time.Date(2025, 1, 11, 19, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.open, isScheduleOpen(schedules, test.now))
+			assert.True(t, test.next.Equal(nextScheduleTransition(schedules, test.now)))
+		})
+	}
+
+	// Overlapping windows are shown as one window
+	friday := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	windows := upcomingWindows(schedules, friday, 3)
+	assert.Len(t, windows, 3)
+	assert.Equal(t, time.Date(2025, 1, 10, 7, 0, 0, 0, time.UTC), windows[0].Start.UTC())
+	assert.Equal(t, time.Date(2025, 1, 10, 19, 0, 0, 0, time.UTC), windows[0].End.UTC())
+	assert.Equal(t, time.Date(2025, 1, 11, 15, 0, 0, 0, time.UTC), windows[1].Start.UTC())
+	assert.Equal(t, time.Date(2025, 1, 12, 15, 0, 0, 0, time.UTC), windows[2].Start.UTC())
+
+	// Schedules that never overlap open one after the other
+	disjoint := []rbacoperatorv1alpha1.Schedule{
+		{Cron: "0 8 * * *", Duration: metav1.Duration{Duration: time.Hour}},
+		{Cron: "0 12 * * *", Duration: metav1.Duration{Duration: time.Hour}},
+	}
+	monday := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	assert.False(t, isScheduleOpen(disjoint, monday))
+	assert.True(t, time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC).Equal(nextScheduleTransition(disjoint, monday)))
+	assert.True(t, isScheduleOpen(disjoint, monday.Add(150*time.Minute)))
+	assert.True(t, time.Date(2025, 1, 6, 13, 0, 0, 0, time.UTC).Equal(nextScheduleTransition(disjoint, monday.Add(150*time.Minute))))
+}
+
+func TestSchedule_ValidateSchedules(t *testing.T) {
+	now := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	assert.NoError(t, validateSchedules([]rbacoperatorv1alpha1.Schedule{makeTestBusinessHoursSchedule()}, now))
+	assert.ErrorContains(t, validateSchedules([]rbacoperatorv1alpha1.Schedule{{Cron: "every day", Duration: metav1.Duration{Duration: time.Hour}}}, now), "invalid cron expression")
+	assert.ErrorContains(t, validateSchedules([]rbacoperatorv1alpha1.Schedule{{Cron: "0 8 * * *", Duration: metav1.Duration{Duration: time.Hour}, TimeZone: "Mars/Olympus"}}, now), "invalid time zone")
+	assert.ErrorContains(t, validateSchedules([]rbacoperatorv1alpha1.Schedule{{Cron: "0 8 * * *"}}, now), "must be positive")
+	assert.ErrorContains(t, validateSchedules([]rbacoperatorv1alpha1.Schedule{{Cron: "0 0 30 2 *", Duration: metav1.Duration{Duration: time.Hour}}}, now), "never fires")
+}
+
+func TestSchedule_NeverFires(t *testing.T) {
+	now := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	never := rbacoperatorv1alpha1.Schedule{Cron: "0 0 30 2 *", Duration: metav1.Duration{Duration: time.Hour}}
+	daily := rbacoperatorv1alpha1.Schedule{Cron: "0 12 * * *", Duration: metav1.Duration{Duration: time.Hour}}
+
+	// A schedule without windows is closed, it doesn't stand for an open window starting at zero time
+	assert.False(t, isScheduleOpen([]rbacoperatorv1alpha1.Schedule{never}, now))
+	assert.True(t, nextScheduleTransition([]rbacoperatorv1alpha1.Schedule{never}, now).IsZero())
+	assert.False(t, isScheduleOpen([]rbacoperatorv1alpha1.Schedule{never, daily}, now))
+	assert.Empty(t, upcomingWindows([]rbacoperatorv1alpha1.Schedule{never, daily}, now, 3))
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+// SecretCacheSelector returns the selector of the Secrets held by the cache of the manager. Only the Secrets labeled
+// with common.LabelSecret are cached, all other Secrets, e.g. the Argo CD Secret, are read with the API reader.
+func SecretCacheSelector() labels.Selector {
+	requirement, err := labels.NewRequirement(common.LabelSecret, selection.Exists, nil)
+	if err != nil {
+		panic(err)
+	}
+	return labels.NewSelector().Add(*requirement)
+}
+
+// createOrUpdateSecret works like controllerutil.CreateOrUpdate, but reads the Secret with the API reader, Secrets
+// written before they were labeled are not in the cache. The Secret is labeled with the given value, so that it is
+// cached and watched afterwards.
+func createOrUpdateSecret(ctx context.Context, apiReader client.Reader, c client.Client, secret *corev1.Secret, value string, f controllerutil.MutateFn) error {
+	mutate := func() error {
+		if err := f(); err != nil {
+			return err
+		}
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		secret.Labels[common.LabelSecret] = value
+		return nil
+	}
+
+	if err := apiReader.Get(ctx, client.ObjectKeyFromObject(secret), secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		if err := mutate(); err != nil {
+			return err
+		}
+		return c.Create(ctx, secret)
+	}
+
+	existing := secret.DeepCopy()
+	if err := mutate(); err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(existing, secret) {
+		return nil
+	}
+	return c.Update(ctx, secret)
+}
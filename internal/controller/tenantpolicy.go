@@ -0,0 +1,131 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+)
+
+// The enforce functions below remove what the ArgoCDRBACTenantPolicies of the namespace do not allow from
+// the spec of the object. The object is only changed in memory, so nothing beyond the policies is rendered,
+// and must not be written back with Update.
+
+// enforceRoleTenantPolicies removes the rules of the ArgoCDRole the tenant policies do not allow, and all of them if
+// the role is named like a built-in role that is not allowed: its rules would be added to the built-in role.
+func enforceRoleTenantPolicies(policies policy.TenantPolicies, role *rbacoperatorv1alpha1.ArgoCDRole) {
+	if len(policies) == 0 {
+		setComplianceCondition(role, role.Status.Conditions, nil)
+		return
+	}
+	if role.Name == common.ArgoCDRoleAdmin || role.Name == common.ArgoCDRoleReadOnly {
+		if violation := policies.BuiltInRole(role.Name); violation != "" {
+			role.Spec.Rules = []rbacoperatorv1alpha1.GlobalRule{}
+			setComplianceCondition(role, role.Status.Conditions, []string{violation})
+			return
+		}
+	}
+	var violations []string
+	role.Spec.Rules, violations = policies.GlobalRules(role.Spec.Rules)
+	setComplianceCondition(role, role.Status.Conditions, violations)
+}
+
+// enforceRoleBindingTenantPolicies removes the subjects of the ArgoCDRoleBinding the tenant policies do not allow,
+// and all of them if the binding references a built-in role that is not allowed.
+func enforceRoleBindingTenantPolicies(policies policy.TenantPolicies, rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) {
+	if len(policies) == 0 {
+		setComplianceCondition(rb, rb.Status.Conditions, nil)
+		return
+	}
+	var violations []string
+	roleName := rb.Spec.ArgoCDRoleRef.Name
+	if roleName == common.ArgoCDRoleAdmin || roleName == common.ArgoCDRoleReadOnly {
+		if violation := policies.BuiltInRole(roleName); violation != "" {
+			rb.Spec.Subjects = []rbacoperatorv1alpha1.GlobalSubject{}
+			setComplianceCondition(rb, rb.Status.Conditions, []string{violation})
+			return
+		}
+	}
+	rb.Spec.Subjects, violations = policies.GlobalSubjects(rb.Spec.Subjects)
+	setComplianceCondition(rb, rb.Status.Conditions, violations)
+}
+
+// enforceProjectRoleTenantPolicies removes the rules of the ArgoCDProjectRole the tenant policies do not allow.
+func enforceProjectRoleTenantPolicies(policies policy.TenantPolicies, role *rbacoperatorv1alpha1.ArgoCDProjectRole) {
+	if len(policies) == 0 {
+		setComplianceCondition(role, role.Status.Conditions, nil)
+		return
+	}
+	var violations []string
+	role.Spec.Rules, violations = policies.ProjectRules(role.Spec.Rules)
+	setComplianceCondition(role, role.Status.Conditions, violations)
+}
+
+// enforceProjectRoleBindingTenantPolicies removes the groups and users of the ArgoCDProjectRoleBinding the tenant policies do not allow.
+func enforceProjectRoleBindingTenantPolicies(policies policy.TenantPolicies, rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) {
+	if len(policies) == 0 {
+		setComplianceCondition(rb, rb.Status.Conditions, nil)
+		return
+	}
+	var violations []string
+	rb.Spec.Subjects, violations = policies.AppProjectSubjects(rb.Spec.Subjects)
+	setComplianceCondition(rb, rb.Status.Conditions, violations)
+}
+
+// setComplianceCondition sets the Compliant condition for the violations. Objects no tenant policy ever applied to
+// don't get the condition.
+func setComplianceCondition(obj interface {
+	SetConditions(...rbacoperatorv1alpha1.Condition)
+}, conditions []rbacoperatorv1alpha1.Condition, violations []string) {
+	switch {
+	case len(violations) > 0:
+		obj.SetConditions(rbacoperatorv1alpha1.PolicyViolation().WithMessage(strings.Join(violations, "; ")))
+	case violations != nil || hasCondition(conditions, rbacoperatorv1alpha1.TypeCompliant, ""):
+		obj.SetConditions(rbacoperatorv1alpha1.Compliant())
+	}
+}
+
+// mapTenantPolicyToObjects returns a MapFunc enqueueing every object of the list when an ArgoCDRBACTenantPolicy changes.
+// The namespaces a policy applied to before the change are not known, so all objects are re-checked.
+func mapTenantPolicyToObjects(c client.Client, newList func() client.ObjectList) handler.MapFunc {
+	return func(ctx context.Context, _ client.Object) []reconcile.Request {
+		list := newList()
+		if err := c.List(ctx, list); err != nil {
+			return nil
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil
+		}
+		requests := []reconcile.Request{}
+		for _, item := range items {
+			if obj, ok := item.(client.Object); ok {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+			}
+		}
+		return requests
+	}
+}
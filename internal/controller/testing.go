@@ -18,6 +18,7 @@ package controller
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
@@ -26,6 +27,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -46,6 +48,19 @@ const (
 	testProjectRoleBindingName = "test-project-role-binding"
 
 	testAppProjectName = "test-appproject"
+
+	testProjectRoleTokenName = "test-project-role-token"
+	testArgoCDSecretName     = "argocd-secret"
+
+	testLocalAccountName = "localUser"
+	testArgoCDCMName     = "argocd-cm"
+
+	testAccessRequestName  = "test-access-request"
+	testApprovalPolicyName = "test-approval-policy"
+	testRequester          = "requester"
+	testApprover           = "approver"
+
+	testRecertificationPolicyName = "test-recertification-policy"
 )
 
 func ZapLogger(development bool) logr.Logger {
@@ -69,6 +84,7 @@ func makeTestArgoCDRoleReconciler(client client.Client, sch *runtime.Scheme) *Ar
 		Scheme:                       sch,
 		ArgoCDRBACConfigMapName:      testRBACCMName,
 		ArgoCDRBACConfigMapNamespace: testRBACCMNamespace,
+		Recorder:                     record.NewFakeRecorder(10),
 	}
 }
 
@@ -78,20 +94,56 @@ func makeTestArgoCDRoleBindingReconciler(client client.Client, sch *runtime.Sche
 		Scheme:                       sch,
 		ArgoCDRBACConfigMapName:      testRBACCMName,
 		ArgoCDRBACConfigMapNamespace: testRBACCMNamespace,
+		Recorder:                     record.NewFakeRecorder(10),
 	}
 }
 
 func makeTestArgoCDProjectRoleReconciler(client client.Client, sch *runtime.Scheme) *ArgoCDProjectRoleReconciler {
 	return &ArgoCDProjectRoleReconciler{
-		Client: client,
-		Scheme: sch,
+		Client:   client,
+		Scheme:   sch,
+		Recorder: record.NewFakeRecorder(10),
 	}
 }
 
 func makeTestArgoCDProjectRoleBindingReconciler(client client.Client, sch *runtime.Scheme) *ArgoCDProjectRoleBindingReconciler {
 	return &ArgoCDProjectRoleBindingReconciler{
-		Client: client,
-		Scheme: sch,
+		Client:                       client,
+		Scheme:                       sch,
+		ArgoCDRBACConfigMapName:      testRBACCMName,
+		ArgoCDRBACConfigMapNamespace: testRBACCMNamespace,
+		Recorder:                     record.NewFakeRecorder(10),
+	}
+}
+
+func makeTestArgoCDProjectRoleTokenReconciler(client client.Client, sch *runtime.Scheme) *ArgoCDProjectRoleTokenReconciler {
+	return &ArgoCDProjectRoleTokenReconciler{
+		Client:                client,
+		APIReader:             client,
+		Scheme:                sch,
+		ArgoCDSecretName:      testArgoCDSecretName,
+		ArgoCDSecretNamespace: testRBACCMNamespace,
+	}
+}
+
+func makeTestArgoCDLocalAccountReconciler(client client.Client, sch *runtime.Scheme) *ArgoCDLocalAccountReconciler {
+	return &ArgoCDLocalAccountReconciler{
+		Client:                   client,
+		APIReader:                client,
+		Scheme:                   sch,
+		ArgoCDConfigMapName:      testArgoCDCMName,
+		ArgoCDConfigMapNamespace: testRBACCMNamespace,
+		ArgoCDSecretName:         testArgoCDSecretName,
+		ArgoCDSecretNamespace:    testRBACCMNamespace,
+	}
+}
+
+func makeTestArgoCDAccessRequestReconciler(client client.Client, sch *runtime.Scheme) *ArgoCDAccessRequestReconciler {
+	return &ArgoCDAccessRequestReconciler{
+		Client:         client,
+		Scheme:         sch,
+		Recorder:       record.NewFakeRecorder(10),
+		TrustApprovals: true,
 	}
 }
 
@@ -114,6 +166,15 @@ func makeTestReconcilerScheme(schOpts ...SchemeOpt) *runtime.Scheme {
 	return s
 }
 
+// conditionReasons returns the reasons of the conditions, to assert on conditions regardless of their transition time.
+func conditionReasons(conditions []rbacoperatorv1alpha1.Condition) []rbacoperatorv1alpha1.ConditionReason {
+	reasons := []rbacoperatorv1alpha1.ConditionReason{}
+	for _, c := range conditions {
+		reasons = append(reasons, c.Reason)
+	}
+	return reasons
+}
+
 // Global RBAC objects used in tests
 
 type argocdRoleOpt func(*rbacoperatorv1alpha1.ArgoCDRole)
@@ -330,6 +391,12 @@ func makeTestCM_ArgoCDRole_WithRoleBindingSSOSubject_Expected() *corev1.ConfigMa
 	return cm
 }
 
+// recordTestPolicySources records the role and binding as sources of the policy key of the role, as the reconciliation does.
+func recordTestPolicySources(cm *corev1.ConfigMap, role *rbacoperatorv1alpha1.ArgoCDRole, rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) *corev1.ConfigMap {
+	recordPolicySources(cm, fmt.Sprintf("policy.%s.%s.csv", role.Namespace, role.Name), rolePolicySources(role, rb)...)
+	return cm
+}
+
 func makeTestCM_ArgoCDRole_WithRoleBindingLocalSubject_Expected() *corev1.ConfigMap {
 	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -406,6 +473,51 @@ func addFinalizerRoleBinding() argocdRoleBindingOpt {
 	}
 }
 
+func setRoleBindingAccessRequest(accessRequestName string) argocdRoleBindingOpt {
+	return func(r *rbacoperatorv1alpha1.ArgoCDRoleBinding) {
+		controller := true
+		r.OwnerReferences = append(r.OwnerReferences, metav1.OwnerReference{
+			APIVersion: rbacoperatorv1alpha1.GroupVersion.String(),
+			Kind:       "ArgoCDAccessRequest",
+			Name:       accessRequestName,
+			UID:        "test-access-request-uid",
+			Controller: &controller,
+		})
+	}
+}
+
+func setRoleBindingWindow(notBefore, expiresAt *metav1.Time) argocdRoleBindingOpt {
+	return func(r *rbacoperatorv1alpha1.ArgoCDRoleBinding) {
+		r.Spec.NotBefore = notBefore
+		r.Spec.ExpiresAt = expiresAt
+	}
+}
+
+func setBreakGlassReason(reason string) argocdRoleBindingOpt {
+	return func(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) {
+		if rb.Annotations == nil {
+			rb.Annotations = map[string]string{}
+		}
+		rb.Annotations[common.AnnotationBreakGlassReason] = reason
+	}
+}
+
+func setRoleBindingCreatedAt(createdAt time.Time) argocdRoleBindingOpt {
+	return func(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) {
+		rb.CreationTimestamp = metav1.NewTime(createdAt)
+	}
+}
+
+func setRoleBindingAttestedAt(attestedAt time.Time, attester string) argocdRoleBindingOpt {
+	return func(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) {
+		if rb.Annotations == nil {
+			rb.Annotations = map[string]string{}
+		}
+		rb.Annotations[common.AnnotationAttestedAt] = attestedAt.UTC().Format(time.RFC3339)
+		rb.Annotations[common.AnnotationAttestedBy] = attester
+	}
+}
+
 func roleBindingDeletedAt(now time.Time) argocdRoleBindingOpt {
 	return func(r *rbacoperatorv1alpha1.ArgoCDRoleBinding) {
 		wrapped := metav1.NewTime(now)
@@ -458,17 +570,47 @@ func addBoundAppProjects(appProjects []string) argocdProjectRoleBindingOpt {
 	}
 }
 
+func setAppProjectSubjectNamespace(namespace string) argocdProjectRoleBindingOpt {
+	return func(r *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) {
+		for i := range r.Spec.Subjects {
+			r.Spec.Subjects[i].Namespace = namespace
+		}
+	}
+}
+
+func setAppProjectSubjectWindow(notBefore, expiresAt *metav1.Time) argocdProjectRoleBindingOpt {
+	return func(r *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) {
+		for i := range r.Spec.Subjects {
+			r.Spec.Subjects[i].NotBefore = notBefore
+			r.Spec.Subjects[i].ExpiresAt = expiresAt
+		}
+	}
+}
+
+func addUsersToAppProjectSubject(users ...rbacoperatorv1alpha1.AppProjectUser) argocdProjectRoleBindingOpt {
+	return func(r *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) {
+		for i := range r.Spec.Subjects {
+			r.Spec.Subjects[i].Users = append(r.Spec.Subjects[i].Users, users...)
+		}
+	}
+}
+
 type argocdAppProjectOpt func(*argocdv1alpha.AppProject)
 
 func addTestRoleToAppProject() argocdAppProjectOpt {
+	return addNamedTestRoleToAppProject(testProjectRoleName)
+}
+
+// addNamedTestRoleToAppProject adds the test project role under the given name, e.g. prefixed with its namespace.
+func addNamedTestRoleToAppProject(name string) argocdAppProjectOpt {
 	return func(ap *argocdv1alpha.AppProject) {
 		ap.Spec.Roles = append(ap.Spec.Roles, argocdv1alpha.ProjectRole{
-			Name:        testProjectRoleName,
+			Name:        name,
 			Description: "Test Project Role",
 			Policies: []string{
-				fmt.Sprintf("p, proj:%s:%s, applications, get, */*, allow", testAppProjectName, testProjectRoleName),
-				fmt.Sprintf("p, proj:%s:%s, applications, list, */*, allow", testAppProjectName, testProjectRoleName),
-				fmt.Sprintf("p, proj:%s:%s, projects, get, *, allow", testAppProjectName, testProjectRoleName),
+				fmt.Sprintf("p, proj:%s:%s, applications, get, */*, allow", testAppProjectName, name),
+				fmt.Sprintf("p, proj:%s:%s, applications, list, */*, allow", testAppProjectName, name),
+				fmt.Sprintf("p, proj:%s:%s, projects, get, *, allow", testAppProjectName, name),
 			},
 			Groups: []string{"group1", "group2"},
 		})
@@ -481,6 +623,21 @@ func setAppProjectName(name string) argocdAppProjectOpt {
 	}
 }
 
+func setAppProjectNamespace(namespace string) argocdAppProjectOpt {
+	return func(ap *argocdv1alpha.AppProject) {
+		ap.Namespace = namespace
+	}
+}
+
+func addAllowedNamespacesToAppProject(namespaces string) argocdAppProjectOpt {
+	return func(ap *argocdv1alpha.AppProject) {
+		if ap.Annotations == nil {
+			ap.Annotations = map[string]string{}
+		}
+		ap.Annotations[common.AnnotationAllowedNamespaces] = namespaces
+	}
+}
+
 // AppProject RBAC Objects
 
 func makeTestAppProject(opts ...argocdAppProjectOpt) *argocdv1alpha.AppProject {
@@ -558,3 +715,192 @@ func makeTestProjectRoleBinding(opts ...argocdProjectRoleBindingOpt) *rbacoperat
 	}
 	return rb
 }
+
+type argocdProjectRoleTokenOpt func(*rbacoperatorv1alpha1.ArgoCDProjectRoleToken)
+
+func addFinalizerProjectRoleToken() argocdProjectRoleTokenOpt {
+	return func(t *rbacoperatorv1alpha1.ArgoCDProjectRoleToken) {
+		t.Finalizers = append(t.Finalizers, rbacoperatorv1alpha1.ArgoCDProjectRoleTokenFinalizerName)
+	}
+}
+
+func setProjectRoleTokenExpiresIn(expiresIn, renewBefore time.Duration) argocdProjectRoleTokenOpt {
+	return func(t *rbacoperatorv1alpha1.ArgoCDProjectRoleToken) {
+		t.Spec.ExpiresIn = &metav1.Duration{Duration: expiresIn}
+		t.Spec.RenewBefore = &metav1.Duration{Duration: renewBefore}
+	}
+}
+
+func makeTestProjectRoleToken(opts ...argocdProjectRoleTokenOpt) *rbacoperatorv1alpha1.ArgoCDProjectRoleToken {
+	t := &rbacoperatorv1alpha1.ArgoCDProjectRoleToken{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testProjectRoleTokenName,
+			Namespace: testNamespace,
+		},
+		Spec: rbacoperatorv1alpha1.ArgoCDProjectRoleTokenSpec{
+			ArgoCDProjectRoleBindingRef: testProjectRoleBindingName,
+			AppProjectRef:               testAppProjectName,
+			SecretName:                  testProjectRoleTokenName,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func makeTestArgoCDSecret() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testArgoCDSecretName,
+			Namespace: testRBACCMNamespace,
+		},
+		Data: map[string][]byte{
+			common.ArgoCDKeyServerSignature: []byte("test-signature"),
+		},
+	}
+}
+
+// Local account objects used in tests
+
+type argocdLocalAccountOpt func(*rbacoperatorv1alpha1.ArgoCDLocalAccount)
+
+func addFinalizerLocalAccount() argocdLocalAccountOpt {
+	return func(a *rbacoperatorv1alpha1.ArgoCDLocalAccount) {
+		a.Finalizers = append(a.Finalizers, rbacoperatorv1alpha1.ArgoCDLocalAccountFinalizerName)
+	}
+}
+
+func setLocalAccountName(name string) argocdLocalAccountOpt {
+	return func(a *rbacoperatorv1alpha1.ArgoCDLocalAccount) {
+		a.Name = name
+	}
+}
+
+func setLocalAccountNamespace(namespace string) argocdLocalAccountOpt {
+	return func(a *rbacoperatorv1alpha1.ArgoCDLocalAccount) {
+		a.Namespace = namespace
+	}
+}
+
+func setLocalAccountCreatedAt(createdAt time.Time) argocdLocalAccountOpt {
+	return func(a *rbacoperatorv1alpha1.ArgoCDLocalAccount) {
+		a.CreationTimestamp = metav1.NewTime(createdAt)
+	}
+}
+
+func addPasswordToLocalAccount(secretName string) argocdLocalAccountOpt {
+	return func(a *rbacoperatorv1alpha1.ArgoCDLocalAccount) {
+		a.Spec.PasswordSecretRef = &rbacoperatorv1alpha1.SecretKeyRef{Name: secretName}
+	}
+}
+
+func addTokenToLocalAccount(id, secretName string) argocdLocalAccountOpt {
+	return func(a *rbacoperatorv1alpha1.ArgoCDLocalAccount) {
+		a.Spec.Capabilities = append(a.Spec.Capabilities, rbacoperatorv1alpha1.AccountCapabilityAPIKey)
+		a.Spec.Tokens = append(a.Spec.Tokens, rbacoperatorv1alpha1.LocalAccountToken{ID: id, SecretName: secretName})
+	}
+}
+
+func makeTestLocalAccount(opts ...argocdLocalAccountOpt) *rbacoperatorv1alpha1.ArgoCDLocalAccount {
+	a := &rbacoperatorv1alpha1.ArgoCDLocalAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testLocalAccountName,
+			Namespace: testNamespace,
+		},
+		Spec: rbacoperatorv1alpha1.ArgoCDLocalAccountSpec{
+			Capabilities: []rbacoperatorv1alpha1.AccountCapability{rbacoperatorv1alpha1.AccountCapabilityLogin},
+		},
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+func makeTestArgoCDConfigMap() *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testArgoCDCMName,
+			Namespace: testRBACCMNamespace,
+		},
+		Data: map[string]string{
+			"accounts.other": "login",
+		},
+	}
+}
+
+func makeTestPasswordSecret(name, password string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+		},
+		Data: map[string][]byte{
+			common.PasswordSecretKey: []byte(password),
+		},
+	}
+}
+
+// Access request objects used in tests
+
+type argocdAccessRequestOpt func(*rbacoperatorv1alpha1.ArgoCDAccessRequest)
+
+func approveAccessRequest(approver string, groups ...string) argocdAccessRequestOpt {
+	return func(r *rbacoperatorv1alpha1.ArgoCDAccessRequest) {
+		r.Annotations[common.AnnotationApprovedBy] = approver
+		r.Annotations[common.AnnotationApprovedByGroups] = strings.Join(groups, ",")
+	}
+}
+
+func makeTestAccessRequest(opts ...argocdAccessRequestOpt) *rbacoperatorv1alpha1.ArgoCDAccessRequest {
+	r := &rbacoperatorv1alpha1.ArgoCDAccessRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testAccessRequestName,
+			Namespace: testNamespace,
+			Annotations: map[string]string{
+				common.AnnotationRequestedBy: testRequester,
+			},
+		},
+		Spec: rbacoperatorv1alpha1.ArgoCDAccessRequestSpec{
+			ArgoCDRoleRef: rbacoperatorv1alpha1.ArgoCDRoleRef{Name: testRoleName},
+			Subject:       rbacoperatorv1alpha1.AccessRequestSubject{Kind: "sso", Name: "gosha"},
+			Duration:      metav1.Duration{Duration: time.Hour},
+			Justification: "incident",
+		},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func makeTestApprovalPolicy(approvers ...rbacoperatorv1alpha1.Approver) *rbacoperatorv1alpha1.ArgoCDApprovalPolicy {
+	return &rbacoperatorv1alpha1.ArgoCDApprovalPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testApprovalPolicyName,
+			Namespace: testNamespace,
+		},
+		Spec: rbacoperatorv1alpha1.ArgoCDApprovalPolicySpec{
+			ArgoCDRoles: []string{testRoleName},
+			Approvers:   approvers,
+			MaxDuration: &metav1.Duration{Duration: 4 * time.Hour},
+		},
+	}
+}
+
+func makeTestRecertificationPolicy(namespace string, interval, gracePeriod time.Duration) *rbacoperatorv1alpha1.ArgoCDRecertificationPolicy {
+	return &rbacoperatorv1alpha1.ArgoCDRecertificationPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testRecertificationPolicyName,
+			Namespace: namespace,
+		},
+		Spec: rbacoperatorv1alpha1.ArgoCDRecertificationPolicySpec{
+			Interval:    metav1.Duration{Duration: interval},
+			GracePeriod: &metav1.Duration{Duration: gracePeriod},
+		},
+	}
+}
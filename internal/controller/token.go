@@ -0,0 +1,129 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	argocdv1alpha "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+// getServerSignature will return the key Argo CD uses to sign and verify its JWT tokens.
+func getServerSignature(rClient client.Reader, name, namespace string) ([]byte, error) {
+	secret := &corev1.Secret{}
+	if err := FetchObject(rClient, namespace, name, secret); err != nil {
+		return nil, err
+	}
+	key, ok := secret.Data[common.ArgoCDKeyServerSignature]
+	if !ok || len(key) == 0 {
+		return nil, fmt.Errorf("%s is missing in Secret %s/%s", common.ArgoCDKeyServerSignature, namespace, name)
+	}
+	return key, nil
+}
+
+// signJWTToken will return a JWT token for the given subject signed the same way Argo CD signs its tokens.
+func signJWTToken(signingKey []byte, subject, id string, issuedAt time.Time, expiresIn time.Duration) (string, error) {
+	claims := jwt.RegisteredClaims{
+		IssuedAt:  jwt.NewNumericDate(issuedAt),
+		Issuer:    common.ArgoCDJWTTokenIssuer,
+		NotBefore: jwt.NewNumericDate(issuedAt),
+		Subject:   subject,
+		ID:        id,
+	}
+	if expiresIn > 0 {
+		claims.ExpiresAt = jwt.NewNumericDate(issuedAt.Add(expiresIn))
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(signingKey)
+}
+
+// projectTokenSubject returns the subject of a JWT token issued for the project role.
+func projectTokenSubject(appProject, roleName string) string {
+	return fmt.Sprintf("proj:%s:%s", appProject, roleName)
+}
+
+// addJWTTokenToAppProject will record the token in the role of the AppProject, replacing the token with the revokeID.
+func addJWTTokenToAppProject(rClient client.Client, appProject *argocdv1alpha.AppProject, roleName string, token argocdv1alpha.JWTToken, revokeID string) error {
+	ogAppProject := appProject.DeepCopy()
+
+	role, index := getRoleInAppProject(appProject, roleName)
+	if role == nil {
+		return fmt.Errorf("role %s not found in AppProject %s/%s", roleName, appProject.Namespace, appProject.Name)
+	}
+	role.JWTTokens = append(withoutJWTToken(role.JWTTokens, revokeID), token)
+	appProject.Spec.Roles[index] = *role
+
+	if appProject.Status.JWTTokensByRole == nil {
+		appProject.Status.JWTTokensByRole = map[string]argocdv1alpha.JWTTokens{}
+	}
+	tokens := appProject.Status.JWTTokensByRole[roleName]
+	tokens.Items = append(withoutJWTToken(tokens.Items, revokeID), token)
+	appProject.Status.JWTTokensByRole[roleName] = tokens
+
+	if err := rClient.Patch(context.TODO(), appProject, client.MergeFrom(ogAppProject)); err != nil {
+		return errors.Wrapf(err, "failed to patch AppProject %s/%s to add token %s", appProject.Namespace, appProject.Name, token.ID)
+	}
+	return nil
+}
+
+// removeJWTTokenFromAppProject will revoke the token by removing it from all roles of the AppProject.
+func removeJWTTokenFromAppProject(rClient client.Client, appProject *argocdv1alpha.AppProject, id string) error {
+	ogAppProject := appProject.DeepCopy()
+	changed := false
+
+	for i, role := range appProject.Spec.Roles {
+		if hasJWTToken(role.JWTTokens, id) {
+			appProject.Spec.Roles[i].JWTTokens = withoutJWTToken(role.JWTTokens, id)
+			changed = true
+		}
+	}
+	for roleName, tokens := range appProject.Status.JWTTokensByRole {
+		if hasJWTToken(tokens.Items, id) {
+			tokens.Items = withoutJWTToken(tokens.Items, id)
+			appProject.Status.JWTTokensByRole[roleName] = tokens
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil // Token not found in AppProject, nothing to revoke
+	}
+	if err := rClient.Patch(context.TODO(), appProject, client.MergeFrom(ogAppProject)); err != nil {
+		return errors.Wrapf(err, "failed to patch AppProject %s/%s to remove token %s", appProject.Namespace, appProject.Name, id)
+	}
+	return nil
+}
+
+func hasJWTToken(tokens []argocdv1alpha.JWTToken, id string) bool {
+	return slices.ContainsFunc(tokens, func(t argocdv1alpha.JWTToken) bool {
+		return t.ID == id
+	})
+}
+
+func withoutJWTToken(tokens []argocdv1alpha.JWTToken, id string) []argocdv1alpha.JWTToken {
+	return slices.DeleteFunc(slices.Clone(tokens), func(t argocdv1alpha.JWTToken) bool {
+		return t.ID == id
+	})
+}
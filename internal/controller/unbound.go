@@ -0,0 +1,109 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+)
+
+// The bound functions below set the Bound condition and the unbound metric of roles nobody can hold: roles without
+// binding, bindings without active subjects and project roles not bound to any AppProject.
+
+// boundRole sets the Bound condition of the ArgoCDRole from the ArgoCDRoleBindings of its namespace. Any binding not
+// being deleted referencing the role binds it, not only the one of status.argocdRoleBindingRef: the others render the
+// rules into their own keys. The rules of unbound roles are not rendered if skipUnbound.
+func boundRole(role *rbacoperatorv1alpha1.ArgoCDRole, bindings []rbacoperatorv1alpha1.ArgoCDRoleBinding, skipUnbound bool) {
+	for _, rb := range bindings {
+		if rb.Namespace == role.Namespace && rb.Spec.ArgoCDRoleRef.Name == role.Name && !rb.IsBeingDeleted() {
+			setBound(role, "ArgoCDRole", role.Namespace, role.Name, rbacoperatorv1alpha1.Bound())
+			return
+		}
+	}
+	message := "no ArgoCDRoleBinding references the role"
+	if skipUnbound {
+		message += ", its rules are not rendered"
+	}
+	setBound(role, "ArgoCDRole", role.Namespace, role.Name, rbacoperatorv1alpha1.Unbound().WithMessage(message))
+}
+
+// boundRoleBinding sets the Bound condition of the ArgoCDRoleBinding. A binding is unbound if all its subjects are
+// expired, or are role subjects referencing roles missing from the graph.
+func boundRoleBinding(graph *policy.RoleGraph, rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, now time.Time) {
+	expired, missing := 0, 0
+	for _, subject := range rb.Spec.Subjects {
+		switch {
+		case globalSubjectWindow(rb, subject).isExpired(now):
+			expired++
+		case subject.Kind == "role" && !graph.HasRole(subject.Name):
+			missing++
+		}
+	}
+	if expired+missing < len(rb.Spec.Subjects) {
+		setBound(rb, "ArgoCDRoleBinding", rb.Namespace, rb.Name, rbacoperatorv1alpha1.Bound())
+		return
+	}
+	message := fmt.Sprintf("%d subjects expired, %d reference missing roles", expired, missing)
+	setBound(rb, "ArgoCDRoleBinding", rb.Namespace, rb.Name, rbacoperatorv1alpha1.NoActiveSubjects().WithMessage(message))
+}
+
+// boundProjectRole sets the Bound condition of the ArgoCDProjectRole from the AppProjects its binding is bound to.
+// The binding is nil if no ArgoCDProjectRoleBinding references the role.
+func boundProjectRole(role *rbacoperatorv1alpha1.ArgoCDProjectRole, rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) {
+	switch {
+	case rb == nil:
+		setBound(role, "ArgoCDProjectRole", role.Namespace, role.Name,
+			rbacoperatorv1alpha1.Unbound().WithMessage("no ArgoCDProjectRoleBinding references the role"))
+	case len(rb.Status.AppProjectsBound) == 0:
+		setBound(role, "ArgoCDProjectRole", role.Namespace, role.Name,
+			rbacoperatorv1alpha1.NoAppProjects().WithMessage(fmt.Sprintf("ArgoCDProjectRoleBinding %s is not bound to any AppProject", rb.Name)))
+	default:
+		setBound(role, "ArgoCDProjectRole", role.Namespace, role.Name, rbacoperatorv1alpha1.Bound())
+	}
+}
+
+// boundProjectRoleBinding sets the Bound condition of the ArgoCDProjectRoleBinding. A binding is unbound if all its
+// subjects are expired.
+func boundProjectRoleBinding(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding, now time.Time) {
+	expired := 0
+	for _, subject := range rb.Spec.Subjects {
+		if appProjectSubjectWindow(rb, subject).isExpired(now) {
+			expired++
+		}
+	}
+	if expired < len(rb.Spec.Subjects) {
+		setBound(rb, "ArgoCDProjectRoleBinding", rb.Namespace, rb.Name, rbacoperatorv1alpha1.Bound())
+		return
+	}
+	message := fmt.Sprintf("%d subjects expired", expired)
+	setBound(rb, "ArgoCDProjectRoleBinding", rb.Namespace, rb.Name, rbacoperatorv1alpha1.NoActiveSubjects().WithMessage(message))
+}
+
+// setBound sets the Bound condition and the unbound metric to 1 if the condition is false.
+func setBound(obj interface {
+	SetConditions(...rbacoperatorv1alpha1.Condition)
+}, kind, namespace, name string, condition rbacoperatorv1alpha1.Condition) {
+	obj.SetConditions(condition)
+	unbound := 0.0
+	if condition.Reason != rbacoperatorv1alpha1.ReasonBound {
+		unbound = 1
+	}
+	unboundResources.WithLabelValues(kind, namespace, name).Set(unbound)
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+// eventReasonAccessExpired is the reason of the Event emitted when a subject's access is removed at the end of its window.
+const eventReasonAccessExpired = "AccessExpired"
+
+// timeNow returns the current time. Replaced in tests to move bindings in and out of their window.
+var timeNow = time.Now
+
+// window is the time a binding or a subject grants access in, restricted by notBefore, expiresAt and recurring schedules.
+type window struct {
+	notBefore *metav1.Time
+	expiresAt *metav1.Time
+	schedules []rbacoperatorv1alpha1.Schedule
+}
+
+// intersect returns the window restricted by the other window.
+func (w window) intersect(other window) window {
+	if other.notBefore != nil && (w.notBefore == nil || other.notBefore.After(w.notBefore.Time)) {
+		w.notBefore = other.notBefore
+	}
+	if other.expiresAt != nil && (w.expiresAt == nil || other.expiresAt.Before(w.expiresAt)) {
+		w.expiresAt = other.expiresAt
+	}
+	w.schedules = append(slices.Clone(w.schedules), other.schedules...)
+	return w
+}
+
+// condition returns the Active condition of the window at the given time.
+func (w window) condition(now time.Time) rbacoperatorv1alpha1.Condition {
+	switch {
+	case w.expiresAt != nil && !now.Before(w.expiresAt.Time):
+		return rbacoperatorv1alpha1.Expired().WithMessage(fmt.Sprintf("expired at %s", w.expiresAt.UTC().Format(time.RFC3339)))
+	case w.notBefore != nil && now.Before(w.notBefore.Time):
+		return rbacoperatorv1alpha1.Scheduled().WithMessage(fmt.Sprintf("active from %s", w.notBefore.UTC().Format(time.RFC3339)))
+	case len(w.schedules) > 0 && !isScheduleOpen(w.schedules, now):
+		if next := nextScheduleTransition(w.schedules, now); !next.IsZero() {
+			return rbacoperatorv1alpha1.Scheduled().WithMessage(fmt.Sprintf("outside of schedule, next window opens at %s", next.UTC().Format(time.RFC3339)))
+		}
+		return rbacoperatorv1alpha1.Scheduled().WithMessage("outside of schedule")
+	}
+	return rbacoperatorv1alpha1.Active()
+}
+
+// isActive returns true if the window grants access at the given time.
+func (w window) isActive(now time.Time) bool {
+	return w.condition(now).Reason == rbacoperatorv1alpha1.ReasonActive
+}
+
+// isExpired returns true if the window is over at the given time.
+func (w window) isExpired(now time.Time) bool {
+	return w.expiresAt != nil && !now.Before(w.expiresAt.Time)
+}
+
+// nextTransition returns the next notBefore, expiresAt or schedule window boundary of the windows after the given time.
+// Returns zero time if there is no boundary ahead.
+func nextTransition(now time.Time, windows ...window) time.Time {
+	next := time.Time{}
+	for _, w := range windows {
+		boundaries := []time.Time{nextScheduleTransition(w.schedules, now)}
+		for _, boundary := range []*metav1.Time{w.notBefore, w.expiresAt} {
+			if boundary != nil {
+				boundaries = append(boundaries, boundary.Time)
+			}
+		}
+		for _, boundary := range boundaries {
+			if boundary.After(now) && (next.IsZero() || boundary.Before(next)) {
+				next = boundary
+			}
+		}
+	}
+	return next
+}
+
+// nextBoundary returns the duration until the next boundary of the windows after the given time.
+// Returns fallback if there is no boundary ahead or it is further away than fallback.
+func nextBoundary(now time.Time, fallback time.Duration, windows ...window) time.Duration {
+	next := nextTransition(now, windows...)
+	if next.IsZero() {
+		return fallback
+	}
+	return min(fallback, next.Sub(now))
+}
+
+func roleBindingWindow(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) window {
+	return window{notBefore: rb.Spec.NotBefore, expiresAt: rb.Spec.ExpiresAt, schedules: rb.Spec.Schedules}
+}
+
+func globalSubjectWindow(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, subject rbacoperatorv1alpha1.GlobalSubject) window {
+	return roleBindingWindow(rb).intersect(window{notBefore: subject.NotBefore, expiresAt: subject.ExpiresAt})
+}
+
+func projectRoleBindingWindow(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) window {
+	return window{notBefore: rb.Spec.NotBefore, expiresAt: rb.Spec.ExpiresAt, schedules: rb.Spec.Schedules}
+}
+
+func appProjectSubjectWindow(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding, subject rbacoperatorv1alpha1.AppProjectSubject) window {
+	return projectRoleBindingWindow(rb).intersect(window{notBefore: subject.NotBefore, expiresAt: subject.ExpiresAt})
+}
+
+// activeGlobalSubjects returns the subjects of the ArgoCDRoleBinding within their window at the given time.
+// No subject is active while the binding is suspended for recertification.
+func activeGlobalSubjects(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, now time.Time) []rbacoperatorv1alpha1.GlobalSubject {
+	subjects := []rbacoperatorv1alpha1.GlobalSubject{}
+	if rb.Status.Recertification.IsSuspended(now) {
+		return subjects
+	}
+	for _, subject := range rb.Spec.Subjects {
+		if globalSubjectWindow(rb, subject).isActive(now) {
+			subjects = append(subjects, subject)
+		}
+	}
+	return subjects
+}
+
+// activeAppProjectSubjects returns the subjects of the ArgoCDProjectRoleBinding within their window at the given time.
+// No subject is active while the binding is suspended for recertification.
+func activeAppProjectSubjects(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding, now time.Time) []rbacoperatorv1alpha1.AppProjectSubject {
+	subjects := []rbacoperatorv1alpha1.AppProjectSubject{}
+	if rb.Status.Recertification.IsSuspended(now) {
+		return subjects
+	}
+	for _, subject := range rb.Spec.Subjects {
+		if appProjectSubjectWindow(rb, subject).isActive(now) {
+			subjects = append(subjects, subject)
+		}
+	}
+	return subjects
+}
+
+func roleBindingWindows(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) []window {
+	windows := []window{roleBindingWindow(rb)}
+	for _, subject := range rb.Spec.Subjects {
+		windows = append(windows, globalSubjectWindow(rb, subject))
+	}
+	return windows
+}
+
+func projectRoleBindingWindows(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) []window {
+	windows := []window{projectRoleBindingWindow(rb)}
+	for _, subject := range rb.Spec.Subjects {
+		windows = append(windows, appProjectSubjectWindow(rb, subject))
+	}
+	return windows
+}
+
+// roleBindingRequeueAfter returns the duration until the next window boundary of the ArgoCDRoleBinding or its subjects,
+// or until its recertification deadlines.
+func roleBindingRequeueAfter(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding, now time.Time, fallback time.Duration) time.Duration {
+	return recertificationRequeueAfter(rb.Status.Recertification, now, nextBoundary(now, fallback, roleBindingWindows(rb)...))
+}
+
+// projectRoleBindingRequeueAfter returns the duration until the next window boundary of the ArgoCDProjectRoleBinding or its subjects,
+// or until its recertification deadlines.
+func projectRoleBindingRequeueAfter(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding, now time.Time, fallback time.Duration) time.Duration {
+	return recertificationRequeueAfter(rb.Status.Recertification, now, nextBoundary(now, fallback, projectRoleBindingWindows(rb)...))
+}
+
+// transitionStatus returns the next transition and the upcoming schedule windows shown in the status of a binding.
+func transitionStatus(now time.Time, schedules []rbacoperatorv1alpha1.Schedule, windows ...window) (*metav1.Time, []rbacoperatorv1alpha1.TimeWindow) {
+	var transition *metav1.Time
+	if next := nextTransition(now, windows...); !next.IsZero() {
+		t := metav1.NewTime(next.UTC())
+		transition = &t
+	}
+	if len(schedules) == 0 {
+		return transition, nil
+	}
+	return transition, upcomingWindows(schedules, now, upcomingWindowsCount)
+}
+
+func globalSubjectKey(subject rbacoperatorv1alpha1.GlobalSubject) string {
+	return fmt.Sprintf("%s:%s", subject.Kind, subject.Name)
+}
+
+func appProjectSubjectKey(subject rbacoperatorv1alpha1.AppProjectSubject, argoCDNamespace, bindingNamespace string) string {
+	return appProjectStatusKey(types.NamespacedName{
+		Name:      subject.AppProjectRef,
+		Namespace: resolveAppProjectNamespace(subject, argoCDNamespace, bindingNamespace),
+	}, bindingNamespace)
+}
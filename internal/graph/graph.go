@@ -0,0 +1,295 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package graph exports the Argo CD RBAC policy as a graph of subjects, bindings, roles and the resources and objects
+// of their rules, and of groups, project roles and AppProjects, in Graphviz DOT, Mermaid or JSON.
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/argoproj/argo-cd/v3/util/glob"
+
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+)
+
+// Kinds of nodes.
+const (
+	KindSubject     = "subject"
+	KindBinding     = "binding"
+	KindRole        = "role"
+	KindProjectRole = "projectRole"
+	KindAppProject  = "appProject"
+	KindResource    = "resource"
+)
+
+// Formats the graph can be written in.
+const (
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+	FormatJSON    = "json"
+)
+
+// Node is a user, group or account, a binding, a role, a project role, an AppProject or the resource and object of rules.
+type Node struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+}
+
+// Edge leads from a subject to its binding, from a binding or role to the role it grants, from a project role to its
+// AppProject, and from a role to the resource and object of its rules, labelled with their actions.
+type Edge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label,omitempty"`
+}
+
+// Graph is the graph of a policy.
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// Filter selects the part of the policy in the graph. Empty fields don't filter.
+type Filter struct {
+	// Namespace keeps the lines rendered from resources in the namespace.
+	Namespace string
+	// Subject keeps the bindings, roles and rules the user, group or account holds.
+	Subject string
+	// Resource keeps the rules applying to the resource, e.g. applications, and what leads to them.
+	Resource string
+}
+
+// Build returns the graph of the policy. Grants rendered from ArgoCDRoleBindings and ArgoCDProjectRoleBindings lead
+// through a node of the binding, other grants lead from the subject to the role directly, labelled with the kind of
+// resource they are read from unless they are read from the AppProject of the project role.
+func Build(p *policy.Policy, filter Filter) *Graph {
+	g := &Graph{Nodes: []Node{}, Edges: []Edge{}}
+	for _, line := range p.Lines {
+		if filter.Namespace != "" && line.Source.Namespace != filter.Namespace {
+			continue
+		}
+		switch {
+		case line.IsGrant():
+			from, to := g.holder(line.Fields[1]), g.role(line.Fields[2])
+			if kind := line.Source.Kind; kind == "ArgoCDRoleBinding" || kind == "ArgoCDProjectRoleBinding" {
+				binding := g.addNode(fmt.Sprintf("%s:%s/%s", kind, line.Source.Namespace, line.Source.Name), KindBinding,
+					fmt.Sprintf("%s %s/%s", kind, line.Source.Namespace, line.Source.Name))
+				g.addEdge(from, binding, "")
+				g.addEdge(binding, to, "")
+			} else if line.Source.Kind == "AppProject" {
+				g.addEdge(from, to, "")
+			} else {
+				g.addEdge(from, to, line.Source.Kind)
+			}
+		case line.IsRule():
+			if filter.Resource != "" && !glob.Match(line.Fields[2], filter.Resource) {
+				continue
+			}
+			holder := g.holder(line.Fields[1])
+			resource := g.addNode(fmt.Sprintf("resource:%s:%s", line.Fields[2], line.Fields[4]), KindResource,
+				fmt.Sprintf("%s %s", line.Fields[2], line.Fields[4]))
+			action := line.Fields[3]
+			if line.Effect() != "allow" {
+				action = line.Effect() + " " + action
+			}
+			g.addEdge(holder, resource, action)
+		}
+	}
+	if p.DefaultRole != "" && (filter.Namespace == "" || p.DefaultRoleSource.Namespace == filter.Namespace) {
+		g.addEdge(g.addNode("subject:*", KindSubject, "*"), g.role(p.DefaultRole), "policy.default")
+	}
+
+	if filter.Subject != "" {
+		g.keep(g.reachable([]string{"subject:" + filter.Subject}, false))
+	}
+	if filter.Resource != "" {
+		resources := []string{}
+		for _, node := range g.Nodes {
+			if node.Kind == KindResource {
+				resources = append(resources, node.ID)
+			}
+		}
+		kept := g.reachable(resources, true)
+		// AppProjects don't lead to the resources, they are kept with their project roles
+		for _, edge := range g.Edges {
+			if kept[edge.From] && strings.HasPrefix(edge.To, "appproject:") {
+				kept[edge.To] = true
+			}
+		}
+		g.keep(kept)
+	}
+	return g
+}
+
+// holder adds the node of the subject or role of a line and returns its ID.
+func (g *Graph) holder(name string) string {
+	if strings.HasPrefix(name, "role:") || strings.HasPrefix(name, "proj:") {
+		return g.role(name)
+	}
+	return g.addNode("subject:"+name, KindSubject, name)
+}
+
+// role adds the node of a role, and of the AppProject of a project role, and returns its ID.
+func (g *Graph) role(name string) string {
+	if rest, ok := strings.CutPrefix(name, "proj:"); ok {
+		project, _, _ := strings.Cut(rest, ":")
+		g.addNode(name, KindProjectRole, name)
+		g.addEdge(name, g.addNode("appproject:"+project, KindAppProject, "AppProject "+project), "")
+		return name
+	}
+	return g.addNode(name, KindRole, name)
+}
+
+// addNode adds the node unless it exists and returns its ID.
+func (g *Graph) addNode(id, kind, label string) string {
+	if !slices.ContainsFunc(g.Nodes, func(n Node) bool { return n.ID == id }) {
+		g.Nodes = append(g.Nodes, Node{ID: id, Kind: kind, Label: label})
+	}
+	return id
+}
+
+// addEdge adds the edge, or the label to the existing edge between the nodes.
+func (g *Graph) addEdge(from, to, label string) {
+	for i, edge := range g.Edges {
+		if edge.From != from || edge.To != to {
+			continue
+		}
+		labels := strings.Split(edge.Label, ", ")
+		if label != "" && !slices.Contains(labels, label) {
+			g.Edges[i].Label = strings.TrimPrefix(edge.Label+", "+label, ", ")
+		}
+		return
+	}
+	g.Edges = append(g.Edges, Edge{From: from, To: to, Label: label})
+}
+
+// reachable returns the nodes reachable from the nodes, following the edges backwards if reverse is true.
+func (g *Graph) reachable(ids []string, reverse bool) map[string]bool {
+	reached := map[string]bool{}
+	queue := slices.Clone(ids)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if reached[id] {
+			continue
+		}
+		reached[id] = true
+		for _, edge := range g.Edges {
+			if !reverse && edge.From == id {
+				queue = append(queue, edge.To)
+			}
+			if reverse && edge.To == id {
+				queue = append(queue, edge.From)
+			}
+		}
+	}
+	return reached
+}
+
+// keep removes the nodes not kept, and their edges.
+func (g *Graph) keep(kept map[string]bool) {
+	g.Nodes = slices.DeleteFunc(g.Nodes, func(n Node) bool { return !kept[n.ID] })
+	g.Edges = slices.DeleteFunc(g.Edges, func(e Edge) bool { return !kept[e.From] || !kept[e.To] })
+}
+
+// Write writes the graph in the format, dot, mermaid or json.
+func (g *Graph) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatDOT:
+		_, err := io.WriteString(w, g.DOT())
+		return err
+	case FormatMermaid:
+		_, err := io.WriteString(w, g.Mermaid())
+		return err
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(g)
+	}
+	return fmt.Errorf("unknown format %q, must be dot, mermaid or json", format)
+}
+
+// dotShapes are the Graphviz shapes of the kinds of nodes.
+var dotShapes = map[string]string{
+	KindSubject:     "ellipse",
+	KindBinding:     "note",
+	KindRole:        "box",
+	KindProjectRole: "box",
+	KindAppProject:  "folder",
+	KindResource:    "component",
+}
+
+// DOT returns the graph in the Graphviz DOT language.
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph rbac {\n  rankdir=LR;\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, shape=%s];\n", dotQuote(node.ID), dotQuote(node.Label), dotShapes[node.Kind])
+	}
+	for _, edge := range g.Edges {
+		if edge.Label == "" {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(edge.From), dotQuote(edge.To))
+		} else {
+			fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", dotQuote(edge.From), dotQuote(edge.To), dotQuote(edge.Label))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// mermaidShapes are the opening and closing brackets of the Mermaid shapes of the kinds of nodes.
+var mermaidShapes = map[string][2]string{
+	KindSubject:     {"([", "])"},
+	KindBinding:     {"[[", "]]"},
+	KindRole:        {"[", "]"},
+	KindProjectRole: {"[", "]"},
+	KindAppProject:  {"[(", ")]"},
+	KindResource:    {">", "]"},
+}
+
+// Mermaid returns the graph as a Mermaid flowchart. Nodes are numbered in the order of the graph.
+func (g *Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	ids := map[string]string{}
+	for i, node := range g.Nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+		shape := mermaidShapes[node.Kind]
+		fmt.Fprintf(&b, "  %s%s%s%s\n", ids[node.ID], shape[0], mermaidQuote(node.Label), shape[1])
+	}
+	for _, edge := range g.Edges {
+		if edge.Label == "" {
+			fmt.Fprintf(&b, "  %s --> %s\n", ids[edge.From], ids[edge.To])
+		} else {
+			fmt.Fprintf(&b, "  %s -->|%s| %s\n", ids[edge.From], mermaidQuote(edge.Label), ids[edge.To])
+		}
+	}
+	return b.String()
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
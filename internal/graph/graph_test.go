@@ -0,0 +1,131 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+)
+
+func makeTestPolicy() *policy.Policy {
+	role := rbacoperatorv1alpha1.PermissionSource{Kind: "ArgoCDRole", Namespace: "team-a", Name: "dev"}
+	binding := rbacoperatorv1alpha1.PermissionSource{Kind: "ArgoCDRoleBinding", Namespace: "team-a", Name: "dev"}
+	appProject := rbacoperatorv1alpha1.PermissionSource{Kind: "AppProject", Namespace: "argocd", Name: "web"}
+	cm := rbacoperatorv1alpha1.PermissionSource{Kind: "ConfigMap", Namespace: "argocd", Name: "argocd-rbac-cm", Key: "policy.csv"}
+	p := &policy.Policy{}
+	p.Lines = append(p.Lines, policy.ParseLines("p, role:dev, applications, get, team-a/*, allow\np, role:dev, applications, sync, team-a/*, allow\np, role:dev, logs, get, team-a/*, deny\n", role)...)
+	p.Lines = append(p.Lines, policy.ParseLines("g, alice, role:dev\ng, team-a, role:dev\n", binding)...)
+	p.Lines = append(p.Lines, policy.ParseLines("p, proj:web:deployer, applications, sync, web/*, allow\ng, web-team, proj:web:deployer\n", appProject)...)
+	p.Lines = append(p.Lines, policy.ParseLines("g, bob, role:dev\n", cm)...)
+	return p
+}
+
+func TestBuild(t *testing.T) {
+	g := Build(makeTestPolicy(), Filter{})
+	assert.Equal(t, []Node{
+		{ID: "role:dev", Kind: KindRole, Label: "role:dev"},
+		{ID: "resource:applications:team-a/*", Kind: KindResource, Label: "applications team-a/*"},
+		{ID: "resource:logs:team-a/*", Kind: KindResource, Label: "logs team-a/*"},
+		{ID: "subject:alice", Kind: KindSubject, Label: "alice"},
+		{ID: "ArgoCDRoleBinding:team-a/dev", Kind: KindBinding, Label: "ArgoCDRoleBinding team-a/dev"},
+		{ID: "subject:team-a", Kind: KindSubject, Label: "team-a"},
+		{ID: "proj:web:deployer", Kind: KindProjectRole, Label: "proj:web:deployer"},
+		{ID: "appproject:web", Kind: KindAppProject, Label: "AppProject web"},
+		{ID: "resource:applications:web/*", Kind: KindResource, Label: "applications web/*"},
+		{ID: "subject:web-team", Kind: KindSubject, Label: "web-team"},
+		{ID: "subject:bob", Kind: KindSubject, Label: "bob"},
+	}, g.Nodes)
+	assert.Equal(t, []Edge{
+		{From: "role:dev", To: "resource:applications:team-a/*", Label: "get, sync"},
+		{From: "role:dev", To: "resource:logs:team-a/*", Label: "deny get"},
+		{From: "subject:alice", To: "ArgoCDRoleBinding:team-a/dev"},
+		{From: "ArgoCDRoleBinding:team-a/dev", To: "role:dev"},
+		{From: "subject:team-a", To: "ArgoCDRoleBinding:team-a/dev"},
+		{From: "proj:web:deployer", To: "appproject:web"},
+		{From: "proj:web:deployer", To: "resource:applications:web/*", Label: "sync"},
+		{From: "subject:web-team", To: "proj:web:deployer"},
+		{From: "subject:bob", To: "role:dev", Label: "ConfigMap"},
+	}, g.Edges)
+}
+
+func TestBuild_Filter(t *testing.T) {
+	nodeIDs := func(g *Graph) []string {
+		ids := []string{}
+		for _, node := range g.Nodes {
+			ids = append(ids, node.ID)
+		}
+		return ids
+	}
+
+	// Only what the subject holds
+	g := Build(makeTestPolicy(), Filter{Subject: "alice"})
+	assert.ElementsMatch(t, []string{"subject:alice", "ArgoCDRoleBinding:team-a/dev", "role:dev",
+		"resource:applications:team-a/*", "resource:logs:team-a/*"}, nodeIDs(g))
+
+	// Only the rules of the resource and what leads to them
+	g = Build(makeTestPolicy(), Filter{Resource: "logs"})
+	assert.ElementsMatch(t, []string{"subject:alice", "subject:team-a", "subject:bob", "ArgoCDRoleBinding:team-a/dev",
+		"role:dev", "resource:logs:team-a/*"}, nodeIDs(g))
+
+	// Only the lines rendered from resources of the namespace
+	g = Build(makeTestPolicy(), Filter{Namespace: "argocd", Resource: "applications"})
+	assert.ElementsMatch(t, []string{"subject:web-team", "proj:web:deployer", "appproject:web", "resource:applications:web/*"}, nodeIDs(g))
+}
+
+func TestGraph_Write(t *testing.T) {
+	g := Build(makeTestPolicy(), Filter{Subject: "web-team"})
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, g.Write(out, FormatDOT))
+	assert.Equal(t, `digraph rbac {
+  rankdir=LR;
+  "proj:web:deployer" [label="proj:web:deployer", shape=box];
+  "appproject:web" [label="AppProject web", shape=folder];
+  "resource:applications:web/*" [label="applications web/*", shape=component];
+  "subject:web-team" [label="web-team", shape=ellipse];
+  "proj:web:deployer" -> "appproject:web";
+  "proj:web:deployer" -> "resource:applications:web/*" [label="sync"];
+  "subject:web-team" -> "proj:web:deployer";
+}
+`, out.String())
+
+	out.Reset()
+	assert.NoError(t, g.Write(out, FormatMermaid))
+	assert.Equal(t, `flowchart LR
+  n0["proj:web:deployer"]
+  n1[("AppProject web")]
+  n2>"applications web/*"]
+  n3(["web-team"])
+  n0 --> n1
+  n0 -->|"sync"| n2
+  n3 --> n0
+`, out.String())
+
+	out.Reset()
+	assert.NoError(t, g.Write(out, FormatJSON))
+	decoded := &Graph{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), decoded))
+	assert.Equal(t, g, decoded)
+
+	assert.Error(t, g.Write(out, "svg"))
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lint reports risky grants of ArgoCDRoles, ArgoCDProjectRoles and their bindings.
+package lint
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/argoproj/argo-cd/v3/util/glob"
+	"sigs.k8s.io/yaml"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+// Severity is how risky a finding is.
+type Severity string
+
+// Severities, from the least to the most risky.
+const (
+	SeverityInfo     Severity = "info"
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+var severities = []Severity{SeverityInfo, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// Rank returns 1 for info up to 5 for critical, and 0 for unknown severities.
+func (s Severity) Rank() int {
+	return slices.Index(severities, s) + 1
+}
+
+// Subject kinds of binding rules. Groups are the groups of ArgoCDProjectRoleBindings.
+const (
+	SubjectKindSSO   = "sso"
+	SubjectKindLocal = "local"
+	SubjectKindRole  = "role"
+	SubjectKindGroup = "group"
+)
+
+// Rule is a risky grant. Rules setting roles or subjectKinds match bindings, all other rules match the rules of roles.
+// Empty lists match everything.
+type Rule struct {
+	// Name of the rule. Rules of the configuration replace the built-in rule of the same name.
+	Name string `json:"name"`
+	// Severity of the findings of the rule.
+	Severity Severity `json:"severity"`
+	// Message describing the risk.
+	Message string `json:"message"`
+	// Resources matched by the rule.
+	Resources []string `json:"resources,omitempty"`
+	// Verbs matched by the rule. A verb of a role matches if it covers one of them, so "*" matches "create".
+	Verbs []string `json:"verbs,omitempty"`
+	// Objects matched by the rule. An object of a role matches if it covers one of them, so "*/*" and "*" match "*/*",
+	// "team-a/*" does not.
+	Objects []string `json:"objects,omitempty"`
+	// Roles are glob patterns of the role names matched by the rule.
+	Roles []string `json:"roles,omitempty"`
+	// SubjectKinds matched by the rule (sso, local, role or group).
+	SubjectKinds []string `json:"subjectKinds,omitempty"`
+}
+
+func (r Rule) isBindingRule() bool {
+	return len(r.Roles) > 0 || len(r.SubjectKinds) > 0
+}
+
+// BuiltinRules are the rules applied unless disabled or replaced by the configuration.
+var BuiltinRules = []Rule{
+	{
+		Name:      "exec-all-applications",
+		Severity:  SeverityCritical,
+		Message:   "grants a shell in the pods of all applications",
+		Resources: []string{"exec"},
+		Verbs:     []string{"create"},
+		Objects:   []string{"*/*"},
+	},
+	{
+		Name:     "wildcard-verbs",
+		Severity: SeverityMedium,
+		Message:  "grants all verbs, including the ones added by future Argo CD versions",
+		Verbs:    []string{"*"},
+	},
+	{
+		Name:      "wildcard-clusters",
+		Severity:  SeverityHigh,
+		Message:   "grants changing all clusters",
+		Resources: []string{"clusters"},
+		Verbs:     []string{"create", "update", "delete"},
+		Objects:   []string{"*"},
+	},
+	{
+		Name:      "accounts-update",
+		Severity:  SeverityHigh,
+		Message:   "grants changing accounts, including their passwords",
+		Resources: []string{"accounts"},
+		Verbs:     []string{"update"},
+	},
+	{
+		Name:         "role-subject-admin",
+		Severity:     SeverityHigh,
+		Message:      "binds the admin role to an Argo CD role, granting admin to everyone holding it",
+		Roles:        []string{"admin"},
+		SubjectKinds: []string{SubjectKindRole},
+	},
+}
+
+// Config adds, replaces and disables rules.
+type Config struct {
+	// Rules added to the built-in rules, or replacing the built-in rule of the same name.
+	Rules []Rule `json:"rules,omitempty"`
+	// Disabled are the names of the rules not applied.
+	Disabled []string `json:"disabled,omitempty"`
+}
+
+// LoadConfig reads the configuration from the YAML file.
+func LoadConfig(name string) (Config, error) {
+	config := Config{}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return config, err
+	}
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return config, fmt.Errorf("invalid lint configuration %s: %v", name, err)
+	}
+	return config, nil
+}
+
+// Finding is a grant matched by a rule.
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Message  string
+}
+
+// String returns the finding as "<severity> <rule>: <message>".
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s: %s", f.Severity, f.Rule, f.Message)
+}
+
+// Linter applies the built-in rules and the ones of the configuration.
+type Linter struct {
+	rules []Rule
+}
+
+// New returns a Linter of the built-in rules changed by the configuration.
+func New(config Config) (*Linter, error) {
+	rules := []Rule{}
+	for _, rule := range BuiltinRules {
+		if !slices.ContainsFunc(config.Rules, func(r Rule) bool { return r.Name == rule.Name }) {
+			rules = append(rules, rule)
+		}
+	}
+	for _, rule := range config.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("lint rule without name")
+		}
+		if rule.Severity.Rank() == 0 {
+			return nil, fmt.Errorf("lint rule %s has unknown severity %q", rule.Name, rule.Severity)
+		}
+		rules = append(rules, rule)
+	}
+	rules = slices.DeleteFunc(rules, func(r Rule) bool { return slices.Contains(config.Disabled, r.Name) })
+	return &Linter{rules: rules}, nil
+}
+
+// GlobalRules returns the findings of the rules of an ArgoCDRole, the most risky first.
+func (l *Linter) GlobalRules(rules []rbacoperatorv1alpha1.GlobalRule) []Finding {
+	findings := []Finding{}
+	for _, rule := range rules {
+		findings = append(findings, l.grant(rule.Resource, rule.Verbs, rule.Objects)...)
+	}
+	return sortFindings(findings)
+}
+
+// ProjectRules returns the findings of the rules of an ArgoCDProjectRole, the most risky first.
+func (l *Linter) ProjectRules(rules []rbacoperatorv1alpha1.ProjectRule) []Finding {
+	findings := []Finding{}
+	for _, rule := range rules {
+		findings = append(findings, l.grant(rule.Resource, rule.Verbs, rule.Objects)...)
+	}
+	return sortFindings(findings)
+}
+
+// RoleBinding returns the findings of the subjects of an ArgoCDRoleBinding, the most risky first.
+func (l *Linter) RoleBinding(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) []Finding {
+	findings := []Finding{}
+	for _, subject := range rb.Spec.Subjects {
+		findings = append(findings, l.binding(rb.Spec.ArgoCDRoleRef.Name, subject.Kind, subject.Name)...)
+	}
+	return sortFindings(findings)
+}
+
+// ProjectRoleBinding returns the findings of the groups and users of an ArgoCDProjectRoleBinding, the most risky first.
+func (l *Linter) ProjectRoleBinding(rb *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) []Finding {
+	findings := []Finding{}
+	for _, subject := range rb.Spec.Subjects {
+		for _, group := range subject.Groups {
+			findings = append(findings, l.binding(rb.Spec.ArgoCDProjectRoleRef.Name, SubjectKindGroup, group)...)
+		}
+		for _, user := range subject.Users {
+			findings = append(findings, l.binding(rb.Spec.ArgoCDProjectRoleRef.Name, user.Kind, user.Name)...)
+		}
+	}
+	return sortFindings(findings)
+}
+
+func (l *Linter) grant(resource string, verbs, objects []string) []Finding {
+	findings := []Finding{}
+	for _, rule := range l.rules {
+		if rule.isBindingRule() || !matchesAny(rule.Resources, resource, exactMatch) {
+			continue
+		}
+		for _, verb := range verbs {
+			for _, object := range objects {
+				if matchesAny(rule.Verbs, verb, covers) && matchesAny(rule.Objects, object, covers) {
+					findings = append(findings, Finding{Rule: rule.Name, Severity: rule.Severity,
+						Message: fmt.Sprintf("%s %s %s %s", resource, verb, object, rule.Message)})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+func (l *Linter) binding(roleName, kind, name string) []Finding {
+	findings := []Finding{}
+	for _, rule := range l.rules {
+		if rule.isBindingRule() && matchesAny(rule.Roles, roleName, globMatch) && matchesAny(rule.SubjectKinds, kind, exactMatch) {
+			findings = append(findings, Finding{Rule: rule.Name, Severity: rule.Severity,
+				Message: fmt.Sprintf("%s:%s %s", kind, name, rule.Message)})
+		}
+	}
+	return findings
+}
+
+// Top returns the most risky finding, nil if there is none. The findings must be sorted.
+func Top(findings []Finding) *Finding {
+	if len(findings) == 0 {
+		return nil
+	}
+	return &findings[0]
+}
+
+func sortFindings(findings []Finding) []Finding {
+	slices.SortStableFunc(findings, func(a, b Finding) int { return b.Severity.Rank() - a.Severity.Rank() })
+	return findings
+}
+
+// matchesAny returns true if the patterns are empty or one of them matches.
+func matchesAny(patterns []string, value string, match func(pattern, value string) bool) bool {
+	return len(patterns) == 0 || slices.ContainsFunc(patterns, func(pattern string) bool { return match(pattern, value) })
+}
+
+func exactMatch(pattern, value string) bool {
+	return pattern == value
+}
+
+// globMatch matches the value like the Argo CD enforcer, "*" also matches "/".
+func globMatch(pattern, value string) bool {
+	return glob.Match(pattern, value)
+}
+
+// covers returns true if the granted value covers the value of the rule, i.e. the grant matches it as glob pattern.
+func covers(value, granted string) bool {
+	return globMatch(granted, value)
+}
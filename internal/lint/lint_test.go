@@ -0,0 +1,121 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+func findingRules(findings []Finding) []string {
+	rules := []string{}
+	for _, finding := range findings {
+		rules = append(rules, finding.Rule)
+	}
+	return rules
+}
+
+func TestLinter_GlobalRules(t *testing.T) {
+	linter, err := New(Config{})
+	assert.NoError(t, err)
+
+	findings := linter.GlobalRules([]rbacoperatorv1alpha1.GlobalRule{
+		{Resource: "applications", Verbs: []string{"*"}, Objects: []string{"team-a/*"}},
+		{Resource: "exec", Verbs: []string{"create"}, Objects: []string{"*/*", "team-a/*"}},
+		{Resource: "clusters", Verbs: []string{"get", "update"}, Objects: []string{"*", "https://kubernetes.default.svc"}},
+		{Resource: "accounts", Verbs: []string{"update"}, Objects: []string{"alice"}},
+	})
+	// The most risky first
+	assert.Equal(t, []string{"exec-all-applications", "wildcard-clusters", "accounts-update", "wildcard-verbs"}, findingRules(findings))
+	assert.Equal(t, "critical exec-all-applications: exec create */* grants a shell in the pods of all applications", findings[0].String())
+	assert.Equal(t, &findings[0], Top(findings))
+
+	assert.Empty(t, linter.GlobalRules([]rbacoperatorv1alpha1.GlobalRule{
+		{Resource: "applications", Verbs: []string{"get", "sync"}, Objects: []string{"*/*"}},
+	}))
+	assert.Nil(t, Top(nil))
+
+	// Objects are matched like the Argo CD enforcer, "*" also matches "/"
+	findings = linter.GlobalRules([]rbacoperatorv1alpha1.GlobalRule{
+		{Resource: "exec", Verbs: []string{"create", "*"}, Objects: []string{"*"}},
+	})
+	assert.Equal(t, []string{"exec-all-applications", "exec-all-applications", "wildcard-verbs"}, findingRules(findings))
+}
+
+func TestLinter_Bindings(t *testing.T) {
+	linter, err := New(Config{})
+	assert.NoError(t, err)
+
+	rb := &rbacoperatorv1alpha1.ArgoCDRoleBinding{Spec: rbacoperatorv1alpha1.ArgoCDRoleBindingSpec{
+		ArgoCDRoleRef: rbacoperatorv1alpha1.ArgoCDRoleRef{Name: "admin"},
+		Subjects: []rbacoperatorv1alpha1.GlobalSubject{
+			{Kind: SubjectKindSSO, Name: "alice"},
+			{Kind: SubjectKindRole, Name: "ops"},
+		},
+	}}
+	findings := linter.RoleBinding(rb)
+	assert.Equal(t, []string{"role-subject-admin"}, findingRules(findings))
+	assert.Contains(t, findings[0].Message, "role:ops")
+
+	rb.Spec.ArgoCDRoleRef.Name = "readonly"
+	assert.Empty(t, linter.RoleBinding(rb))
+}
+
+func TestLinter_Config(t *testing.T) {
+	linter, err := New(Config{
+		Rules: []Rule{
+			{Name: "wildcard-verbs", Severity: SeverityLow, Message: "grants all verbs", Verbs: []string{"*"}},
+			{Name: "sso-group-admins", Severity: SeverityHigh, Message: "binds the admins group", SubjectKinds: []string{SubjectKindGroup}, Roles: []string{"*admin*"}},
+		},
+		Disabled: []string{"exec-all-applications"},
+	})
+	assert.NoError(t, err)
+
+	findings := linter.ProjectRules([]rbacoperatorv1alpha1.ProjectRule{
+		{Resource: "exec", Verbs: []string{"*"}, Objects: []string{"*/*"}},
+	})
+	assert.Equal(t, []Finding{{Rule: "wildcard-verbs", Severity: SeverityLow, Message: "exec * */* grants all verbs"}}, findings)
+
+	rb := &rbacoperatorv1alpha1.ArgoCDProjectRoleBinding{Spec: rbacoperatorv1alpha1.ArgoCDProjectRoleBindingSpec{
+		ArgoCDProjectRoleRef: rbacoperatorv1alpha1.ArgoCDProjectRoleRef{Name: "project-admin"},
+		Subjects:             []rbacoperatorv1alpha1.AppProjectSubject{{AppProjectRef: "team-a", Groups: []string{"team-a-admins"}}},
+	}}
+	assert.Equal(t, []string{"sso-group-admins"}, findingRules(linter.ProjectRoleBinding(rb)))
+
+	_, err = New(Config{Rules: []Rule{{Name: "unknown", Severity: "severe"}}})
+	assert.ErrorContains(t, err, `lint rule unknown has unknown severity "severe"`)
+}
+
+func TestLoadConfig(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "lint.yaml")
+	assert.NoError(t, os.WriteFile(name, []byte("disabled:\n- wildcard-verbs\nrules:\n- name: logs\n  severity: info\n  message: grants logs\n  resources: [logs]\n"), 0o600))
+	config, err := LoadConfig(name)
+	assert.NoError(t, err)
+	assert.Equal(t, Config{
+		Rules:    []Rule{{Name: "logs", Severity: SeverityInfo, Message: "grants logs", Resources: []string{"logs"}}},
+		Disabled: []string{"wildcard-verbs"},
+	}, config)
+
+	assert.NoError(t, os.WriteFile(name, []byte("rulez: []\n"), 0o600))
+	_, err = LoadConfig(name)
+	assert.Error(t, err)
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package notify posts CloudEvents of the changes to the Argo CD RBAC policy to HTTP sinks.
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/lint"
+)
+
+var log = logf.Log.WithName("notify")
+
+// Types of the events. The type of the CloudEvent is the type prefixed by TypePrefix.
+const (
+	TypeBindingCreated        = "binding.created"
+	TypeBindingExpired        = "binding.expired"
+	TypeSubjectAdded          = "subject.added"
+	TypeSubjectRemoved        = "subject.removed"
+	TypeRoleRulesChanged      = "role.rules.changed"
+	TypeAppProjectRolePatched = "appproject.role.patched"
+	TypeDriftReverted         = "drift.reverted"
+
+	TypePrefix = "io.argoproj-labs.rbac-operator."
+)
+
+// SignatureHeader is the header holding the HMAC-SHA256 of "<timestamp>.<body>" as "sha256=<hex>", if the sink has
+// a secret. The timestamp is the value of the TimestampHeader.
+const SignatureHeader = "X-RBAC-Operator-Signature"
+
+// TimestampHeader is the header holding the time the event is posted in Unix seconds, signed with the body so the
+// sinks can reject replayed events.
+const TimestampHeader = "X-RBAC-Operator-Timestamp"
+
+const (
+	defaultRetries = 3
+	defaultBackoff = time.Second
+)
+
+// Event is a change to the Argo CD RBAC policy.
+type Event struct {
+	// Type of the change, e.g. TypeSubjectAdded.
+	Type string `json:"type"`
+	// Severity of the change.
+	Severity lint.Severity `json:"severity"`
+	// Time of the change.
+	Time time.Time `json:"time"`
+	// Resource whose reconciliation made the change.
+	Resource Resource `json:"resource"`
+	// Role is the Argo CD role or AppProject role changed or bound.
+	Role string `json:"role,omitempty"`
+	// Subjects added, removed, bound or expired.
+	Subjects []string `json:"subjects,omitempty"`
+	// Target is the ConfigMap or AppProject written, as "<kind> <namespace>/<name>".
+	Target string `json:"target,omitempty"`
+	// Key of the ConfigMap or role of the AppProject written.
+	Key string `json:"key,omitempty"`
+	// Added are the lines added to the key.
+	Added []string `json:"added,omitempty"`
+	// Removed are the lines removed from the key.
+	Removed []string `json:"removed,omitempty"`
+	// Message describing the change.
+	Message string `json:"message"`
+}
+
+// Resource references the resource whose reconciliation made a change.
+type Resource struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// source returns the API path of the resource.
+func (r Resource) source() string {
+	return fmt.Sprintf("/apis/rbac-operator.argoproj-labs.io/v1alpha1/namespaces/%s/%ss/%s", r.Namespace, strings.ToLower(r.Kind), r.Name)
+}
+
+// cloudEvent is the structured mode JSON representation of a CloudEvent carrying an Event.
+type cloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Severity        string    `json:"severity"`
+	Data            Event     `json:"data"`
+}
+
+// Sink is an HTTP endpoint the events matching its filters are posted to.
+type Sink struct {
+	// Name of the sink, used in logs.
+	Name string `json:"name"`
+	// URL the events are posted to.
+	URL string `json:"url"`
+	// SecretFile is the file holding the key the body is signed with in the SignatureHeader. Not signed if empty.
+	SecretFile string `json:"secretFile,omitempty"`
+	// Retries are the number of retries of a failed post, with exponential backoff. Defaults to 3.
+	Retries *int `json:"retries,omitempty"`
+	// Namespaces are glob patterns of the namespaces of the resources whose events are posted.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Roles are glob patterns of the roles whose events are posted.
+	Roles []string `json:"roles,omitempty"`
+	// Types of the events posted.
+	Types []string `json:"types,omitempty"`
+	// MinSeverity is the lowest severity of the events posted.
+	MinSeverity lint.Severity `json:"minSeverity,omitempty"`
+}
+
+// matches returns true if the event passes the filters of the sink. Empty filters match everything.
+func (s Sink) matches(event Event) bool {
+	return matchesAny(s.Namespaces, event.Resource.Namespace) &&
+		matchesAny(s.Roles, event.Role) &&
+		(len(s.Types) == 0 || slices.Contains(s.Types, event.Type)) &&
+		event.Severity.Rank() >= s.MinSeverity.Rank()
+}
+
+func matchesAny(patterns []string, value string) bool {
+	return len(patterns) == 0 || slices.ContainsFunc(patterns, func(pattern string) bool {
+		matched, err := path.Match(pattern, value)
+		return err == nil && matched
+	})
+}
+
+// Config are the sinks events are posted to.
+type Config struct {
+	Sinks []Sink `json:"sinks"`
+}
+
+// LoadConfig reads the configuration from the YAML file.
+func LoadConfig(name string) (Config, error) {
+	config := Config{}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return config, err
+	}
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return config, fmt.Errorf("invalid notification configuration %s: %v", name, err)
+	}
+	return config, nil
+}
+
+// Notifier posts the events to the sinks of the configuration in the background.
+type Notifier struct {
+	sinks   []Sink
+	secrets map[string][]byte
+	client  *http.Client
+	// backoff is the wait before the first retry, doubled for every further retry.
+	backoff time.Duration
+	wg      sync.WaitGroup
+}
+
+// New returns a Notifier posting to the sinks of the configuration.
+func New(config Config) (*Notifier, error) {
+	n := &Notifier{
+		secrets: map[string][]byte{},
+		client:  &http.Client{Timeout: 10 * time.Second},
+		backoff: defaultBackoff,
+	}
+	for _, sink := range config.Sinks {
+		if sink.Name == "" {
+			return nil, fmt.Errorf("notification sink without name")
+		}
+		if !strings.HasPrefix(sink.URL, "http://") && !strings.HasPrefix(sink.URL, "https://") {
+			return nil, fmt.Errorf("notification sink %s has no http(s) URL", sink.Name)
+		}
+		if sink.MinSeverity != "" && sink.MinSeverity.Rank() == 0 {
+			return nil, fmt.Errorf("notification sink %s has unknown severity %q", sink.Name, sink.MinSeverity)
+		}
+		if sink.SecretFile != "" {
+			secret, err := os.ReadFile(sink.SecretFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read secret of notification sink %s: %v", sink.Name, err)
+			}
+			n.secrets[sink.Name] = bytes.TrimSpace(secret)
+		}
+		n.sinks = append(n.sinks, sink)
+	}
+	return n, nil
+}
+
+// Send posts the event to every sink it matches the filters of. The posts are retried in the background,
+// failures are logged.
+func (n *Notifier) Send(event Event) {
+	ce := cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              string(uuid.NewUUID()),
+		Source:          event.Resource.source(),
+		Type:            TypePrefix + event.Type,
+		Subject:         event.Role,
+		Time:            event.Time,
+		DataContentType: "application/json",
+		Severity:        string(event.Severity),
+		Data:            event,
+	}
+	body, err := json.Marshal(ce)
+	if err != nil {
+		log.Error(err, "Failed to encode event", "type", event.Type)
+		return
+	}
+	for _, sink := range n.sinks {
+		if !sink.matches(event) {
+			continue
+		}
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			if err := n.post(sink, body); err != nil {
+				log.Error(err, "Failed to post event", "sink", sink.Name, "type", ce.Type, "id", ce.ID)
+			}
+		}()
+	}
+}
+
+// Wait blocks until the events sent are posted or failed.
+func (n *Notifier) Wait() {
+	n.wg.Wait()
+}
+
+// sign returns the hex HMAC-SHA256 of "<timestamp>.<body>" with the secret.
+func sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// post posts the body to the sink, retrying connection errors, 429 and 5xx responses.
+func (n *Notifier) post(sink Sink, body []byte) error {
+	retries := defaultRetries
+	if sink.Retries != nil {
+		retries = *sink.Retries
+	}
+	backoff := n.backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		if retry, err = n.postOnce(sink, body); err == nil || !retry || attempt >= retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (n *Notifier) postOnce(sink Sink, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, sink.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/cloudevents+json; charset=UTF-8")
+	if secret, ok := n.secrets[sink.Name]; ok {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, "sha256="+sign(secret, timestamp, body))
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		return retry, fmt.Errorf("notification sink %s responded %s", sink.Name, resp.Status)
+	}
+	return false, nil
+}
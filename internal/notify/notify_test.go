@@ -0,0 +1,191 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/lint"
+)
+
+// testServer records the requests posted and responds with the queued status codes, 200 once they are used up.
+type testServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func newTestServer(statuses ...int) *testServer {
+	s := &testServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.bodies = append(s.bodies, body)
+		s.headers = append(s.headers, r.Header)
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	return s
+}
+
+func makeTestEvent() Event {
+	return Event{
+		Type:     TypeSubjectAdded,
+		Severity: lint.SeverityCritical,
+		Time:     time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+		Resource: Resource{Kind: "ArgoCDRoleBinding", Namespace: "team-a", Name: "admins"},
+		Role:     "admin",
+		Subjects: []string{"alice"},
+		Target:   "ConfigMap argocd/argocd-rbac-cm",
+		Key:      "policy.team-a.admin.csv",
+		Added:    []string{"g, alice, role:admin"},
+		Message:  "alice is granted admin",
+	}
+}
+
+func TestNotifier_Send(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	assert.NoError(t, os.WriteFile(secretFile, []byte("s3cr3t\n"), 0o600))
+
+	notifier, err := New(Config{Sinks: []Sink{{Name: "siem", URL: server.URL, SecretFile: secretFile}}})
+	assert.NoError(t, err)
+	notifier.Send(makeTestEvent())
+	notifier.Wait()
+
+	// The event is posted as structured CloudEvent signed with the secret
+	assert.Len(t, server.bodies, 1)
+	var ce cloudEvent
+	assert.NoError(t, json.Unmarshal(server.bodies[0], &ce))
+	assert.Equal(t, "1.0", ce.SpecVersion)
+	assert.NotEmpty(t, ce.ID)
+	assert.Equal(t, "io.argoproj-labs.rbac-operator.subject.added", ce.Type)
+	assert.Equal(t, "/apis/rbac-operator.argoproj-labs.io/v1alpha1/namespaces/team-a/argocdrolebindings/admins", ce.Source)
+	assert.Equal(t, "admin", ce.Subject)
+	assert.Equal(t, "critical", ce.Severity)
+	assert.Equal(t, makeTestEvent(), ce.Data)
+	assert.Equal(t, "application/cloudevents+json; charset=UTF-8", server.headers[0].Get("Content-Type"))
+	timestamp, err := strconv.ParseInt(server.headers[0].Get(TimestampHeader), 10, 64)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), time.Unix(timestamp, 0), time.Minute)
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	mac.Write([]byte(server.headers[0].Get(TimestampHeader) + "."))
+	mac.Write(server.bodies[0])
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), server.headers[0].Get(SignatureHeader))
+}
+
+func TestNotifier_Retry(t *testing.T) {
+	// Server errors and throttling are retried
+	server := newTestServer(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer server.Close()
+	notifier, err := New(Config{Sinks: []Sink{{Name: "chat", URL: server.URL}}})
+	assert.NoError(t, err)
+	notifier.backoff = time.Millisecond
+	notifier.Send(makeTestEvent())
+	notifier.Wait()
+	assert.Len(t, server.bodies, 3)
+	assert.Empty(t, server.headers[0].Get(SignatureHeader))
+	assert.Empty(t, server.headers[0].Get(TimestampHeader))
+
+	// Retries are given up after the configured number of retries
+	retries := 1
+	server = newTestServer(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	defer server.Close()
+	notifier, err = New(Config{Sinks: []Sink{{Name: "chat", URL: server.URL, Retries: &retries}}})
+	assert.NoError(t, err)
+	notifier.backoff = time.Millisecond
+	notifier.Send(makeTestEvent())
+	notifier.Wait()
+	assert.Len(t, server.bodies, 2)
+
+	// Rejected events are not retried
+	server = newTestServer(http.StatusBadRequest)
+	defer server.Close()
+	notifier, err = New(Config{Sinks: []Sink{{Name: "chat", URL: server.URL}}})
+	assert.NoError(t, err)
+	notifier.backoff = time.Millisecond
+	notifier.Send(makeTestEvent())
+	notifier.Wait()
+	assert.Len(t, server.bodies, 1)
+}
+
+func TestNotifier_Filters(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	notifier, err := New(Config{Sinks: []Sink{
+		{Name: "admins", URL: server.URL + "/admins", Roles: []string{"admin"}},
+		{Name: "team-b", URL: server.URL + "/team-b", Namespaces: []string{"team-b*"}},
+		{Name: "critical", URL: server.URL + "/critical", MinSeverity: lint.SeverityCritical},
+		{Name: "drift", URL: server.URL + "/drift", Types: []string{TypeDriftReverted}},
+	}})
+	assert.NoError(t, err)
+
+	event := makeTestEvent()
+	notifier.Send(event)
+	event.Role = "dev"
+	event.Severity = lint.SeverityMedium
+	event.Resource.Namespace = "team-b-dev"
+	notifier.Send(event)
+	notifier.Wait()
+
+	// The admin grant is posted to the admins and critical sinks, the dev grant to the team-b sink
+	types := map[string]int{}
+	for _, body := range server.bodies {
+		var ce cloudEvent
+		assert.NoError(t, json.Unmarshal(body, &ce))
+		types[ce.Data.Role]++
+	}
+	assert.Equal(t, map[string]int{"admin": 2, "dev": 1}, types)
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	for _, sink := range []Sink{
+		{URL: "https://siem.example.com"},
+		{Name: "siem", URL: "siem.example.com"},
+		{Name: "siem", URL: "https://siem.example.com", MinSeverity: "severe"},
+		{Name: "siem", URL: "https://siem.example.com", SecretFile: filepath.Join(t.TempDir(), "missing")},
+	} {
+		_, err := New(Config{Sinks: []Sink{sink}})
+		assert.Error(t, err, sink)
+	}
+
+	name := filepath.Join(t.TempDir(), "notifications.yaml")
+	assert.NoError(t, os.WriteFile(name, []byte("sinks:\n- name: siem\n  url: https://siem.example.com\n  severity: high\n"), 0o600))
+	_, err := LoadConfig(name)
+	assert.Error(t, err)
+}
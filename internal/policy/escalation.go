@@ -0,0 +1,130 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"encoding/csv"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/argoproj/argo-cd/v3/util/assets"
+	"github.com/argoproj/argo-cd/v3/util/rbac"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+// SubjectMapping maps Kubernetes users and groups to the Argo CD subjects they act as.
+type SubjectMapping struct {
+	// UserPrefix is removed from Kubernetes user names. Users without the prefix are not mapped.
+	UserPrefix string
+	// GroupPrefix is removed from Kubernetes group names. Groups without the prefix are not mapped.
+	GroupPrefix string
+}
+
+// Subjects returns the Argo CD subjects of the Kubernetes user and its groups.
+func (m SubjectMapping) Subjects(user string, groups []string) []string {
+	subjects := []string{}
+	if name, ok := strings.CutPrefix(user, m.UserPrefix); ok && name != "" {
+		subjects = append(subjects, name)
+	}
+	for _, group := range groups {
+		if name, ok := strings.CutPrefix(group, m.GroupPrefix); ok && name != "" {
+			subjects = append(subjects, name)
+		}
+	}
+	return subjects
+}
+
+// NewArgoCDEnforcer returns an Argo CD enforcer of the built-in policy and the policy in the data of the Argo CD RBAC ConfigMap.
+func NewArgoCDEnforcer(data map[string]string) (*rbac.Enforcer, error) {
+	enforcer := rbac.NewEnforcer(nil, "", "", nil)
+	if err := enforcer.SetBuiltinPolicy(assets.BuiltinPolicyCSV); err != nil {
+		return nil, err
+	}
+	if err := enforcer.SetUserPolicy(rbac.PolicyCSV(data)); err != nil {
+		return nil, err
+	}
+	enforcer.SetDefaultRole(data[rbac.ConfigMapPolicyDefaultKey])
+	enforcer.SetMatchMode(data[rbac.ConfigMapMatchModeKey])
+	return enforcer, nil
+}
+
+// EscalatingRules returns the violations of the permissions granted by the rules that none of the subjects holds.
+// Subjects without any mapping still hold the default role of the policy. Rules splitting their policy line are
+// refused without asking the enforcer, its globs also match the lines they would add.
+func EscalatingRules(enforcer *rbac.Enforcer, subjects []string, rules []rbacoperatorv1alpha1.GlobalRule) []string {
+	if len(subjects) == 0 {
+		subjects = []string{""}
+	}
+	if violations := SplittingRules(rules); len(violations) > 0 {
+		return violations
+	}
+	violations := []string{}
+	for _, rule := range rules {
+		for _, verb := range rule.Verbs {
+			for _, object := range rule.Objects {
+				if !slices.ContainsFunc(subjects, func(subject string) bool { return enforcer.Enforce(subject, rule.Resource, verb, object) }) {
+					violations = append(violations, fmt.Sprintf("%s %s %s is not held by the requester", rule.Resource, verb, object))
+				}
+			}
+		}
+	}
+	return violations
+}
+
+// SplittingRules returns the violations of the rules with a resource, verb or object splitting their policy line.
+func SplittingRules(rules []rbacoperatorv1alpha1.GlobalRule) []string {
+	violations := []string{}
+	for _, rule := range rules {
+		for _, verb := range rule.Verbs {
+			for _, object := range rule.Objects {
+				if splitsPolicyLine(rule.Resource) || splitsPolicyLine(verb) || splitsPolicyLine(object) {
+					violations = append(violations, fmt.Sprintf("%q %q %q must not contain commas or line breaks", rule.Resource, verb, object))
+				}
+			}
+		}
+	}
+	return violations
+}
+
+// BuiltInRoleRules returns the rules of the built-in Argo CD role, including the ones of the roles it inherits.
+func BuiltInRoleRules(roleName string) ([]rbacoperatorv1alpha1.GlobalRule, error) {
+	reader := csv.NewReader(strings.NewReader(assets.BuiltinPolicyCSV))
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	lines, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	roles := []string{"role:" + roleName}
+	rules := []rbacoperatorv1alpha1.GlobalRule{}
+	for i := 0; i < len(roles); i++ {
+		for _, line := range lines {
+			for j := range line {
+				line[j] = strings.TrimSpace(line[j])
+			}
+			switch {
+			case len(line) == 3 && line[0] == "g" && line[1] == roles[i] && !slices.Contains(roles, line[2]):
+				roles = append(roles, line[2])
+			case len(line) == 6 && line[0] == "p" && line[1] == roles[i] && line[5] == "allow":
+				rules = append(rules, rbacoperatorv1alpha1.GlobalRule{Resource: line[2], Verbs: []string{line[3]}, Objects: []string{line[4]}})
+			}
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+func TestSubjectMapping_Subjects(t *testing.T) {
+	mapping := SubjectMapping{UserPrefix: "oidc:", GroupPrefix: "oidc:"}
+	assert.Equal(t, []string{"alice", "team-a"}, mapping.Subjects("oidc:alice", []string{"oidc:team-a", "system:authenticated"}))
+	assert.Empty(t, mapping.Subjects("system:serviceaccount:default:ci", nil))
+
+	assert.Equal(t, []string{"alice", "team-a"}, SubjectMapping{}.Subjects("alice", []string{"team-a"}))
+}
+
+func TestEscalatingRules(t *testing.T) {
+	enforcer, err := NewArgoCDEnforcer(map[string]string{
+		"policy.csv": "p, role:team-a, applications, *, team-a/*, allow\n" +
+			"g, team-a, role:team-a\n",
+		"policy.default": "role:readonly",
+	})
+	assert.NoError(t, err)
+
+	rules := []rbacoperatorv1alpha1.GlobalRule{
+		{Resource: "applications", Verbs: []string{"get", "sync"}, Objects: []string{"team-a/*"}},
+	}
+	assert.Empty(t, EscalatingRules(enforcer, []string{"alice", "team-a"}, rules))
+	assert.Equal(t, []string{"applications sync team-a/* is not held by the requester"}, EscalatingRules(enforcer, []string{"bob"}, rules))
+	// The default role applies to requesters without subjects
+	assert.Equal(t, []string{"applications sync team-a/* is not held by the requester"}, EscalatingRules(enforcer, nil, rules))
+
+	// Wildcards are only held through wildcards
+	rules = []rbacoperatorv1alpha1.GlobalRule{
+		{Resource: "applications", Verbs: []string{"sync"}, Objects: []string{"*/*"}},
+	}
+	assert.Equal(t, []string{"applications sync */* is not held by the requester"}, EscalatingRules(enforcer, []string{"team-a"}, rules))
+}
+
+func TestEscalatingRules_PolicyLineInjection(t *testing.T) {
+	enforcer, err := NewArgoCDEnforcer(map[string]string{
+		"policy.csv": "p, role:team-a, applications, get, team-a-*, allow\n" +
+			"g, team-a, role:team-a\n",
+	})
+	assert.NoError(t, err)
+
+	// The glob of the held object also matches the embedded g line granting role:admin
+	object := "team-a-x, allow\ng, team-a, role:admin\np, team-a-y"
+	assert.True(t, enforcer.Enforce("team-a", "applications", "get", object))
+
+	rules := []rbacoperatorv1alpha1.GlobalRule{
+		{Resource: "applications", Verbs: []string{"get"}, Objects: []string{object}},
+	}
+	assert.Equal(t, []string{
+		`"applications" "get" "team-a-x, allow\ng, team-a, role:admin\np, team-a-y" must not contain commas or line breaks`,
+	}, EscalatingRules(enforcer, []string{"team-a"}, rules))
+}
+
+func TestBuiltInRoleRules(t *testing.T) {
+	readOnly, err := BuiltInRoleRules("readonly")
+	assert.NoError(t, err)
+	assert.Contains(t, readOnly, rbacoperatorv1alpha1.GlobalRule{Resource: "applications", Verbs: []string{"get"}, Objects: []string{"*/*"}})
+
+	admin, err := BuiltInRoleRules("admin")
+	assert.NoError(t, err)
+	assert.Contains(t, admin, rbacoperatorv1alpha1.GlobalRule{Resource: "applications", Verbs: []string{"sync"}, Objects: []string{"*/*"}})
+	// Inherited from role:readonly
+	assert.Subset(t, admin, readOnly)
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/argoproj/argo-cd/v3/util/glob"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+// Step is a line of the chain from a subject to a rule, and the resource it was rendered from.
+type Step struct {
+	Line   string                                `json:"line"`
+	Source rbacoperatorv1alpha1.PermissionSource `json:"source"`
+}
+
+// Match is a rule matching a request, and the chain of lines granting it to the subject.
+type Match struct {
+	Subject string `json:"subject"`
+	Effect  string `json:"effect"`
+	// Chain are the g-lines from the subject to the role holding the rule, followed by the p-line of the rule.
+	Chain []Step `json:"chain"`
+}
+
+// Decision is the decision of the policy on a request, and the rules matching it.
+type Decision struct {
+	Subjects []string `json:"subjects"`
+	Resource string   `json:"resource"`
+	Action   string   `json:"action"`
+	Object   string   `json:"object"`
+	Allowed  bool     `json:"allowed"`
+	// Decisive is the index of the match deciding the request: the first deny, or else the first allow.
+	// -1 if no rule matches, the request is denied by default.
+	Decisive int     `json:"decisive"`
+	Matches  []Match `json:"matches"`
+}
+
+// Explain decides the request of the subjects, a user and its groups, the way Argo CD does: the request is allowed
+// if a rule of a subject or of a role it holds matches it and no matching rule denies it. Resource, action and
+// object of the rules are glob patterns.
+func (p *Policy) Explain(subjects []string, resource, action, object string) Decision {
+	decision := Decision{Subjects: subjects, Resource: resource, Action: action, Object: object, Decisive: -1, Matches: []Match{}}
+	for _, subject := range subjects {
+		permissions := p.Permissions(subject)
+		grants := append(append([]rbacoperatorv1alpha1.RoleGrant{}, permissions.Roles...), permissions.ProjectRoles...)
+		for _, rule := range permissions.Rules {
+			if !glob.Match(rule.Resource, resource) || !glob.Match(rule.Action, action) || !glob.Match(rule.Object, object) {
+				continue
+			}
+			holder := subject
+			if rule.Role != "" {
+				holder = rule.Role
+			}
+			chain := append(p.grantChain(subject, rule.Role, grants), Step{
+				Line:   fmt.Sprintf("p, %s, %s, %s, %s, %s", holder, rule.Resource, rule.Action, rule.Object, rule.Effect),
+				Source: rule.Source,
+			})
+			decision.Matches = append(decision.Matches, Match{Subject: subject, Effect: rule.Effect, Chain: chain})
+		}
+	}
+
+	for i, match := range decision.Matches {
+		if match.Effect == "deny" {
+			decision.Decisive = i
+			return decision
+		}
+		if decision.Decisive == -1 {
+			decision.Decisive = i
+		}
+	}
+	decision.Allowed = decision.Decisive != -1
+	return decision
+}
+
+// grantChain returns the g-lines granting the role to the subject, shortest first since the grants are in breadth
+// first order. Empty if the role is empty, the rule being the subject's own.
+func (p *Policy) grantChain(subject, role string, grants []rbacoperatorv1alpha1.RoleGrant) []Step {
+	chain := []Step{}
+	for role != "" && len(chain) <= len(grants) {
+		var grant *rbacoperatorv1alpha1.RoleGrant
+		for i := range grants {
+			if grants[i].Role == role {
+				grant = &grants[i]
+				break
+			}
+		}
+		if grant == nil {
+			break
+		}
+		holder := grant.Via
+		if holder == "" {
+			holder = subject
+		}
+		line := fmt.Sprintf("g, %s, %s", holder, grant.Role)
+		if grant.Via == "" && grant.Role == p.DefaultRole && grant.Source == p.DefaultRoleSource {
+			line = "policy.default: " + grant.Role
+		}
+		chain = append([]Step{{Line: line, Source: grant.Source}}, chain...)
+		role = grant.Via
+	}
+	return chain
+}
+
+// String returns the decision and the chain of the decisive rule, one line per step.
+func (d Decision) String() string {
+	verdict := "denied"
+	if d.Allowed {
+		verdict = "allowed"
+	}
+	s := fmt.Sprintf("%s: %s %s %s %s\n", verdict, strings.Join(d.Subjects, ","), d.Resource, d.Action, d.Object)
+	if d.Decisive == -1 {
+		return s + "  no rule matches\n"
+	}
+	for _, step := range d.Matches[d.Decisive].Chain {
+		s += fmt.Sprintf("  %s\t(%s)\n", step.Line, step.Source)
+	}
+	return s
+}
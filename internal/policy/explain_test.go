@@ -0,0 +1,67 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+func TestPolicy_Explain(t *testing.T) {
+	role := rbacoperatorv1alpha1.PermissionSource{Kind: "ArgoCDRole", Namespace: "team-a", Name: "dev", Field: "spec.rules[1]"}
+	binding := rbacoperatorv1alpha1.PermissionSource{Kind: "ArgoCDRoleBinding", Namespace: "team-a", Name: "dev-binding", Field: "spec.subjects[0]"}
+	viewer := rbacoperatorv1alpha1.PermissionSource{Kind: "ArgoCDRoleBinding", Namespace: "team-a", Name: "viewer-binding", Field: "spec.subjects[0]"}
+	p := Policy{}
+	p.Lines = append(p.Lines, ParseLines("p, role:viewer, applications, get, */*, allow\np, role:dev, applications, sync, team-a/*, allow\np, role:dev, applications, sync, team-a/prod-*, deny\n", role)...)
+	p.Lines = append(p.Lines, ParseLines("g, team-a, role:dev\n", binding)...)
+	p.Lines = append(p.Lines, ParseLines("g, role:dev, role:viewer\n", viewer)...)
+
+	// The chain leads from the group through the roles to the rule
+	decision := p.Explain([]string{"alice", "team-a"}, "applications", "get", "team-a/guestbook")
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, []Step{
+		{Line: "g, team-a, role:dev", Source: binding},
+		{Line: "g, role:dev, role:viewer", Source: viewer},
+		{Line: "p, role:viewer, applications, get, */*, allow", Source: role},
+	}, decision.Matches[decision.Decisive].Chain)
+	assert.Equal(t, "team-a", decision.Matches[decision.Decisive].Subject)
+
+	// A matching deny rule decides
+	decision = p.Explain([]string{"team-a"}, "applications", "sync", "team-a/prod-web")
+	assert.False(t, decision.Allowed)
+	assert.Len(t, decision.Matches, 2)
+	assert.Equal(t, "deny", decision.Matches[decision.Decisive].Effect)
+
+	// Denied by default
+	decision = p.Explain([]string{"bob"}, "applications", "get", "team-a/guestbook")
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, -1, decision.Decisive)
+	assert.Equal(t, "denied: bob applications get team-a/guestbook\n  no rule matches\n", decision.String())
+}
+
+func TestPolicy_ExplainDefaultRole(t *testing.T) {
+	defaultRole := rbacoperatorv1alpha1.PermissionSource{Kind: "ConfigMap", Namespace: "argocd", Name: "argocd-rbac-cm", Key: "policy.default"}
+	p := Policy{Lines: BuiltinLines(), DefaultRole: "role:readonly", DefaultRoleSource: defaultRole}
+
+	decision := p.Explain([]string{"bob"}, "applications", "get", "web/guestbook")
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, "policy.default: role:readonly", decision.Matches[decision.Decisive].Chain[0].Line)
+	assert.Equal(t, "BuiltinPolicy", decision.Matches[decision.Decisive].Chain[1].Source.Kind)
+}
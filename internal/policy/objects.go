@@ -0,0 +1,206 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/argoproj/argo-cd/v3/util/glob"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+// applicationObjectResources are the resources whose objects are applications or applicationsets.
+var applicationObjectResources = []string{"applications", "applicationsets", "logs", "exec"}
+
+// ObjectFormat is the format Argo CD expects the objects of applications in: "<project>/<name>" for applications in
+// the namespace of Argo CD, and "<project>/<namespace>/<name>" for applications in any other namespace.
+type ObjectFormat struct {
+	// Namespace is the namespace of Argo CD.
+	Namespace string
+	// ApplicationNamespaces are the other namespaces applications may be created in, as globs or /regexps/.
+	// Applications in any namespace are disabled if empty.
+	ApplicationNamespaces []string
+}
+
+// LoadObjectFormat returns the object format of the Argo CD in the namespace, from the application namespaces of its
+// parameters ConfigMap. Applications in any namespace are disabled if the ConfigMap does not exist.
+func LoadObjectFormat(ctx context.Context, c client.Reader, namespace string) (ObjectFormat, error) {
+	format := ObjectFormat{Namespace: namespace}
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, client.ObjectKey{Name: common.ArgoCDCmdParamsConfigMapName, Namespace: namespace}, cm); err != nil {
+		return format, client.IgnoreNotFound(err)
+	}
+	for _, pattern := range strings.Split(cm.Data[common.ArgoCDKeyApplicationNamespaces], ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			format.ApplicationNamespaces = append(format.ApplicationNamespaces, pattern)
+		}
+	}
+	return format, nil
+}
+
+// GlobalRules returns the rules of an ArgoCDRole with their application objects rendered into their objects, and
+// the errors of the application objects that can't be rendered. Those are left out.
+func (f ObjectFormat) GlobalRules(rules []rbacoperatorv1alpha1.GlobalRule) ([]rbacoperatorv1alpha1.GlobalRule, field.ErrorList) {
+	rendered := []rbacoperatorv1alpha1.GlobalRule{}
+	errs := field.ErrorList{}
+	for i, rule := range rules {
+		if len(rule.ApplicationObjects) == 0 {
+			rendered = append(rendered, rule)
+			continue
+		}
+		path := field.NewPath("spec", "rules").Index(i).Child("applicationObjects")
+		if !slices.Contains(applicationObjectResources, rule.Resource) {
+			errs = append(errs, field.Forbidden(path, fmt.Sprintf("only allowed for %s", strings.Join(applicationObjectResources, ", "))))
+			continue
+		}
+		objects := slices.Clone(rule.Objects)
+		for j, object := range rule.ApplicationObjects {
+			strs, err := f.Objects(path.Index(j), object)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			objects = append(objects, strs...)
+		}
+		rendered = append(rendered, rbacoperatorv1alpha1.GlobalRule{Resource: rule.Resource, Verbs: rule.Verbs, Objects: objects})
+	}
+	return rendered, errs
+}
+
+// Objects returns the objects the application object is rendered to. An application object whose namespace glob
+// matches both the namespace of Argo CD and other namespaces is rendered to both forms.
+func (f ObjectFormat) Objects(path *field.Path, object rbacoperatorv1alpha1.ApplicationObject) ([]string, *field.Error) {
+	for _, part := range []struct{ name, value string }{
+		{"project", object.Project}, {"namespace", object.Namespace}, {"name", object.Name},
+	} {
+		if strings.Contains(part.value, "/") {
+			return nil, field.Invalid(path.Child(part.name), part.value, "must not contain /")
+		}
+	}
+	if object.Project == "" {
+		return nil, field.Required(path.Child("project"), "")
+	}
+	if object.Name == "" {
+		return nil, field.Required(path.Child("name"), "")
+	}
+
+	local, err := f.localObject(path, object)
+	if object.Namespace == "" || object.Namespace == f.Namespace {
+		if err != nil {
+			return nil, err
+		}
+		return []string{local}, nil
+	}
+	namespaced := fmt.Sprintf("%s/%s/%s", object.Project, object.Namespace, object.Name)
+	if !strings.ContainsAny(object.Namespace, "*?[") {
+		if !glob.MatchStringInList(f.ApplicationNamespaces, object.Namespace, glob.REGEXP) {
+			return nil, field.Invalid(path.Child("namespace"), object.Namespace, f.namespaceMessage("is"))
+		}
+		return []string{namespaced}, nil
+	}
+
+	objects := []string{}
+	if glob.Match(object.Namespace, f.Namespace) {
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, local)
+	}
+	if f.matchesApplicationNamespaces(object.Namespace) {
+		objects = append(objects, namespaced)
+	}
+	if len(objects) == 0 {
+		return nil, field.Invalid(path.Child("namespace"), object.Namespace, f.namespaceMessage("matches"))
+	}
+	return objects, nil
+}
+
+// localObject returns the object of applications in the namespace of Argo CD, "<project>/<name>". Argo CD matches
+// objects with globs whose wildcards also match /, so with applications in any namespace "p/*" also matches the objects
+// "p/<namespace>/<name>" of the applications in the other namespaces. ? is rendered as [!/] and / is added to the
+// negated character classes then, and * is invalid unless the application object is meant for all namespaces anyway.
+func (f ObjectFormat) localObject(path *field.Path, object rbacoperatorv1alpha1.ApplicationObject) (string, *field.Error) {
+	if len(f.ApplicationNamespaces) == 0 || object.Namespace == "*" {
+		return fmt.Sprintf("%s/%s", object.Project, object.Name), nil
+	}
+	parts := []string{}
+	for _, part := range []struct{ name, value string }{{"project", object.Project}, {"name", object.Name}} {
+		if strings.Contains(part.value, "*") {
+			return "", field.Invalid(path.Child(part.name), part.value, fmt.Sprintf("must not contain * for applications in "+
+				"the namespace of Argo CD (%s) with applications in any namespace enabled, it would also match the applications "+
+				"of the other namespaces. Set the namespace to * for applications in all namespaces", f.Namespace))
+		}
+		parts = append(parts, localGlob(part.value))
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+// localGlob returns the glob with its ? and negated character classes restricted to characters other than /.
+func localGlob(pattern string) string {
+	b := strings.Builder{}
+	class, negated, escaped := false, false, false
+	for i, r := range pattern {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case class && r == ']':
+			if negated {
+				b.WriteRune('/')
+			}
+			class = false
+		case class:
+		case r == '[':
+			class, negated = true, strings.HasPrefix(pattern[i:], "[!")
+		case r == '?':
+			b.WriteString("[!/]")
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// matchesApplicationNamespaces returns true if the namespace glob may match one of the application namespaces: it
+// matches the glob of an application namespace, or the glob or /regexp/ of an application namespace matches it.
+func (f ObjectFormat) matchesApplicationNamespaces(namespace string) bool {
+	for _, pattern := range f.ApplicationNamespaces {
+		if glob.Match(namespace, pattern) || glob.MatchStringInList([]string{pattern}, namespace, glob.REGEXP) {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceMessage returns the message of a namespace that is neither the namespace of Argo CD nor an application namespace.
+func (f ObjectFormat) namespaceMessage(verb string) string {
+	namespaces := "none"
+	if len(f.ApplicationNamespaces) > 0 {
+		namespaces = strings.Join(f.ApplicationNamespaces, ",")
+	}
+	return fmt.Sprintf("%s neither the namespace of Argo CD (%s) nor one of the namespaces of %s in %s (%s)",
+		verb, f.Namespace, common.ArgoCDKeyApplicationNamespaces, common.ArgoCDCmdParamsConfigMapName, namespaces)
+}
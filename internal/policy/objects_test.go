@@ -0,0 +1,117 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+func TestLoadObjectFormat(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	// Applications in any namespace are disabled without parameters
+	format, err := LoadObjectFormat(context.TODO(), c, "argocd")
+	assert.NoError(t, err)
+	assert.Equal(t, ObjectFormat{Namespace: "argocd"}, format)
+
+	assert.NoError(t, c.Create(context.TODO(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "argocd-cmd-params-cm", Namespace: "argocd"},
+		Data:       map[string]string{"application.namespaces": "team-a-*, /^team-b-(dev|prod)$/"},
+	}))
+	format, err = LoadObjectFormat(context.TODO(), c, "argocd")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team-a-*", "/^team-b-(dev|prod)$/"}, format.ApplicationNamespaces)
+}
+
+func TestObjectFormat_GlobalRules(t *testing.T) {
+	format := ObjectFormat{Namespace: "argocd", ApplicationNamespaces: []string{"team-a-*", "/^team-b-(dev|prod)$/"}}
+	rules := []rbacoperatorv1alpha1.GlobalRule{
+		{Resource: "clusters", Verbs: []string{"get"}, Objects: []string{"*"}},
+		{Resource: "applications", Verbs: []string{"get"}, Objects: []string{"default/*"}, ApplicationObjects: []rbacoperatorv1alpha1.ApplicationObject{
+			{Project: "team-a", Name: "guestbook-?"},
+			{Project: "team-a", Namespace: "argocd", Name: "guestbook"},
+			{Project: "team-a", Namespace: "team-a-apps", Name: "guestbook-*"},
+			{Project: "team-b", Namespace: "team-b-prod", Name: "*"},
+			{Project: "*", Namespace: "*", Name: "*"},
+		}},
+	}
+	rendered, errs := format.GlobalRules(rules)
+	assert.Empty(t, errs)
+	assert.Equal(t, []rbacoperatorv1alpha1.GlobalRule{
+		rules[0],
+		{Resource: "applications", Verbs: []string{"get"}, Objects: []string{
+			"default/*", "team-a/guestbook-[!/]", "team-a/guestbook", "team-a/team-a-apps/guestbook-*", "team-b/team-b-prod/*", "*/*", "*/*/*",
+		}},
+	}, rendered)
+	// The rules are not changed
+	assert.Equal(t, []string{"default/*"}, rules[1].Objects)
+
+	// Without applications in any namespace only the namespace of Argo CD can be matched
+	rendered, errs = ObjectFormat{Namespace: "argocd"}.GlobalRules(rules)
+	assert.Equal(t, []string{"default/*", "team-a/guestbook-?", "team-a/guestbook", "*/*"}, rendered[1].Objects)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "spec.rules[1].applicationObjects[2].namespace", errs[0].Field)
+	assert.Contains(t, errs[0].Error(), "is neither the namespace of Argo CD (argocd) nor one of the namespaces of application.namespaces in argocd-cmd-params-cm (none)")
+	assert.Equal(t, "spec.rules[1].applicationObjects[3].namespace", errs[1].Field)
+
+	// Namespace globs are rendered with namespace if they may match an application namespace, and are invalid if they
+	// match neither
+	objects, err := format.Objects(nil, rbacoperatorv1alpha1.ApplicationObject{Project: "p", Namespace: "team-*", Name: "a"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"p/team-*/a"}, objects)
+	objects, err = format.Objects(nil, rbacoperatorv1alpha1.ApplicationObject{Project: "p", Namespace: "team-a-dev*", Name: "a"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"p/team-a-dev*/a"}, objects)
+	_, err = format.Objects(nil, rbacoperatorv1alpha1.ApplicationObject{Project: "p", Namespace: "team-c-*", Name: "a"})
+	assert.ErrorContains(t, err, "matches neither the namespace of Argo CD")
+	_, err = ObjectFormat{Namespace: "argocd"}.Objects(nil, rbacoperatorv1alpha1.ApplicationObject{Project: "p", Namespace: "team-*", Name: "a"})
+	assert.ErrorContains(t, err, "matches neither the namespace of Argo CD")
+
+	// With applications in any namespace, objects of the namespace of Argo CD don't match the other namespaces
+	objects, err = format.Objects(nil, rbacoperatorv1alpha1.ApplicationObject{Project: "p?", Name: "[!a]pp"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"p[!/]/[!a/]pp"}, objects)
+	_, err = format.Objects(field.NewPath("object"), rbacoperatorv1alpha1.ApplicationObject{Project: "p", Name: "*"})
+	assert.ErrorContains(t, err, "object.name: Invalid value: \"*\": must not contain * for applications in the namespace of Argo CD (argocd)")
+	_, err = format.Objects(field.NewPath("object"), rbacoperatorv1alpha1.ApplicationObject{Project: "*", Namespace: "argo*", Name: "a"})
+	assert.ErrorContains(t, err, "object.project: Invalid value")
+	objects, err = ObjectFormat{Namespace: "argocd"}.Objects(nil, rbacoperatorv1alpha1.ApplicationObject{Project: "p", Name: "*"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"p/*"}, objects)
+
+	// Fields are single path segments, and only applications and their logs and exec have application objects
+	_, errs = format.GlobalRules([]rbacoperatorv1alpha1.GlobalRule{
+		{Resource: "logs", Verbs: []string{"get"}, ApplicationObjects: []rbacoperatorv1alpha1.ApplicationObject{{Project: "team-a/team-a-apps", Name: "*"}}},
+		{Resource: "clusters", Verbs: []string{"get"}, ApplicationObjects: []rbacoperatorv1alpha1.ApplicationObject{{Project: "*", Name: "*"}}},
+	})
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "spec.rules[0].applicationObjects[0].project", errs[0].Field)
+	assert.Equal(t, "spec.rules[1].applicationObjects", errs[1].Field)
+}
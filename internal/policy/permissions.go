@@ -0,0 +1,180 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"encoding/csv"
+	"slices"
+	"strings"
+
+	"github.com/argoproj/argo-cd/v3/util/assets"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+// Line is a line of the Argo CD RBAC policy and the resource it was rendered from.
+type Line struct {
+	// Fields of the line, e.g. ["g", "alice", "role:admin"].
+	Fields []string
+	Source rbacoperatorv1alpha1.PermissionSource
+}
+
+// IsGrant returns true if the line grants the role in Fields[2] to the subject or role in Fields[1].
+func (l Line) IsGrant() bool {
+	return len(l.Fields) == 3 && l.Fields[0] == "g"
+}
+
+// IsRule returns true if the line allows or denies an action to the subject or role in Fields[1].
+func (l Line) IsRule() bool {
+	return len(l.Fields) >= 5 && l.Fields[0] == "p"
+}
+
+// Effect returns the effect of a rule, allow if not set.
+func (l Line) Effect() string {
+	if len(l.Fields) > 5 && l.Fields[5] != "" {
+		return l.Fields[5]
+	}
+	return "allow"
+}
+
+// ParseLines returns the lines of the policy CSV rendered from the source. Comments and invalid lines are skipped.
+func ParseLines(policyCSV string, source rbacoperatorv1alpha1.PermissionSource) []Line {
+	reader := csv.NewReader(strings.NewReader(policyCSV))
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	lines := []Line{}
+	for {
+		fields, err := reader.Read()
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); ok {
+				continue
+			}
+			return lines
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		line := Line{Fields: fields, Source: source}
+		if line.IsGrant() || line.IsRule() {
+			lines = append(lines, line)
+		}
+	}
+}
+
+// BuiltinLines returns the lines of the built-in policy of Argo CD.
+func BuiltinLines() []Line {
+	return ParseLines(assets.BuiltinPolicyCSV, rbacoperatorv1alpha1.PermissionSource{Kind: "BuiltinPolicy"})
+}
+
+// Policy is an Argo CD RBAC policy whose lines are attributed to the resources they were rendered from.
+type Policy struct {
+	Lines []Line
+	// DefaultRole is the role every subject holds. No role if empty.
+	DefaultRole string
+	// DefaultRoleSource is the resource the default role is read from.
+	DefaultRoleSource rbacoperatorv1alpha1.PermissionSource
+}
+
+// isRole returns true if the subject of a line is a global or project role rather than a user, group or account.
+func isRole(subject string) bool {
+	return strings.HasPrefix(subject, "role:") || strings.HasPrefix(subject, "proj:")
+}
+
+// Subjects returns the users, groups and accounts granted a role or rule by the policy, sorted.
+func (p *Policy) Subjects() []string {
+	subjects := []string{}
+	for _, line := range p.Lines {
+		if subject := line.Fields[1]; !isRole(subject) && !slices.Contains(subjects, subject) {
+			subjects = append(subjects, subject)
+		}
+	}
+	slices.Sort(subjects)
+	return subjects
+}
+
+// SubjectPermissions returns the effective permissions of every subject of the policy, sorted by subject.
+func (p *Policy) SubjectPermissions() []rbacoperatorv1alpha1.ArgoCDSubjectPermissionsSpec {
+	permissions := []rbacoperatorv1alpha1.ArgoCDSubjectPermissionsSpec{}
+	for _, subject := range p.Subjects() {
+		permissions = append(permissions, p.Permissions(subject))
+	}
+	return permissions
+}
+
+// Permissions returns the roles held by the subject through transitive grants, and the rules of the subject
+// and of every role it holds.
+func (p *Policy) Permissions(subject string) rbacoperatorv1alpha1.ArgoCDSubjectPermissionsSpec {
+	permissions := rbacoperatorv1alpha1.ArgoCDSubjectPermissionsSpec{Subject: subject}
+	grants := []rbacoperatorv1alpha1.RoleGrant{}
+	for _, line := range p.Lines {
+		if line.IsGrant() && line.Fields[1] == subject {
+			grants = append(grants, rbacoperatorv1alpha1.RoleGrant{Role: line.Fields[2], Source: line.Source})
+		}
+	}
+	if p.DefaultRole != "" {
+		grants = append(grants, rbacoperatorv1alpha1.RoleGrant{Role: p.DefaultRole, Source: p.DefaultRoleSource})
+	}
+
+	// Breadth first, every role is expanded once even if it is granted through several paths
+	expanded := []string{}
+	for i := 0; i < len(grants); i++ {
+		role := grants[i].Role
+		if slices.Contains(expanded, role) {
+			continue
+		}
+		expanded = append(expanded, role)
+		for _, line := range p.Lines {
+			if !line.IsGrant() || line.Fields[1] != role {
+				continue
+			}
+			grant := rbacoperatorv1alpha1.RoleGrant{Role: line.Fields[2], Via: role, Source: line.Source}
+			if !slices.Contains(grants, grant) {
+				grants = append(grants, grant)
+			}
+		}
+	}
+	for _, grant := range grants {
+		if strings.HasPrefix(grant.Role, "proj:") {
+			permissions.ProjectRoles = append(permissions.ProjectRoles, grant)
+		} else {
+			permissions.Roles = append(permissions.Roles, grant)
+		}
+	}
+
+	for _, holder := range append([]string{subject}, expanded...) {
+		for _, line := range p.Lines {
+			if !line.IsRule() || line.Fields[1] != holder {
+				continue
+			}
+			rule := rbacoperatorv1alpha1.PermissionRule{
+				Resource: line.Fields[2],
+				Action:   line.Fields[3],
+				Object:   line.Fields[4],
+				Effect:   line.Effect(),
+				Source:   line.Source,
+			}
+			if holder != subject {
+				rule.Role = holder
+			}
+			if !slices.Contains(permissions.Rules, rule) {
+				permissions.Rules = append(permissions.Rules, rule)
+			}
+		}
+	}
+	return permissions
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+func TestParseLines(t *testing.T) {
+	source := rbacoperatorv1alpha1.PermissionSource{Kind: "ConfigMap", Namespace: "argocd", Name: "argocd-rbac-cm", Key: "policy.csv"}
+	lines := ParseLines("# comment\np, role:dev, applications, get, */*\n\ng,alice, role:dev\nx, invalid\n", source)
+	assert.Equal(t, []Line{
+		{Fields: []string{"p", "role:dev", "applications", "get", "*/*"}, Source: source},
+		{Fields: []string{"g", "alice", "role:dev"}, Source: source},
+	}, lines)
+	assert.Equal(t, "allow", lines[0].Effect())
+	assert.Equal(t, "ConfigMap argocd/argocd-rbac-cm policy.csv", source.String())
+}
+
+func TestPolicy_SubjectPermissions(t *testing.T) {
+	role := rbacoperatorv1alpha1.PermissionSource{Kind: "ArgoCDRole", Namespace: "team-a", Name: "dev"}
+	binding := rbacoperatorv1alpha1.PermissionSource{Kind: "ArgoCDRoleBinding", Namespace: "team-a", Name: "dev-binding"}
+	projectRole := rbacoperatorv1alpha1.PermissionSource{Kind: "ArgoCDProjectRole", Namespace: "team-a", Name: "deployer"}
+	projectBinding := rbacoperatorv1alpha1.PermissionSource{Kind: "ArgoCDProjectRoleBinding", Namespace: "team-a", Name: "deployers"}
+	defaultRole := rbacoperatorv1alpha1.PermissionSource{Kind: "ConfigMap", Namespace: "argocd", Name: "argocd-rbac-cm", Key: "policy.default"}
+
+	p := Policy{DefaultRole: "role:guest", DefaultRoleSource: defaultRole}
+	p.Lines = append(p.Lines, ParseLines("p, role:dev, applications, sync, team-a/*, allow\np, role:dev, logs, get, team-a/*, deny\n", role)...)
+	p.Lines = append(p.Lines, ParseLines("g, team-a, role:dev\ng, role:dev, role:viewer\np, ci, applications, get, */*, allow\n", binding)...)
+	p.Lines = append(p.Lines, ParseLines("p, role:viewer, projects, get, *\ng, role:viewer, role:dev\n", role)...)
+	p.Lines = append(p.Lines, ParseLines("p, proj:web:deployer, applications, sync, web/*, allow\n", projectRole)...)
+	p.Lines = append(p.Lines, ParseLines("g, team-a, proj:web:deployer\n", projectBinding)...)
+
+	permissions := p.SubjectPermissions()
+	assert.Equal(t, []string{"ci", "team-a"}, []string{permissions[0].Subject, permissions[1].Subject})
+
+	// Local accounts are granted rules directly
+	assert.Equal(t, []rbacoperatorv1alpha1.PermissionRule{
+		{Resource: "applications", Action: "get", Object: "*/*", Effect: "allow", Source: binding},
+	}, permissions[0].Rules)
+	assert.Equal(t, []rbacoperatorv1alpha1.RoleGrant{{Role: "role:guest", Source: defaultRole}}, permissions[0].Roles)
+
+	// Roles are held transitively, the cycle between dev and viewer is expanded once
+	teamA := permissions[1]
+	assert.Equal(t, []rbacoperatorv1alpha1.RoleGrant{
+		{Role: "role:dev", Source: binding},
+		{Role: "role:guest", Source: defaultRole},
+		{Role: "role:viewer", Via: "role:dev", Source: binding},
+		{Role: "role:dev", Via: "role:viewer", Source: role},
+	}, teamA.Roles)
+	assert.Equal(t, []rbacoperatorv1alpha1.RoleGrant{{Role: "proj:web:deployer", Source: projectBinding}}, teamA.ProjectRoles)
+	assert.Equal(t, []rbacoperatorv1alpha1.PermissionRule{
+		{Resource: "applications", Action: "sync", Object: "team-a/*", Effect: "allow", Role: "role:dev", Source: role},
+		{Resource: "logs", Action: "get", Object: "team-a/*", Effect: "deny", Role: "role:dev", Source: role},
+		{Resource: "applications", Action: "sync", Object: "web/*", Effect: "allow", Role: "proj:web:deployer", Source: projectRole},
+		{Resource: "projects", Action: "get", Object: "*", Effect: "allow", Role: "role:viewer", Source: role},
+	}, teamA.Rules)
+}
+
+func TestPolicy_BuiltinRoles(t *testing.T) {
+	binding := rbacoperatorv1alpha1.PermissionSource{Kind: "ArgoCDRoleBinding", Namespace: "team-a", Name: "admins"}
+	p := Policy{Lines: append(BuiltinLines(), ParseLines("g, alice, role:admin\n", binding)...)}
+
+	// The built-in policy grants the local admin account role:admin
+	permissions := p.SubjectPermissions()
+	assert.Equal(t, []string{"admin", "alice"}, []string{permissions[0].Subject, permissions[1].Subject})
+	assert.Contains(t, permissions[1].Roles, rbacoperatorv1alpha1.RoleGrant{
+		Role: "role:readonly", Via: "role:admin", Source: rbacoperatorv1alpha1.PermissionSource{Kind: "BuiltinPolicy"},
+	})
+	assert.Contains(t, permissions[1].Rules, rbacoperatorv1alpha1.PermissionRule{
+		Resource: "applications", Action: "sync", Object: "*/*", Effect: "allow", Role: "role:admin",
+		Source: rbacoperatorv1alpha1.PermissionSource{Kind: "BuiltinPolicy"},
+	})
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+// RoleGraph is the graph of the roles granted to other roles by the role subjects of ArgoCDRoleBindings: a role subject
+// of a binding renders `g, role:<subject>, role:<role>`, an edge from the role of the subject to the bound role.
+// Roles are global in Argo CD, so the graph spans all namespaces.
+type RoleGraph struct {
+	roles    []string
+	bindings []rbacoperatorv1alpha1.ArgoCDRoleBinding
+	// change is the binding being changed, see WithChange.
+	change client.ObjectKey
+}
+
+// RoleCycle is a role subject of a binding closing a cycle in the graph.
+type RoleCycle struct {
+	// Subject is the index of the role subject in spec.subjects.
+	Subject int
+	// Roles are the roles of the cycle, starting and ending with the role of the subject.
+	Roles []string
+}
+
+// String returns the cycle, e.g. "role:a -> role:b -> role:a".
+func (c RoleCycle) String() string {
+	roles := []string{}
+	for _, role := range c.Roles {
+		roles = append(roles, "role:"+role)
+	}
+	return strings.Join(roles, " -> ")
+}
+
+// RoleGraphAnalysis are the problems of the role subjects and the role of one binding.
+type RoleGraphAnalysis struct {
+	// Role is the role of the binding.
+	Role string
+	// Cycles are the role subjects closing a cycle.
+	Cycles []RoleCycle
+	// Dangling are the role subjects referencing neither an ArgoCDRole nor a built-in role.
+	Dangling []string
+	// Unreachable is true if no sso or local subject holds the role of the binding, directly or through other roles.
+	Unreachable bool
+}
+
+// LoadRoleGraph returns the graph of all ArgoCDRoles and ArgoCDRoleBindings.
+func LoadRoleGraph(ctx context.Context, c client.Reader) (*RoleGraph, error) {
+	roles := rbacoperatorv1alpha1.ArgoCDRoleList{}
+	if err := c.List(ctx, &roles); err != nil {
+		return nil, err
+	}
+	bindings := rbacoperatorv1alpha1.ArgoCDRoleBindingList{}
+	if err := c.List(ctx, &bindings); err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, role := range roles.Items {
+		names = append(names, role.Name)
+	}
+	return NewRoleGraph(names, bindings.Items), nil
+}
+
+// NewRoleGraph returns the graph of the bindings. Roles are the names of the ArgoCDRoles, the built-in roles always exist.
+func NewRoleGraph(roles []string, bindings []rbacoperatorv1alpha1.ArgoCDRoleBinding) *RoleGraph {
+	return &RoleGraph{
+		roles:    append(slices.Clone(roles), common.ArgoCDRoleAdmin, common.ArgoCDRoleReadOnly),
+		bindings: bindings,
+	}
+}
+
+// With returns the graph with the binding replacing the one of the same namespace and name, or added if there is none.
+func (g *RoleGraph) With(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) *RoleGraph {
+	bindings := slices.DeleteFunc(slices.Clone(g.bindings), func(b rbacoperatorv1alpha1.ArgoCDRoleBinding) bool {
+		return b.Namespace == rb.Namespace && b.Name == rb.Name
+	})
+	return &RoleGraph{roles: g.roles, bindings: append(bindings, *rb)}
+}
+
+// WithChange returns the graph with the binding like With, as the newest change of the graph: its role subjects closing
+// a cycle are dropped rather than the ones of any other binding. Webhooks validate the changes of bindings this way.
+func (g *RoleGraph) WithChange(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) *RoleGraph {
+	graph := g.With(rb)
+	graph.change = client.ObjectKeyFromObject(rb)
+	return graph
+}
+
+// HasRole returns true if the role is an ArgoCDRole of the graph or a built-in role.
+func (g *RoleGraph) HasRole(name string) bool {
+	return slices.Contains(g.roles, name)
+}
+
+// Analyze returns the problems of the binding in the graph. The binding should be part of the graph, see With.
+func (g *RoleGraph) Analyze(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) RoleGraphAnalysis {
+	role := rb.Spec.ArgoCDRoleRef.Name
+	analysis := RoleGraphAnalysis{Role: role}
+	edges, dropped := g.acyclicEdges()
+	for i, subject := range rb.Spec.Subjects {
+		if subject.Kind != "role" {
+			continue
+		}
+		if dropped[roleEdge{from: subject.Name, to: role}] {
+			analysis.Cycles = append(analysis.Cycles, RoleCycle{Subject: i, Roles: append([]string{subject.Name}, findPath(edges, role, subject.Name)...)})
+		}
+		if !g.HasRole(subject.Name) {
+			analysis.Dangling = append(analysis.Dangling, subject.Name)
+		}
+	}
+	analysis.Unreachable = !g.reachable(edges)[role]
+	return analysis
+}
+
+// roleEdge is an edge of the graph, from the role of a role subject to the role of its binding.
+type roleEdge struct {
+	from string
+	to   string
+}
+
+// acyclicEdges returns the edges of the graph by role of the subject without the edges closing a cycle, and the edges
+// left out. The edges are added binding by binding from the oldest to the newest, see age, and an edge closing
+// a cycle with the edges added before is left out. So only the edge of the newest binding introducing a cycle is
+// dropped, the edges of the bindings it closes the cycle with are kept.
+func (g *RoleGraph) acyclicEdges() (map[string][]string, map[roleEdge]bool) {
+	bindings := slices.Clone(g.bindings)
+	slices.SortStableFunc(bindings, func(a, b rbacoperatorv1alpha1.ArgoCDRoleBinding) int {
+		return cmp.Or(
+			cmp.Compare(g.age(&a), g.age(&b)),
+			a.CreationTimestamp.Compare(b.CreationTimestamp.Time),
+			cmp.Compare(a.Namespace, b.Namespace),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+	edges := map[string][]string{}
+	dropped := map[roleEdge]bool{}
+	for _, rb := range bindings {
+		role := rb.Spec.ArgoCDRoleRef.Name
+		for _, subject := range rb.Spec.Subjects {
+			edge := roleEdge{from: subject.Name, to: role}
+			if subject.Kind != "role" || dropped[edge] || slices.Contains(edges[edge.from], edge.to) {
+				continue
+			}
+			if findPath(edges, edge.to, edge.from) != nil {
+				dropped[edge] = true
+				continue
+			}
+			edges[edge.from] = append(edges[edge.from], edge.to)
+		}
+	}
+	return edges, dropped
+}
+
+// age orders the bindings by how long their role subjects are rendered: 0 if the role subjects of the current
+// generation were resolved without cycle, 1 if they were not resolved yet, 2 if they closed a cycle, and 3 for the
+// binding being changed. The role subjects of a binding changed since they were resolved are newer than the role
+// subjects of the unchanged bindings.
+func (g *RoleGraph) age(rb *rbacoperatorv1alpha1.ArgoCDRoleBinding) int {
+	if client.ObjectKeyFromObject(rb) == g.change {
+		return 3
+	}
+	for _, condition := range rb.Status.Conditions {
+		if condition.Type != rbacoperatorv1alpha1.TypeResolved || condition.ObservedGeneration != rb.Generation {
+			continue
+		}
+		if condition.Reason == rbacoperatorv1alpha1.ReasonRoleCycle {
+			return 2
+		}
+		return 0
+	}
+	return 1
+}
+
+// reachable returns the roles held by an sso or local subject, directly or through other roles, following the acyclic
+// edges: edges closing a cycle are not rendered. The built-in admin role is held by the built-in admin account.
+func (g *RoleGraph) reachable(edges map[string][]string) map[string]bool {
+	queue := []string{common.ArgoCDRoleAdmin}
+	for _, rb := range g.bindings {
+		for _, subject := range rb.Spec.Subjects {
+			if subject.Kind == "sso" || subject.Kind == "local" {
+				queue = append(queue, rb.Spec.ArgoCDRoleRef.Name)
+				break
+			}
+		}
+	}
+	reached := map[string]bool{}
+	for len(queue) > 0 {
+		role := queue[0]
+		queue = queue[1:]
+		if reached[role] {
+			continue
+		}
+		reached[role] = true
+		queue = append(queue, edges[role]...)
+	}
+	return reached
+}
+
+// findPath returns the shortest path of roles from one role to another, both included, or nil if there is none.
+func findPath(edges map[string][]string, from, to string) []string {
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		role := queue[0]
+		queue = queue[1:]
+		if role == to {
+			path := []string{}
+			for ; role != from; role = previous[role] {
+				path = append([]string{role}, path...)
+			}
+			return append([]string{from}, path...)
+		}
+		for _, next := range edges[role] {
+			if _, seen := previous[next]; !seen {
+				previous[next] = role
+				queue = append(queue, next)
+			}
+		}
+	}
+	return nil
+}
+
+// Messages returns the problems of the analysis, one message each.
+func (a RoleGraphAnalysis) Messages() []string {
+	messages := []string{}
+	for _, cycle := range a.Cycles {
+		messages = append(messages, fmt.Sprintf("role subject %s closes the cycle %s", cycle.Roles[0], cycle))
+	}
+	for _, name := range a.Dangling {
+		messages = append(messages, fmt.Sprintf("role subject %s references no ArgoCDRole", name))
+	}
+	if a.Unreachable {
+		messages = append(messages, fmt.Sprintf("role:%s is not held by any sso or local subject", a.Role))
+	}
+	return messages
+}
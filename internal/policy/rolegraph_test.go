@@ -0,0 +1,97 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+func roleBinding(name, role string, subjects ...rbacoperatorv1alpha1.GlobalSubject) rbacoperatorv1alpha1.ArgoCDRoleBinding {
+	return rbacoperatorv1alpha1.ArgoCDRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a"},
+		Spec: rbacoperatorv1alpha1.ArgoCDRoleBindingSpec{
+			ArgoCDRoleRef: rbacoperatorv1alpha1.ArgoCDRoleRef{Name: role},
+			Subjects:      subjects,
+		},
+	}
+}
+
+func TestRoleGraph_Analyze(t *testing.T) {
+	sso := rbacoperatorv1alpha1.GlobalSubject{Kind: "sso", Name: "team-a"}
+	dev := roleBinding("dev", "dev", sso)
+	viewer := roleBinding("viewer", "viewer", rbacoperatorv1alpha1.GlobalSubject{Kind: "role", Name: "dev"})
+	graph := NewRoleGraph([]string{"dev", "viewer", "ops"}, []rbacoperatorv1alpha1.ArgoCDRoleBinding{dev, viewer})
+	assert.True(t, graph.HasRole("ops"))
+	assert.True(t, graph.HasRole("admin"))
+	assert.False(t, graph.HasRole("qa"))
+
+	// Roles held through other roles are reachable
+	assert.Equal(t, RoleGraphAnalysis{Role: "viewer"}, graph.Analyze(&viewer))
+
+	// A binding changed to close a cycle is reported with the cycle
+	cyclic := roleBinding("dev", "dev", sso, rbacoperatorv1alpha1.GlobalSubject{Kind: "role", Name: "viewer"})
+	analysis := graph.WithChange(&cyclic).Analyze(&cyclic)
+	assert.Equal(t, []RoleCycle{{Subject: 1, Roles: []string{"viewer", "dev", "viewer"}}}, analysis.Cycles)
+	assert.Equal(t, "role:viewer -> role:dev -> role:viewer", analysis.Cycles[0].String())
+	assert.False(t, analysis.Unreachable)
+
+	// Only the edge of the newest binding closing the cycle is dropped: the viewer binding resolved for its generation
+	// keeps its edge, also when the cyclic binding was created first
+	viewer.Generation = 1
+	viewer.SetConditions(rbacoperatorv1alpha1.Resolved().WithObservedGeneration(1))
+	cyclic.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	graph = graph.With(&viewer).With(&cyclic)
+	assert.Empty(t, graph.Analyze(&viewer).Cycles)
+	assert.Equal(t, []RoleCycle{{Subject: 1, Roles: []string{"viewer", "dev", "viewer"}}}, graph.Analyze(&cyclic).Cycles)
+
+	// Once resolved for its generation, the cyclic binding keeps closing the cycle when the viewer binding changes
+	cyclic.SetConditions(rbacoperatorv1alpha1.RoleCycle().WithObservedGeneration(0))
+	viewer.Generation = 2
+	graph = graph.With(&viewer).With(&cyclic)
+	assert.Empty(t, graph.Analyze(&viewer).Cycles)
+	assert.Len(t, graph.Analyze(&cyclic).Cycles, 1)
+
+	// The binding being changed closes the cycle, whatever the other bindings
+	assert.Equal(t, []RoleCycle{{Subject: 0, Roles: []string{"dev", "viewer", "dev"}}}, graph.WithChange(&viewer).Analyze(&viewer).Cycles)
+	graph = NewRoleGraph([]string{"dev", "viewer", "ops"}, []rbacoperatorv1alpha1.ArgoCDRoleBinding{dev, viewer})
+
+	// Edges on a cycle don't lead anywhere, roles only held through them are unreachable
+	ops := roleBinding("ops", "ops", rbacoperatorv1alpha1.GlobalSubject{Kind: "role", Name: "ops"})
+	analysis = graph.With(&ops).Analyze(&ops)
+	assert.Equal(t, []RoleCycle{{Subject: 0, Roles: []string{"ops", "ops"}}}, analysis.Cycles)
+	assert.True(t, analysis.Unreachable)
+
+	// Role subjects referencing no role are dangling
+	dangling := roleBinding("release", "readonly", rbacoperatorv1alpha1.GlobalSubject{Kind: "role", Name: "release-managers"})
+	analysis = graph.With(&dangling).Analyze(&dangling)
+	assert.Equal(t, []string{"release-managers"}, analysis.Dangling)
+	assert.True(t, analysis.Unreachable)
+	assert.Equal(t, []string{
+		"role subject release-managers references no ArgoCDRole",
+		"role:readonly is not held by any sso or local subject",
+	}, analysis.Messages())
+
+	// The built-in admin role is held by the built-in admin account
+	admin := roleBinding("admin", "admin")
+	assert.Equal(t, RoleGraphAnalysis{Role: "admin"}, graph.With(&admin).Analyze(&admin))
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy evaluates what the roles and bindings of a namespace may grant, against the ArgoCDRBACTenantPolicies
+// and against the Argo CD permissions of the requester.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/argoproj/argo-cd/v3/util/glob"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+// TenantPolicies are the ArgoCDRBACTenantPolicies applying to one namespace.
+type TenantPolicies []rbacoperatorv1alpha1.ArgoCDRBACTenantPolicy
+
+// LoadTenantPolicies returns the ArgoCDRBACTenantPolicies applying to the namespace.
+func LoadTenantPolicies(ctx context.Context, c client.Reader, namespace string) (TenantPolicies, error) {
+	policies := rbacoperatorv1alpha1.ArgoCDRBACTenantPolicyList{}
+	if err := c.List(ctx, &policies); err != nil {
+		return nil, err
+	}
+	return ForNamespace(policies.Items, namespace), nil
+}
+
+// ForNamespace returns the policies applying to the namespace.
+func ForNamespace(policies []rbacoperatorv1alpha1.ArgoCDRBACTenantPolicy, namespace string) TenantPolicies {
+	applying := TenantPolicies{}
+	for _, policy := range policies {
+		if matchesAny(policy.Spec.Namespaces, namespace, globMatch) {
+			applying = append(applying, policy)
+		}
+	}
+	return applying
+}
+
+// GlobalRules returns the rules of an ArgoCDRole reduced to what the policies allow, and the violations of the rest.
+func (p TenantPolicies) GlobalRules(rules []rbacoperatorv1alpha1.GlobalRule) ([]rbacoperatorv1alpha1.GlobalRule, []string) {
+	allowed := []rbacoperatorv1alpha1.GlobalRule{}
+	violations := []string{}
+	for _, rule := range rules {
+		verbs, objects, ruleViolations := p.rule(rule.Resource, rule.Verbs, rule.Objects, true)
+		violations = append(violations, ruleViolations...)
+		if len(verbs) > 0 && len(objects) > 0 {
+			allowed = append(allowed, rbacoperatorv1alpha1.GlobalRule{Resource: rule.Resource, Verbs: verbs, Objects: objects})
+		}
+	}
+	return allowed, violations
+}
+
+// ProjectRules returns the rules of an ArgoCDProjectRole reduced to what the policies allow, and the violations of the rest.
+func (p TenantPolicies) ProjectRules(rules []rbacoperatorv1alpha1.ProjectRule) ([]rbacoperatorv1alpha1.ProjectRule, []string) {
+	allowed := []rbacoperatorv1alpha1.ProjectRule{}
+	violations := []string{}
+	for _, rule := range rules {
+		verbs, objects, ruleViolations := p.rule(rule.Resource, rule.Verbs, rule.Objects, false)
+		violations = append(violations, ruleViolations...)
+		if len(verbs) > 0 && len(objects) > 0 {
+			allowed = append(allowed, rbacoperatorv1alpha1.ProjectRule{Resource: rule.Resource, Verbs: verbs, Objects: objects})
+		}
+	}
+	return allowed, violations
+}
+
+// rule returns the allowed verbs and objects of a rule. Objects are only restricted by the policies if checkObjects is set,
+// those splitting the policy line are always removed.
+func (p TenantPolicies) rule(resource string, verbs, objects []string, checkObjects bool) ([]string, []string, []string) {
+	if splitsPolicyLine(resource) {
+		return nil, nil, []string{fmt.Sprintf("resource %q must not contain commas or line breaks", resource)}
+	}
+	if name := p.deny(resource, allowedResourcesOf, exactMatch); name != "" {
+		return nil, nil, []string{fmt.Sprintf("resource %s is not allowed by ArgoCDRBACTenantPolicy %s", resource, name)}
+	}
+	violations := []string{}
+	allowedVerbs := []string{}
+	for _, verb := range verbs {
+		if splitsPolicyLine(verb) {
+			violations = append(violations, fmt.Sprintf("verb %q on %s must not contain commas or line breaks", verb, resource))
+			continue
+		}
+		if name := p.deny(verb, allowedVerbsOf, exactMatch); name != "" {
+			violations = append(violations, fmt.Sprintf("verb %s on %s is not allowed by ArgoCDRBACTenantPolicy %s", verb, resource, name))
+			continue
+		}
+		allowedVerbs = append(allowedVerbs, verb)
+	}
+	allowedObjects := []string{}
+	for _, object := range objects {
+		if splitsPolicyLine(object) {
+			violations = append(violations, fmt.Sprintf("object %q of %s must not contain commas or line breaks", object, resource))
+			continue
+		}
+		if !checkObjects {
+			allowedObjects = append(allowedObjects, object)
+			continue
+		}
+		if name := p.deny(object, allowedObjectsOf, globMatch); name != "" {
+			violations = append(violations, fmt.Sprintf("object %s of %s is not allowed by ArgoCDRBACTenantPolicy %s", object, resource, name))
+			continue
+		}
+		allowedObjects = append(allowedObjects, object)
+	}
+	return allowedVerbs, allowedObjects, violations
+}
+
+// GlobalSubjects returns the subjects of an ArgoCDRoleBinding the policies allow, and the violations of the rest.
+func (p TenantPolicies) GlobalSubjects(subjects []rbacoperatorv1alpha1.GlobalSubject) ([]rbacoperatorv1alpha1.GlobalSubject, []string) {
+	allowed := []rbacoperatorv1alpha1.GlobalSubject{}
+	violations := []string{}
+	for _, subject := range subjects {
+		if splitsPolicyLine(subject.Name) {
+			violations = append(violations, fmt.Sprintf("subject %s:%q must not contain commas or line breaks", subject.Kind, subject.Name))
+			continue
+		}
+		if name := p.deny(subject.Name, allowedSubjectsOf, globMatch); name != "" {
+			violations = append(violations, fmt.Sprintf("subject %s:%s is not allowed by ArgoCDRBACTenantPolicy %s", subject.Kind, subject.Name, name))
+			continue
+		}
+		allowed = append(allowed, subject)
+	}
+	return allowed, violations
+}
+
+// AppProjectSubjects returns the subjects of an ArgoCDProjectRoleBinding reduced to the groups and users the policies allow,
+// and the violations of the rest.
+func (p TenantPolicies) AppProjectSubjects(subjects []rbacoperatorv1alpha1.AppProjectSubject) ([]rbacoperatorv1alpha1.AppProjectSubject, []string) {
+	allowed := []rbacoperatorv1alpha1.AppProjectSubject{}
+	violations := []string{}
+	for _, subject := range subjects {
+		groups := []string{}
+		for _, group := range subject.Groups {
+			if splitsPolicyLine(group) {
+				violations = append(violations, fmt.Sprintf("group %q must not contain commas or line breaks", group))
+				continue
+			}
+			if name := p.deny(group, allowedSubjectsOf, globMatch); name != "" {
+				violations = append(violations, fmt.Sprintf("group %s is not allowed by ArgoCDRBACTenantPolicy %s", group, name))
+				continue
+			}
+			groups = append(groups, group)
+		}
+		users := []rbacoperatorv1alpha1.AppProjectUser{}
+		for _, user := range subject.Users {
+			if splitsPolicyLine(user.Name) {
+				violations = append(violations, fmt.Sprintf("user %s:%q must not contain commas or line breaks", user.Kind, user.Name))
+				continue
+			}
+			if name := p.deny(user.Name, allowedSubjectsOf, globMatch); name != "" {
+				violations = append(violations, fmt.Sprintf("user %s:%s is not allowed by ArgoCDRBACTenantPolicy %s", user.Kind, user.Name, name))
+				continue
+			}
+			users = append(users, user)
+		}
+		subject.Groups = groups
+		subject.Users = users
+		allowed = append(allowed, subject)
+	}
+	return allowed, violations
+}
+
+// BuiltInRole returns the violation of binding the built-in role, or an empty string if the policies allow it.
+func (p TenantPolicies) BuiltInRole(roleName string) string {
+	for _, policy := range p {
+		if !policy.Spec.AllowBuiltInRoles {
+			return fmt.Sprintf("built-in role %s is not allowed by ArgoCDRBACTenantPolicy %s", roleName, policy.Name)
+		}
+	}
+	return ""
+}
+
+// deny returns the name of the first policy not allowing the value, or an empty string if all allow it.
+func (p TenantPolicies) deny(value string, allowedOf func(rbacoperatorv1alpha1.ArgoCDRBACTenantPolicySpec) []string, match func(pattern, value string) bool) string {
+	for _, policy := range p {
+		allowed := allowedOf(policy.Spec)
+		if len(allowed) > 0 && !matchesAny(allowed, value, match) {
+			return policy.Name
+		}
+	}
+	return ""
+}
+
+func allowedResourcesOf(spec rbacoperatorv1alpha1.ArgoCDRBACTenantPolicySpec) []string {
+	return spec.Resources
+}
+
+func allowedVerbsOf(spec rbacoperatorv1alpha1.ArgoCDRBACTenantPolicySpec) []string { return spec.Verbs }
+
+func allowedObjectsOf(spec rbacoperatorv1alpha1.ArgoCDRBACTenantPolicySpec) []string {
+	return spec.Objects
+}
+
+func allowedSubjectsOf(spec rbacoperatorv1alpha1.ArgoCDRBACTenantPolicySpec) []string {
+	return spec.Subjects
+}
+
+func matchesAny(patterns []string, value string, match func(pattern, value string) bool) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool { return match(pattern, value) })
+}
+
+// splitsPolicyLine returns true if the value can't be a field of a policy line: a comma would start another field and a
+// line break another line, e.g. "x, allow\ng, me, role:admin". Globs match both, so values are checked before matching.
+func splitsPolicyLine(value string) bool {
+	return strings.ContainsAny(value, ",\r\n")
+}
+
+func exactMatch(pattern, value string) bool {
+	return pattern == value
+}
+
+// globMatch matches the value against the pattern like the Argo CD enforcer, "*" also matches "/". Wildcards in the
+// value stand for any value, so they are only matched by wildcards of the pattern: "*/*" is not allowed by "team-a-*/*".
+func globMatch(pattern, value string) bool {
+	if !strings.ContainsAny(value, globMetaChars) {
+		return glob.Match(pattern, value)
+	}
+	if strings.ContainsAny(strings.ReplaceAll(value, "*", ""), globMetaChars) ||
+		strings.ContainsAny(strings.ReplaceAll(pattern, "*", ""), globMetaChars) {
+		return pattern == value
+	}
+	return coversWildcards(pattern, value)
+}
+
+// globMetaChars are the characters with a special meaning in the glob patterns of Argo CD.
+const globMetaChars = "*?[]{}\\"
+
+// coversWildcards returns true if every value matched by the value pattern is matched by the pattern, both only
+// having "*" as wildcard. A "*" of the pattern matches any part of the value, a "*" of the value only a "*" of the pattern.
+func coversWildcards(pattern, value string) bool {
+	// covers[j] is true if the rest of the pattern matches value[j:]
+	covers := make([]bool, len(value)+1)
+	covers[len(value)] = true
+	for i := len(pattern) - 1; i >= 0; i-- {
+		next := covers
+		covers = make([]bool, len(value)+1)
+		for j := len(value); j >= 0; j-- {
+			if pattern[i] == '*' {
+				covers[j] = next[j] || (j < len(value) && covers[j+1])
+			} else {
+				covers[j] = j < len(value) && value[j] != '*' && value[j] == pattern[i] && next[j+1]
+			}
+		}
+	}
+	return covers[0]
+}
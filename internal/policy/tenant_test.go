@@ -0,0 +1,168 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+func makeTestTenantPolicy(name string, spec rbacoperatorv1alpha1.ArgoCDRBACTenantPolicySpec) rbacoperatorv1alpha1.ArgoCDRBACTenantPolicy {
+	return rbacoperatorv1alpha1.ArgoCDRBACTenantPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       spec,
+	}
+}
+
+func makeTestTeamAPolicies() TenantPolicies {
+	return ForNamespace([]rbacoperatorv1alpha1.ArgoCDRBACTenantPolicy{
+		makeTestTenantPolicy("team-a", rbacoperatorv1alpha1.ArgoCDRBACTenantPolicySpec{
+			Namespaces: []string{"team-a-*"},
+			Resources:  []string{"applications", "logs"},
+			Verbs:      []string{"get", "sync"},
+			Objects:    []string{"team-a-*/*"},
+			Subjects:   []string{"team-a-*"},
+		}),
+		makeTestTenantPolicy("team-b", rbacoperatorv1alpha1.ArgoCDRBACTenantPolicySpec{
+			Namespaces:        []string{"team-b"},
+			AllowBuiltInRoles: true,
+		}),
+	}, "team-a-dev")
+}
+
+func TestTenantPolicies_ForNamespace(t *testing.T) {
+	policies := makeTestTeamAPolicies()
+	assert.Len(t, policies, 1)
+	assert.Equal(t, "team-a", policies[0].Name)
+}
+
+func TestTenantPolicies_GlobalRules(t *testing.T) {
+	rules := []rbacoperatorv1alpha1.GlobalRule{
+		{Resource: "applications", Verbs: []string{"get", "*"}, Objects: []string{"team-a-x/*", "team-a-x/ns/app", "*/*"}},
+		{Resource: "clusters", Verbs: []string{"get"}, Objects: []string{"*"}},
+		{Resource: "logs", Verbs: []string{"delete"}, Objects: []string{"team-a-x/*"}},
+	}
+
+	allowed, violations := makeTestTeamAPolicies().GlobalRules(rules)
+	assert.Equal(t, []rbacoperatorv1alpha1.GlobalRule{
+		{Resource: "applications", Verbs: []string{"get"}, Objects: []string{"team-a-x/*", "team-a-x/ns/app"}},
+	}, allowed)
+	assert.Equal(t, []string{
+		"verb * on applications is not allowed by ArgoCDRBACTenantPolicy team-a",
+		"object */* of applications is not allowed by ArgoCDRBACTenantPolicy team-a",
+		"resource clusters is not allowed by ArgoCDRBACTenantPolicy team-a",
+		"verb delete on logs is not allowed by ArgoCDRBACTenantPolicy team-a",
+	}, violations)
+}
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		match   bool
+	}{
+		{"team-a-*/*", "team-a-x/app", true},
+		// "*" matches "/" like in Argo CD, so applications in any namespace are matched
+		{"team-a-*/*", "team-a-x/ns/app", true},
+		{"team-a-*/*", "team-b/app", false},
+		// Wildcards of the value are only matched by wildcards of the pattern
+		{"team-a-*/*", "team-a-x/ns/*", true},
+		{"team-a-*/*", "*/*", false},
+		{"team-a-?/*", "team-a-*/*", false},
+		{"team-a-?/*", "team-a-?/*", true},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern+" "+test.value, func(t *testing.T) {
+			assert.Equal(t, test.match, globMatch(test.pattern, test.value))
+		})
+	}
+}
+
+func TestTenantPolicies_ProjectRules(t *testing.T) {
+	rules := []rbacoperatorv1alpha1.ProjectRule{
+		{Resource: "applications", Verbs: []string{"get"}, Objects: []string{"*/*"}},
+	}
+
+	// Objects of project roles are confined to the AppProject by Argo CD
+	allowed, violations := makeTestTeamAPolicies().ProjectRules(rules)
+	assert.Equal(t, rules, allowed)
+	assert.Empty(t, violations)
+}
+
+func TestTenantPolicies_Subjects(t *testing.T) {
+	policies := makeTestTeamAPolicies()
+
+	subjects, violations := policies.GlobalSubjects([]rbacoperatorv1alpha1.GlobalSubject{
+		{Kind: "sso", Name: "team-a-devs"},
+		{Kind: "sso", Name: "platform-admins"},
+	})
+	assert.Equal(t, []rbacoperatorv1alpha1.GlobalSubject{{Kind: "sso", Name: "team-a-devs"}}, subjects)
+	assert.Equal(t, []string{"subject sso:platform-admins is not allowed by ArgoCDRBACTenantPolicy team-a"}, violations)
+
+	appProjectSubjects, violations := policies.AppProjectSubjects([]rbacoperatorv1alpha1.AppProjectSubject{{
+		AppProjectRef: "team-a",
+		Groups:        []string{"team-a-devs", "everyone"},
+		Users:         []rbacoperatorv1alpha1.AppProjectUser{{Kind: "local", Name: "ci"}},
+	}})
+	assert.Equal(t, []string{"team-a-devs"}, appProjectSubjects[0].Groups)
+	assert.Empty(t, appProjectSubjects[0].Users)
+	assert.Len(t, violations, 2)
+}
+
+func TestTenantPolicies_PolicyLineInjection(t *testing.T) {
+	policies := ForNamespace([]rbacoperatorv1alpha1.ArgoCDRBACTenantPolicy{
+		makeTestTenantPolicy("team-a", rbacoperatorv1alpha1.ArgoCDRBACTenantPolicySpec{
+			Namespaces: []string{"team-a-*"},
+			Objects:    []string{"team-a-*"},
+			Subjects:   []string{"team-a-*"},
+		}),
+	}, "team-a-dev")
+
+	// "*" of the policy also matches commas and line breaks, the value would add "g, team-a-dev, role:admin"
+	allowed, violations := policies.GlobalRules([]rbacoperatorv1alpha1.GlobalRule{{
+		Resource: "applications",
+		Verbs:    []string{"get"},
+		Objects:  []string{"team-a-x, allow\ng, team-a-dev, role:admin\np, team-a-y"},
+	}})
+	assert.Empty(t, allowed)
+	assert.Equal(t, []string{
+		`object "team-a-x, allow\ng, team-a-dev, role:admin\np, team-a-y" of applications must not contain commas or line breaks`,
+	}, violations)
+
+	subjects, violations := policies.GlobalSubjects([]rbacoperatorv1alpha1.GlobalSubject{
+		{Kind: "sso", Name: "team-a-x, role:admin\ng, me"},
+	})
+	assert.Empty(t, subjects)
+	assert.Equal(t, []string{`subject sso:"team-a-x, role:admin\ng, me" must not contain commas or line breaks`}, violations)
+
+	projectRules, violations := policies.ProjectRules([]rbacoperatorv1alpha1.ProjectRule{
+		{Resource: "applications", Verbs: []string{"get", "get\r"}, Objects: []string{"team-a/*", "*, allow\np, x"}},
+	})
+	assert.Equal(t, []rbacoperatorv1alpha1.ProjectRule{
+		{Resource: "applications", Verbs: []string{"get"}, Objects: []string{"team-a/*"}},
+	}, projectRules)
+	assert.Len(t, violations, 2)
+}
+
+func TestTenantPolicies_BuiltInRole(t *testing.T) {
+	assert.Equal(t, "built-in role admin is not allowed by ArgoCDRBACTenantPolicy team-a", makeTestTeamAPolicies().BuiltInRole("admin"))
+	assert.Empty(t, TenantPolicies{}.BuiltInRole("admin"))
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+var argocdaccessrequestlog = logf.Log.WithName("argocdaccessrequest-resource")
+
+// SetupArgoCDAccessRequestWebhookWithManager registers the webhook for ArgoCDAccessRequest in the manager.
+func SetupArgoCDAccessRequestWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&rbacoperatorv1alpha1.ArgoCDAccessRequest{}).
+		WithDefaulter(&ArgoCDAccessRequestCustomDefaulter{}).
+		WithValidator(&ArgoCDAccessRequestCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-rbac-operator-argoproj-labs-io-v1alpha1-argocdaccessrequest,mutating=true,failurePolicy=fail,sideEffects=None,groups=rbac-operator.argoproj-labs.io,resources=argocdaccessrequests,verbs=create;update,versions=v1alpha1,name=margocdaccessrequest-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ArgoCDAccessRequestCustomDefaulter records the identity of the requester and the approver
+// in the annotations of the ArgoCDAccessRequest, so they can't be forged.
+type ArgoCDAccessRequestCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &ArgoCDAccessRequestCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind ArgoCDAccessRequest.
+func (d *ArgoCDAccessRequestCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	accessRequest, ok := obj.(*rbacoperatorv1alpha1.ArgoCDAccessRequest)
+	if !ok {
+		return fmt.Errorf("expected an ArgoCDAccessRequest object but got %T", obj)
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	argocdaccessrequestlog.Info("Defaulting for ArgoCDAccessRequest", "name", accessRequest.GetName(), "user", req.UserInfo.Username)
+
+	if accessRequest.Annotations == nil {
+		accessRequest.Annotations = map[string]string{}
+	}
+	if req.Operation == admissionv1.Create {
+		accessRequest.Annotations[common.AnnotationRequestedBy] = req.UserInfo.Username
+		if _, ok := accessRequest.Annotations[common.AnnotationApprovedBy]; ok {
+			stampApprover(accessRequest, req.UserInfo.Username, req.UserInfo.Groups)
+		}
+		return nil
+	}
+
+	oldAccessRequest := &rbacoperatorv1alpha1.ArgoCDAccessRequest{}
+	if err := json.Unmarshal(req.OldObject.Raw, oldAccessRequest); err != nil {
+		return err
+	}
+	copyAnnotation(accessRequest, oldAccessRequest, common.AnnotationRequestedBy)
+	newApprover, approved := accessRequest.Annotations[common.AnnotationApprovedBy]
+	oldApprover, wasApproved := oldAccessRequest.Annotations[common.AnnotationApprovedBy]
+	if approved && (!wasApproved || newApprover != oldApprover) {
+		stampApprover(accessRequest, req.UserInfo.Username, req.UserInfo.Groups)
+		return nil
+	}
+	copyAnnotation(accessRequest, oldAccessRequest, common.AnnotationApprovedByGroups)
+	return nil
+}
+
+// stampApprover sets the approver annotations to the identity of the user approving the request.
+func stampApprover(accessRequest *rbacoperatorv1alpha1.ArgoCDAccessRequest, user string, groups []string) {
+	accessRequest.Annotations[common.AnnotationApprovedBy] = user
+	accessRequest.Annotations[common.AnnotationApprovedByGroups] = strings.Join(groups, ",")
+}
+
+// copyAnnotation sets the annotation to its value in the old object, removing it if it was not set.
+func copyAnnotation(accessRequest, oldAccessRequest *rbacoperatorv1alpha1.ArgoCDAccessRequest, key string) {
+	if value, ok := oldAccessRequest.Annotations[key]; ok {
+		accessRequest.Annotations[key] = value
+		return
+	}
+	delete(accessRequest.Annotations, key)
+}
+
+// +kubebuilder:webhook:path=/validate-rbac-operator-argoproj-labs-io-v1alpha1-argocdaccessrequest,mutating=false,failurePolicy=fail,sideEffects=None,groups=rbac-operator.argoproj-labs.io,resources=argocdaccessrequests,verbs=create;update,versions=v1alpha1,name=vargocdaccessrequest-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ArgoCDAccessRequestCustomValidator rejects approvals by identities not listed in an ArgoCDApprovalPolicy.
+type ArgoCDAccessRequestCustomValidator struct {
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &ArgoCDAccessRequestCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type ArgoCDAccessRequest.
+func (v *ArgoCDAccessRequestCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	accessRequest, ok := obj.(*rbacoperatorv1alpha1.ArgoCDAccessRequest)
+	if !ok {
+		return nil, fmt.Errorf("expected an ArgoCDAccessRequest object but got %T", obj)
+	}
+	if _, ok := accessRequest.Annotations[common.AnnotationApprovedBy]; ok {
+		return nil, v.validateApproval(ctx, accessRequest)
+	}
+	return nil, nil
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type ArgoCDAccessRequest.
+func (v *ArgoCDAccessRequestCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	accessRequest, ok := newObj.(*rbacoperatorv1alpha1.ArgoCDAccessRequest)
+	if !ok {
+		return nil, fmt.Errorf("expected an ArgoCDAccessRequest object for the newObj but got %T", newObj)
+	}
+	oldAccessRequest, ok := oldObj.(*rbacoperatorv1alpha1.ArgoCDAccessRequest)
+	if !ok {
+		return nil, fmt.Errorf("expected an ArgoCDAccessRequest object for the oldObj but got %T", oldObj)
+	}
+	approver, approved := accessRequest.Annotations[common.AnnotationApprovedBy]
+	oldApprover, wasApproved := oldAccessRequest.Annotations[common.AnnotationApprovedBy]
+	if !approved || (wasApproved && approver == oldApprover) {
+		return nil, nil
+	}
+	if oldAccessRequest.Status.Phase != "" && oldAccessRequest.Status.Phase != rbacoperatorv1alpha1.AccessRequestPhaseRequested {
+		return nil, apierrors.NewForbidden(rbacoperatorv1alpha1.GroupVersion.WithResource("argocdaccessrequests").GroupResource(),
+			accessRequest.Name, fmt.Errorf("request is already %s", oldAccessRequest.Status.Phase))
+	}
+	return nil, v.validateApproval(ctx, accessRequest)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type ArgoCDAccessRequest.
+func (v *ArgoCDAccessRequestCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateApproval returns a Forbidden error if the approver stamped by the defaulter can't approve the request.
+func (v *ArgoCDAccessRequestCustomValidator) validateApproval(ctx context.Context, accessRequest *rbacoperatorv1alpha1.ArgoCDAccessRequest) error {
+	policies := rbacoperatorv1alpha1.ArgoCDApprovalPolicyList{}
+	if err := v.Client.List(ctx, &policies, client.InNamespace(accessRequest.Namespace)); err != nil {
+		return err
+	}
+	var groups []string
+	if value := accessRequest.Annotations[common.AnnotationApprovedByGroups]; value != "" {
+		groups = strings.Split(value, ",")
+	}
+	if err := accessRequest.CheckApproval(policies.Items, accessRequest.Annotations[common.AnnotationRequestedBy],
+		accessRequest.Annotations[common.AnnotationApprovedBy], groups); err != nil {
+		return apierrors.NewForbidden(rbacoperatorv1alpha1.GroupVersion.WithResource("argocdaccessrequests").GroupResource(), accessRequest.Name, err)
+	}
+	return nil
+}
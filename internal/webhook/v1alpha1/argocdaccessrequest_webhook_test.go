@@ -0,0 +1,139 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+func makeTestAccessRequest(annotations map[string]string) *rbacoperatorv1alpha1.ArgoCDAccessRequest {
+	return &rbacoperatorv1alpha1.ArgoCDAccessRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-access-request",
+			Namespace:   "default",
+			Annotations: annotations,
+		},
+		Spec: rbacoperatorv1alpha1.ArgoCDAccessRequestSpec{
+			ArgoCDRoleRef: rbacoperatorv1alpha1.ArgoCDRoleRef{Name: "test-role"},
+			Subject:       rbacoperatorv1alpha1.AccessRequestSubject{Kind: "sso", Name: "gosha"},
+			Duration:      metav1.Duration{Duration: time.Hour},
+			Justification: "incident",
+		},
+	}
+}
+
+func makeTestAdmissionContext(t *testing.T, operation admissionv1.Operation, user string, groups []string, oldObj runtime.Object) context.Context {
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: operation,
+		UserInfo:  authenticationv1.UserInfo{Username: user, Groups: groups},
+	}}
+	if oldObj != nil {
+		raw, err := json.Marshal(oldObj)
+		assert.NoError(t, err)
+		req.OldObject = runtime.RawExtension{Raw: raw}
+	}
+	return admission.NewContextWithRequest(context.TODO(), req)
+}
+
+func TestArgoCDAccessRequestCustomDefaulter_Default(t *testing.T) {
+	defaulter := &ArgoCDAccessRequestCustomDefaulter{}
+
+	// The requester is recorded on creation, whatever the annotation says
+	accessRequest := makeTestAccessRequest(map[string]string{common.AnnotationRequestedBy: "someone-else"})
+	ctx := makeTestAdmissionContext(t, admissionv1.Create, "requester", nil, nil)
+	assert.NoError(t, defaulter.Default(ctx, accessRequest))
+	assert.Equal(t, "requester", accessRequest.Annotations[common.AnnotationRequestedBy])
+
+	// The approver is replaced by the identity of the user approving the request
+	oldAccessRequest := accessRequest.DeepCopy()
+	accessRequest.Annotations[common.AnnotationApprovedBy] = "true"
+	accessRequest.Annotations[common.AnnotationRequestedBy] = "approver"
+	ctx = makeTestAdmissionContext(t, admissionv1.Update, "approver", []string{"leads", "devs"}, oldAccessRequest)
+	assert.NoError(t, defaulter.Default(ctx, accessRequest))
+	assert.Equal(t, "requester", accessRequest.Annotations[common.AnnotationRequestedBy])
+	assert.Equal(t, "approver", accessRequest.Annotations[common.AnnotationApprovedBy])
+	assert.Equal(t, "leads,devs", accessRequest.Annotations[common.AnnotationApprovedByGroups])
+
+	// The approver groups can't be changed afterwards
+	oldAccessRequest = accessRequest.DeepCopy()
+	accessRequest.Annotations[common.AnnotationApprovedByGroups] = "admins"
+	ctx = makeTestAdmissionContext(t, admissionv1.Update, "someone", nil, oldAccessRequest)
+	assert.NoError(t, defaulter.Default(ctx, accessRequest))
+	assert.Equal(t, "leads,devs", accessRequest.Annotations[common.AnnotationApprovedByGroups])
+}
+
+func TestArgoCDAccessRequestCustomValidator_ValidateUpdate(t *testing.T) {
+	policy := &rbacoperatorv1alpha1.ArgoCDApprovalPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-approval-policy", Namespace: "default"},
+		Spec: rbacoperatorv1alpha1.ArgoCDApprovalPolicySpec{
+			Approvers: []rbacoperatorv1alpha1.Approver{{Kind: rbacoperatorv1alpha1.ApproverKindGroup, Name: "leads"}},
+		},
+	}
+	scheme := runtime.NewScheme()
+	assert.NoError(t, rbacoperatorv1alpha1.AddToScheme(scheme))
+	validator := &ArgoCDAccessRequestCustomValidator{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(policy).Build()}
+
+	oldAccessRequest := makeTestAccessRequest(map[string]string{common.AnnotationRequestedBy: "requester"})
+
+	tests := []struct {
+		name      string
+		approver  string
+		groups    string
+		forbidden bool
+	}{
+		{"approver in group", "approver", "leads", false},
+		{"approver not in group", "approver", "devs", true},
+		{"requester in group", "requester", "leads", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			accessRequest := makeTestAccessRequest(map[string]string{
+				common.AnnotationRequestedBy:      "requester",
+				common.AnnotationApprovedBy:       test.approver,
+				common.AnnotationApprovedByGroups: test.groups,
+			})
+			_, err := validator.ValidateUpdate(context.TODO(), oldAccessRequest, accessRequest)
+			assert.Equal(t, test.forbidden, apierrors.IsForbidden(err))
+		})
+	}
+
+	// Requests can't be approved again once expired
+	expiredAccessRequest := oldAccessRequest.DeepCopy()
+	expiredAccessRequest.Status.Phase = rbacoperatorv1alpha1.AccessRequestPhaseExpired
+	accessRequest := makeTestAccessRequest(map[string]string{
+		common.AnnotationRequestedBy:      "requester",
+		common.AnnotationApprovedBy:       "approver",
+		common.AnnotationApprovedByGroups: "leads",
+	})
+	_, err := validator.ValidateUpdate(context.TODO(), expiredAccessRequest, accessRequest)
+	assert.True(t, apierrors.IsForbidden(err))
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+)
+
+var argocdprojectrolelog = logf.Log.WithName("argocdprojectrole-resource")
+
+// SetupArgoCDProjectRoleWebhookWithManager registers the webhook for ArgoCDProjectRole in the manager.
+// Grants are not checked for escalation if escalation is nil.
+func SetupArgoCDProjectRoleWebhookWithManager(mgr ctrl.Manager, escalation *EscalationCheck) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&rbacoperatorv1alpha1.ArgoCDProjectRole{}).
+		WithDefaulter(&ChangedByDefaulter{}).
+		WithValidator(&ArgoCDProjectRoleCustomValidator{Client: mgr.GetClient(), Escalation: escalation}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-rbac-operator-argoproj-labs-io-v1alpha1-argocdprojectrole,mutating=false,failurePolicy=fail,sideEffects=None,groups=rbac-operator.argoproj-labs.io,resources=argocdprojectroles,verbs=create;update,versions=v1alpha1,name=vargocdprojectrole-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ArgoCDProjectRoleCustomValidator rejects ArgoCDProjectRoles granting more than the ArgoCDRBACTenantPolicies of their namespace allow,
+// or more than the requester holds in Argo CD.
+type ArgoCDProjectRoleCustomValidator struct {
+	Client     client.Reader
+	Escalation *EscalationCheck
+}
+
+var _ webhook.CustomValidator = &ArgoCDProjectRoleCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type ArgoCDProjectRole.
+func (v *ArgoCDProjectRoleCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	projectRole, ok := obj.(*rbacoperatorv1alpha1.ArgoCDProjectRole)
+	if !ok {
+		return nil, fmt.Errorf("expected an ArgoCDProjectRole object but got %T", obj)
+	}
+	argocdprojectrolelog.Info("Validation for ArgoCDProjectRole upon creation", "name", projectRole.GetName())
+	return nil, v.validate(ctx, projectRole)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type ArgoCDProjectRole.
+// Updates not changing the spec, like the finalizer removed by the operator, are always allowed.
+func (v *ArgoCDProjectRoleCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	projectRole, ok := newObj.(*rbacoperatorv1alpha1.ArgoCDProjectRole)
+	if !ok {
+		return nil, fmt.Errorf("expected an ArgoCDProjectRole object for the newObj but got %T", newObj)
+	}
+	oldProjectRole, ok := oldObj.(*rbacoperatorv1alpha1.ArgoCDProjectRole)
+	if !ok {
+		return nil, fmt.Errorf("expected an ArgoCDProjectRole object for the oldObj but got %T", oldObj)
+	}
+	if equality.Semantic.DeepEqual(projectRole.Spec, oldProjectRole.Spec) {
+		return nil, nil
+	}
+	argocdprojectrolelog.Info("Validation for ArgoCDProjectRole upon update", "name", projectRole.GetName())
+	return nil, v.validate(ctx, projectRole)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type ArgoCDProjectRole.
+func (v *ArgoCDProjectRoleCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ArgoCDProjectRoleCustomValidator) validate(ctx context.Context, projectRole *rbacoperatorv1alpha1.ArgoCDProjectRole) error {
+	if err := validateTenantPolicies(ctx, v.Client, "argocdprojectroles", projectRole.Namespace, projectRole.Name, func(policies policy.TenantPolicies) []string {
+		_, violations := policies.ProjectRules(projectRole.Spec.Rules)
+		return violations
+	}); err != nil {
+		return err
+	}
+	return v.Escalation.validate(ctx, "argocdprojectroles", projectRole.Namespace, projectRole.Name, projectRoleRules(projectRole.Spec.Rules))
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+)
+
+var argocdprojectrolebindinglog = logf.Log.WithName("argocdprojectrolebinding-resource")
+
+// SetupArgoCDProjectRoleBindingWebhookWithManager registers the webhook for ArgoCDProjectRoleBinding in the manager.
+// Grants are not checked for escalation if escalation is nil.
+func SetupArgoCDProjectRoleBindingWebhookWithManager(mgr ctrl.Manager, escalation *EscalationCheck) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&rbacoperatorv1alpha1.ArgoCDProjectRoleBinding{}).
+		WithDefaulter(&ChangedByDefaulter{}).
+		WithValidator(&ArgoCDProjectRoleBindingCustomValidator{Client: mgr.GetClient(), Escalation: escalation}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-rbac-operator-argoproj-labs-io-v1alpha1-argocdprojectrolebinding,mutating=false,failurePolicy=fail,sideEffects=None,groups=rbac-operator.argoproj-labs.io,resources=argocdprojectrolebindings,verbs=create;update,versions=v1alpha1,name=vargocdprojectrolebinding-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ArgoCDProjectRoleBindingCustomValidator rejects ArgoCDProjectRoleBindings granting more than the ArgoCDRBACTenantPolicies of their namespace allow,
+// or binding a project role with permissions the requester does not hold in Argo CD.
+type ArgoCDProjectRoleBindingCustomValidator struct {
+	Client     client.Reader
+	Escalation *EscalationCheck
+}
+
+var _ webhook.CustomValidator = &ArgoCDProjectRoleBindingCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type ArgoCDProjectRoleBinding.
+func (v *ArgoCDProjectRoleBindingCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	projectRoleBinding, ok := obj.(*rbacoperatorv1alpha1.ArgoCDProjectRoleBinding)
+	if !ok {
+		return nil, fmt.Errorf("expected an ArgoCDProjectRoleBinding object but got %T", obj)
+	}
+	argocdprojectrolebindinglog.Info("Validation for ArgoCDProjectRoleBinding upon creation", "name", projectRoleBinding.GetName())
+	return nil, v.validate(ctx, projectRoleBinding)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type ArgoCDProjectRoleBinding.
+// Updates not changing the spec, like the finalizer removed by the operator, are always allowed.
+func (v *ArgoCDProjectRoleBindingCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	projectRoleBinding, ok := newObj.(*rbacoperatorv1alpha1.ArgoCDProjectRoleBinding)
+	if !ok {
+		return nil, fmt.Errorf("expected an ArgoCDProjectRoleBinding object for the newObj but got %T", newObj)
+	}
+	oldProjectRoleBinding, ok := oldObj.(*rbacoperatorv1alpha1.ArgoCDProjectRoleBinding)
+	if !ok {
+		return nil, fmt.Errorf("expected an ArgoCDProjectRoleBinding object for the oldObj but got %T", oldObj)
+	}
+	if equality.Semantic.DeepEqual(projectRoleBinding.Spec, oldProjectRoleBinding.Spec) {
+		return nil, nil
+	}
+	argocdprojectrolebindinglog.Info("Validation for ArgoCDProjectRoleBinding upon update", "name", projectRoleBinding.GetName())
+	return nil, v.validate(ctx, projectRoleBinding)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type ArgoCDProjectRoleBinding.
+func (v *ArgoCDProjectRoleBindingCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ArgoCDProjectRoleBindingCustomValidator) validate(ctx context.Context, projectRoleBinding *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding) error {
+	if err := validateTenantPolicies(ctx, v.Client, "argocdprojectrolebindings", projectRoleBinding.Namespace, projectRoleBinding.Name, func(policies policy.TenantPolicies) []string {
+		_, violations := policies.AppProjectSubjects(projectRoleBinding.Spec.Subjects)
+		return violations
+	}); err != nil {
+		return err
+	}
+	if v.Escalation == nil {
+		return nil
+	}
+	// Binding an ArgoCDProjectRole not created yet is forbidden, its rules can't be checked
+	projectRole := &rbacoperatorv1alpha1.ArgoCDProjectRole{}
+	roleName := projectRoleBinding.Spec.ArgoCDProjectRoleRef.Name
+	if err := v.Client.Get(ctx, client.ObjectKey{Name: roleName, Namespace: projectRoleBinding.Namespace}, projectRole); err != nil {
+		if apierrors.IsNotFound(err) {
+			return apierrors.NewForbidden(rbacoperatorv1alpha1.GroupVersion.WithResource("argocdprojectrolebindings").GroupResource(),
+				projectRoleBinding.Name, fmt.Errorf("ArgoCDProjectRole %s does not exist, its permissions can't be checked", roleName))
+		}
+		return err
+	}
+	return v.Escalation.validate(ctx, "argocdprojectrolebindings", projectRoleBinding.Namespace, projectRoleBinding.Name, projectRoleRules(projectRole.Spec.Rules))
+}
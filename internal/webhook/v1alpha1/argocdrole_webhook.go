@@ -0,0 +1,117 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+)
+
+var argocdrolelog = logf.Log.WithName("argocdrole-resource")
+
+// SetupArgoCDRoleWebhookWithManager registers the webhook for ArgoCDRole in the manager. Application objects are
+// validated against the Argo CD in argoCDNamespace. Grants are not checked for escalation if escalation is nil.
+func SetupArgoCDRoleWebhookWithManager(mgr ctrl.Manager, argoCDNamespace string, escalation *EscalationCheck) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&rbacoperatorv1alpha1.ArgoCDRole{}).
+		WithDefaulter(&ChangedByDefaulter{}).
+		WithValidator(&ArgoCDRoleCustomValidator{Client: mgr.GetClient(), ArgoCDNamespace: argoCDNamespace, Escalation: escalation}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-rbac-operator-argoproj-labs-io-v1alpha1-argocdrole,mutating=false,failurePolicy=fail,sideEffects=None,groups=rbac-operator.argoproj-labs.io,resources=argocdroles,verbs=create;update,versions=v1alpha1,name=vargocdrole-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ArgoCDRoleCustomValidator rejects ArgoCDRoles with application objects the Argo CD can't match, and ArgoCDRoles
+// granting more than the ArgoCDRBACTenantPolicies of their namespace allow, or more than the requester holds in Argo CD.
+type ArgoCDRoleCustomValidator struct {
+	Client client.Reader
+	// ArgoCDNamespace is the namespace of Argo CD, whose parameters enable applications in any namespace.
+	ArgoCDNamespace string
+	Escalation      *EscalationCheck
+}
+
+var _ webhook.CustomValidator = &ArgoCDRoleCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type ArgoCDRole.
+func (v *ArgoCDRoleCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	role, ok := obj.(*rbacoperatorv1alpha1.ArgoCDRole)
+	if !ok {
+		return nil, fmt.Errorf("expected an ArgoCDRole object but got %T", obj)
+	}
+	argocdrolelog.Info("Validation for ArgoCDRole upon creation", "name", role.GetName())
+	return nil, v.validate(ctx, role)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type ArgoCDRole.
+// Updates not changing the spec, like the finalizer removed by the operator, are always allowed.
+func (v *ArgoCDRoleCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	role, ok := newObj.(*rbacoperatorv1alpha1.ArgoCDRole)
+	if !ok {
+		return nil, fmt.Errorf("expected an ArgoCDRole object for the newObj but got %T", newObj)
+	}
+	oldRole, ok := oldObj.(*rbacoperatorv1alpha1.ArgoCDRole)
+	if !ok {
+		return nil, fmt.Errorf("expected an ArgoCDRole object for the oldObj but got %T", oldObj)
+	}
+	if equality.Semantic.DeepEqual(role.Spec, oldRole.Spec) {
+		return nil, nil
+	}
+	argocdrolelog.Info("Validation for ArgoCDRole upon update", "name", role.GetName())
+	return nil, v.validate(ctx, role)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type ArgoCDRole.
+func (v *ArgoCDRoleCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ArgoCDRoleCustomValidator) validate(ctx context.Context, role *rbacoperatorv1alpha1.ArgoCDRole) error {
+	format, err := policy.LoadObjectFormat(ctx, v.Client, v.ArgoCDNamespace)
+	if err != nil {
+		return err
+	}
+	// The rules are checked as rendered
+	rules, errs := format.GlobalRules(role.Spec.Rules)
+	if len(errs) > 0 {
+		return apierrors.NewInvalid(rbacoperatorv1alpha1.GroupVersion.WithKind("ArgoCDRole").GroupKind(), role.Name, errs)
+	}
+	if err := validateTenantPolicies(ctx, v.Client, "argocdroles", role.Namespace, role.Name, func(policies policy.TenantPolicies) []string {
+		_, violations := policies.GlobalRules(rules)
+		// The rules of a role named like a built-in role are added to the built-in role
+		if role.Name == common.ArgoCDRoleAdmin || role.Name == common.ArgoCDRoleReadOnly {
+			if violation := policies.BuiltInRole(role.Name); violation != "" {
+				violations = append(violations, violation)
+			}
+		}
+		return violations
+	}); err != nil {
+		return err
+	}
+	return v.Escalation.validate(ctx, "argocdroles", role.Namespace, role.Name, rules)
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+func makeTestRole(objects ...string) *rbacoperatorv1alpha1.ArgoCDRole {
+	return &rbacoperatorv1alpha1.ArgoCDRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-role",
+			Namespace: "team-a-dev",
+		},
+		Spec: rbacoperatorv1alpha1.ArgoCDRoleSpec{
+			Rules: []rbacoperatorv1alpha1.GlobalRule{
+				{Resource: "applications", Verbs: []string{"get"}, Objects: objects},
+			},
+		},
+	}
+}
+
+func TestArgoCDRoleCustomValidator_TenantPolicy(t *testing.T) {
+	tenantPolicy := &rbacoperatorv1alpha1.ArgoCDRBACTenantPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-a"},
+		Spec: rbacoperatorv1alpha1.ArgoCDRBACTenantPolicySpec{
+			Namespaces: []string{"team-a-*"},
+			Objects:    []string{"team-a-*/*"},
+		},
+	}
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, rbacoperatorv1alpha1.AddToScheme(scheme))
+	validator := &ArgoCDRoleCustomValidator{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(tenantPolicy).Build()}
+
+	_, err := validator.ValidateCreate(context.TODO(), makeTestRole("team-a-x/*"))
+	assert.NoError(t, err)
+
+	_, err = validator.ValidateCreate(context.TODO(), makeTestRole("*/*"))
+	assert.True(t, apierrors.IsForbidden(err))
+	assert.ErrorContains(t, err, "object */* of applications is not allowed by ArgoCDRBACTenantPolicy team-a")
+
+	// Roles created before the policy can still be updated without changing the spec, e.g. to remove the finalizer
+	oldRole := makeTestRole("*/*")
+	role := oldRole.DeepCopy()
+	role.Finalizers = []string{rbacoperatorv1alpha1.ArgoCDRoleFinalizerName}
+	_, err = validator.ValidateUpdate(context.TODO(), oldRole, role)
+	assert.NoError(t, err)
+
+	role.Spec.Rules[0].Verbs = []string{"*"}
+	_, err = validator.ValidateUpdate(context.TODO(), oldRole, role)
+	assert.True(t, apierrors.IsForbidden(err))
+
+	// Namespaces without policy are not restricted
+	role = makeTestRole("*/*")
+	role.Namespace = "team-b"
+	_, err = validator.ValidateCreate(context.TODO(), role)
+	assert.NoError(t, err)
+
+	// The rules of a role named like a built-in role would be added to the built-in role
+	role = makeTestRole("team-a-x/*")
+	role.Name = "readonly"
+	_, err = validator.ValidateCreate(context.TODO(), role)
+	assert.True(t, apierrors.IsForbidden(err))
+	assert.ErrorContains(t, err, "built-in role readonly is not allowed by ArgoCDRBACTenantPolicy team-a")
+}
+
+func TestArgoCDRoleCustomValidator_ApplicationObjects(t *testing.T) {
+	tenantPolicy := &rbacoperatorv1alpha1.ArgoCDRBACTenantPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-a"},
+		Spec: rbacoperatorv1alpha1.ArgoCDRBACTenantPolicySpec{
+			Namespaces: []string{"team-a-*"},
+			Objects:    []string{"team-a/*", "team-a/*/*"},
+		},
+	}
+	params := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "argocd-cmd-params-cm", Namespace: "argocd"},
+		Data:       map[string]string{"application.namespaces": "team-a-*"},
+	}
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, rbacoperatorv1alpha1.AddToScheme(scheme))
+	validator := &ArgoCDRoleCustomValidator{
+		Client:          fake.NewClientBuilder().WithScheme(scheme).WithObjects(tenantPolicy, params).Build(),
+		ArgoCDNamespace: "argocd",
+	}
+
+	role := makeTestRole()
+	role.Spec.Rules[0].ApplicationObjects = []rbacoperatorv1alpha1.ApplicationObject{{Project: "team-a", Namespace: "team-a-apps", Name: "*"}}
+	_, err := validator.ValidateCreate(context.TODO(), role)
+	assert.NoError(t, err)
+
+	// Namespaces applications can't be created in are invalid
+	role.Spec.Rules[0].ApplicationObjects[0].Namespace = "team-b-apps"
+	_, err = validator.ValidateCreate(context.TODO(), role)
+	assert.True(t, apierrors.IsInvalid(err))
+	assert.ErrorContains(t, err, "spec.rules[0].applicationObjects[0].namespace")
+
+	// Application objects are checked against the tenant policies as rendered
+	role.Spec.Rules[0].ApplicationObjects[0] = rbacoperatorv1alpha1.ApplicationObject{Project: "team-b", Name: "guestbook"}
+	_, err = validator.ValidateCreate(context.TODO(), role)
+	assert.True(t, apierrors.IsForbidden(err))
+	assert.ErrorContains(t, err, "object team-b/guestbook of applications is not allowed by ArgoCDRBACTenantPolicy team-a")
+}
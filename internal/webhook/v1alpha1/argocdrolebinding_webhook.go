@@ -0,0 +1,162 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+)
+
+var argocdrolebindinglog = logf.Log.WithName("argocdrolebinding-resource")
+
+// SetupArgoCDRoleBindingWebhookWithManager registers the webhook for ArgoCDRoleBinding in the manager. Application
+// objects of the bound roles are rendered for the Argo CD in argoCDNamespace. Grants are not checked for escalation
+// if escalation is nil.
+func SetupArgoCDRoleBindingWebhookWithManager(mgr ctrl.Manager, argoCDNamespace string, escalation *EscalationCheck) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&rbacoperatorv1alpha1.ArgoCDRoleBinding{}).
+		WithDefaulter(&ChangedByDefaulter{}).
+		WithValidator(&ArgoCDRoleBindingCustomValidator{Client: mgr.GetClient(), ArgoCDNamespace: argoCDNamespace, Escalation: escalation}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-rbac-operator-argoproj-labs-io-v1alpha1-argocdrolebinding,mutating=false,failurePolicy=fail,sideEffects=None,groups=rbac-operator.argoproj-labs.io,resources=argocdrolebindings,verbs=create;update,versions=v1alpha1,name=vargocdrolebinding-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ArgoCDRoleBindingCustomValidator rejects ArgoCDRoleBindings granting more than the ArgoCDRBACTenantPolicies of their namespace allow,
+// binding a role with permissions the requester does not hold in Argo CD, or with role subjects closing a role cycle.
+type ArgoCDRoleBindingCustomValidator struct {
+	Client client.Reader
+	// ArgoCDNamespace is the namespace of Argo CD, whose parameters enable applications in any namespace.
+	ArgoCDNamespace string
+	Escalation      *EscalationCheck
+}
+
+var _ webhook.CustomValidator = &ArgoCDRoleBindingCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type ArgoCDRoleBinding.
+func (v *ArgoCDRoleBindingCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	roleBinding, ok := obj.(*rbacoperatorv1alpha1.ArgoCDRoleBinding)
+	if !ok {
+		return nil, fmt.Errorf("expected an ArgoCDRoleBinding object but got %T", obj)
+	}
+	argocdrolebindinglog.Info("Validation for ArgoCDRoleBinding upon creation", "name", roleBinding.GetName())
+	return nil, v.validate(ctx, roleBinding)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type ArgoCDRoleBinding.
+// Updates not changing the spec, like the finalizer removed by the operator, are always allowed.
+func (v *ArgoCDRoleBindingCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	roleBinding, ok := newObj.(*rbacoperatorv1alpha1.ArgoCDRoleBinding)
+	if !ok {
+		return nil, fmt.Errorf("expected an ArgoCDRoleBinding object for the newObj but got %T", newObj)
+	}
+	oldRoleBinding, ok := oldObj.(*rbacoperatorv1alpha1.ArgoCDRoleBinding)
+	if !ok {
+		return nil, fmt.Errorf("expected an ArgoCDRoleBinding object for the oldObj but got %T", oldObj)
+	}
+	if equality.Semantic.DeepEqual(roleBinding.Spec, oldRoleBinding.Spec) {
+		return nil, nil
+	}
+	argocdrolebindinglog.Info("Validation for ArgoCDRoleBinding upon update", "name", roleBinding.GetName())
+	return nil, v.validate(ctx, roleBinding)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type ArgoCDRoleBinding.
+func (v *ArgoCDRoleBindingCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ArgoCDRoleBindingCustomValidator) validate(ctx context.Context, roleBinding *rbacoperatorv1alpha1.ArgoCDRoleBinding) error {
+	if err := validateTenantPolicies(ctx, v.Client, "argocdrolebindings", roleBinding.Namespace, roleBinding.Name, func(policies policy.TenantPolicies) []string {
+		_, violations := policies.GlobalSubjects(roleBinding.Spec.Subjects)
+		if roleName := roleBinding.Spec.ArgoCDRoleRef.Name; roleName == common.ArgoCDRoleAdmin || roleName == common.ArgoCDRoleReadOnly {
+			if violation := policies.BuiltInRole(roleName); violation != "" {
+				violations = append(violations, violation)
+			}
+		}
+		return violations
+	}); err != nil {
+		return err
+	}
+	if err := v.validateRoleGraph(ctx, roleBinding); err != nil {
+		return err
+	}
+	if v.Escalation == nil {
+		return nil
+	}
+	rules, err := v.roleRules(ctx, roleBinding)
+	if err != nil {
+		return err
+	}
+	return v.Escalation.validate(ctx, "argocdrolebindings", roleBinding.Namespace, roleBinding.Name, rules)
+}
+
+// validateRoleGraph returns a Forbidden error if role subjects of the binding close a cycle with the other ArgoCDRoleBindings.
+// Dangling and unreachable roles are allowed, the roles and bindings may be created later.
+func (v *ArgoCDRoleBindingCustomValidator) validateRoleGraph(ctx context.Context, roleBinding *rbacoperatorv1alpha1.ArgoCDRoleBinding) error {
+	graph, err := policy.LoadRoleGraph(ctx, v.Client)
+	if err != nil {
+		return err
+	}
+	cycles := []string{}
+	for _, cycle := range graph.WithChange(roleBinding).Analyze(roleBinding).Cycles {
+		cycles = append(cycles, fmt.Sprintf("spec.subjects[%d] closes the role cycle %s", cycle.Subject, cycle))
+	}
+	if len(cycles) > 0 {
+		return apierrors.NewForbidden(rbacoperatorv1alpha1.GroupVersion.WithResource("argocdrolebindings").GroupResource(),
+			roleBinding.Name, fmt.Errorf("%s", strings.Join(cycles, "; ")))
+	}
+	return nil
+}
+
+// roleRules returns the rules of the role referenced by the binding, with their application objects rendered as they
+// are granted. Binding an ArgoCDRole not created yet is forbidden, its rules can't be checked and whoever creates it
+// later is not checked for the subjects of the binding.
+func (v *ArgoCDRoleBindingCustomValidator) roleRules(ctx context.Context, roleBinding *rbacoperatorv1alpha1.ArgoCDRoleBinding) ([]rbacoperatorv1alpha1.GlobalRule, error) {
+	roleName := roleBinding.Spec.ArgoCDRoleRef.Name
+	if roleName == common.ArgoCDRoleAdmin || roleName == common.ArgoCDRoleReadOnly {
+		return policy.BuiltInRoleRules(roleName)
+	}
+	role := &rbacoperatorv1alpha1.ArgoCDRole{}
+	if err := v.Client.Get(ctx, client.ObjectKey{Name: roleName, Namespace: roleBinding.Namespace}, role); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, apierrors.NewForbidden(rbacoperatorv1alpha1.GroupVersion.WithResource("argocdrolebindings").GroupResource(),
+				roleBinding.Name, fmt.Errorf("ArgoCDRole %s does not exist, its permissions can't be checked", roleName))
+		}
+		return nil, err
+	}
+	format, err := policy.LoadObjectFormat(ctx, v.Client, v.ArgoCDNamespace)
+	if err != nil {
+		return nil, err
+	}
+	// Application objects that can't be rendered are not granted either
+	rules, _ := format.GlobalRules(role.Spec.Rules)
+	return rules, nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+)
+
+func makeTestRoleBinding(name, role string, subjects ...rbacoperatorv1alpha1.GlobalSubject) *rbacoperatorv1alpha1.ArgoCDRoleBinding {
+	return &rbacoperatorv1alpha1.ArgoCDRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a-dev"},
+		Spec: rbacoperatorv1alpha1.ArgoCDRoleBindingSpec{
+			ArgoCDRoleRef: rbacoperatorv1alpha1.ArgoCDRoleRef{Name: role},
+			Subjects:      subjects,
+		},
+	}
+}
+
+func TestArgoCDRoleBindingCustomValidator_RoleCycle(t *testing.T) {
+	viewer := makeTestRoleBinding("viewer", "viewer", rbacoperatorv1alpha1.GlobalSubject{Kind: "role", Name: "dev"})
+	scheme := runtime.NewScheme()
+	assert.NoError(t, rbacoperatorv1alpha1.AddToScheme(scheme))
+	validator := &ArgoCDRoleBindingCustomValidator{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(viewer).Build()}
+
+	// Dangling roles may be created later
+	oldDev := makeTestRoleBinding("dev", "dev", rbacoperatorv1alpha1.GlobalSubject{Kind: "sso", Name: "team-a"})
+	_, err := validator.ValidateCreate(context.TODO(), oldDev)
+	assert.NoError(t, err)
+
+	dev := oldDev.DeepCopy()
+	dev.Spec.Subjects = append(dev.Spec.Subjects, rbacoperatorv1alpha1.GlobalSubject{Kind: "role", Name: "viewer"})
+	_, err = validator.ValidateUpdate(context.TODO(), oldDev, dev)
+	assert.True(t, apierrors.IsForbidden(err))
+	assert.ErrorContains(t, err, "spec.subjects[1] closes the role cycle role:viewer -> role:dev -> role:viewer")
+
+	// The binding replaces its stored version in the graph
+	_, err = validator.ValidateUpdate(context.TODO(), viewer, makeTestRoleBinding("viewer", "viewer", rbacoperatorv1alpha1.GlobalSubject{Kind: "role", Name: "admin"}))
+	assert.NoError(t, err)
+}
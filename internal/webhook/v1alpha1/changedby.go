@@ -0,0 +1,120 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+var changedbylog = logf.Log.WithName("changedby-resource")
+
+// +kubebuilder:webhook:path=/mutate-rbac-operator-argoproj-labs-io-v1alpha1-argocdrole,mutating=true,failurePolicy=fail,sideEffects=None,groups=rbac-operator.argoproj-labs.io,resources=argocdroles,verbs=create;update,versions=v1alpha1,name=margocdrole-v1alpha1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-rbac-operator-argoproj-labs-io-v1alpha1-argocdrolebinding,mutating=true,failurePolicy=fail,sideEffects=None,groups=rbac-operator.argoproj-labs.io,resources=argocdrolebindings,verbs=create;update,versions=v1alpha1,name=margocdrolebinding-v1alpha1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-rbac-operator-argoproj-labs-io-v1alpha1-argocdprojectrole,mutating=true,failurePolicy=fail,sideEffects=None,groups=rbac-operator.argoproj-labs.io,resources=argocdprojectroles,verbs=create;update,versions=v1alpha1,name=margocdprojectrole-v1alpha1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-rbac-operator-argoproj-labs-io-v1alpha1-argocdprojectrolebinding,mutating=true,failurePolicy=fail,sideEffects=None,groups=rbac-operator.argoproj-labs.io,resources=argocdprojectrolebindings,verbs=create;update,versions=v1alpha1,name=margocdprojectrolebinding-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ChangedByDefaulter records the user creating or changing the spec or the require-approval label of a role or binding
+// in its changed-by annotation, so it can't be forged. The annotation is kept by updates not changing them. The user
+// attesting a binding and the user approving a generation of a role are recorded the same way in the attested-by and
+// approved-generation-by annotations. A change drops the approval given before it.
+type ChangedByDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &ChangedByDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the roles and bindings.
+func (d *ChangedByDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	object, ok := obj.(client.Object)
+	if !ok {
+		return fmt.Errorf("expected a Kubernetes object but got %T", obj)
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	annotations := object.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	defer object.SetAnnotations(annotations)
+
+	oldObject := &unstructured.Unstructured{}
+	if req.Operation == admissionv1.Update {
+		if err := json.Unmarshal(req.OldObject.Raw, &oldObject.Object); err != nil {
+			return err
+		}
+	}
+	recordAnnotationUser(annotations, oldObject.GetAnnotations(), common.AnnotationAttestedAt, common.AnnotationAttestedBy, req.UserInfo.Username)
+
+	if req.Operation == admissionv1.Update {
+		newObject, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		// Adding or removing the require-approval label is a change of the spec that is rendered
+		if equality.Semantic.DeepEqual(oldObject.Object["spec"], newObject["spec"]) &&
+			oldObject.GetLabels()[common.LabelRequireApproval] == object.GetLabels()[common.LabelRequireApproval] {
+			if changedBy, ok := oldObject.GetAnnotations()[common.AnnotationChangedBy]; ok {
+				annotations[common.AnnotationChangedBy] = changedBy
+			} else {
+				delete(annotations, common.AnnotationChangedBy)
+			}
+			recordAnnotationUser(annotations, oldObject.GetAnnotations(), common.AnnotationApprovedGeneration, common.AnnotationApprovedGenerationBy, req.UserInfo.Username)
+			return nil
+		}
+		// An approval given before the change does not approve it, the change needs a new one
+		if approved, ok := annotations[common.AnnotationApprovedGeneration]; ok && approved == oldObject.GetAnnotations()[common.AnnotationApprovedGeneration] {
+			delete(annotations, common.AnnotationApprovedGeneration)
+		}
+	}
+	changedbylog.Info("Recording the user changing the spec", "name", object.GetName(), "user", req.UserInfo.Username)
+	annotations[common.AnnotationChangedBy] = req.UserInfo.Username
+	recordAnnotationUser(annotations, oldObject.GetAnnotations(), common.AnnotationApprovedGeneration, common.AnnotationApprovedGenerationBy, req.UserInfo.Username)
+	return nil
+}
+
+// recordAnnotationUser sets the userKey annotation to the user if the key annotation is set or changed, keeps its old
+// value if the key annotation is unchanged and removes it if the key annotation is removed, so it can't be forged.
+func recordAnnotationUser(annotations, oldAnnotations map[string]string, key, userKey, user string) {
+	value, ok := annotations[key]
+	if !ok {
+		delete(annotations, userKey)
+		return
+	}
+	if oldValue, wasSet := oldAnnotations[key]; !wasSet || value != oldValue {
+		changedbylog.Info("Recording the user setting an annotation", "annotation", key, "user", user)
+		annotations[userKey] = user
+		return
+	}
+	if oldUser, ok := oldAnnotations[userKey]; ok {
+		annotations[userKey] = oldUser
+	} else {
+		delete(annotations, userKey)
+	}
+}
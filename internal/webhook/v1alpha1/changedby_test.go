@@ -0,0 +1,126 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/controller/common"
+)
+
+func TestChangedByDefaulter_Default(t *testing.T) {
+	defaulter := &ChangedByDefaulter{}
+
+	// The creator is recorded, whatever the annotation says
+	role := makeTestRole("*/*")
+	role.Annotations = map[string]string{common.AnnotationChangedBy: "someone-else"}
+	ctx := makeTestAdmissionContext(t, admissionv1.Create, "alice", nil, nil)
+	assert.NoError(t, defaulter.Default(ctx, role))
+	assert.Equal(t, "alice", role.Annotations[common.AnnotationChangedBy])
+
+	// Updates not changing the spec keep the annotation of the old object
+	oldRole := role.DeepCopy()
+	role.Annotations[common.AnnotationChangedBy] = "someone-else"
+	role.Labels = map[string]string{"team": "a"}
+	ctx = makeTestAdmissionContext(t, admissionv1.Update, "bob", nil, oldRole)
+	assert.NoError(t, defaulter.Default(ctx, role))
+	assert.Equal(t, "alice", role.Annotations[common.AnnotationChangedBy])
+
+	// The annotation can't be added without changing the spec
+	delete(oldRole.Annotations, common.AnnotationChangedBy)
+	ctx = makeTestAdmissionContext(t, admissionv1.Update, "bob", nil, oldRole)
+	assert.NoError(t, defaulter.Default(ctx, role))
+	assert.NotContains(t, role.Annotations, common.AnnotationChangedBy)
+
+	// Updates changing the spec record the user
+	role.Spec.Rules[0].Verbs = []string{"get", "sync"}
+	ctx = makeTestAdmissionContext(t, admissionv1.Update, "bob", nil, oldRole)
+	assert.NoError(t, defaulter.Default(ctx, role))
+	assert.Equal(t, "bob", role.Annotations[common.AnnotationChangedBy])
+}
+
+func TestChangedByDefaulter_AttestedBy(t *testing.T) {
+	defaulter := &ChangedByDefaulter{}
+
+	// The attester is recorded, whatever the annotation says
+	rb := makeTestRoleBinding("test-rolebinding", "test-role")
+	rb.Annotations = map[string]string{
+		common.AnnotationAttestedAt: "2024-01-01T00:00:00Z",
+		common.AnnotationAttestedBy: "someone-else",
+	}
+	ctx := makeTestAdmissionContext(t, admissionv1.Create, "alice", nil, nil)
+	assert.NoError(t, defaulter.Default(ctx, rb))
+	assert.Equal(t, "alice", rb.Annotations[common.AnnotationAttestedBy])
+
+	// Updates not changing the attestation keep the attester of the old object
+	oldRB := rb.DeepCopy()
+	rb.Annotations[common.AnnotationAttestedBy] = "someone-else"
+	ctx = makeTestAdmissionContext(t, admissionv1.Update, "bob", nil, oldRB)
+	assert.NoError(t, defaulter.Default(ctx, rb))
+	assert.Equal(t, "alice", rb.Annotations[common.AnnotationAttestedBy])
+
+	// Changing the attestation records the user
+	rb.Annotations[common.AnnotationAttestedAt] = "2024-02-01T00:00:00Z"
+	ctx = makeTestAdmissionContext(t, admissionv1.Update, "bob", nil, oldRB)
+	assert.NoError(t, defaulter.Default(ctx, rb))
+	assert.Equal(t, "bob", rb.Annotations[common.AnnotationAttestedBy])
+
+	// The attester can't be set without an attestation
+	delete(rb.Annotations, common.AnnotationAttestedAt)
+	ctx = makeTestAdmissionContext(t, admissionv1.Update, "bob", nil, oldRB)
+	assert.NoError(t, defaulter.Default(ctx, rb))
+	assert.NotContains(t, rb.Annotations, common.AnnotationAttestedBy)
+}
+
+func TestChangedByDefaulter_ApprovedGenerationBy(t *testing.T) {
+	defaulter := &ChangedByDefaulter{}
+
+	role := makeTestRole("*/*")
+	role.Labels = map[string]string{common.LabelRequireApproval: "true"}
+	ctx := makeTestAdmissionContext(t, admissionv1.Create, "alice", nil, nil)
+	assert.NoError(t, defaulter.Default(ctx, role))
+
+	// The approver is recorded, whatever the annotation says
+	oldRole := role.DeepCopy()
+	role.Annotations[common.AnnotationApprovedGeneration] = "1"
+	role.Annotations[common.AnnotationApprovedGenerationBy] = "someone-else"
+	ctx = makeTestAdmissionContext(t, admissionv1.Update, "bob", nil, oldRole)
+	assert.NoError(t, defaulter.Default(ctx, role))
+	assert.Equal(t, "bob", role.Annotations[common.AnnotationApprovedGenerationBy])
+	assert.Equal(t, "alice", role.Annotations[common.AnnotationChangedBy])
+
+	// Removing the label is a change, it drops the approval given before it
+	oldRole = role.DeepCopy()
+	delete(role.Labels, common.LabelRequireApproval)
+	ctx = makeTestAdmissionContext(t, admissionv1.Update, "alice", nil, oldRole)
+	assert.NoError(t, defaulter.Default(ctx, role))
+	assert.Equal(t, "alice", role.Annotations[common.AnnotationChangedBy])
+	assert.NotContains(t, role.Annotations, common.AnnotationApprovedGeneration)
+	assert.NotContains(t, role.Annotations, common.AnnotationApprovedGenerationBy)
+
+	// Approving a change together with making it records the editor as approver
+	oldRole = role.DeepCopy()
+	role.Spec.Rules[0].Verbs = []string{"get", "sync"}
+	role.Annotations[common.AnnotationApprovedGeneration] = "2"
+	ctx = makeTestAdmissionContext(t, admissionv1.Update, "carol", nil, oldRole)
+	assert.NoError(t, defaulter.Default(ctx, role))
+	assert.Equal(t, "carol", role.Annotations[common.AnnotationChangedBy])
+	assert.Equal(t, "carol", role.Annotations[common.AnnotationApprovedGenerationBy])
+}
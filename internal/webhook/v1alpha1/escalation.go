@@ -0,0 +1,120 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+)
+
+// verbEscalate is the Kubernetes verb on argocdroles, argocdrolebindings, argocdprojectroles and argocdprojectrolebindings
+// allowing to grant permissions the requester does not hold in Argo CD.
+const verbEscalate = "escalate"
+
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// EscalationCheck rejects grants of Argo CD permissions the requesting Kubernetes user does not hold itself.
+// A nil EscalationCheck allows all grants.
+type EscalationCheck struct {
+	Client                       client.Client
+	ArgoCDRBACConfigMapName      string
+	ArgoCDRBACConfigMapNamespace string
+	// Mapping maps the requesting Kubernetes user to its Argo CD subjects.
+	Mapping policy.SubjectMapping
+}
+
+// validate returns a Forbidden error if the requester of the admission request in ctx does not hold
+// all permissions granted by the rules, unless it may escalate on the resource.
+func (c *EscalationCheck) validate(ctx context.Context, resource, namespace, name string, rules []rbacoperatorv1alpha1.GlobalRule) error {
+	if c == nil || len(rules) == 0 {
+		return nil
+	}
+	// Checked before the escalate verb, the lines they would add are beyond the rules
+	if violations := policy.SplittingRules(rules); len(violations) > 0 {
+		return apierrors.NewForbidden(rbacoperatorv1alpha1.GroupVersion.WithResource(resource).GroupResource(), name,
+			fmt.Errorf("%s", strings.Join(violations, "; ")))
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	allowed, err := c.canEscalate(ctx, req, resource, namespace)
+	if err != nil || allowed {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := c.Client.Get(ctx, client.ObjectKey{Name: c.ArgoCDRBACConfigMapName, Namespace: c.ArgoCDRBACConfigMapNamespace}, cm); err != nil {
+		return err
+	}
+	enforcer, err := policy.NewArgoCDEnforcer(cm.Data)
+	if err != nil {
+		return err
+	}
+	subjects := c.Mapping.Subjects(req.UserInfo.Username, req.UserInfo.Groups)
+	if violations := policy.EscalatingRules(enforcer, subjects, rules); len(violations) > 0 {
+		return apierrors.NewForbidden(rbacoperatorv1alpha1.GroupVersion.WithResource(resource).GroupResource(), name,
+			fmt.Errorf("%s; the %s verb on %s is required to grant them", strings.Join(violations, "; "), verbEscalate, resource))
+	}
+	return nil
+}
+
+// projectRoleRules returns the rules of an ArgoCDProjectRole as global rules, so they are checked as the policy lines
+// they are rendered to in the AppProjects.
+func projectRoleRules(rules []rbacoperatorv1alpha1.ProjectRule) []rbacoperatorv1alpha1.GlobalRule {
+	globalRules := []rbacoperatorv1alpha1.GlobalRule{}
+	for _, rule := range rules {
+		globalRules = append(globalRules, rbacoperatorv1alpha1.GlobalRule{Resource: rule.Resource, Verbs: rule.Verbs, Objects: rule.Objects})
+	}
+	return globalRules
+}
+
+// canEscalate returns true if the requester is allowed the escalate verb on the resource in the namespace.
+func (c *EscalationCheck) canEscalate(ctx context.Context, req admission.Request, resource, namespace string) (bool, error) {
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, values := range req.UserInfo.Extra {
+		extra[key] = authorizationv1.ExtraValue(values)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   req.UserInfo.Username,
+			Groups: req.UserInfo.Groups,
+			UID:    req.UserInfo.UID,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      verbEscalate,
+				Group:     rbacoperatorv1alpha1.GroupVersion.Group,
+				Resource:  resource,
+			},
+		},
+	}
+	if err := c.Client.Create(ctx, review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+)
+
+// makeTestEscalationCheck returns an EscalationCheck allowing the escalate verb to the given Kubernetes users.
+func makeTestEscalationCheck(t *testing.T, escalators ...string) *EscalationCheck {
+	rbacCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "argocd-rbac-cm", Namespace: "argocd"},
+		Data: map[string]string{
+			"policy.csv": "p, role:team-a, applications, *, team-a/*, allow\n" +
+				"g, team-a, role:team-a\n",
+		},
+	}
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, rbacoperatorv1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(rbacCM).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if review, ok := obj.(*authorizationv1.SubjectAccessReview); ok {
+				review.Status.Allowed = review.Spec.ResourceAttributes.Verb == verbEscalate && slices.Contains(escalators, review.Spec.User)
+				return nil
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	}).Build()
+	return &EscalationCheck{
+		Client:                       c,
+		ArgoCDRBACConfigMapName:      "argocd-rbac-cm",
+		ArgoCDRBACConfigMapNamespace: "argocd",
+		Mapping:                      policy.SubjectMapping{UserPrefix: "oidc:", GroupPrefix: "oidc:"},
+	}
+}
+
+func TestArgoCDRoleCustomValidator_Escalation(t *testing.T) {
+	escalation := makeTestEscalationCheck(t, "admin")
+	validator := &ArgoCDRoleCustomValidator{Client: escalation.Client, Escalation: escalation}
+
+	_, err := validator.ValidateCreate(makeTestAdmissionContext(t, admissionv1.Create, "oidc:alice", []string{"oidc:team-a"}, nil), makeTestRole("team-a/*"))
+	assert.NoError(t, err)
+
+	_, err = validator.ValidateCreate(makeTestAdmissionContext(t, admissionv1.Create, "oidc:alice", []string{"oidc:team-a"}, nil), makeTestRole("team-b/*"))
+	assert.True(t, apierrors.IsForbidden(err))
+	assert.ErrorContains(t, err, "applications get team-b/* is not held by the requester")
+
+	// Groups without the prefix are not mapped
+	_, err = validator.ValidateCreate(makeTestAdmissionContext(t, admissionv1.Create, "oidc:alice", []string{"team-a"}, nil), makeTestRole("team-a/*"))
+	assert.True(t, apierrors.IsForbidden(err))
+
+	// The escalate verb bypasses the check
+	_, err = validator.ValidateCreate(makeTestAdmissionContext(t, admissionv1.Create, "admin", nil, nil), makeTestRole("*/*"))
+	assert.NoError(t, err)
+
+	// But not of objects adding policy lines
+	_, err = validator.ValidateCreate(makeTestAdmissionContext(t, admissionv1.Create, "admin", nil, nil), makeTestRole("team-a/x, allow\ng, alice, role:admin"))
+	assert.True(t, apierrors.IsForbidden(err))
+	assert.ErrorContains(t, err, "must not contain commas or line breaks")
+}
+
+func TestArgoCDRoleBindingCustomValidator_Escalation(t *testing.T) {
+	escalation := makeTestEscalationCheck(t)
+	assert.NoError(t, escalation.Client.Create(context.TODO(), makeTestRole("team-a/*")))
+	validator := &ArgoCDRoleBindingCustomValidator{Client: escalation.Client, Escalation: escalation}
+
+	makeBinding := func(roleName string) *rbacoperatorv1alpha1.ArgoCDRoleBinding {
+		return &rbacoperatorv1alpha1.ArgoCDRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-binding", Namespace: "team-a-dev"},
+			Spec: rbacoperatorv1alpha1.ArgoCDRoleBindingSpec{
+				ArgoCDRoleRef: rbacoperatorv1alpha1.ArgoCDRoleRef{Name: roleName},
+				Subjects:      []rbacoperatorv1alpha1.GlobalSubject{{Kind: "sso", Name: "bob"}},
+			},
+		}
+	}
+
+	_, err := validator.ValidateCreate(makeTestAdmissionContext(t, admissionv1.Create, "oidc:alice", []string{"oidc:team-a"}, nil), makeBinding("test-role"))
+	assert.NoError(t, err)
+
+	_, err = validator.ValidateCreate(makeTestAdmissionContext(t, admissionv1.Create, "oidc:carol", nil, nil), makeBinding("test-role"))
+	assert.True(t, apierrors.IsForbidden(err))
+
+	_, err = validator.ValidateCreate(makeTestAdmissionContext(t, admissionv1.Create, "oidc:alice", []string{"oidc:team-a"}, nil), makeBinding("admin"))
+	assert.True(t, apierrors.IsForbidden(err))
+
+	// A role created later can't be checked for the subjects of the binding
+	_, err = validator.ValidateCreate(makeTestAdmissionContext(t, admissionv1.Create, "oidc:alice", []string{"oidc:team-a"}, nil), makeBinding("missing-role"))
+	assert.True(t, apierrors.IsForbidden(err))
+	assert.ErrorContains(t, err, "ArgoCDRole missing-role does not exist")
+
+	// Application objects are checked as they are rendered
+	appObjectsRole := makeTestRole()
+	appObjectsRole.Name = "app-objects-role"
+	appObjectsRole.Spec.Rules[0].ApplicationObjects = []rbacoperatorv1alpha1.ApplicationObject{{Project: "*", Namespace: "*", Name: "*"}}
+	assert.NoError(t, escalation.Client.Create(context.TODO(), appObjectsRole))
+	_, err = validator.ValidateCreate(makeTestAdmissionContext(t, admissionv1.Create, "oidc:alice", []string{"oidc:team-a"}, nil), makeBinding("app-objects-role"))
+	assert.True(t, apierrors.IsForbidden(err))
+	assert.ErrorContains(t, err, "applications get */* is not held by the requester")
+}
+
+func makeTestProjectRole(objects ...string) *rbacoperatorv1alpha1.ArgoCDProjectRole {
+	return &rbacoperatorv1alpha1.ArgoCDProjectRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-project-role", Namespace: "team-a-dev"},
+		Spec: rbacoperatorv1alpha1.ArgoCDProjectRoleSpec{
+			Rules: []rbacoperatorv1alpha1.ProjectRule{{Resource: "applications", Verbs: []string{"get"}, Objects: objects}},
+		},
+	}
+}
+
+func TestArgoCDProjectRoleCustomValidator_Escalation(t *testing.T) {
+	escalation := makeTestEscalationCheck(t, "admin")
+	validator := &ArgoCDProjectRoleCustomValidator{Client: escalation.Client, Escalation: escalation}
+
+	_, err := validator.ValidateCreate(makeTestAdmissionContext(t, admissionv1.Create, "oidc:alice", []string{"oidc:team-a"}, nil), makeTestProjectRole("team-a/*"))
+	assert.NoError(t, err)
+
+	_, err = validator.ValidateCreate(makeTestAdmissionContext(t, admissionv1.Create, "oidc:alice", []string{"oidc:team-a"}, nil), makeTestProjectRole("*/*"))
+	assert.True(t, apierrors.IsForbidden(err))
+	assert.ErrorContains(t, err, "applications get */* is not held by the requester")
+
+	_, err = validator.ValidateCreate(makeTestAdmissionContext(t, admissionv1.Create, "admin", nil, nil), makeTestProjectRole("*/*"))
+	assert.NoError(t, err)
+}
+
+func TestArgoCDProjectRoleBindingCustomValidator_Escalation(t *testing.T) {
+	escalation := makeTestEscalationCheck(t)
+	assert.NoError(t, escalation.Client.Create(context.TODO(), makeTestProjectRole("team-a/*")))
+	validator := &ArgoCDProjectRoleBindingCustomValidator{Client: escalation.Client, Escalation: escalation}
+
+	makeBinding := func(roleName string) *rbacoperatorv1alpha1.ArgoCDProjectRoleBinding {
+		return &rbacoperatorv1alpha1.ArgoCDProjectRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-binding", Namespace: "team-a-dev"},
+			Spec: rbacoperatorv1alpha1.ArgoCDProjectRoleBindingSpec{
+				ArgoCDProjectRoleRef: rbacoperatorv1alpha1.ArgoCDProjectRoleRef{Name: roleName},
+				Subjects:             []rbacoperatorv1alpha1.AppProjectSubject{{AppProjectRef: "team-a", Groups: []string{"team-a-devs"}}},
+			},
+		}
+	}
+
+	_, err := validator.ValidateCreate(makeTestAdmissionContext(t, admissionv1.Create, "oidc:alice", []string{"oidc:team-a"}, nil), makeBinding("test-project-role"))
+	assert.NoError(t, err)
+
+	_, err = validator.ValidateCreate(makeTestAdmissionContext(t, admissionv1.Create, "oidc:carol", nil, nil), makeBinding("test-project-role"))
+	assert.True(t, apierrors.IsForbidden(err))
+
+	_, err = validator.ValidateCreate(makeTestAdmissionContext(t, admissionv1.Create, "oidc:alice", []string{"oidc:team-a"}, nil), makeBinding("missing-role"))
+	assert.True(t, apierrors.IsForbidden(err))
+	assert.ErrorContains(t, err, "ArgoCDProjectRole missing-role does not exist")
+}
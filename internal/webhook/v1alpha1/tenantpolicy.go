@@ -0,0 +1,46 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rbacoperatorv1alpha1 "github.com/argoproj-labs/argocd-rbac-operator/api/v1alpha1"
+	"github.com/argoproj-labs/argocd-rbac-operator/internal/policy"
+)
+
+// validateTenantPolicies returns a Forbidden error listing the violations of the ArgoCDRBACTenantPolicies
+// applying to the namespace, as reported by check.
+func validateTenantPolicies(ctx context.Context, c client.Reader, resource, namespace, name string, check func(policy.TenantPolicies) []string) error {
+	policies, err := policy.LoadTenantPolicies(ctx, c, namespace)
+	if err != nil {
+		return err
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+	if violations := check(policies); len(violations) > 0 {
+		return apierrors.NewForbidden(rbacoperatorv1alpha1.GroupVersion.WithResource(resource).GroupResource(),
+			name, fmt.Errorf("%s", strings.Join(violations, "; ")))
+	}
+	return nil
+}